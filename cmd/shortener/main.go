@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -15,9 +17,21 @@ import (
 	"github.com/dtroode/urlshorter/internal/logger"
 	"github.com/dtroode/urlshorter/internal/router"
 	"github.com/dtroode/urlshorter/internal/service"
+	"github.com/dtroode/urlshorter/internal/service/cache"
+	"github.com/dtroode/urlshorter/internal/service/fallback"
+	"github.com/dtroode/urlshorter/internal/service/keygen"
+	"github.com/dtroode/urlshorter/internal/service/workerpool"
 	"github.com/dtroode/urlshorter/internal/storage"
 	"github.com/dtroode/urlshorter/internal/storage/inmemory"
 	"github.com/dtroode/urlshorter/internal/storage/postgres"
+	"github.com/dtroode/urlshorter/internal/storage/tiered"
+)
+
+// Storage modes selected with config.StorageMode.
+const (
+	storageModeFile     = "file"
+	storageModePostgres = "postgres"
+	storageModeTiered   = "tiered"
 )
 
 var (
@@ -37,20 +51,96 @@ func main() {
 
 	logger := logger.NewLog(config.LogLevel)
 
+	compactInterval, err := time.ParseDuration(config.CompactInterval)
+	if err != nil {
+		logger.Fatal("failed to parse compact interval", "error", err)
+	}
+
+	storageMode := config.StorageMode
+	if storageMode == "" {
+		storageMode = storageModeFile
+		if config.DatabaseDSN != "" {
+			storageMode = storageModePostgres
+		}
+	}
+
 	var urlStorage storage.Storage
-	dsn := config.DatabaseDSN
-	if dsn != "" {
-		urlStorage, err = postgres.NewStorage(dsn)
+	var databaseStorage *postgres.Storage
+	switch storageMode {
+	case storageModePostgres, storageModeTiered:
+		if config.DatabaseDSN == "" {
+			logger.Fatal("database dsn is required", "storage_mode", storageMode)
+		}
+
+		var replicaDSNs []string
+		if config.ReplicaDSNs != "" {
+			replicaDSNs = strings.Split(config.ReplicaDSNs, ",")
+		}
+		replicaCheckInterval, err := time.ParseDuration(config.ReplicaCheckInterval)
+		if err != nil {
+			logger.Fatal("failed to parse replica check interval", "error", err)
+		}
+
+		databaseStorage, err = postgres.NewStorage(config.DatabaseDSN, postgres.WithReplicas(replicaDSNs, replicaCheckInterval))
 		if err != nil {
 			logger.Fatal("failed to create database storage", "error", err)
 		}
-		logger.Debug("using database storage")
-	} else {
-		urlStorage, err = inmemory.NewStorage(config.FileStoragePath)
+		urlStorage = databaseStorage
+		logger.Debug("using database storage", "replicas", len(replicaDSNs))
+
+		if storageMode == storageModeTiered {
+			flushInterval, err := time.ParseDuration(config.TieredFlushInterval)
+			if err != nil {
+				logger.Fatal("failed to parse tiered flush interval", "error", err)
+			}
+
+			flushPool := workerpool.NewPool(config.ConcurrencyLimit, config.QueueSize)
+			flushPool.Start()
+
+			journalFilename := config.FileStoragePath + ".journal"
+			tieredStorage, err := tiered.NewStorage(databaseStorage, journalFilename, flushPool, logger, tiered.WithFlush(config.TieredBatchSize, flushInterval))
+			if err != nil {
+				logger.Fatal("failed to create tiered storage", "error", err, "journal", journalFilename)
+			}
+			go tieredStorage.Run(ctx)
+			urlStorage = tieredStorage
+			logger.Debug("using tiered storage", "journal", journalFilename, "batch_size", config.TieredBatchSize, "flush_interval", flushInterval)
+		}
+	case storageModeFile:
+		syncPolicy, err := inmemory.ParseSyncPolicy(config.FileSync)
+		if err != nil {
+			logger.Fatal("failed to parse file sync policy", "error", err)
+		}
+		syncInterval, err := time.ParseDuration(config.FileSyncInterval)
 		if err != nil {
-			logger.Fatal("failed to create inmemory storage", "error", err, "file", config.FileStoragePath)
+			logger.Fatal("failed to parse file sync interval", "error", err)
 		}
-		logger.Debug("using inmemory storage")
+
+		syncOption := inmemory.WithSync(syncPolicy, syncInterval)
+
+		if config.FileStorageShards > 0 {
+			shardedStorage, err := inmemory.NewShardedStorage(config.FileStoragePath, config.FileStorageShards, syncOption)
+			if err != nil {
+				logger.Fatal("failed to create sharded inmemory storage", "error", err, "file", config.FileStoragePath)
+			}
+			if compactInterval > 0 {
+				go shardedStorage.RunCompaction(ctx, compactInterval, logger)
+			}
+			urlStorage = shardedStorage
+			logger.Debug("using sharded inmemory storage", "shards", config.FileStorageShards)
+		} else {
+			memoryStorage, err := inmemory.NewStorage(config.FileStoragePath, syncOption)
+			if err != nil {
+				logger.Fatal("failed to create inmemory storage", "error", err, "file", config.FileStoragePath)
+			}
+			if compactInterval > 0 {
+				go memoryStorage.RunCompaction(ctx, compactInterval, logger)
+			}
+			urlStorage = memoryStorage
+			logger.Debug("using inmemory storage")
+		}
+	default:
+		logger.Fatal("unknown storage mode", "storage_mode", storageMode)
 	}
 	defer func() {
 		if err := urlStorage.Close(); err != nil {
@@ -58,11 +148,102 @@ func main() {
 		}
 	}()
 
-	urlService := service.NewURL(config.BaseURL, config.ShortKeyLength, config.ConcurrencyLimit, config.QueueSize, urlStorage)
-	healthService := service.NewHealth(urlStorage)
+	var keySequence keygen.Sequence
+	if config.KeyGenerator == keygen.KindHashids {
+		if databaseStorage != nil {
+			keySequence = databaseStorage.KeySequence()
+		} else {
+			keySequenceFilename := config.FileStoragePath + ".keyseq"
+			keySequence, err = keygen.NewFileSequence(keySequenceFilename, keygen.DefaultSequenceBlockSize)
+			if err != nil {
+				logger.Fatal("failed to create key sequence", "error", err, "file", keySequenceFilename)
+			}
+		}
+	}
+
+	keyGenerator, err := service.NewKeyGenerator(config.KeyGenerator, config.KeyGeneratorSalt, keySequence)
+	if err != nil {
+		logger.Fatal("failed to create key generator", "error", err)
+	}
+
+	dedupeScope, err := storage.ParseDedupeScope(config.DedupeScope)
+	if err != nil {
+		logger.Fatal("failed to parse dedupe scope", "error", err)
+	}
+
+	normalizer := service.NewURLNormalizer(strings.Split(config.AllowedSchemes, ","), config.DropURLFragment)
 
 	jwt := auth.NewJWT(config.JWTSecretKey)
 
+	var (
+		serviceStorage service.URLStorage = urlStorage
+		healthPinger   service.Pinger     = urlStorage
+	)
+	if config.FallbackSnapshotPath != "" {
+		if storageMode != storageModePostgres {
+			logger.Fatal("fallback snapshot requires postgres storage mode", "storage_mode", storageMode)
+		}
+
+		fallbackRefreshInterval, err := time.ParseDuration(config.FallbackRefreshInterval)
+		if err != nil {
+			logger.Fatal("failed to parse fallback refresh interval", "error", err)
+		}
+
+		fallbackStorage, err := fallback.NewStorage(databaseStorage, config.FallbackSnapshotPath, fallbackRefreshInterval, logger)
+		if err != nil {
+			logger.Fatal("failed to create fallback storage", "error", err)
+		}
+		go fallbackStorage.Run(ctx)
+
+		serviceStorage = fallbackStorage
+		healthPinger = fallbackStorage
+		logger.Debug("using fallback snapshot", "file", config.FallbackSnapshotPath, "refresh_interval", fallbackRefreshInterval)
+	}
+
+	if config.CacheSize > 0 {
+		cacheTTL, err := time.ParseDuration(config.CacheTTL)
+		if err != nil {
+			logger.Fatal("failed to parse cache ttl", "error", err)
+		}
+		cacheNegativeTTL, err := time.ParseDuration(config.CacheNegativeTTL)
+		if err != nil {
+			logger.Fatal("failed to parse cache negative ttl", "error", err)
+		}
+
+		cachedStorage := cache.NewStorage(serviceStorage, config.CacheSize, cacheTTL, cache.WithNegativeTTL(cacheNegativeTTL))
+		// Hits and misses are served by the profiler at /debug/vars.
+		expvar.Publish("url_cache", expvar.Func(func() any {
+			return cachedStorage.Stats()
+		}))
+		serviceStorage = cachedStorage
+
+		// Other instances sharing the database change URLs behind the cache.
+		if databaseStorage != nil {
+			go databaseStorage.ListenURLChanges(ctx, cachedStorage, logger)
+		}
+		logger.Debug("using url cache", "size", config.CacheSize, "ttl", cacheTTL)
+	}
+
+	urlService := service.NewURL(config.BaseURL, config.ShortKeyLength, keyGenerator, normalizer, dedupeScope, jwt, config.ConcurrencyLimit, config.QueueSize, config.ClickWorkers, config.ClickQueueSize, serviceStorage)
+	// Clicks dropped under load are served by the profiler at /debug/vars.
+	expvar.Publish("dropped_clicks", expvar.Func(func() any {
+		return urlService.DroppedClicks()
+	}))
+	healthService := service.NewHealth(healthPinger)
+
+	deletedRetention, err := time.ParseDuration(config.DeletedRetention)
+	if err != nil {
+		logger.Fatal("failed to parse deleted urls retention", "error", err)
+	}
+	purgeInterval, err := time.ParseDuration(config.PurgeInterval)
+	if err != nil {
+		logger.Fatal("failed to parse purge interval", "error", err)
+	}
+	if purgeInterval > 0 {
+		purger := service.NewPurger(urlService, deletedRetention, purgeInterval, logger)
+		go purger.Run(ctx)
+	}
+
 	r := router.NewRouter()
 	r.RegisterProfiler()
 	r.RegisterAPIRoutes(urlService, jwt, logger)
@@ -12,18 +12,41 @@ import (
 
 // Config contains application configuration parameters.
 type Config struct {
-	RunAddr            string `env:"SERVER_ADDRESS" json:"server_address"`
-	BaseURL            string `env:"BASE_URL" json:"base_url"`
-	ShortKeyLength     int    `env:"SHORT_URL_LENGTH" json:"short_url_length"`
-	LogLevel           string `env:"LOG_LEVEL" json:"log_level"`
-	FileStoragePath    string `env:"FILE_STORAGE_PATH" json:"file_storage_path"`
-	DatabaseDSN        string `env:"DATABASE_DSN" json:"database_dsn"`
-	JWTSecretKey       string `env:"JWT_SECRET_KEY" json:"jwt_secret_key"`
-	ConcurrencyLimit   int    `env:"CONCURRENCY_LIMIT" json:"concurrency_limit"`
-	QueueSize          int    `env:"QUEUE_SIZE" json:"queue_size"`
-	EnableHTTPS        bool   `env:"ENABLE_HTTPS" json:"enable_https"`
-	CertFileName       string `env:"CERT_FILE_NAME" json:"cert_file_name"`
-	PrivateKeyFileName string `env:"PRIVATE_KEY_FILE_NAME" json:"private_key_file_name"`
+	RunAddr                 string `env:"SERVER_ADDRESS" json:"server_address"`
+	BaseURL                 string `env:"BASE_URL" json:"base_url"`
+	ShortKeyLength          int    `env:"SHORT_URL_LENGTH" json:"short_url_length"`
+	LogLevel                string `env:"LOG_LEVEL" json:"log_level"`
+	FileStoragePath         string `env:"FILE_STORAGE_PATH" json:"file_storage_path"`
+	DatabaseDSN             string `env:"DATABASE_DSN" json:"database_dsn"`
+	JWTSecretKey            string `env:"JWT_SECRET_KEY" json:"jwt_secret_key"`
+	ConcurrencyLimit        int    `env:"CONCURRENCY_LIMIT" json:"concurrency_limit"`
+	QueueSize               int    `env:"QUEUE_SIZE" json:"queue_size"`
+	EnableHTTPS             bool   `env:"ENABLE_HTTPS" json:"enable_https"`
+	CertFileName            string `env:"CERT_FILE_NAME" json:"cert_file_name"`
+	PrivateKeyFileName      string `env:"PRIVATE_KEY_FILE_NAME" json:"private_key_file_name"`
+	KeyGenerator            string `env:"KEY_GENERATOR" json:"key_generator"`
+	KeyGeneratorSalt        string `env:"KEY_GENERATOR_SALT" json:"key_generator_salt"`
+	AllowedSchemes          string `env:"ALLOWED_SCHEMES" json:"allowed_schemes"`
+	DropURLFragment         bool   `env:"DROP_URL_FRAGMENT" json:"drop_url_fragment"`
+	DedupeScope             string `env:"DEDUPE_SCOPE" json:"dedupe_scope"`
+	DeletedRetention        string `env:"DELETED_RETENTION" json:"deleted_retention"`
+	PurgeInterval           string `env:"PURGE_INTERVAL" json:"purge_interval"`
+	CompactInterval         string `env:"COMPACT_INTERVAL" json:"compact_interval"`
+	FileSync                string `env:"FILE_SYNC" json:"file_sync"`
+	FileSyncInterval        string `env:"FILE_SYNC_INTERVAL" json:"file_sync_interval"`
+	FileStorageShards       int    `env:"FILE_STORAGE_SHARDS" json:"file_storage_shards"`
+	ReplicaDSNs             string `env:"DATABASE_REPLICA_DSNS" json:"database_replica_dsns"`
+	ReplicaCheckInterval    string `env:"REPLICA_CHECK_INTERVAL" json:"replica_check_interval"`
+	CacheSize               int    `env:"CACHE_SIZE" json:"cache_size"`
+	CacheTTL                string `env:"CACHE_TTL" json:"cache_ttl"`
+	CacheNegativeTTL        string `env:"CACHE_NEGATIVE_TTL" json:"cache_negative_ttl"`
+	FallbackSnapshotPath    string `env:"FALLBACK_SNAPSHOT_PATH" json:"fallback_snapshot_path"`
+	FallbackRefreshInterval string `env:"FALLBACK_REFRESH_INTERVAL" json:"fallback_refresh_interval"`
+	StorageMode             string `env:"STORAGE_MODE" json:"storage_mode"`
+	TieredBatchSize         int    `env:"TIERED_BATCH_SIZE" json:"tiered_batch_size"`
+	TieredFlushInterval     string `env:"TIERED_FLUSH_INTERVAL" json:"tiered_flush_interval"`
+	ClickWorkers            int    `env:"CLICK_WORKERS" json:"click_workers"`
+	ClickQueueSize          int    `env:"CLICK_QUEUE_SIZE" json:"click_queue_size"`
 }
 
 func (c *Config) setDefaults() {
@@ -39,6 +62,29 @@ func (c *Config) setDefaults() {
 	c.EnableHTTPS = false
 	c.CertFileName = ""
 	c.PrivateKeyFileName = ""
+	c.KeyGenerator = "base62"
+	c.KeyGeneratorSalt = ""
+	c.AllowedSchemes = "http,https"
+	c.DropURLFragment = false
+	c.DedupeScope = "global"
+	c.DeletedRetention = "720h"
+	c.PurgeInterval = "1h"
+	c.CompactInterval = "10m"
+	c.FileSync = "always"
+	c.FileSyncInterval = "1s"
+	c.FileStorageShards = 0
+	c.ReplicaDSNs = ""
+	c.ReplicaCheckInterval = "5s"
+	c.CacheSize = 0
+	c.CacheTTL = "1m"
+	c.CacheNegativeTTL = "5s"
+	c.FallbackSnapshotPath = ""
+	c.FallbackRefreshInterval = "1m"
+	c.StorageMode = ""
+	c.TieredBatchSize = 500
+	c.TieredFlushInterval = "1s"
+	c.ClickWorkers = 4
+	c.ClickQueueSize = 0
 }
 
 // Initialize creates and initializes application configuration.
@@ -98,6 +144,29 @@ func parseCommandLineFlags(config *Config) error {
 	flagSet.BoolVar(&config.EnableHTTPS, "s", config.EnableHTTPS, "should server serve https")
 	flagSet.StringVar(&config.CertFileName, "sc", config.CertFileName, "cert file name")
 	flagSet.StringVar(&config.PrivateKeyFileName, "sp", config.PrivateKeyFileName, "private key file name")
+	flagSet.StringVar(&config.KeyGenerator, "kg", config.KeyGenerator, "short key generator: base62, crockford, hashids or crypto")
+	flagSet.StringVar(&config.KeyGeneratorSalt, "ks", config.KeyGeneratorSalt, "salt for hashids short key generator")
+	flagSet.StringVar(&config.AllowedSchemes, "as", config.AllowedSchemes, "comma separated list of url schemes allowed for shortening")
+	flagSet.BoolVar(&config.DropURLFragment, "df", config.DropURLFragment, "should fragments be removed from shortened urls")
+	flagSet.StringVar(&config.DedupeScope, "ds", config.DedupeScope, "scope of original url deduplication: global, user or none")
+	flagSet.StringVar(&config.DeletedRetention, "dr", config.DeletedRetention, "time deleted urls are kept before they are purged, e.g. 720h")
+	flagSet.StringVar(&config.PurgeInterval, "pi", config.PurgeInterval, "time between purges of deleted urls, 0 disables purging")
+	flagSet.StringVar(&config.CompactInterval, "ci", config.CompactInterval, "time between compactions of the storage file, 0 disables compaction")
+	flagSet.StringVar(&config.FileSync, "fs", config.FileSync, "when writes to the storage file are synced to disk: always, interval or never")
+	flagSet.StringVar(&config.FileSyncInterval, "fsi", config.FileSyncInterval, "time between syncs of the storage file with interval sync policy")
+	flagSet.IntVar(&config.FileStorageShards, "fss", config.FileStorageShards, "number of shards of the in-memory storage, 0 disables sharding")
+	flagSet.StringVar(&config.ReplicaDSNs, "rd", config.ReplicaDSNs, "comma separated list of strings for connecting to postgres read replicas")
+	flagSet.StringVar(&config.ReplicaCheckInterval, "rci", config.ReplicaCheckInterval, "time between health checks of postgres read replicas")
+	flagSet.IntVar(&config.CacheSize, "cs", config.CacheSize, "number of url lookups cached in memory, 0 disables the cache")
+	flagSet.StringVar(&config.CacheTTL, "ct", config.CacheTTL, "time found urls are cached for")
+	flagSet.StringVar(&config.CacheNegativeTTL, "cnt", config.CacheNegativeTTL, "time lookups of missing urls are cached for, 0 disables caching of misses")
+	flagSet.StringVar(&config.FallbackSnapshotPath, "fbs", config.FallbackSnapshotPath, "file of the snapshot redirects are served from while postgres is down, empty disables the fallback")
+	flagSet.StringVar(&config.FallbackRefreshInterval, "fbi", config.FallbackRefreshInterval, "time between refreshes of the fallback snapshot")
+	flagSet.StringVar(&config.StorageMode, "sm", config.StorageMode, "url storage: file, postgres or tiered, empty picks postgres if database dsn is set and file otherwise")
+	flagSet.IntVar(&config.TieredBatchSize, "tbs", config.TieredBatchSize, "maximum number of urls written behind to postgres at once in tiered mode")
+	flagSet.StringVar(&config.TieredFlushInterval, "tfi", config.TieredFlushInterval, "time between writes of pending urls to postgres in tiered mode")
+	flagSet.IntVar(&config.ClickWorkers, "cw", config.ClickWorkers, "number of workers saving click events")
+	flagSet.IntVar(&config.ClickQueueSize, "cq", config.ClickQueueSize, "click events queue size, if not passed, will be set based on the number of click workers")
 
 	return flagSet.Parse(os.Args[1:])
 }
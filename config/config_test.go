@@ -21,66 +21,147 @@ func TestInitialize(t *testing.T) {
 		"default values": {
 			args: []string{"cmd"},
 			wantConfig: &Config{
-				RunAddr:            ":8080",
-				BaseURL:            "http://localhost:8080",
-				ShortKeyLength:     8,
-				LogLevel:           "INFO",
-				FileStoragePath:    os.TempDir() + "/urls",
-				DatabaseDSN:        "",
-				JWTSecretKey:       "a-string-secret-at-least-256-bits-long",
-				ConcurrencyLimit:   3,
-				QueueSize:          0,
-				EnableHTTPS:        false,
-				CertFileName:       "",
-				PrivateKeyFileName: "",
+				RunAddr:                 ":8080",
+				BaseURL:                 "http://localhost:8080",
+				ShortKeyLength:          8,
+				LogLevel:                "INFO",
+				FileStoragePath:         os.TempDir() + "/urls",
+				DatabaseDSN:             "",
+				JWTSecretKey:            "a-string-secret-at-least-256-bits-long",
+				ConcurrencyLimit:        3,
+				QueueSize:               0,
+				EnableHTTPS:             false,
+				CertFileName:            "",
+				PrivateKeyFileName:      "",
+				KeyGenerator:            "base62",
+				AllowedSchemes:          "http,https",
+				DedupeScope:             "global",
+				DeletedRetention:        "720h",
+				PurgeInterval:           "1h",
+				CompactInterval:         "10m",
+				FileSync:                "always",
+				FileSyncInterval:        "1s",
+				ReplicaCheckInterval:    "5s",
+				CacheTTL:                "1m",
+				CacheNegativeTTL:        "5s",
+				FallbackRefreshInterval: "1m",
+				TieredBatchSize:         500,
+				TieredFlushInterval:     "1s",
+				ClickWorkers:            4,
 			},
 		},
 		"with command line flags": {
-			args: []string{"cmd", "-a", ":9090", "-b", "https://example.com", "-u", "10", "-l", "DEBUG", "-f", "/tmp/test.json", "-d", "postgres://test", "-j", "custom-secret", "-cl", "5", "-q", "100", "-s", "-sc", "cert.pem", "-sp", "key.pem"},
+			args: []string{"cmd", "-a", ":9090", "-b", "https://example.com", "-u", "10", "-l", "DEBUG", "-f", "/tmp/test.json", "-d", "postgres://test", "-j", "custom-secret", "-cl", "5", "-q", "100", "-s", "-sc", "cert.pem", "-sp", "key.pem", "-kg", "hashids", "-ks", "pepper", "-as", "https,ftp", "-df", "-ds", "user", "-dr", "24h", "-pi", "10m", "-ci", "5m", "-fs", "interval", "-fsi", "100ms", "-fss", "16", "-rd", "postgres://replica", "-rci", "10s", "-cs", "1000", "-ct", "30s", "-cnt", "1s", "-fbs", "/tmp/snapshot", "-fbi", "30s", "-sm", "tiered", "-tbs", "100", "-tfi", "200ms", "-cw", "8", "-cq", "5000"},
 			wantConfig: &Config{
-				RunAddr:            ":9090",
-				BaseURL:            "https://example.com",
-				ShortKeyLength:     10,
-				LogLevel:           "DEBUG",
-				FileStoragePath:    "/tmp/test.json",
-				DatabaseDSN:        "postgres://test",
-				JWTSecretKey:       "custom-secret",
-				ConcurrencyLimit:   5,
-				QueueSize:          100,
-				EnableHTTPS:        true,
-				CertFileName:       "cert.pem",
-				PrivateKeyFileName: "key.pem",
+				RunAddr:                 ":9090",
+				BaseURL:                 "https://example.com",
+				ShortKeyLength:          10,
+				LogLevel:                "DEBUG",
+				FileStoragePath:         "/tmp/test.json",
+				DatabaseDSN:             "postgres://test",
+				JWTSecretKey:            "custom-secret",
+				ConcurrencyLimit:        5,
+				QueueSize:               100,
+				EnableHTTPS:             true,
+				CertFileName:            "cert.pem",
+				PrivateKeyFileName:      "key.pem",
+				KeyGenerator:            "hashids",
+				KeyGeneratorSalt:        "pepper",
+				AllowedSchemes:          "https,ftp",
+				DropURLFragment:         true,
+				DedupeScope:             "user",
+				DeletedRetention:        "24h",
+				PurgeInterval:           "10m",
+				CompactInterval:         "5m",
+				FileSync:                "interval",
+				FileSyncInterval:        "100ms",
+				FileStorageShards:       16,
+				ReplicaDSNs:             "postgres://replica",
+				ReplicaCheckInterval:    "10s",
+				CacheSize:               1000,
+				CacheTTL:                "30s",
+				CacheNegativeTTL:        "1s",
+				FallbackSnapshotPath:    "/tmp/snapshot",
+				FallbackRefreshInterval: "30s",
+				StorageMode:             "tiered",
+				TieredBatchSize:         100,
+				TieredFlushInterval:     "200ms",
+				ClickWorkers:            8,
+				ClickQueueSize:          5000,
 			},
 		},
 		"with environment variables": {
 			envVars: map[string]string{
-				"SERVER_ADDRESS":        ":9090",
-				"BASE_URL":              "https://example.com",
-				"SHORT_URL_LENGTH":      "10",
-				"LOG_LEVEL":             "DEBUG",
-				"FILE_STORAGE_PATH":     "/tmp/test.json",
-				"DATABASE_DSN":          "postgres://test",
-				"JWT_SECRET_KEY":        "custom-secret",
-				"CONCURRENCY_LIMIT":     "5",
-				"QUEUE_SIZE":            "100",
-				"ENABLE_HTTPS":          "true",
-				"CERT_FILE_NAME":        "cert.pem",
-				"PRIVATE_KEY_FILE_NAME": "key.pem",
+				"SERVER_ADDRESS":            ":9090",
+				"BASE_URL":                  "https://example.com",
+				"SHORT_URL_LENGTH":          "10",
+				"LOG_LEVEL":                 "DEBUG",
+				"FILE_STORAGE_PATH":         "/tmp/test.json",
+				"DATABASE_DSN":              "postgres://test",
+				"JWT_SECRET_KEY":            "custom-secret",
+				"CONCURRENCY_LIMIT":         "5",
+				"QUEUE_SIZE":                "100",
+				"ENABLE_HTTPS":              "true",
+				"CERT_FILE_NAME":            "cert.pem",
+				"PRIVATE_KEY_FILE_NAME":     "key.pem",
+				"KEY_GENERATOR":             "crockford",
+				"KEY_GENERATOR_SALT":        "pepper",
+				"ALLOWED_SCHEMES":           "https",
+				"DROP_URL_FRAGMENT":         "true",
+				"DEDUPE_SCOPE":              "none",
+				"DELETED_RETENTION":         "48h",
+				"PURGE_INTERVAL":            "0",
+				"COMPACT_INTERVAL":          "0",
+				"FILE_SYNC":                 "never",
+				"FILE_STORAGE_SHARDS":       "4",
+				"DATABASE_REPLICA_DSNS":     "postgres://replica1,postgres://replica2",
+				"CACHE_SIZE":                "500",
+				"CACHE_TTL":                 "2m",
+				"FALLBACK_SNAPSHOT_PATH":    "/var/lib/urlshorter/snapshot",
+				"FALLBACK_REFRESH_INTERVAL": "5m",
+				"STORAGE_MODE":              "postgres",
+				"TIERED_BATCH_SIZE":         "1000",
+				"TIERED_FLUSH_INTERVAL":     "5s",
+				"CLICK_WORKERS":             "2",
+				"CLICK_QUEUE_SIZE":          "1000",
 			},
 			args: []string{"cmd"},
 			wantConfig: &Config{
-				RunAddr:            ":9090",
-				BaseURL:            "https://example.com",
-				ShortKeyLength:     10,
-				LogLevel:           "DEBUG",
-				FileStoragePath:    "/tmp/test.json",
-				DatabaseDSN:        "postgres://test",
-				JWTSecretKey:       "custom-secret",
-				ConcurrencyLimit:   5,
-				QueueSize:          100,
-				EnableHTTPS:        true,
-				CertFileName:       "cert.pem",
-				PrivateKeyFileName: "key.pem",
+				RunAddr:                 ":9090",
+				BaseURL:                 "https://example.com",
+				ShortKeyLength:          10,
+				LogLevel:                "DEBUG",
+				FileStoragePath:         "/tmp/test.json",
+				DatabaseDSN:             "postgres://test",
+				JWTSecretKey:            "custom-secret",
+				ConcurrencyLimit:        5,
+				QueueSize:               100,
+				EnableHTTPS:             true,
+				CertFileName:            "cert.pem",
+				PrivateKeyFileName:      "key.pem",
+				KeyGenerator:            "crockford",
+				KeyGeneratorSalt:        "pepper",
+				AllowedSchemes:          "https",
+				DropURLFragment:         true,
+				DedupeScope:             "none",
+				DeletedRetention:        "48h",
+				PurgeInterval:           "0",
+				CompactInterval:         "0",
+				FileSync:                "never",
+				FileSyncInterval:        "1s",
+				FileStorageShards:       4,
+				ReplicaDSNs:             "postgres://replica1,postgres://replica2",
+				ReplicaCheckInterval:    "5s",
+				CacheSize:               500,
+				CacheTTL:                "2m",
+				CacheNegativeTTL:        "5s",
+				FallbackSnapshotPath:    "/var/lib/urlshorter/snapshot",
+				FallbackRefreshInterval: "5m",
+				StorageMode:             "postgres",
+				TieredBatchSize:         1000,
+				TieredFlushInterval:     "5s",
+				ClickWorkers:            2,
+				ClickQueueSize:          1000,
 			},
 		},
 		"environment variables override flags": {
@@ -90,18 +171,33 @@ func TestInitialize(t *testing.T) {
 			},
 			args: []string{"cmd", "-a", ":8080", "-b", "http://localhost:8080"},
 			wantConfig: &Config{
-				RunAddr:            ":9090",
-				BaseURL:            "https://example.com",
-				ShortKeyLength:     8,
-				LogLevel:           "INFO",
-				FileStoragePath:    os.TempDir() + "/urls",
-				DatabaseDSN:        "",
-				JWTSecretKey:       "a-string-secret-at-least-256-bits-long",
-				ConcurrencyLimit:   3,
-				QueueSize:          0,
-				EnableHTTPS:        false,
-				CertFileName:       "",
-				PrivateKeyFileName: "",
+				RunAddr:                 ":9090",
+				BaseURL:                 "https://example.com",
+				ShortKeyLength:          8,
+				LogLevel:                "INFO",
+				FileStoragePath:         os.TempDir() + "/urls",
+				DatabaseDSN:             "",
+				JWTSecretKey:            "a-string-secret-at-least-256-bits-long",
+				ConcurrencyLimit:        3,
+				QueueSize:               0,
+				EnableHTTPS:             false,
+				CertFileName:            "",
+				PrivateKeyFileName:      "",
+				KeyGenerator:            "base62",
+				AllowedSchemes:          "http,https",
+				DedupeScope:             "global",
+				DeletedRetention:        "720h",
+				PurgeInterval:           "1h",
+				CompactInterval:         "10m",
+				FileSync:                "always",
+				FileSyncInterval:        "1s",
+				ReplicaCheckInterval:    "5s",
+				CacheTTL:                "1m",
+				CacheNegativeTTL:        "5s",
+				FallbackRefreshInterval: "1m",
+				TieredBatchSize:         500,
+				TieredFlushInterval:     "1s",
+				ClickWorkers:            4,
 			},
 		},
 		"with config file": {
@@ -419,18 +515,33 @@ func TestSetDefaults(t *testing.T) {
 	config.setDefaults()
 
 	expected := &Config{
-		RunAddr:            ":8080",
-		BaseURL:            "http://localhost:8080",
-		ShortKeyLength:     8,
-		LogLevel:           "INFO",
-		FileStoragePath:    os.TempDir() + "/urls",
-		DatabaseDSN:        "",
-		JWTSecretKey:       "a-string-secret-at-least-256-bits-long",
-		ConcurrencyLimit:   3,
-		QueueSize:          0,
-		EnableHTTPS:        false,
-		CertFileName:       "",
-		PrivateKeyFileName: "",
+		RunAddr:                 ":8080",
+		BaseURL:                 "http://localhost:8080",
+		ShortKeyLength:          8,
+		LogLevel:                "INFO",
+		FileStoragePath:         os.TempDir() + "/urls",
+		DatabaseDSN:             "",
+		JWTSecretKey:            "a-string-secret-at-least-256-bits-long",
+		ConcurrencyLimit:        3,
+		QueueSize:               0,
+		EnableHTTPS:             false,
+		CertFileName:            "",
+		PrivateKeyFileName:      "",
+		KeyGenerator:            "base62",
+		AllowedSchemes:          "http,https",
+		DedupeScope:             "global",
+		DeletedRetention:        "720h",
+		PurgeInterval:           "1h",
+		CompactInterval:         "10m",
+		FileSync:                "always",
+		FileSyncInterval:        "1s",
+		ReplicaCheckInterval:    "5s",
+		CacheTTL:                "1m",
+		CacheNegativeTTL:        "5s",
+		FallbackRefreshInterval: "1m",
+		TieredBatchSize:         500,
+		TieredFlushInterval:     "1s",
+		ClickWorkers:            4,
 	}
 
 	assert.Equal(t, expected, config)
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS clicks (
+id uuid PRIMARY KEY,
+url_id uuid NOT NULL REFERENCES urls (id) ON DELETE CASCADE,
+created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+referrer text NOT NULL DEFAULT '',
+user_agent text NOT NULL DEFAULT '',
+client_ip varchar(64) NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS clicks_url_id_created_at_idx ON clicks (url_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS clicks;
+-- +goose StatementEnd
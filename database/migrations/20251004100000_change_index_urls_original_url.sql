@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+DROP INDEX IF EXISTS urls_original_url_idx;
+CREATE INDEX IF NOT EXISTS urls_original_url_user_id_idx ON urls (original_url, user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- Original URLs may be duplicated once the unique index is dropped,
+-- so the index is restored without the uniqueness constraint.
+-- +goose StatementBegin
+DROP INDEX IF EXISTS urls_original_url_user_id_idx;
+CREATE INDEX IF NOT EXISTS urls_original_url_idx ON urls (original_url);
+-- +goose StatementEnd
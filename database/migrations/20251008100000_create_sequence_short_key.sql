@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE SEQUENCE IF NOT EXISTS short_key_sequence AS bigint INCREMENT BY 100 MINVALUE 1 START WITH 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP SEQUENCE IF EXISTS short_key_sequence;
+-- +goose StatementEnd
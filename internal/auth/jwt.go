@@ -17,6 +17,19 @@ type Claims struct {
 	UserID uuid.UUID `json:"user_id"`
 }
 
+// linkTokenAudience is the audience of tokens that give access to password-protected URLs.
+// It keeps these tokens from being accepted as authentication tokens and vice versa.
+const linkTokenAudience = "link"
+
+// LinkClaims represents the claims of a token that gives access to a password-protected URL.
+type LinkClaims struct {
+	jwt.RegisteredClaims
+	// URLID is the ID of the URL the token gives access to.
+	URLID uuid.UUID `json:"url_id"`
+	// PasswordVersion identifies the password of the URL the token was issued for.
+	PasswordVersion string `json:"password_version"`
+}
+
 // JWT represents a JWT token service for authentication.
 // It provides methods for creating and validating JWT tokens.
 type JWT struct {
@@ -48,13 +61,7 @@ func NewJWT(secretKey string) *JWT {
 func (j *JWT) GetUserID(_ context.Context, tokenString string) (uuid.UUID, error) {
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("wrong signing method %v", t.Header["alg"])
-		}
-
-		return []byte(j.secretKey), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, j.keyFunc)
 
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("failed to parse token: %w", err)
@@ -64,6 +71,10 @@ func (j *JWT) GetUserID(_ context.Context, tokenString string) (uuid.UUID, error
 		return uuid.Nil, fmt.Errorf("token is invalid")
 	}
 
+	if len(claims.Audience) > 0 {
+		return uuid.Nil, fmt.Errorf("token is not an authentication token")
+	}
+
 	return claims.UserID, nil
 }
 
@@ -92,3 +103,66 @@ func (j *JWT) CreateToken(_ context.Context, userID uuid.UUID) (string, error) {
 
 	return tokenString, nil
 }
+
+// GetLinkID extracts and validates a URL ID from a token created with CreateLinkToken.
+//
+// Parameters:
+//   - ctx: The request context (unused in current implementation)
+//   - tokenString: The JWT token string to parse and validate
+//
+// Returns the URL UUID and the password version from the token claims or an error if validation fails.
+// If the token is invalid, expired or was not created for URL access, returns uuid.Nil and an error.
+func (j *JWT) GetLinkID(_ context.Context, tokenString string) (uuid.UUID, string, error) {
+	claims := &LinkClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, j.keyFunc, jwt.WithAudience(linkTokenAudience))
+	if err != nil {
+		return uuid.Nil, "", fmt.Errorf("failed to parse token: %w", err)
+	}
+
+	if !token.Valid {
+		return uuid.Nil, "", fmt.Errorf("token is invalid")
+	}
+
+	return claims.URLID, claims.PasswordVersion, nil
+}
+
+// CreateLinkToken creates a new JWT token that gives access to the password-protected URL
+// with the specified ID.
+//
+// Parameters:
+//   - ctx: The request context (unused in current implementation)
+//   - urlID: The UUID of the URL to give access to
+//   - passwordVersion: The version of the password the token is issued for
+//   - ttl: The duration the token is valid for
+//
+// Returns the signed JWT token string or an error if token creation fails.
+func (j *JWT) CreateLinkToken(_ context.Context, urlID uuid.UUID, passwordVersion string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, LinkClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{linkTokenAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		URLID:           urlID,
+		PasswordVersion: passwordVersion,
+	})
+
+	tokenString, err := token.SignedString([]byte(j.secretKey))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return tokenString, nil
+}
+
+// keyFunc returns the key used to validate a token.
+// Tokens signed with anything but HMAC are rejected.
+func (j *JWT) keyFunc(t *jwt.Token) (interface{}, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("wrong signing method %v", t.Header["alg"])
+	}
+
+	return []byte(j.secretKey), nil
+}
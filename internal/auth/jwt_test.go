@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -89,3 +90,69 @@ func TestJWT_CreateToken(t *testing.T) {
 		assert.Equal(t, userID, claims.UserID)
 	})
 }
+
+func TestJWT_LinkToken(t *testing.T) {
+	secretKey := "a-string-secret-at-least-256-bits-long"
+	urlID := uuid.New()
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		j := NewJWT(secretKey)
+
+		tokenString, err := j.CreateLinkToken(ctx, urlID, "version", time.Minute)
+		require.NoError(t, err)
+
+		linkID, passwordVersion, err := j.GetLinkID(ctx, tokenString)
+		require.NoError(t, err)
+		assert.Equal(t, urlID, linkID)
+		assert.Equal(t, "version", passwordVersion)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		t.Parallel()
+
+		j := NewJWT(secretKey)
+
+		tokenString, err := j.CreateLinkToken(ctx, urlID, "version", -time.Minute)
+		require.NoError(t, err)
+
+		_, _, err = j.GetLinkID(ctx, tokenString)
+		require.Error(t, err)
+	})
+
+	t.Run("wrong secret key", func(t *testing.T) {
+		t.Parallel()
+
+		tokenString, err := NewJWT("another-secret").CreateLinkToken(ctx, urlID, "version", time.Minute)
+		require.NoError(t, err)
+
+		_, _, err = NewJWT(secretKey).GetLinkID(ctx, tokenString)
+		require.Error(t, err)
+	})
+
+	t.Run("authentication token is not a link token", func(t *testing.T) {
+		t.Parallel()
+
+		j := NewJWT(secretKey)
+
+		tokenString, err := j.CreateToken(ctx, uuid.New())
+		require.NoError(t, err)
+
+		_, _, err = j.GetLinkID(ctx, tokenString)
+		require.Error(t, err)
+	})
+
+	t.Run("link token is not an authentication token", func(t *testing.T) {
+		t.Parallel()
+
+		j := NewJWT(secretKey)
+
+		tokenString, err := j.CreateLinkToken(ctx, urlID, "version", time.Minute)
+		require.NoError(t, err)
+
+		_, err = j.GetUserID(ctx, tokenString)
+		require.Error(t, err)
+	})
+}
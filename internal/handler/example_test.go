@@ -20,13 +20,16 @@ import (
 	"github.com/dtroode/urlshorter/internal/logger"
 	"github.com/dtroode/urlshorter/internal/request"
 	"github.com/dtroode/urlshorter/internal/response"
+	"github.com/dtroode/urlshorter/internal/service/dto"
 )
 
 // ExampleURL_GetOriginalURL demonstrates how to handle a GET request to retrieve the original URL.
 func ExampleURL_GetOriginalURL() {
 	service := mocks.NewURLService(&testing.T{})
 
-	service.On("GetOriginalURL", mock.Anything, "abc123").Return("https://example.com/very-long-url-path", nil)
+	service.On("GetOriginalURL", mock.Anything, mock.MatchedBy(func(dto *dto.GetOriginalURL) bool {
+		return dto.ShortKey == "abc123"
+	})).Return("https://example.com/very-long-url-path", nil)
 
 	logger := &logger.Logger{}
 
@@ -2,14 +2,18 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 
 	"github.com/dtroode/urlshorter/internal/logger"
+	"github.com/dtroode/urlshorter/internal/response"
+	"github.com/dtroode/urlshorter/internal/service"
 )
 
 // HealthService defines interface for health check service.
 type HealthService interface {
 	Ping(ctx context.Context) error
+	Check(ctx context.Context) (*response.Health, error)
 }
 
 // Health represents handler for service health check.
@@ -46,3 +50,31 @@ func (h *Health) Ping(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// Check handles request for the status of the service components.
+// Unlike Ping, it reports every component and succeeds while the service is degraded.
+// @Summary Component health check
+// @Description Report the status of the service and its components, like database pools
+// @Tags Health
+// @Accept json
+// @Produce json
+// @Success 200 {object} response.Health "Service is healthy or degraded"
+// @Failure 500 {object} response.Health "Service is unhealthy"
+// @Router /health [get]
+func (h *Health) Check(w http.ResponseWriter, r *http.Request) {
+	health, err := h.service.Check(r.Context())
+	if err != nil {
+		h.logger.Error("health check failed", "error", err, "status", health.Status)
+	}
+
+	w.Header().Set("content-type", "application/json")
+	if health.Status == service.HealthStatusUnavailable {
+		w.WriteHeader(http.StatusInternalServerError)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if err := json.NewEncoder(w).Encode(health); err != nil {
+		h.logger.Error("failed to encode response", "error", err)
+	}
+}
@@ -12,6 +12,8 @@ import (
 
 	"github.com/dtroode/urlshorter/internal/handler/mocks"
 	"github.com/dtroode/urlshorter/internal/logger"
+	"github.com/dtroode/urlshorter/internal/response"
+	"github.com/dtroode/urlshorter/internal/service"
 )
 
 func TestHealth_Ping(t *testing.T) {
@@ -50,6 +52,72 @@ func TestHealth_Ping(t *testing.T) {
 			h.Ping(w, r)
 
 			require.Equal(t, tt.expectedStatusCode, w.Code)
+			require.Empty(t, w.Body.String())
+		})
+	}
+}
+
+func TestHealth_Check(t *testing.T) {
+	dummyLogger := &logger.Logger{
+		Logger: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+	}
+
+	tests := map[string]struct {
+		serviceHealth      *response.Health
+		serviceError       error
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		"error from service": {
+			serviceHealth: &response.Health{
+				Status:     service.HealthStatusUnavailable,
+				Components: []*response.HealthComponent{{Name: "primary", Status: service.ComponentStatusDown}},
+			},
+			serviceError:       errors.New("service error"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       `{"status":"unavailable","components":[{"name":"primary","status":"down"}]}`,
+		},
+		"degraded service": {
+			serviceHealth: &response.Health{
+				Status: service.HealthStatusDegraded,
+				Components: []*response.HealthComponent{
+					{Name: "primary", Status: service.ComponentStatusUp},
+					{Name: "replica-1", Status: service.ComponentStatusDown},
+				},
+			},
+			serviceError:       errors.New("replica error"),
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"status":"degraded","components":[{"name":"primary","status":"up"},{"name":"replica-1","status":"down"}]}`,
+		},
+		"nil from service": {
+			serviceHealth: &response.Health{
+				Status:     service.HealthStatusOK,
+				Components: []*response.HealthComponent{{Name: "primary", Status: service.ComponentStatusUp}},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"status":"ok","components":[{"name":"primary","status":"up"}]}`,
+		},
+	}
+
+	for tn, tt := range tests {
+		tt := tt
+
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/health", nil)
+			ctx := r.Context()
+			w := httptest.NewRecorder()
+
+			serviceMock := mocks.NewHealthService(t)
+			serviceMock.On("Check", ctx).Once().Return(tt.serviceHealth, tt.serviceError)
+
+			h := NewHealth(serviceMock, dummyLogger)
+
+			h.Check(w, r)
+
+			require.Equal(t, tt.expectedStatusCode, w.Code)
+			require.JSONEq(t, tt.expectedBody, w.Body.String())
 		})
 	}
 }
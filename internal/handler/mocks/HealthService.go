@@ -5,6 +5,7 @@ package mocks
 import (
 	context "context"
 
+	response "github.com/dtroode/urlshorter/internal/response"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -21,6 +22,64 @@ func (_m *HealthService) EXPECT() *HealthService_Expecter {
 	return &HealthService_Expecter{mock: &_m.Mock}
 }
 
+// Check provides a mock function with given fields: ctx
+func (_m *HealthService) Check(ctx context.Context) (*response.Health, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Check")
+	}
+
+	var r0 *response.Health
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*response.Health, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *response.Health); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*response.Health)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// HealthService_Check_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Check'
+type HealthService_Check_Call struct {
+	*mock.Call
+}
+
+// Check is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *HealthService_Expecter) Check(ctx interface{}) *HealthService_Check_Call {
+	return &HealthService_Check_Call{Call: _e.mock.On("Check", ctx)}
+}
+
+func (_c *HealthService_Check_Call) Run(run func(ctx context.Context)) *HealthService_Check_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *HealthService_Check_Call) Return(_a0 *response.Health, _a1 error) *HealthService_Check_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *HealthService_Check_Call) RunAndReturn(run func(context.Context) (*response.Health, error)) *HealthService_Check_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Ping provides a mock function with given fields: ctx
 func (_m *HealthService) Ping(ctx context.Context) error {
 	ret := _m.Called(ctx)
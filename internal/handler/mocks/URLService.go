@@ -7,11 +7,9 @@ import (
 
 	dto "github.com/dtroode/urlshorter/internal/service/dto"
 
-	mock "github.com/stretchr/testify/mock"
-
 	response "github.com/dtroode/urlshorter/internal/response"
-
 	uuid "github.com/google/uuid"
+	mock "github.com/stretchr/testify/mock"
 )
 
 // URLService is an autogenerated mock type for the URLService type
@@ -190,9 +188,68 @@ func (_c *URLService_DeleteURLs_Call) RunAndReturn(run func(context.Context, *dt
 	return _c
 }
 
-// GetOriginalURL provides a mock function with given fields: ctx, shortKey
-func (_m *URLService) GetOriginalURL(ctx context.Context, shortKey string) (string, error) {
-	ret := _m.Called(ctx, shortKey)
+// GetDeletedUserURLs provides a mock function with given fields: ctx, userID
+func (_m *URLService) GetDeletedUserURLs(ctx context.Context, userID uuid.UUID) ([]*response.GetUserURL, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDeletedUserURLs")
+	}
+
+	var r0 []*response.GetUserURL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ([]*response.GetUserURL, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []*response.GetUserURL); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*response.GetUserURL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// URLService_GetDeletedUserURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetDeletedUserURLs'
+type URLService_GetDeletedUserURLs_Call struct {
+	*mock.Call
+}
+
+// GetDeletedUserURLs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userID uuid.UUID
+func (_e *URLService_Expecter) GetDeletedUserURLs(ctx interface{}, userID interface{}) *URLService_GetDeletedUserURLs_Call {
+	return &URLService_GetDeletedUserURLs_Call{Call: _e.mock.On("GetDeletedUserURLs", ctx, userID)}
+}
+
+func (_c *URLService_GetDeletedUserURLs_Call) Run(run func(ctx context.Context, userID uuid.UUID)) *URLService_GetDeletedUserURLs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uuid.UUID))
+	})
+	return _c
+}
+
+func (_c *URLService_GetDeletedUserURLs_Call) Return(_a0 []*response.GetUserURL, _a1 error) *URLService_GetDeletedUserURLs_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *URLService_GetDeletedUserURLs_Call) RunAndReturn(run func(context.Context, uuid.UUID) ([]*response.GetUserURL, error)) *URLService_GetDeletedUserURLs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetOriginalURL provides a mock function with given fields: ctx, _a1
+func (_m *URLService) GetOriginalURL(ctx context.Context, _a1 *dto.GetOriginalURL) (string, error) {
+	ret := _m.Called(ctx, _a1)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetOriginalURL")
@@ -200,17 +257,17 @@ func (_m *URLService) GetOriginalURL(ctx context.Context, shortKey string) (stri
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
-		return rf(ctx, shortKey)
+	if rf, ok := ret.Get(0).(func(context.Context, *dto.GetOriginalURL) (string, error)); ok {
+		return rf(ctx, _a1)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
-		r0 = rf(ctx, shortKey)
+	if rf, ok := ret.Get(0).(func(context.Context, *dto.GetOriginalURL) string); ok {
+		r0 = rf(ctx, _a1)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, shortKey)
+	if rf, ok := ret.Get(1).(func(context.Context, *dto.GetOriginalURL) error); ok {
+		r1 = rf(ctx, _a1)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -225,14 +282,14 @@ type URLService_GetOriginalURL_Call struct {
 
 // GetOriginalURL is a helper method to define mock.On call
 //   - ctx context.Context
-//   - shortKey string
-func (_e *URLService_Expecter) GetOriginalURL(ctx interface{}, shortKey interface{}) *URLService_GetOriginalURL_Call {
-	return &URLService_GetOriginalURL_Call{Call: _e.mock.On("GetOriginalURL", ctx, shortKey)}
+//   - _a1 *dto.GetOriginalURL
+func (_e *URLService_Expecter) GetOriginalURL(ctx interface{}, _a1 interface{}) *URLService_GetOriginalURL_Call {
+	return &URLService_GetOriginalURL_Call{Call: _e.mock.On("GetOriginalURL", ctx, _a1)}
 }
 
-func (_c *URLService_GetOriginalURL_Call) Run(run func(ctx context.Context, shortKey string)) *URLService_GetOriginalURL_Call {
+func (_c *URLService_GetOriginalURL_Call) Run(run func(ctx context.Context, _a1 *dto.GetOriginalURL)) *URLService_GetOriginalURL_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string))
+		run(args[0].(context.Context), args[1].(*dto.GetOriginalURL))
 	})
 	return _c
 }
@@ -242,7 +299,66 @@ func (_c *URLService_GetOriginalURL_Call) Return(_a0 string, _a1 error) *URLServ
 	return _c
 }
 
-func (_c *URLService_GetOriginalURL_Call) RunAndReturn(run func(context.Context, string) (string, error)) *URLService_GetOriginalURL_Call {
+func (_c *URLService_GetOriginalURL_Call) RunAndReturn(run func(context.Context, *dto.GetOriginalURL) (string, error)) *URLService_GetOriginalURL_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetURLStats provides a mock function with given fields: ctx, _a1
+func (_m *URLService) GetURLStats(ctx context.Context, _a1 *dto.GetURLStats) (*response.URLStats, error) {
+	ret := _m.Called(ctx, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetURLStats")
+	}
+
+	var r0 *response.URLStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *dto.GetURLStats) (*response.URLStats, error)); ok {
+		return rf(ctx, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *dto.GetURLStats) *response.URLStats); ok {
+		r0 = rf(ctx, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*response.URLStats)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *dto.GetURLStats) error); ok {
+		r1 = rf(ctx, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// URLService_GetURLStats_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetURLStats'
+type URLService_GetURLStats_Call struct {
+	*mock.Call
+}
+
+// GetURLStats is a helper method to define mock.On call
+//   - ctx context.Context
+//   - _a1 *dto.GetURLStats
+func (_e *URLService_Expecter) GetURLStats(ctx interface{}, _a1 interface{}) *URLService_GetURLStats_Call {
+	return &URLService_GetURLStats_Call{Call: _e.mock.On("GetURLStats", ctx, _a1)}
+}
+
+func (_c *URLService_GetURLStats_Call) Run(run func(ctx context.Context, _a1 *dto.GetURLStats)) *URLService_GetURLStats_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*dto.GetURLStats))
+	})
+	return _c
+}
+
+func (_c *URLService_GetURLStats_Call) Return(_a0 *response.URLStats, _a1 error) *URLService_GetURLStats_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *URLService_GetURLStats_Call) RunAndReturn(run func(context.Context, *dto.GetURLStats) (*response.URLStats, error)) *URLService_GetURLStats_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -306,6 +422,169 @@ func (_c *URLService_GetUserURLs_Call) RunAndReturn(run func(context.Context, uu
 	return _c
 }
 
+// RestoreURLs provides a mock function with given fields: ctx, _a1
+func (_m *URLService) RestoreURLs(ctx context.Context, _a1 *dto.RestoreURLs) error {
+	ret := _m.Called(ctx, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreURLs")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *dto.RestoreURLs) error); ok {
+		r0 = rf(ctx, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// URLService_RestoreURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RestoreURLs'
+type URLService_RestoreURLs_Call struct {
+	*mock.Call
+}
+
+// RestoreURLs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - _a1 *dto.RestoreURLs
+func (_e *URLService_Expecter) RestoreURLs(ctx interface{}, _a1 interface{}) *URLService_RestoreURLs_Call {
+	return &URLService_RestoreURLs_Call{Call: _e.mock.On("RestoreURLs", ctx, _a1)}
+}
+
+func (_c *URLService_RestoreURLs_Call) Run(run func(ctx context.Context, _a1 *dto.RestoreURLs)) *URLService_RestoreURLs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*dto.RestoreURLs))
+	})
+	return _c
+}
+
+func (_c *URLService_RestoreURLs_Call) Return(_a0 error) *URLService_RestoreURLs_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *URLService_RestoreURLs_Call) RunAndReturn(run func(context.Context, *dto.RestoreURLs) error) *URLService_RestoreURLs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UnlockURL provides a mock function with given fields: ctx, _a1
+func (_m *URLService) UnlockURL(ctx context.Context, _a1 *dto.UnlockURL) (string, error) {
+	ret := _m.Called(ctx, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnlockURL")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *dto.UnlockURL) (string, error)); ok {
+		return rf(ctx, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *dto.UnlockURL) string); ok {
+		r0 = rf(ctx, _a1)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *dto.UnlockURL) error); ok {
+		r1 = rf(ctx, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// URLService_UnlockURL_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UnlockURL'
+type URLService_UnlockURL_Call struct {
+	*mock.Call
+}
+
+// UnlockURL is a helper method to define mock.On call
+//   - ctx context.Context
+//   - _a1 *dto.UnlockURL
+func (_e *URLService_Expecter) UnlockURL(ctx interface{}, _a1 interface{}) *URLService_UnlockURL_Call {
+	return &URLService_UnlockURL_Call{Call: _e.mock.On("UnlockURL", ctx, _a1)}
+}
+
+func (_c *URLService_UnlockURL_Call) Run(run func(ctx context.Context, _a1 *dto.UnlockURL)) *URLService_UnlockURL_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*dto.UnlockURL))
+	})
+	return _c
+}
+
+func (_c *URLService_UnlockURL_Call) Return(_a0 string, _a1 error) *URLService_UnlockURL_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *URLService_UnlockURL_Call) RunAndReturn(run func(context.Context, *dto.UnlockURL) (string, error)) *URLService_UnlockURL_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateURL provides a mock function with given fields: ctx, _a1
+func (_m *URLService) UpdateURL(ctx context.Context, _a1 *dto.UpdateURL) (*response.GetUserURL, error) {
+	ret := _m.Called(ctx, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateURL")
+	}
+
+	var r0 *response.GetUserURL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *dto.UpdateURL) (*response.GetUserURL, error)); ok {
+		return rf(ctx, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *dto.UpdateURL) *response.GetUserURL); ok {
+		r0 = rf(ctx, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*response.GetUserURL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *dto.UpdateURL) error); ok {
+		r1 = rf(ctx, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// URLService_UpdateURL_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateURL'
+type URLService_UpdateURL_Call struct {
+	*mock.Call
+}
+
+// UpdateURL is a helper method to define mock.On call
+//   - ctx context.Context
+//   - _a1 *dto.UpdateURL
+func (_e *URLService_Expecter) UpdateURL(ctx interface{}, _a1 interface{}) *URLService_UpdateURL_Call {
+	return &URLService_UpdateURL_Call{Call: _e.mock.On("UpdateURL", ctx, _a1)}
+}
+
+func (_c *URLService_UpdateURL_Call) Run(run func(ctx context.Context, _a1 *dto.UpdateURL)) *URLService_UpdateURL_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*dto.UpdateURL))
+	})
+	return _c
+}
+
+func (_c *URLService_UpdateURL_Call) Return(_a0 *response.GetUserURL, _a1 error) *URLService_UpdateURL_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *URLService_UpdateURL_Call) RunAndReturn(run func(context.Context, *dto.UpdateURL) (*response.GetUserURL, error)) *URLService_UpdateURL_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewURLService creates a new instance of URLService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewURLService(t interface {
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/dtroode/urlshorter/internal/service"
+	"github.com/dtroode/urlshorter/internal/service/dto"
+)
+
+// linkAccessCookie is the name of the cookie that holds the access token of a password-protected URL.
+// The cookie is scoped to the path of the URL, so every URL has its own token.
+const linkAccessCookie = "link_access"
+
+// passwordForm is the page shown instead of a redirect for password-protected URLs.
+// The form posts the password to the same path.
+var passwordForm = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Password required</title>
+</head>
+<body>
+<form method="post">
+<p>This link is password-protected.</p>
+{{if .}}<p role="alert">{{.}}</p>
+{{end}}<label>Password <input type="password" name="password" autocomplete="current-password" required autofocus></label>
+<button type="submit">Open</button>
+</form>
+</body>
+</html>
+`))
+
+// UnlockURL handles POST requests with the password of a password-protected URL.
+// @Summary Unlock password-protected URL
+// @Description Checks the password of a password-protected URL. After a correct password
+// @Description a short-lived link access cookie is set and the client is redirected back to the short URL.
+// @Description The number of attempts per URL is limited.
+// @Tags URLs
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Param id path string true "Short URL identifier"
+// @Param password formData string true "Password of the URL"
+// @Success 303 {string} string "Redirect to the short URL"
+// @Failure 400 {string} string "Bad request - missing short key or invalid form"
+// @Failure 403 {string} string "Password form with an error - wrong password"
+// @Failure 404 {string} string "URL not found"
+// @Failure 410 {string} string "URL has been deleted or has expired"
+// @Failure 429 {string} string "Password form with an error - too many attempts"
+// @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "Service unavailable - storage is down"
+// @Router /{id} [post]
+func (h *URL) UnlockURL(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	dto := dto.NewUnlockURL(id, r.PostForm.Get("password"))
+	token, err := h.service.UnlockURL(ctx, dto)
+	if err != nil {
+		if errors.Is(err, service.ErrWrongPassword) {
+			h.writePasswordForm(w, http.StatusForbidden, "Wrong password.")
+
+			return
+		}
+		if errors.Is(err, service.ErrTooManyAttempts) {
+			h.writePasswordForm(w, http.StatusTooManyRequests, "Too many attempts, try again later.")
+
+			return
+		}
+		if errors.Is(err, service.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+		if errors.Is(err, service.ErrGone) {
+			w.WriteHeader(http.StatusGone)
+
+			return
+		}
+		if errors.Is(err, service.ErrUnavailable) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+		h.logger.Error("service error", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	path := "/" + url.PathEscape(id)
+	if token != "" {
+		cookie := http.Cookie{
+			Name:     linkAccessCookie,
+			Value:    token,
+			Path:     path,
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		}
+		http.SetCookie(w, &cookie)
+	}
+
+	w.Header().Set("location", path)
+	w.WriteHeader(http.StatusSeeOther)
+}
+
+// writePasswordForm writes the password form with the given status code.
+// A non-empty message is shown above the password field.
+func (h *URL) writePasswordForm(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("content-type", "text/html; charset=utf-8")
+	w.Header().Set("cache-control", "no-store")
+	w.WriteHeader(statusCode)
+
+	if err := passwordForm.Execute(w, message); err != nil {
+		h.logger.Error("failed to render password form", "error", err)
+	}
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/handler/mocks"
+	"github.com/dtroode/urlshorter/internal/logger"
+	"github.com/dtroode/urlshorter/internal/service"
+	"github.com/dtroode/urlshorter/internal/service/dto"
+)
+
+func TestHandler_UnlockURL(t *testing.T) {
+	dummyLogger := &logger.Logger{
+		Logger: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+	}
+
+	tests := map[string]struct {
+		id              string
+		serviceResponse string
+		serviceError    error
+		wantStatusCode  int
+		wantCookie      bool
+		wantLocation    string
+		wantMessage     string
+	}{
+		"id is empty": {
+			id:             "",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"service error": {
+			id:             "secret",
+			serviceError:   errors.New("service error"),
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		"not found": {
+			id:             "secret",
+			serviceError:   service.ErrNotFound,
+			wantStatusCode: http.StatusNotFound,
+		},
+		"deleted": {
+			id:             "secret",
+			serviceError:   service.ErrGone,
+			wantStatusCode: http.StatusGone,
+		},
+		"wrong password": {
+			id:             "secret",
+			serviceError:   service.ErrWrongPassword,
+			wantStatusCode: http.StatusForbidden,
+			wantMessage:    "Wrong password.",
+		},
+		"too many attempts": {
+			id:             "secret",
+			serviceError:   service.ErrTooManyAttempts,
+			wantStatusCode: http.StatusTooManyRequests,
+			wantMessage:    "Too many attempts",
+		},
+		"not protected": {
+			id:             "secret",
+			wantStatusCode: http.StatusSeeOther,
+			wantLocation:   "/secret",
+		},
+		"success": {
+			id:              "secret",
+			serviceResponse: "token",
+			wantStatusCode:  http.StatusSeeOther,
+			wantCookie:      true,
+			wantLocation:    "/secret",
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			form := url.Values{"password": {"s3cret"}}
+			r := httptest.NewRequest(http.MethodPost, "/"+tt.id, strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			chiContext := chi.NewRouteContext()
+			chiContext.URLParams.Add("id", tt.id)
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, chiContext)
+			r = r.WithContext(ctx)
+
+			w := httptest.NewRecorder()
+
+			s := mocks.NewURLService(t)
+			dto := dto.NewUnlockURL(tt.id, "s3cret")
+			s.On("UnlockURL", ctx, dto).Maybe().Return(tt.serviceResponse, tt.serviceError)
+
+			h := NewURL(s, dummyLogger)
+
+			h.UnlockURL(w, r)
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+			assert.Equal(t, tt.wantLocation, res.Header.Get("location"))
+
+			if tt.wantCookie {
+				require.Len(t, res.Cookies(), 1)
+				cookie := res.Cookies()[0]
+				assert.Equal(t, linkAccessCookie, cookie.Name)
+				assert.Equal(t, tt.serviceResponse, cookie.Value)
+				assert.Equal(t, "/"+tt.id, cookie.Path)
+				assert.True(t, cookie.HttpOnly)
+			} else {
+				assert.Empty(t, res.Cookies())
+			}
+
+			if tt.wantMessage != "" {
+				body, err := io.ReadAll(res.Body)
+				require.NoError(t, err)
+				assert.Contains(t, string(body), `<input type="password" name="password"`)
+				assert.Contains(t, string(body), tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestHandler_GetOriginalURL_PasswordForm(t *testing.T) {
+	dummyLogger := &logger.Logger{
+		Logger: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/secret", nil)
+	chiContext := chi.NewRouteContext()
+	chiContext.URLParams.Add("id", "secret")
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, chiContext)
+	r = r.WithContext(ctx)
+
+	w := httptest.NewRecorder()
+
+	s := mocks.NewURLService(t)
+	s.On("GetOriginalURL", ctx, dto.NewGetOriginalURL("secret", "", "", "192.0.2.1")).Return("", service.ErrPasswordRequired)
+
+	h := NewURL(s, dummyLogger)
+
+	h.GetOriginalURL(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", res.Header.Get("content-type"))
+	assert.Empty(t, res.Header.Get("location"))
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `<form method="post">`)
+	assert.NotContains(t, string(body), `role="alert"`)
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"net"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -21,9 +23,13 @@ import (
 // URLService defines the interface for URL shortening operations.
 // It provides methods for creating, retrieving, and managing shortened URLs.
 type URLService interface {
-	// GetOriginalURL retrieves the original URL associated with a short key.
+	// GetOriginalURL retrieves the original URL associated with a short key and records a click.
 	// Returns the original URL string or an error if not found or deleted.
-	GetOriginalURL(ctx context.Context, shortKey string) (string, error)
+	GetOriginalURL(ctx context.Context, dto *dto.GetOriginalURL) (string, error)
+
+	// UnlockURL checks the password of a password-protected URL.
+	// Returns a token that gives access to the URL or an error if the password is wrong.
+	UnlockURL(ctx context.Context, dto *dto.UnlockURL) (string, error)
 
 	// GetUserURLs retrieves all URLs created by a specific user.
 	// Returns a slice of user URLs or an error if the operation fails.
@@ -37,9 +43,25 @@ type URLService interface {
 	// Returns a slice of created URLs with their correlation IDs or an error if creation fails.
 	CreateShortURLBatch(ctx context.Context, dto *dto.CreateShortURLBatch) ([]*response.CreateShortURLBatch, error)
 
+	// UpdateURL changes attributes of a URL owned by the given user.
+	// Returns the changed URL or an error if the operation fails.
+	UpdateURL(ctx context.Context, dto *dto.UpdateURL) (*response.GetUserURL, error)
+
 	// DeleteURLs marks the specified URLs as deleted for the given user.
 	// Returns an error if the deletion operation fails.
 	DeleteURLs(ctx context.Context, dto *dto.DeleteURLs) error
+
+	// GetDeletedUserURLs retrieves all deleted URLs created by a specific user.
+	// Returns a slice of deleted user URLs or an error if the operation fails.
+	GetDeletedUserURLs(ctx context.Context, userID uuid.UUID) ([]*response.GetUserURL, error)
+
+	// RestoreURLs clears the deletion mark of the specified URLs for the given user.
+	// Returns an error if the restoration fails.
+	RestoreURLs(ctx context.Context, dto *dto.RestoreURLs) error
+
+	// GetURLStats retrieves click statistics of a URL owned by the given user.
+	// Returns statistics of the URL or an error if the operation fails.
+	GetURLStats(ctx context.Context, dto *dto.GetURLStats) (*response.URLStats, error)
 }
 
 // URL represents the URL shortening HTTP handler.
@@ -59,16 +81,19 @@ func NewURL(s URLService, l *logger.Logger) *URL {
 
 // GetOriginalURL handles GET requests to retrieve the original URL from a short key.
 // @Summary Get original URL by short key
-// @Description Redirects to the original URL associated with the provided short key
+// @Description Redirects to the original URL associated with the provided short key.
+// @Description For password-protected URLs a password form is shown unless the link access cookie is set.
 // @Tags URLs
 // @Accept json
-// @Produce json
+// @Produce json,html
 // @Param id path string true "Short URL identifier"
+// @Success 200 {string} string "Password form of a password-protected URL"
 // @Success 307 {string} string "Temporary redirect to original URL"
 // @Failure 400 {string} string "Bad request - missing short key"
 // @Failure 404 {string} string "URL not found"
-// @Failure 410 {string} string "URL has been deleted"
+// @Failure 410 {string} string "URL has been deleted, has expired or has no clicks left"
 // @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "Service unavailable - storage is down"
 // @Router /{id} [get]
 func (h *URL) GetOriginalURL(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -80,8 +105,17 @@ func (h *URL) GetOriginalURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	originalURL, err := h.service.GetOriginalURL(ctx, id)
+	dto := dto.NewGetOriginalURL(id, r.Referer(), r.UserAgent(), clientIP(r))
+	if cookie, err := r.Cookie(linkAccessCookie); err == nil {
+		dto.AccessToken = cookie.Value
+	}
+	originalURL, err := h.service.GetOriginalURL(ctx, dto)
 	if err != nil {
+		if errors.Is(err, service.ErrPasswordRequired) {
+			h.writePasswordForm(w, http.StatusOK, "")
+
+			return
+		}
 		if errors.Is(err, service.ErrNotFound) {
 			w.WriteHeader(http.StatusNotFound)
 
@@ -92,6 +126,11 @@ func (h *URL) GetOriginalURL(w http.ResponseWriter, r *http.Request) {
 
 			return
 		}
+		if errors.Is(err, service.ErrUnavailable) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
 		h.logger.Error("service error", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 
@@ -111,9 +150,10 @@ func (h *URL) GetOriginalURL(w http.ResponseWriter, r *http.Request) {
 // @Param url body string true "Original URL to shorten"
 // @Success 201 {string} string "Shortened URL created"
 // @Success 409 {string} string "URL already exists"
-// @Failure 400 {string} string "Bad request - invalid URL"
+// @Failure 400 {object} response.Error "Bad request - invalid URL"
 // @Failure 401 {string} string "Unauthorized - invalid or missing authentication"
 // @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "Service unavailable - storage is down"
 // @Router / [post]
 func (h *URL) CreateShortURL(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -135,7 +175,17 @@ func (h *URL) CreateShortURL(w http.ResponseWriter, r *http.Request) {
 	url := string(body)
 	dto := dto.NewCreateShortURL(url, userID)
 	shortURL, err := h.service.CreateShortURL(ctx, dto)
+	if errors.Is(err, service.ErrInvalidURL) {
+		h.writeError(w, http.StatusBadRequest, err)
+
+		return
+	}
 	if err != nil && !errors.Is(err, service.ErrConflict) {
+		if errors.Is(err, service.ErrUnavailable) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
 		h.logger.Error("service error", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 
@@ -155,16 +205,22 @@ func (h *URL) CreateShortURL(w http.ResponseWriter, r *http.Request) {
 
 // CreateShortURLJSON handles POST requests to create a shortened URL from JSON.
 // @Summary Create short URL from JSON
-// @Description Creates a shortened URL from the provided JSON request
+// @Description Creates a shortened URL from the provided JSON request.
+// @Description An optional alias sets the short key. If the alias is used by another URL,
+// @Description 409 is returned with an error body instead of a shortened URL.
+// @Description With force_new a new shortened URL is created even if the URL has already been shortened.
+// @Description With password the shortened URL opens only after the password is entered.
+// @Description With max_clicks the shortened URL stops working after that many redirects.
 // @Tags URLs
 // @Accept json
 // @Produce json
 // @Param request body request.CreateShortURL true "URL shortening request"
 // @Success 201 {object} response.CreateShortURL "Shortened URL created"
 // @Success 409 {object} response.CreateShortURL "URL already exists"
-// @Failure 400 {string} string "Bad request - invalid JSON"
+// @Failure 400 {object} response.Error "Bad request - invalid JSON, URL, alias, expiration, password or max clicks"
 // @Failure 401 {string} string "Unauthorized - invalid or missing authentication"
 // @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "Service unavailable - storage is down"
 // @Router /api/shorten [post]
 func (h *URL) CreateShortURLJSON(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -185,8 +241,29 @@ func (h *URL) CreateShortURLJSON(w http.ResponseWriter, r *http.Request) {
 	}
 
 	dto := dto.NewCreateShortURL(request.URL, userID)
+	dto.Alias = request.Alias
+	dto.TTL = request.TTL
+	dto.ExpiresAt = request.ExpiresAt
+	dto.ForceNew = request.ForceNew
+	dto.Password = request.Password
+	dto.MaxClicks = request.MaxClicks
 	shortURL, err := h.service.CreateShortURL(ctx, dto)
+	if isInvalidInput(err) {
+		h.writeError(w, http.StatusBadRequest, err)
+
+		return
+	}
+	if errors.Is(err, service.ErrAliasTaken) {
+		h.writeError(w, http.StatusConflict, err)
+
+		return
+	}
 	if err != nil && !errors.Is(err, service.ErrConflict) {
+		if errors.Is(err, service.ErrUnavailable) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
 		h.logger.Error("service error", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 
@@ -213,15 +290,21 @@ func (h *URL) CreateShortURLJSON(w http.ResponseWriter, r *http.Request) {
 
 // CreateShortURLBatch handles POST requests to create multiple shortened URLs in batch.
 // @Summary Create multiple short URLs in batch
-// @Description Creates multiple shortened URLs from the provided batch request
+// @Description Creates multiple shortened URLs from the provided batch request.
+// @Description With force_new new shortened URLs are created even if the URLs have already been shortened.
+// @Description With password a shortened URL opens only after the password is entered.
+// @Description With max_clicks a shortened URL stops working after that many redirects.
 // @Tags URLs
 // @Accept json
 // @Produce json
 // @Param request body []request.CreateShortURLBatch true "Batch URL shortening request"
+// @Param force_new query bool false "Create new shortened URLs even if the URLs have already been shortened"
 // @Success 201 {array} response.CreateShortURLBatch "Shortened URLs created"
-// @Failure 400 {string} string "Bad request - invalid JSON or empty batch"
+// @Failure 400 {object} response.Error "Bad request - invalid JSON, empty batch, URL, alias, expiration, password or max clicks"
+// @Failure 409 {object} response.Error "Alias is already taken"
 // @Failure 401 {string} string "Unauthorized - invalid or missing authentication"
 // @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "Service unavailable - storage is down"
 // @Router /api/shorten/batch [post]
 func (h *URL) CreateShortURLBatch(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -247,9 +330,37 @@ func (h *URL) CreateShortURLBatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var forceNew bool
+	if value := r.URL.Query().Get("force_new"); value != "" {
+		var err error
+		forceNew, err = strconv.ParseBool(value)
+		if err != nil {
+			h.logger.Info("failed to parse force_new", "value", value)
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+	}
+
 	dto := dto.NewCreateShortURLBatch(request, userID)
+	dto.ForceNew = forceNew
 	shortURLs, err := h.service.CreateShortURLBatch(ctx, dto)
+	if isInvalidInput(err) {
+		h.writeError(w, http.StatusBadRequest, err)
+
+		return
+	}
+	if errors.Is(err, service.ErrAliasTaken) {
+		h.writeError(w, http.StatusConflict, err)
+
+		return
+	}
 	if err != nil {
+		if errors.Is(err, service.ErrUnavailable) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
 		h.logger.Error("service error", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 
@@ -269,7 +380,8 @@ func (h *URL) CreateShortURLBatch(w http.ResponseWriter, r *http.Request) {
 
 // GetUserURLs handles GET requests to retrieve all URLs created by the authenticated user.
 // @Summary Get user's URLs
-// @Description Retrieves all URLs created by the authenticated user
+// @Description Retrieves all URLs created by the authenticated user.
+// @Description Expired URLs are included and marked as expired.
 // @Tags User
 // @Accept json
 // @Produce json
@@ -277,6 +389,7 @@ func (h *URL) CreateShortURLBatch(w http.ResponseWriter, r *http.Request) {
 // @Success 204 {string} string "No URLs found"
 // @Failure 401 {string} string "Unauthorized - invalid or missing authentication"
 // @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "Service unavailable - storage is down"
 // @Router /api/user/urls [get]
 func (h *URL) GetUserURLs(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -296,6 +409,11 @@ func (h *URL) GetUserURLs(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if errors.Is(err, service.ErrUnavailable) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
 		h.logger.Error("service error", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 
@@ -313,6 +431,92 @@ func (h *URL) GetUserURLs(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// UpdateURL handles PATCH requests to change a URL owned by the authenticated user.
+// @Summary Update user's URL
+// @Description Changes the destination and other attributes of a URL created by the authenticated user.
+// @Description Omitted fields are left unchanged.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param key path string true "Short URL identifier"
+// @Param request body request.UpdateURL true "URL changes"
+// @Success 200 {object} response.GetUserURL "Updated URL"
+// @Failure 400 {object} response.Error "Bad request - invalid JSON, URL, expiration, password or max clicks"
+// @Failure 401 {string} string "Unauthorized - invalid or missing authentication"
+// @Failure 403 {string} string "URL belongs to another user"
+// @Failure 404 {string} string "URL not found"
+// @Failure 410 {string} string "URL has been deleted"
+// @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "Service unavailable - storage is down"
+// @Router /api/user/urls/{key} [patch]
+func (h *URL) UpdateURL(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		h.logger.Error("failed to get user id from context")
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	key := chi.URLParam(r, "key")
+	if key == "" {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	request := request.UpdateURL{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.logger.Info("failed to decode request")
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	dto := dto.NewUpdateURL(key, &request, userID)
+	userURL, err := h.service.UpdateURL(ctx, dto)
+	if err != nil {
+		if isInvalidInput(err) {
+			h.writeError(w, http.StatusBadRequest, err)
+
+			return
+		}
+		if errors.Is(err, service.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+		if errors.Is(err, service.ErrForbidden) {
+			w.WriteHeader(http.StatusForbidden)
+
+			return
+		}
+		if errors.Is(err, service.ErrGone) {
+			w.WriteHeader(http.StatusGone)
+
+			return
+		}
+
+		if errors.Is(err, service.ErrUnavailable) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+		h.logger.Error("service error", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(userURL); err != nil {
+		h.logger.Error("failed to encode response", "error", err)
+	}
+}
+
 // DeleteURLs handles DELETE requests to mark URLs as deleted for the authenticated user.
 // @Summary Delete user's URLs
 // @Description Marks the specified URLs as deleted for the authenticated user
@@ -353,3 +557,205 @@ func (h *URL) DeleteURLs(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusAccepted)
 }
+
+// GetDeletedUserURLs handles GET requests to retrieve URLs deleted by the authenticated user.
+// @Summary Get user's deleted URLs
+// @Description Retrieves all URLs created and then deleted by the authenticated user.
+// @Description Deleted URLs can be restored until they are purged.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Success 200 {array} response.GetUserURL "User's deleted URLs"
+// @Success 204 {string} string "No deleted URLs found"
+// @Failure 401 {string} string "Unauthorized - invalid or missing authentication"
+// @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "Service unavailable - storage is down"
+// @Router /api/user/urls/trash [get]
+func (h *URL) GetDeletedUserURLs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		h.logger.Error("failed to get user id from context")
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	userURLs, err := h.service.GetDeletedUserURLs(ctx, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrNoContent) {
+			w.WriteHeader(http.StatusNoContent)
+
+			return
+		}
+
+		if errors.Is(err, service.ErrUnavailable) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+		h.logger.Error("service error", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(userURLs); err != nil {
+		h.logger.Error("failed to encode response", "error", err)
+	}
+}
+
+// RestoreURLs handles POST requests to restore URLs deleted by the authenticated user.
+// @Summary Restore user's URLs
+// @Description Clears the deletion mark of the specified URLs created by the authenticated user.
+// @Description Restored URLs redirect again immediately. Unknown keys and URLs of other users are skipped.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param shortKeys body []string true "Array of short keys to restore"
+// @Success 204 {string} string "URLs restored"
+// @Failure 400 {string} string "Bad request - invalid JSON"
+// @Failure 401 {string} string "Unauthorized - invalid or missing authentication"
+// @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "Service unavailable - storage is down"
+// @Router /api/user/urls/restore [post]
+func (h *URL) RestoreURLs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		h.logger.Error("failed to get user id from context")
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	var shortKeys []string
+	if err := json.NewDecoder(r.Body).Decode(&shortKeys); err != nil {
+		h.logger.Info("failed to decode request")
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	dto := dto.NewRestoreURLs(shortKeys, userID)
+	if err := h.service.RestoreURLs(ctx, dto); err != nil {
+		if errors.Is(err, service.ErrUnavailable) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+		h.logger.Error("failed to restore urls", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetURLStats handles GET requests to retrieve click statistics of a URL owned by the authenticated user.
+// @Summary Get URL statistics
+// @Description Retrieves click statistics of a URL created by the authenticated user
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param key path string true "Short URL identifier"
+// @Param bucket query string false "Size of time series buckets" Enums(hour, day) default(day)
+// @Success 200 {object} response.URLStats "URL statistics"
+// @Failure 400 {object} response.Error "Bad request - invalid bucket"
+// @Failure 401 {string} string "Unauthorized - invalid or missing authentication"
+// @Failure 403 {string} string "URL belongs to another user"
+// @Failure 404 {string} string "URL not found"
+// @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "Service unavailable - storage is down"
+// @Router /api/user/urls/{key}/stats [get]
+func (h *URL) GetURLStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		h.logger.Error("failed to get user id from context")
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	key := chi.URLParam(r, "key")
+	if key == "" {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	dto := dto.NewGetURLStats(key, r.URL.Query().Get("bucket"), userID)
+	stats, err := h.service.GetURLStats(ctx, dto)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidStatsBucket) {
+			h.writeError(w, http.StatusBadRequest, err)
+
+			return
+		}
+		if errors.Is(err, service.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+		if errors.Is(err, service.ErrForbidden) {
+			w.WriteHeader(http.StatusForbidden)
+
+			return
+		}
+
+		if errors.Is(err, service.ErrUnavailable) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+		h.logger.Error("service error", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		h.logger.Error("failed to encode response", "error", err)
+	}
+}
+
+// isInvalidInput reports whether err is caused by invalid values in the request.
+func isInvalidInput(err error) bool {
+	return errors.Is(err, service.ErrInvalidURL) ||
+		errors.Is(err, service.ErrInvalidAlias) ||
+		errors.Is(err, service.ErrInvalidExpiration) ||
+		errors.Is(err, service.ErrInvalidPassword) ||
+		errors.Is(err, service.ErrInvalidMaxClicks)
+}
+
+// writeError writes an error response with the given status code and a JSON body
+// describing err.
+func (h *URL) writeError(w http.ResponseWriter, statusCode int, err error) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(statusCode)
+
+	resp := response.Error{
+		Error: err.Error(),
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error("failed to encode response", "error", err)
+	}
+}
+
+// clientIP returns the IP address of the client that sent the request.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
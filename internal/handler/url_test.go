@@ -11,6 +11,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -42,6 +43,7 @@ func TestHandler_GetOriginalURL(t *testing.T) {
 
 	tests := map[string]struct {
 		id              string
+		accessToken     string
 		serviceResponse string
 		serviceError    error
 		wantError       bool
@@ -71,12 +73,31 @@ func TestHandler_GetOriginalURL(t *testing.T) {
 			wantError:      true,
 			wantStatusCode: http.StatusGone,
 		},
+		"storage unavailable": {
+			id:             "d8398Sj3",
+			serviceError:   fmt.Errorf("failed to get original URL: %w", service.ErrUnavailable),
+			wantError:      true,
+			wantStatusCode: http.StatusServiceUnavailable,
+		},
+		"password required": {
+			id:             "d8398Sj3",
+			serviceError:   service.ErrPasswordRequired,
+			wantError:      true,
+			wantStatusCode: http.StatusOK,
+		},
 		"success": {
 			id:              "d8398Sj3",
 			serviceResponse: responseURL,
 			wantStatusCode:  http.StatusTemporaryRedirect,
 			wantResponse:    responseURL,
 		},
+		"success with access token": {
+			id:              "d8398Sj3",
+			accessToken:     "token",
+			serviceResponse: responseURL,
+			wantStatusCode:  http.StatusTemporaryRedirect,
+			wantResponse:    responseURL,
+		},
 	}
 
 	for tn, tt := range tests {
@@ -84,6 +105,11 @@ func TestHandler_GetOriginalURL(t *testing.T) {
 			t.Parallel()
 
 			r := httptest.NewRequest(http.MethodGet, "/"+tt.id, nil)
+			r.Header.Set("Referer", "https://ya.ru/")
+			r.Header.Set("User-Agent", "Mozilla/5.0")
+			if tt.accessToken != "" {
+				r.AddCookie(&http.Cookie{Name: linkAccessCookie, Value: tt.accessToken})
+			}
 
 			// add chi context to basic context and
 			// url param to chi context for handler
@@ -97,7 +123,9 @@ func TestHandler_GetOriginalURL(t *testing.T) {
 			w := httptest.NewRecorder()
 
 			service := mocks.NewURLService(t)
-			service.On("GetOriginalURL", ctx, tt.id).Maybe().Return(tt.serviceResponse, tt.serviceError)
+			dto := dto.NewGetOriginalURL(tt.id, "https://ya.ru/", "Mozilla/5.0", "192.0.2.1")
+			dto.AccessToken = tt.accessToken
+			service.On("GetOriginalURL", ctx, dto).Maybe().Return(tt.serviceResponse, tt.serviceError)
 
 			h := NewURL(service, dummyLogger)
 
@@ -157,6 +185,23 @@ func TestHandler_CreateShortURL(t *testing.T) {
 			wantError:        true,
 			wantStatusCode:   http.StatusInternalServerError,
 		},
+		"service error unavailable": {
+			ctx:              auth.SetUserIDToContext(context.Background(), userID),
+			body:             strings.NewReader(url),
+			readBodyResponse: 0,
+			serviceError:     service.ErrUnavailable,
+			wantError:        true,
+			wantStatusCode:   http.StatusServiceUnavailable,
+		},
+		"service error invalid url": {
+			ctx:              auth.SetUserIDToContext(context.Background(), userID),
+			body:             strings.NewReader(url),
+			readBodyResponse: 0,
+			serviceError:     fmt.Errorf("%w: scheme is missing", service.ErrInvalidURL),
+			wantStatusCode:   http.StatusBadRequest,
+			wantContentType:  "application/json",
+			wantResponse:     []byte(`{"error":"invalid url: scheme is missing"}` + "\n"),
+		},
 		"service error conflict": {
 			ctx:              auth.SetUserIDToContext(context.Background(), userID),
 			body:             strings.NewReader(url),
@@ -221,6 +266,7 @@ func TestHandler_CreateShortURLJSON(t *testing.T) {
 	tests := map[string]struct {
 		ctx             context.Context
 		body            string
+		forceNew        bool
 		serviceResponse string
 		serviceError    error
 		wantError       bool
@@ -246,6 +292,54 @@ func TestHandler_CreateShortURLJSON(t *testing.T) {
 			wantError:      true,
 			wantStatusCode: http.StatusInternalServerError,
 		},
+		"service error invalid alias": {
+			ctx:             auth.SetUserIDToContext(context.Background(), userID),
+			body:            fmt.Sprintf(`{"url": "%s"}`, url),
+			serviceError:    service.ErrInvalidAlias,
+			wantStatusCode:  http.StatusBadRequest,
+			wantContentType: "application/json",
+			wantResponse:    `{"error": "invalid alias"}`,
+		},
+		"service error invalid url": {
+			ctx:             auth.SetUserIDToContext(context.Background(), userID),
+			body:            fmt.Sprintf(`{"url": "%s"}`, url),
+			serviceError:    fmt.Errorf("%w: scheme is missing", service.ErrInvalidURL),
+			wantStatusCode:  http.StatusBadRequest,
+			wantContentType: "application/json",
+			wantResponse:    `{"error": "invalid url: scheme is missing"}`,
+		},
+		"service error invalid expiration": {
+			ctx:             auth.SetUserIDToContext(context.Background(), userID),
+			body:            fmt.Sprintf(`{"url": "%s"}`, url),
+			serviceError:    service.ErrInvalidExpiration,
+			wantStatusCode:  http.StatusBadRequest,
+			wantContentType: "application/json",
+			wantResponse:    `{"error": "invalid expiration"}`,
+		},
+		"service error invalid password": {
+			ctx:             auth.SetUserIDToContext(context.Background(), userID),
+			body:            fmt.Sprintf(`{"url": "%s"}`, url),
+			serviceError:    service.ErrInvalidPassword,
+			wantStatusCode:  http.StatusBadRequest,
+			wantContentType: "application/json",
+			wantResponse:    `{"error": "invalid password"}`,
+		},
+		"service error invalid max clicks": {
+			ctx:             auth.SetUserIDToContext(context.Background(), userID),
+			body:            fmt.Sprintf(`{"url": "%s"}`, url),
+			serviceError:    service.ErrInvalidMaxClicks,
+			wantStatusCode:  http.StatusBadRequest,
+			wantContentType: "application/json",
+			wantResponse:    `{"error": "invalid max clicks"}`,
+		},
+		"service error alias taken": {
+			ctx:             auth.SetUserIDToContext(context.Background(), userID),
+			body:            fmt.Sprintf(`{"url": "%s"}`, url),
+			serviceError:    service.ErrAliasTaken,
+			wantStatusCode:  http.StatusConflict,
+			wantContentType: "application/json",
+			wantResponse:    `{"error": "alias is already taken"}`,
+		},
 		"service error conflict": {
 			ctx:             auth.SetUserIDToContext(context.Background(), userID),
 			body:            fmt.Sprintf(`{"url": "%s"}`, url),
@@ -263,6 +357,15 @@ func TestHandler_CreateShortURLJSON(t *testing.T) {
 			wantContentType: "application/json",
 			wantResponse:    fmt.Sprintf(`{"result": "%s"}`, responseURL),
 		},
+		"success force new": {
+			ctx:             auth.SetUserIDToContext(context.Background(), userID),
+			body:            fmt.Sprintf(`{"url": "%s", "force_new": true}`, url),
+			forceNew:        true,
+			serviceResponse: responseURL,
+			wantStatusCode:  http.StatusCreated,
+			wantContentType: "application/json",
+			wantResponse:    fmt.Sprintf(`{"result": "%s"}`, responseURL),
+		},
 	}
 
 	for tn, tt := range tests {
@@ -275,6 +378,7 @@ func TestHandler_CreateShortURLJSON(t *testing.T) {
 
 			s := mocks.NewURLService(t)
 			dto := dto.NewCreateShortURL(url, userID)
+			dto.ForceNew = tt.forceNew
 			s.On("CreateShortURL", r.Context(), dto).Maybe().Return(tt.serviceResponse, tt.serviceError)
 
 			h := NewURL(s, dummyLogger)
@@ -306,8 +410,10 @@ func TestHandler_CreateShortURLBatch(t *testing.T) {
 
 	tests := map[string]struct {
 		ctx             context.Context
+		query           string
 		body            string
 		serviceRequest  []*request.CreateShortURLBatch
+		serviceForceNew bool
 		serviceResponse []*response.CreateShortURLBatch
 		serviceError    error
 		wantError       bool
@@ -332,6 +438,34 @@ func TestHandler_CreateShortURLBatch(t *testing.T) {
 			wantError:      true,
 			wantStatusCode: http.StatusBadRequest,
 		},
+		"invalid force new": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			query:          "?force_new=maybe",
+			body:           `[{"correlation_id": "1", "original_url": "http://yandex.ru/"}]`,
+			wantError:      true,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"force new": {
+			ctx:   auth.SetUserIDToContext(context.Background(), userID),
+			query: "?force_new=true",
+			body:  `[{"correlation_id": "1", "original_url": "http://yandex.ru/"}]`,
+			serviceRequest: []*request.CreateShortURLBatch{
+				{
+					CorrelationID: "1",
+					OriginalURL:   "http://yandex.ru/",
+				},
+			},
+			serviceForceNew: true,
+			serviceResponse: []*response.CreateShortURLBatch{
+				{
+					CorrelationID: "1",
+					ShortURL:      "http://localhost:8000/yndx",
+				},
+			},
+			wantStatusCode:  http.StatusCreated,
+			wantContentType: "application/json",
+			wantResponse:    `[{"correlation_id": "1", "short_url": "http://localhost:8000/yndx"}]`,
+		},
 		"service error": {
 			ctx:  auth.SetUserIDToContext(context.Background(), userID),
 			body: `[{"correlation_id": "1", "original_url": "http://yandex.ru/"}, {"correlation_id": "2", "original_url": "http://google.com"}]`,
@@ -349,6 +483,35 @@ func TestHandler_CreateShortURLBatch(t *testing.T) {
 			wantError:      true,
 			wantStatusCode: http.StatusInternalServerError,
 		},
+		"service error invalid url": {
+			ctx:  auth.SetUserIDToContext(context.Background(), userID),
+			body: `[{"correlation_id": "1", "original_url": "yandex.ru"}]`,
+			serviceRequest: []*request.CreateShortURLBatch{
+				{
+					CorrelationID: "1",
+					OriginalURL:   "yandex.ru",
+				},
+			},
+			serviceError:    fmt.Errorf("correlation id %q: %w: scheme is missing", "1", service.ErrInvalidURL),
+			wantStatusCode:  http.StatusBadRequest,
+			wantContentType: "application/json",
+			wantResponse:    `{"error": "correlation id \"1\": invalid url: scheme is missing"}`,
+		},
+		"service error alias taken": {
+			ctx:  auth.SetUserIDToContext(context.Background(), userID),
+			body: `[{"correlation_id": "1", "original_url": "http://yandex.ru/", "alias": "yandex"}]`,
+			serviceRequest: []*request.CreateShortURLBatch{
+				{
+					CorrelationID: "1",
+					OriginalURL:   "http://yandex.ru/",
+					Alias:         "yandex",
+				},
+			},
+			serviceError:    service.ErrAliasTaken,
+			wantStatusCode:  http.StatusConflict,
+			wantContentType: "application/json",
+			wantResponse:    `{"error": "alias is already taken"}`,
+		},
 		"success": {
 			ctx:  auth.SetUserIDToContext(context.Background(), userID),
 			body: `[{"correlation_id": "1", "original_url": "http://yandex.ru/"}, {"correlation_id": "2", "original_url": "http://google.com"}]`,
@@ -382,12 +545,13 @@ func TestHandler_CreateShortURLBatch(t *testing.T) {
 		t.Run(tn, func(t *testing.T) {
 			t.Parallel()
 
-			r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(tt.body))
+			r := httptest.NewRequest(http.MethodPost, "/api/shorten/batch"+tt.query, strings.NewReader(tt.body))
 			r = r.WithContext(tt.ctx)
 			w := httptest.NewRecorder()
 
 			s := mocks.NewURLService(t)
 			dto := dto.NewCreateShortURLBatch(tt.serviceRequest, userID)
+			dto.ForceNew = tt.serviceForceNew
 			s.On("CreateShortURLBatch", r.Context(), dto).Maybe().Return(tt.serviceResponse, tt.serviceError)
 
 			h := NewURL(s, dummyLogger)
@@ -554,3 +718,403 @@ func TestHandler_DeleteURLs(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_GetURLStats(t *testing.T) {
+	dummyLogger := &logger.Logger{
+		Logger: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+	}
+
+	userID := uuid.New()
+	key := "ABOBA"
+
+	tests := map[string]struct {
+		ctx             context.Context
+		key             string
+		bucket          string
+		serviceResponse *response.URLStats
+		serviceError    error
+		wantStatusCode  int
+		wantResponse    string
+	}{
+		"failed to get user id from context": {
+			ctx:            context.Background(),
+			key:            key,
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		"key is empty": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"service error": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			key:            key,
+			serviceError:   errors.New("service error"),
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		"service error invalid bucket": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			key:            key,
+			bucket:         "week",
+			serviceError:   service.ErrInvalidStatsBucket,
+			wantStatusCode: http.StatusBadRequest,
+			wantResponse:   `{"error": "invalid stats bucket"}`,
+		},
+		"service error not found": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			key:            key,
+			serviceError:   service.ErrNotFound,
+			wantStatusCode: http.StatusNotFound,
+		},
+		"service error forbidden": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			key:            key,
+			serviceError:   service.ErrForbidden,
+			wantStatusCode: http.StatusForbidden,
+		},
+		"success": {
+			ctx:    auth.SetUserIDToContext(context.Background(), userID),
+			key:    key,
+			bucket: "hour",
+			serviceResponse: &response.URLStats{
+				TotalClicks:    2,
+				UniqueVisitors: 1,
+				Series: []*response.StatsBucket{
+					{Start: time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC), Clicks: 2},
+				},
+				TopReferrers: []*response.StatsCount{{Value: "direct", Clicks: 2}},
+				UserAgents:   []*response.StatsCount{{Value: "Chrome", Clicks: 2}},
+			},
+			wantStatusCode: http.StatusOK,
+			wantResponse: `{
+				"total_clicks": 2,
+				"unique_visitors": 1,
+				"series": [{"start": "2025-10-01T12:00:00Z", "clicks": 2}],
+				"top_referrers": [{"value": "direct", "clicks": 2}],
+				"user_agents": [{"value": "Chrome", "clicks": 2}]
+			}`,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/api/user/urls/"+tt.key+"/stats?bucket="+tt.bucket, nil)
+
+			chiContext := chi.NewRouteContext()
+			chiContext.URLParams.Add("key", tt.key)
+			ctx := context.WithValue(tt.ctx, chi.RouteCtxKey, chiContext)
+			r = r.WithContext(ctx)
+
+			w := httptest.NewRecorder()
+
+			service := mocks.NewURLService(t)
+			dto := dto.NewGetURLStats(tt.key, tt.bucket, userID)
+			service.On("GetURLStats", ctx, dto).Maybe().Return(tt.serviceResponse, tt.serviceError)
+
+			h := NewURL(service, dummyLogger)
+
+			h.GetURLStats(w, r)
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+
+			if tt.wantResponse != "" {
+				resBody, err := io.ReadAll(res.Body)
+				require.NoError(t, err)
+
+				assert.JSONEq(t, tt.wantResponse, string(resBody))
+			}
+		})
+	}
+}
+
+func TestHandler_UpdateURL(t *testing.T) {
+	dummyLogger := &logger.Logger{
+		Logger: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+	}
+
+	userID := uuid.New()
+	key := "ABOBA"
+	originalURL := "https://ya.ru"
+
+	tests := map[string]struct {
+		ctx             context.Context
+		key             string
+		body            string
+		changes         *request.UpdateURL
+		serviceResponse *response.GetUserURL
+		serviceError    error
+		wantStatusCode  int
+		wantResponse    string
+	}{
+		"failed to get user id from context": {
+			ctx:            context.Background(),
+			key:            key,
+			body:           `{"original_url": "https://ya.ru"}`,
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		"key is empty": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			body:           `{"original_url": "https://ya.ru"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"failed to decode body": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			key:            key,
+			body:           "fail",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"service error": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			key:            key,
+			body:           `{"original_url": "https://ya.ru"}`,
+			serviceError:   errors.New("service error"),
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		"service error invalid url": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			key:            key,
+			body:           `{"original_url": "https://ya.ru"}`,
+			serviceError:   service.ErrInvalidURL,
+			wantStatusCode: http.StatusBadRequest,
+			wantResponse:   `{"error": "invalid url"}`,
+		},
+		"service error not found": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			key:            key,
+			body:           `{"original_url": "https://ya.ru"}`,
+			serviceError:   service.ErrNotFound,
+			wantStatusCode: http.StatusNotFound,
+		},
+		"service error forbidden": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			key:            key,
+			body:           `{"original_url": "https://ya.ru"}`,
+			serviceError:   service.ErrForbidden,
+			wantStatusCode: http.StatusForbidden,
+		},
+		"service error gone": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			key:            key,
+			body:           `{"original_url": "https://ya.ru"}`,
+			serviceError:   service.ErrGone,
+			wantStatusCode: http.StatusGone,
+		},
+		"success": {
+			ctx:  auth.SetUserIDToContext(context.Background(), userID),
+			key:  key,
+			body: `{"original_url": "https://ya.ru"}`,
+			serviceResponse: &response.GetUserURL{
+				ShortURL:    "http://localhost/ABOBA",
+				OriginalURL: originalURL,
+			},
+			wantStatusCode: http.StatusOK,
+			wantResponse:   `{"short_url": "http://localhost/ABOBA", "original_url": "https://ya.ru"}`,
+		},
+		"null clears expiration and click limit": {
+			ctx:  auth.SetUserIDToContext(context.Background(), userID),
+			key:  key,
+			body: `{"expires_at": null, "max_clicks": null}`,
+			changes: &request.UpdateURL{
+				ExpiresAt: request.Nullable[time.Time]{Set: true},
+				MaxClicks: request.Nullable[int64]{Set: true},
+			},
+			serviceResponse: &response.GetUserURL{
+				ShortURL:    "http://localhost/ABOBA",
+				OriginalURL: originalURL,
+			},
+			wantStatusCode: http.StatusOK,
+			wantResponse:   `{"short_url": "http://localhost/ABOBA", "original_url": "https://ya.ru"}`,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodPatch, "/api/user/urls/"+tt.key, strings.NewReader(tt.body))
+
+			chiContext := chi.NewRouteContext()
+			chiContext.URLParams.Add("key", tt.key)
+			ctx := context.WithValue(tt.ctx, chi.RouteCtxKey, chiContext)
+			r = r.WithContext(ctx)
+
+			w := httptest.NewRecorder()
+
+			service := mocks.NewURLService(t)
+			changes := tt.changes
+			if changes == nil {
+				changes = &request.UpdateURL{OriginalURL: &originalURL}
+			}
+			dto := dto.NewUpdateURL(tt.key, changes, userID)
+			service.On("UpdateURL", ctx, dto).Maybe().Return(tt.serviceResponse, tt.serviceError)
+
+			h := NewURL(service, dummyLogger)
+
+			h.UpdateURL(w, r)
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+
+			if tt.wantResponse != "" {
+				resBody, err := io.ReadAll(res.Body)
+				require.NoError(t, err)
+
+				assert.JSONEq(t, tt.wantResponse, string(resBody))
+			}
+		})
+	}
+}
+
+func TestHandler_GetDeletedUserURLs(t *testing.T) {
+	dummyLogger := &logger.Logger{
+		Logger: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+	}
+
+	userID := uuid.New()
+	deletedAt := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		ctx             context.Context
+		serviceResponse []*response.GetUserURL
+		serviceError    error
+		wantStatusCode  int
+		wantResponse    string
+	}{
+		"failed to get user id from context": {
+			ctx:            context.Background(),
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		"service error": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			serviceError:   errors.New("service error"),
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		"service error no content": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			serviceError:   service.ErrNoContent,
+			wantStatusCode: http.StatusNoContent,
+		},
+		"success": {
+			ctx: auth.SetUserIDToContext(context.Background(), userID),
+			serviceResponse: []*response.GetUserURL{
+				{
+					ShortURL:    "http://localhost/ABOBA",
+					OriginalURL: "http://yandex.ru",
+					DeletedAt:   &deletedAt,
+				},
+			},
+			wantStatusCode: http.StatusOK,
+			wantResponse:   `[{"short_url": "http://localhost/ABOBA", "original_url": "http://yandex.ru", "deleted_at": "2025-10-01T12:00:00Z"}]`,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/api/user/urls/trash", nil)
+			r = r.WithContext(tt.ctx)
+
+			w := httptest.NewRecorder()
+
+			service := mocks.NewURLService(t)
+			service.On("GetDeletedUserURLs", tt.ctx, userID).Maybe().Return(tt.serviceResponse, tt.serviceError)
+
+			h := NewURL(service, dummyLogger)
+
+			h.GetDeletedUserURLs(w, r)
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+
+			if tt.wantResponse != "" {
+				resBody, err := io.ReadAll(res.Body)
+				require.NoError(t, err)
+
+				assert.JSONEq(t, tt.wantResponse, string(resBody))
+			}
+		})
+	}
+}
+
+func TestHandler_RestoreURLs(t *testing.T) {
+	dummyLogger := &logger.Logger{
+		Logger: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+	}
+
+	shortKeys := []string{"ggl", "ydx"}
+	shortKeysBytes, err := json.Marshal(shortKeys)
+	require.NoError(t, err)
+	userID := uuid.New()
+
+	tests := map[string]struct {
+		ctx            context.Context
+		body           string
+		serviceError   error
+		wantStatusCode int
+	}{
+		"failed to get user id from context": {
+			ctx:            context.Background(),
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		"failed to decode body": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			body:           "fail",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"service error": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			body:           string(shortKeysBytes),
+			serviceError:   errors.New("service error"),
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		"service unavailable": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			body:           string(shortKeysBytes),
+			serviceError:   service.ErrUnavailable,
+			wantStatusCode: http.StatusServiceUnavailable,
+		},
+		"success": {
+			ctx:            auth.SetUserIDToContext(context.Background(), userID),
+			body:           string(shortKeysBytes),
+			wantStatusCode: http.StatusNoContent,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodPost, "/api/user/urls/restore", strings.NewReader(tt.body))
+			r = r.WithContext(tt.ctx)
+
+			w := httptest.NewRecorder()
+
+			serviceMock := mocks.NewURLService(t)
+			dto := dto.NewRestoreURLs(shortKeys, userID)
+			serviceMock.On("RestoreURLs", tt.ctx, dto).Maybe().
+				Return(tt.serviceError)
+
+			h := NewURL(serviceMock, dummyLogger)
+
+			h.RestoreURLs(w, r)
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+
+			resBody, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Empty(t, resBody)
+		})
+	}
+}
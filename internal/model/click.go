@@ -0,0 +1,72 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Click represents a single redirect through a shortened URL.
+// It is recorded for analytics and never changes after creation.
+type Click struct {
+	// ID is the unique identifier for the click record.
+	ID uuid.UUID `json:"id"`
+
+	// URLID is the identifier of the URL that was followed.
+	URLID uuid.UUID `json:"url_id"`
+
+	// CreatedAt is the moment the redirect happened.
+	CreatedAt time.Time `json:"created_at"`
+
+	// Referrer is the value of the Referer header of the request.
+	// Empty if the header was not sent.
+	Referrer string `json:"referrer"`
+
+	// UserAgent is the value of the User-Agent header of the request.
+	UserAgent string `json:"user_agent"`
+
+	// ClientIP is the anonymized address of the client.
+	// It is truncated to a network prefix before being stored, so it can't identify a single host.
+	ClientIP string `json:"client_ip"`
+}
+
+// NewClick creates a new Click instance with the provided parameters.
+// The ID field is automatically generated using a new UUID.
+//
+// Parameters:
+//   - urlID: The ID of the URL that was followed
+//   - createdAt: The moment of the redirect
+//   - referrer: The referrer of the request
+//   - userAgent: The user agent of the client
+//   - clientIP: The anonymized address of the client
+//
+// Returns a pointer to the newly created Click instance.
+func NewClick(urlID uuid.UUID, createdAt time.Time, referrer, userAgent, clientIP string) *Click {
+	return &Click{
+		ID:        uuid.New(),
+		URLID:     urlID,
+		CreatedAt: createdAt,
+		Referrer:  referrer,
+		UserAgent: userAgent,
+		ClientIP:  clientIP,
+	}
+}
+
+// ClickStats is an aggregate of the click events of a URL.
+// Referrers and user agents are counted as they were sent.
+type ClickStats struct {
+	// Total is the number of clicks.
+	Total int
+
+	// UniqueVisitors is the number of distinct pairs of client address and user agent.
+	UniqueVisitors int
+
+	// Buckets maps the start of every period with clicks to the number of clicks in it.
+	Buckets map[time.Time]int
+
+	// Referrers maps referrers to the number of clicks with them.
+	Referrers map[string]int
+
+	// UserAgents maps user agents to the number of clicks with them.
+	UserAgents map[string]int
+}
@@ -28,6 +28,40 @@ type URL struct {
 	// DeletedAt is the timestamp when the URL was marked as deleted.
 	// If nil, the URL is active. If not nil, the URL has been soft deleted.
 	DeletedAt *time.Time `json:"deleted_at"`
+
+	// ExpiresAt is the timestamp after which the URL stops redirecting.
+	// If nil, the URL never expires.
+	ExpiresAt *time.Time `json:"expires_at"`
+
+	// PasswordHash is the salted hash of the password required to follow the URL.
+	// If empty, the URL is not password-protected.
+	PasswordHash string `json:"password_hash,omitempty"`
+
+	// ClicksLeft is the number of redirects the URL still allows.
+	// If nil, the number of redirects is not limited.
+	ClicksLeft *int64 `json:"clicks_left,omitempty"`
+}
+
+// IsExpired reports whether the URL has expired by the given moment.
+func (u *URL) IsExpired(now time.Time) bool {
+	return u.ExpiresAt != nil && !now.Before(*u.ExpiresAt)
+}
+
+// IsProtected reports whether following the URL requires a password.
+func (u *URL) IsProtected() bool {
+	return u.PasswordHash != ""
+}
+
+// HasClicksLeft reports whether the URL allows at least one more redirect.
+func (u *URL) HasClicksLeft() bool {
+	return u.ClicksLeft == nil || *u.ClicksLeft > 0
+}
+
+// CanBeReused reports whether the URL may be returned for another request
+// to shorten the same original URL. Password-protected and click-limited URLs
+// are meant for specific recipients, so they are always created anew.
+func (u *URL) CanBeReused() bool {
+	return !u.IsProtected() && u.ClicksLeft == nil
 }
 
 // NewURL creates a new URL instance with the provided parameters.
@@ -1,5 +1,34 @@
 package request
 
+import (
+	"encoding/json"
+	"time"
+)
+
+// Nullable is an optional JSON field that can be set to null to clear the attribute it changes.
+// Set is false if the field is omitted, and Value is nil if the field is null.
+type Nullable[T any] struct {
+	Set   bool
+	Value *T
+}
+
+// UnmarshalJSON marks the field as set and decodes its value, leaving Value nil for null.
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	n.Value = nil
+	if string(data) == "null" {
+		return nil
+	}
+
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	n.Value = &value
+
+	return nil
+}
+
 // CreateShortURL represents a request to create a shortened URL.
 // @Description Request structure for creating a shortened URL
 type CreateShortURL struct {
@@ -7,6 +36,36 @@ type CreateShortURL struct {
 	// Must be a valid HTTP/HTTPS URL.
 	// @Example "https://example.com/very-long-url-path"
 	URL string `json:"url" example:"https://example.com/very-long-url-path"`
+
+	// Alias is an optional custom short key to use instead of a generated one.
+	// Must be 3 to 32 characters long and contain only letters, digits, '-' and '_'.
+	// @Example "q3-launch"
+	Alias string `json:"alias,omitempty" example:"q3-launch"`
+
+	// TTL is an optional lifetime of the shortened URL in seconds.
+	// Can't be combined with ExpiresAt.
+	// @Example 86400
+	TTL int64 `json:"ttl,omitempty" example:"86400"`
+
+	// ExpiresAt is an optional moment after which the shortened URL stops working.
+	// Must be in the future. Can't be combined with TTL.
+	// @Example "2026-01-01T00:00:00Z"
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2026-01-01T00:00:00Z"`
+
+	// ForceNew makes the service create a new shortened URL even if
+	// the original URL has already been shortened.
+	// @Example true
+	ForceNew bool `json:"force_new,omitempty" example:"true"`
+
+	// Password is an optional password required to follow the shortened URL.
+	// Password-protected URLs are never reused for other requests.
+	// @Example "s3cret"
+	Password string `json:"password,omitempty" example:"s3cret"`
+
+	// MaxClicks is an optional number of redirects after which the shortened URL stops working.
+	// Click-limited URLs are never reused for other requests.
+	// @Example 1
+	MaxClicks int64 `json:"max_clicks,omitempty" example:"1"`
 }
 
 // CreateShortURLBatch represents a request item for batch URL shortening.
@@ -21,4 +80,58 @@ type CreateShortURLBatch struct {
 	// Must be a valid HTTP/HTTPS URL.
 	// @Example "https://example.com/very-long-url-path"
 	OriginalURL string `json:"original_url" example:"https://example.com/very-long-url-path"`
+
+	// Alias is an optional custom short key to use instead of a generated one.
+	// Must be 3 to 32 characters long and contain only letters, digits, '-' and '_'.
+	// @Example "q3-launch"
+	Alias string `json:"alias,omitempty" example:"q3-launch"`
+
+	// TTL is an optional lifetime of the shortened URL in seconds.
+	// Can't be combined with ExpiresAt.
+	// @Example 86400
+	TTL int64 `json:"ttl,omitempty" example:"86400"`
+
+	// ExpiresAt is an optional moment after which the shortened URL stops working.
+	// Must be in the future. Can't be combined with TTL.
+	// @Example "2026-01-01T00:00:00Z"
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2026-01-01T00:00:00Z"`
+
+	// Password is an optional password required to follow the shortened URL.
+	// Password-protected URLs are never reused for other requests.
+	// @Example "s3cret"
+	Password string `json:"password,omitempty" example:"s3cret"`
+
+	// MaxClicks is an optional number of redirects after which the shortened URL stops working.
+	// Click-limited URLs are never reused for other requests.
+	// @Example 1
+	MaxClicks int64 `json:"max_clicks,omitempty" example:"1"`
+}
+
+// UpdateURL represents a request to change attributes of a shortened URL.
+// Omitted fields are left unchanged.
+// @Description Request structure for changing a shortened URL
+type UpdateURL struct {
+	// OriginalURL is the new URL the shortened URL redirects to.
+	// @Example "https://example.com/new-destination"
+	OriginalURL *string `json:"original_url,omitempty" example:"https://example.com/new-destination"`
+
+	// TTL is the new lifetime of the shortened URL in seconds counted from now.
+	// Zero removes the expiration. Can't be combined with ExpiresAt.
+	// @Example 86400
+	TTL *int64 `json:"ttl,omitempty" example:"86400"`
+
+	// ExpiresAt is the new moment after which the shortened URL stops working.
+	// Must be in the future. Null removes the expiration. Can't be combined with TTL.
+	// @Example "2026-01-01T00:00:00Z"
+	ExpiresAt Nullable[time.Time] `json:"expires_at" swaggertype:"string" example:"2026-01-01T00:00:00Z"`
+
+	// MaxClicks is the new number of redirects after which the shortened URL stops working,
+	// counted from now. Zero or null removes the click limit.
+	// @Example 10
+	MaxClicks Nullable[int64] `json:"max_clicks" swaggertype:"integer" example:"10"`
+
+	// Password is the new password required to follow the shortened URL.
+	// An empty string removes the password.
+	// @Example "s3cret"
+	Password *string `json:"password,omitempty" example:"s3cret"`
 }
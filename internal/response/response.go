@@ -1,5 +1,7 @@
 package response
 
+import "time"
+
 // CreateShortURL represents a response for a created shortened URL.
 // @Description Response structure for a created shortened URL
 type CreateShortURL struct {
@@ -35,4 +37,107 @@ type GetUserURL struct {
 	// Contains the full original URL that was provided during creation.
 	// @Example "https://example.com/very-long-url-path"
 	OriginalURL string `json:"original_url" example:"https://example.com/very-long-url-path"`
+
+	// ExpiresAt is the moment after which the shortened URL stops working.
+	// Omitted for URLs without expiration.
+	// @Example "2026-01-01T00:00:00Z"
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2026-01-01T00:00:00Z"`
+
+	// Expired is true if the shortened URL has already expired.
+	// @Example true
+	Expired bool `json:"expired,omitempty" example:"true"`
+
+	// Protected is true if following the shortened URL requires a password.
+	// @Example true
+	Protected bool `json:"protected,omitempty" example:"true"`
+
+	// ClicksLeft is the number of redirects the shortened URL still allows.
+	// Omitted for URLs without click limit.
+	// @Example 1
+	ClicksLeft *int64 `json:"clicks_left,omitempty" example:"1"`
+
+	// DeletedAt is the moment the shortened URL was deleted.
+	// Omitted for URLs that are not deleted.
+	// @Example "2025-10-01T12:00:00Z"
+	DeletedAt *time.Time `json:"deleted_at,omitempty" example:"2025-10-01T12:00:00Z"`
+}
+
+// URLStats represents click statistics of a shortened URL.
+// @Description Response structure for click statistics of a shortened URL
+type URLStats struct {
+	// TotalClicks is the number of times the shortened URL was followed.
+	// @Example 42
+	TotalClicks int `json:"total_clicks" example:"42"`
+
+	// UniqueVisitors is the number of distinct visitors that followed the shortened URL.
+	// Visitors are told apart by their anonymized address and user agent.
+	// @Example 17
+	UniqueVisitors int `json:"unique_visitors" example:"17"`
+
+	// Series is the number of clicks in consecutive time buckets
+	// from the first click to the last one.
+	Series []*StatsBucket `json:"series"`
+
+	// TopReferrers are the referrer hosts that brought the most clicks.
+	// Clicks without referrer are counted as "direct".
+	TopReferrers []*StatsCount `json:"top_referrers"`
+
+	// UserAgents is the number of clicks per client family.
+	UserAgents []*StatsCount `json:"user_agents"`
+}
+
+// StatsBucket represents the number of clicks in a single time bucket.
+// @Description Response structure for a time bucket of click statistics
+type StatsBucket struct {
+	// Start is the beginning of the bucket in UTC.
+	// @Example "2025-10-01T00:00:00Z"
+	Start time.Time `json:"start" example:"2025-10-01T00:00:00Z"`
+
+	// Clicks is the number of clicks in the bucket.
+	// @Example 5
+	Clicks int `json:"clicks" example:"5"`
+}
+
+// StatsCount represents the number of clicks that share a value.
+// @Description Response structure for a group of clicks in click statistics
+type StatsCount struct {
+	// Value is the value shared by the clicks, e.g. referrer host or client family.
+	// @Example "google.com"
+	Value string `json:"value" example:"google.com"`
+
+	// Clicks is the number of clicks with the value.
+	// @Example 5
+	Clicks int `json:"clicks" example:"5"`
+}
+
+// Error represents an error response.
+// @Description Response structure for a request that failed
+type Error struct {
+	// Error is the human readable description of what went wrong.
+	// @Example "alias is already taken"
+	Error string `json:"error" example:"alias is already taken"`
+}
+
+// Health represents the health of the service and its components.
+// @Description Response structure for the service health check
+type Health struct {
+	// Status is ok if every component is up, degraded if some components are down
+	// but the service still works, and unavailable if the service doesn't work.
+	// @Example "ok"
+	Status string `json:"status" example:"ok" enums:"ok,degraded,unavailable"`
+
+	// Components is the status of every component the service depends on.
+	Components []*HealthComponent `json:"components"`
+}
+
+// HealthComponent represents the status of a component the service depends on.
+// @Description Response structure for the status of a service component
+type HealthComponent struct {
+	// Name identifies the component, e.g. a database connection pool.
+	// @Example "primary"
+	Name string `json:"name" example:"primary"`
+
+	// Status is up if the component is available and down otherwise.
+	// @Example "up"
+	Status string `json:"status" example:"up" enums:"up,down"`
 }
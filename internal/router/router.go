@@ -44,6 +44,7 @@ func (r *Router) RegisterAPIRoutes(s *service.URL, token middleware.Token, l *lo
 
 		r.With(compressor).Post("/", h.CreateShortURL)
 		r.Get("/{id}", h.GetOriginalURL)
+		r.Post("/{id}", h.UnlockURL)
 	})
 
 	r.Route("/api", func(r chi.Router) {
@@ -60,6 +61,10 @@ func (r *Router) RegisterAPIRoutes(s *service.URL, token middleware.Token, l *lo
 		r.Route("/user", func(r chi.Router) {
 			r.Get("/urls", h.GetUserURLs)
 			r.Delete("/urls", h.DeleteURLs)
+			r.Get("/urls/trash", h.GetDeletedUserURLs)
+			r.Post("/urls/restore", h.RestoreURLs)
+			r.Patch("/urls/{key}", h.UpdateURL)
+			r.Get("/urls/{key}/stats", h.GetURLStats)
 		})
 	})
 }
@@ -70,4 +75,5 @@ func (r *Router) RegisterHealthRoutes(s *service.Health, l *logger.Logger) {
 	h := handler.NewHealth(s, l)
 
 	r.With(loggerMiddleware).Get("/ping", h.Ping)
+	r.With(loggerMiddleware).Get("/health", h.Check)
 }
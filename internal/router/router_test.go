@@ -10,7 +10,9 @@ import (
 	"github.com/dtroode/urlshorter/internal/auth"
 	"github.com/dtroode/urlshorter/internal/logger"
 	"github.com/dtroode/urlshorter/internal/service"
+	"github.com/dtroode/urlshorter/internal/service/keygen"
 	"github.com/dtroode/urlshorter/internal/service/mocks"
+	"github.com/dtroode/urlshorter/internal/storage"
 )
 
 func TestNewRouter(t *testing.T) {
@@ -29,7 +31,7 @@ func TestRouter_RegisterProfiler(t *testing.T) {
 func TestRouter_RegisterAPIRoutes(t *testing.T) {
 	router := NewRouter()
 	mockStorage := mocks.NewURLStorage(t)
-	urlService := service.NewURL("http://localhost:8080", 8, 3, 0, mockStorage)
+	urlService := service.NewURL("http://localhost:8080", 8, keygen.NewRandom(keygen.Base62Alphabet), service.URLNormalizer{}, storage.DedupeGlobal, nil, 3, 0, 1, 0, mockStorage)
 	token := auth.NewJWT("test-secret")
 	logger := &logger.Logger{Logger: slog.New(slog.NewJSONHandler(io.Discard, nil))}
 	assert.NotPanics(t, func() {
@@ -49,7 +51,7 @@ func TestRouter_RegisterHealthRoutes(t *testing.T) {
 func TestRouter_CompleteSetup(t *testing.T) {
 	router := NewRouter()
 	mockStorage := mocks.NewURLStorage(t)
-	urlService := service.NewURL("http://localhost:8080", 8, 3, 0, mockStorage)
+	urlService := service.NewURL("http://localhost:8080", 8, keygen.NewRandom(keygen.Base62Alphabet), service.URLNormalizer{}, storage.DedupeGlobal, nil, 3, 0, 1, 0, mockStorage)
 	token := auth.NewJWT("test-secret")
 	logger := &logger.Logger{Logger: slog.New(slog.NewJSONHandler(io.Discard, nil))}
 	healthService := &service.Health{}
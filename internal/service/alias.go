@@ -0,0 +1,43 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	// minAliasLength is the minimum length of a custom alias.
+	minAliasLength = 3
+	// maxAliasLength is the maximum length of a custom alias.
+	// It matches the size of the short_key column.
+	maxAliasLength = 32
+)
+
+// aliasPattern describes characters allowed in a custom alias.
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// reservedAliases contains path segments already used by the router.
+// Aliases equal to them would never be reachable.
+var reservedAliases = map[string]struct{}{
+	"api":   {},
+	"debug": {},
+	"ping":  {},
+}
+
+// validateAlias checks that alias can be used as a short key.
+// Returns ErrInvalidAlias wrapped with the reason if it can't.
+func validateAlias(alias string) error {
+	if len(alias) < minAliasLength || len(alias) > maxAliasLength {
+		return fmt.Errorf("%w: length must be between %d and %d", ErrInvalidAlias, minAliasLength, maxAliasLength)
+	}
+
+	if !aliasPattern.MatchString(alias) {
+		return fmt.Errorf("%w: only letters, digits, '-' and '_' are allowed", ErrInvalidAlias)
+	}
+
+	if _, ok := reservedAliases[alias]; ok {
+		return fmt.Errorf("%w: %q is reserved", ErrInvalidAlias, alias)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAlias(t *testing.T) {
+	tests := map[string]struct {
+		alias   string
+		wantErr bool
+	}{
+		"valid":            {alias: "q3-launch"},
+		"valid underscore": {alias: "Q3_launch"},
+		"too short":        {alias: "ab", wantErr: true},
+		"too long":         {alias: "abcdefghijklmnopqrstuvwxyz0123456", wantErr: true},
+		"slash":            {alias: "q3/launch", wantErr: true},
+		"space":            {alias: "q3 launch", wantErr: true},
+		"non ascii":        {alias: "запуск", wantErr: true},
+		"reserved":         {alias: "api", wantErr: true},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateAlias(tt.alias)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidAlias)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
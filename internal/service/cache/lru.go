@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"container/list"
+	"time"
+)
+
+// lruEntry is an entry of lru.
+type lruEntry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// lru is a cache of a fixed capacity that evicts the least recently used entries
+// once it's full. Entries also expire after the time they are added with.
+// lru is not safe for concurrent use.
+type lru[K comparable, V any] struct {
+	capacity int
+	items    map[K]*list.Element
+	// order holds entries from the most recently used to the least recently used one.
+	order *list.List
+	// onRemove is called with every entry that is evicted, expires or is removed.
+	onRemove func(key K, value V)
+}
+
+// newLRU creates an lru that holds at most capacity entries.
+func newLRU[K comparable, V any](capacity int, onRemove func(key K, value V)) *lru[K, V] {
+	return &lru[K, V]{
+		capacity: capacity,
+		items:    make(map[K]*list.Element, capacity),
+		order:    list.New(),
+		onRemove: onRemove,
+	}
+}
+
+// get returns the value of key unless it has expired by now and marks it as recently used.
+func (c *lru[K, V]) get(key K, now time.Time) (V, bool) {
+	element, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	entry := element.Value.(*lruEntry[K, V])
+	if !now.Before(entry.expiresAt) {
+		c.removeElement(element)
+
+		var zero V
+		return zero, false
+	}
+
+	c.order.MoveToFront(element)
+
+	return entry.value, true
+}
+
+// add sets the value of key until expiresAt and evicts the least recently used entry if the cache is full.
+func (c *lru[K, V]) add(key K, value V, expiresAt time.Time) {
+	if element, ok := c.items[key]; ok {
+		c.removeElement(element)
+	}
+
+	if c.order.Len() >= c.capacity {
+		if oldest := c.order.Back(); oldest != nil {
+			c.removeElement(oldest)
+		}
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value, expiresAt: expiresAt})
+}
+
+// remove removes key from the cache.
+func (c *lru[K, V]) remove(key K) {
+	if element, ok := c.items[key]; ok {
+		c.removeElement(element)
+	}
+}
+
+// clear removes all entries from the cache.
+func (c *lru[K, V]) clear() {
+	for c.order.Len() > 0 {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// len returns the number of entries in the cache, including expired ones that weren't removed yet.
+func (c *lru[K, V]) len() int {
+	return c.order.Len()
+}
+
+func (c *lru[K, V]) removeElement(element *list.Element) {
+	entry := c.order.Remove(element).(*lruEntry[K, V])
+	delete(c.items, entry.key)
+
+	if c.onRemove != nil {
+		c.onRemove(entry.key, entry.value)
+	}
+}
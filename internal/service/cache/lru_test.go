@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	now := time.Now()
+	expiresAt := now.Add(time.Minute)
+
+	var removed []string
+	c := newLRU(2, func(key string, _ int) {
+		removed = append(removed, key)
+	})
+
+	c.add("a", 1, expiresAt)
+	c.add("b", 2, expiresAt)
+
+	value, ok := c.get("a", now)
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	// b is the least recently used entry.
+	c.add("c", 3, expiresAt)
+	assert.Equal(t, []string{"b"}, removed)
+	_, ok = c.get("b", now)
+	assert.False(t, ok)
+
+	_, ok = c.get("a", expiresAt)
+	assert.False(t, ok, "expired entry is returned")
+	assert.Equal(t, []string{"b", "a"}, removed)
+	assert.Equal(t, 1, c.len())
+
+	c.remove("c")
+	c.remove("missing")
+	assert.Equal(t, []string{"b", "a", "c"}, removed)
+	assert.Zero(t, c.len())
+}
+
+func TestLRU_Replace(t *testing.T) {
+	now := time.Now()
+	c := newLRU[string, int](2, nil)
+
+	c.add("a", 1, now.Add(time.Second))
+	c.add("a", 2, now.Add(time.Minute))
+	assert.Equal(t, 1, c.len())
+
+	value, ok := c.get("a", now.Add(time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, 2, value)
+
+	c.add("b", 3, now.Add(time.Minute))
+	c.clear()
+	assert.Zero(t, c.len())
+}
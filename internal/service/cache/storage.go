@@ -0,0 +1,355 @@
+// Package cache provides a read-through cache of URL lookups in front of a URL storage.
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/service"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+// Option configures Storage.
+type Option func(*options)
+
+type options struct {
+	negativeTTL *time.Duration
+}
+
+// WithNegativeTTL sets the time lookups of missing short keys are cached for.
+// Zero disables caching of missing short keys. By default they are cached as long as found URLs.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.negativeTTL = &ttl
+	}
+}
+
+// Stats holds the counters of cache lookups.
+type Stats struct {
+	// Hits is the number of lookups served from the cache.
+	Hits int64 `json:"hits"`
+	// Misses is the number of lookups passed to the storage.
+	Misses int64 `json:"misses"`
+	// Size is the number of cached lookups.
+	Size int `json:"size"`
+}
+
+// Storage is a service.URLStorage that caches lookups of URLs by short key in process.
+// Found URLs and missing short keys are cached in an LRU cache for a limited time,
+// concurrent lookups of the same short key are collapsed into one storage call.
+// Writes made through Storage invalidate the cached lookups they affect,
+// writes made to the wrapped storage directly are seen once the lookups expire
+// or are invalidated with InvalidateShortKeys.
+type Storage struct {
+	next        service.URLStorage
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries *lru[string, *model.URL]
+	// shortKeys holds the short keys of cached URLs by URL ID.
+	shortKeys map[uuid.UUID]string
+	// seq numbers invalidations. Lookups remember the last number when they start
+	// and don't cache their results if what they loaded was invalidated after that.
+	seq uint64
+	// loading counts the lookups being loaded by the seq they started at.
+	loading map[uint64]int
+	// invalidatedKeys and invalidatedIDs are tombstones holding the seq of the last invalidation
+	// of short keys and URL IDs. They are only kept while lookups that started before them are loading.
+	invalidatedKeys map[string]uint64
+	invalidatedIDs  map[uuid.UUID]uint64
+	// invalidatedAll is the seq of the last invalidation of all lookups.
+	invalidatedAll uint64
+
+	lookups singleflight.Group
+	hits    atomic.Int64
+	misses  atomic.Int64
+}
+
+var _ service.URLStorage = (*Storage)(nil)
+
+// NewStorage creates a new Storage instance.
+//
+// Parameters:
+//   - next: The storage whose lookups are cached
+//   - capacity: The maximum number of cached lookups
+//   - ttl: The time found URLs are cached for
+//   - opts: Options of the cache
+//
+// Returns a pointer to the newly created Storage instance.
+func NewStorage(next service.URLStorage, capacity int, ttl time.Duration, opts ...Option) *Storage {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	s := &Storage{
+		next:        next,
+		ttl:         ttl,
+		negativeTTL: ttl,
+		now:         time.Now,
+		shortKeys:   make(map[uuid.UUID]string),
+
+		loading:         make(map[uint64]int),
+		invalidatedKeys: make(map[string]uint64),
+		invalidatedIDs:  make(map[uuid.UUID]uint64),
+	}
+	if o.negativeTTL != nil {
+		s.negativeTTL = *o.negativeTTL
+	}
+
+	s.entries = newLRU(capacity, func(_ string, url *model.URL) {
+		if url != nil {
+			delete(s.shortKeys, url.ID)
+		}
+	})
+
+	return s
+}
+
+// Stats returns the counters of cache lookups.
+func (s *Storage) Stats() Stats {
+	s.mu.Lock()
+	size := s.entries.len()
+	s.mu.Unlock()
+
+	return Stats{
+		Hits:   s.hits.Load(),
+		Misses: s.misses.Load(),
+		Size:   size,
+	}
+}
+
+// GetURL retrieves a URL by its short key from the cache or from the wrapped storage.
+// Returns storage.ErrNotFound for cached missing short keys.
+func (s *Storage) GetURL(ctx context.Context, shortKey string) (*model.URL, error) {
+	s.mu.Lock()
+	url, ok := s.entries.get(shortKey, s.now())
+	s.mu.Unlock()
+
+	if ok {
+		s.hits.Add(1)
+		if url == nil {
+			return nil, storage.ErrNotFound
+		}
+		return copyURL(url), nil
+	}
+	s.misses.Add(1)
+
+	// The lookup is shared by concurrent callers, so it must not be canceled with the one that started it.
+	lookup := s.lookups.DoChan(shortKey, func() (any, error) {
+		return s.load(context.WithoutCancel(ctx), shortKey)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-lookup:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return copyURL(res.Val.(*model.URL)), nil
+	}
+}
+
+// load retrieves a URL from the wrapped storage and caches the result
+// unless the short key or the URL was invalidated in the meantime.
+func (s *Storage) load(ctx context.Context, shortKey string) (*model.URL, error) {
+	s.mu.Lock()
+	start := s.seq
+	s.loading[start]++
+	s.mu.Unlock()
+
+	url, err := s.next.GetURL(ctx, shortKey)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invalidated := s.invalidatedAll > start || s.invalidatedKeys[shortKey] > start ||
+		(url != nil && s.invalidatedIDs[url.ID] > start)
+	s.finishLoad(start)
+
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	if invalidated {
+		return url, err
+	}
+
+	if err != nil {
+		if s.negativeTTL > 0 {
+			s.entries.add(shortKey, nil, s.now().Add(s.negativeTTL))
+		}
+		return nil, err
+	}
+
+	s.entries.add(shortKey, copyURL(url), s.now().Add(s.ttl))
+	s.shortKeys[url.ID] = shortKey
+
+	return url, nil
+}
+
+// finishLoad marks a lookup that started at start as loaded and drops the tombstones
+// no lookup that is still loading started before. Callers must hold s.mu.
+func (s *Storage) finishLoad(start uint64) {
+	if s.loading[start]--; s.loading[start] == 0 {
+		delete(s.loading, start)
+	}
+
+	oldest := s.seq
+	for seq := range s.loading {
+		oldest = min(oldest, seq)
+	}
+
+	for shortKey, seq := range s.invalidatedKeys {
+		if seq <= oldest {
+			delete(s.invalidatedKeys, shortKey)
+		}
+	}
+	for id, seq := range s.invalidatedIDs {
+		if seq <= oldest {
+			delete(s.invalidatedIDs, id)
+		}
+	}
+}
+
+// InvalidateShortKeys removes the cached lookups of shortKeys.
+// It's used to evict URLs changed in the wrapped storage directly, e.g. by another instance.
+func (s *Storage) InvalidateShortKeys(shortKeys ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	for _, shortKey := range shortKeys {
+		s.entries.remove(shortKey)
+		s.lookups.Forget(shortKey)
+		if len(s.loading) > 0 {
+			s.invalidatedKeys[shortKey] = s.seq
+		}
+	}
+}
+
+// invalidateIDs removes the cached lookups of URLs with ids.
+func (s *Storage) invalidateIDs(ids ...uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	for _, id := range ids {
+		if shortKey, ok := s.shortKeys[id]; ok {
+			s.entries.remove(shortKey)
+			s.lookups.Forget(shortKey)
+		}
+		// A lookup that is loading may return the URL without knowing its ID yet.
+		if len(s.loading) > 0 {
+			s.invalidatedIDs[id] = s.seq
+		}
+	}
+}
+
+// InvalidateAll removes all cached lookups.
+func (s *Storage) InvalidateAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	s.invalidatedAll = s.seq
+	s.entries.clear()
+}
+
+// GetURLs retrieves multiple URLs by their short keys from the wrapped storage.
+func (s *Storage) GetURLs(ctx context.Context, shortKeys []string) ([]*model.URL, error) {
+	return s.next.GetURLs(ctx, shortKeys)
+}
+
+// GetURLsByUserID retrieves all URLs created by a specific user from the wrapped storage.
+func (s *Storage) GetURLsByUserID(ctx context.Context, userID uuid.UUID) ([]*model.URL, error) {
+	return s.next.GetURLsByUserID(ctx, userID)
+}
+
+// GetDeletedURLsByUserID retrieves all deleted URLs created by a specific user from the wrapped storage.
+func (s *Storage) GetDeletedURLsByUserID(ctx context.Context, userID uuid.UUID) ([]*model.URL, error) {
+	return s.next.GetDeletedURLsByUserID(ctx, userID)
+}
+
+// SetURL stores a single URL and invalidates a cached miss of its short key.
+func (s *Storage) SetURL(ctx context.Context, url *model.URL, scope storage.DedupeScope) (*model.URL, error) {
+	defer s.InvalidateShortKeys(url.ShortKey)
+
+	return s.next.SetURL(ctx, url, scope)
+}
+
+// SetURLs stores multiple URLs and invalidates cached misses of their short keys.
+func (s *Storage) SetURLs(ctx context.Context, urls []*model.URL, scope storage.DedupeScope) ([]*model.URL, error) {
+	shortKeys := make([]string, len(urls))
+	for i, url := range urls {
+		shortKeys[i] = url.ShortKey
+	}
+	defer s.InvalidateShortKeys(shortKeys...)
+
+	return s.next.SetURLs(ctx, urls, scope)
+}
+
+// UpdateURL updates a URL and invalidates its cached lookup.
+func (s *Storage) UpdateURL(ctx context.Context, url *model.URL, fields storage.URLFields) (*model.URL, error) {
+	defer s.InvalidateShortKeys(url.ShortKey)
+
+	return s.next.UpdateURL(ctx, url, fields)
+}
+
+// DeleteURLs marks URLs as deleted and invalidates their cached lookups.
+func (s *Storage) DeleteURLs(ctx context.Context, ids []uuid.UUID) error {
+	defer s.invalidateIDs(ids...)
+
+	return s.next.DeleteURLs(ctx, ids)
+}
+
+// RestoreURLs clears the deletion mark of URLs and invalidates their cached lookups.
+func (s *Storage) RestoreURLs(ctx context.Context, ids []uuid.UUID) error {
+	defer s.invalidateIDs(ids...)
+
+	return s.next.RestoreURLs(ctx, ids)
+}
+
+// PurgeDeletedURLs permanently removes deleted URLs. The removed URLs aren't known,
+// so all cached lookups are invalidated if any URL may have been removed.
+func (s *Storage) PurgeDeletedURLs(ctx context.Context, before time.Time, limit int) (int64, error) {
+	removed, err := s.next.PurgeDeletedURLs(ctx, before, limit)
+	if removed > 0 || err != nil {
+		s.InvalidateAll()
+	}
+
+	return removed, err
+}
+
+// DecrementClicksLeft uses up one redirect of a click-limited URL and invalidates its cached lookup.
+func (s *Storage) DecrementClicksLeft(ctx context.Context, id uuid.UUID) error {
+	defer s.invalidateIDs(id)
+
+	return s.next.DecrementClicksLeft(ctx, id)
+}
+
+// SaveClick stores a single click event in the wrapped storage.
+func (s *Storage) SaveClick(ctx context.Context, click *model.Click) error {
+	return s.next.SaveClick(ctx, click)
+}
+
+// GetClickStats aggregates the click events of a URL in the wrapped storage.
+func (s *Storage) GetClickStats(ctx context.Context, urlID uuid.UUID, bucket time.Duration) (*model.ClickStats, error) {
+	return s.next.GetClickStats(ctx, urlID, bucket)
+}
+
+// copyURL returns a copy of url, so that callers can't change cached URLs.
+func copyURL(url *model.URL) *model.URL {
+	clone := *url
+
+	return &clone
+}
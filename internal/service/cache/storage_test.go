@@ -0,0 +1,385 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/service/mocks"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+// testClock is a clock moved by tests.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func newTestStorage(t *testing.T, next *mocks.URLStorage, opts ...Option) (*Storage, *testClock) {
+	clock := &testClock{now: time.Now()}
+	s := NewStorage(next, 10, time.Minute, opts...)
+	s.now = clock.Now
+
+	return s, clock
+}
+
+func TestStorage_GetURL(t *testing.T) {
+	ctx := context.Background()
+	url := &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru"}
+
+	next := mocks.NewURLStorage(t)
+	next.On("GetURL", mock.Anything, "abc").Once().Return(url, nil)
+	s, clock := newTestStorage(t, next)
+
+	for range 3 {
+		got, err := s.GetURL(ctx, "abc")
+		require.NoError(t, err)
+		assert.Equal(t, url, got)
+	}
+	assert.Equal(t, Stats{Hits: 2, Misses: 1, Size: 1}, s.Stats())
+
+	// Cached URLs can't be changed by callers.
+	got, err := s.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	got.OriginalURL = "https://changed.ru"
+
+	clock.now = clock.now.Add(time.Minute)
+	next.On("GetURL", mock.Anything, "abc").Once().Return(url, nil)
+
+	got, err = s.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, url, got)
+	assert.Equal(t, Stats{Hits: 3, Misses: 2, Size: 1}, s.Stats())
+}
+
+func TestStorage_GetURL_NotFound(t *testing.T) {
+	ctx := context.Background()
+
+	tests := map[string]struct {
+		opts          []Option
+		expectedCalls int
+	}{
+		"misses are cached": {
+			expectedCalls: 1,
+		},
+		"misses are cached for negative ttl": {
+			opts:          []Option{WithNegativeTTL(time.Second)},
+			expectedCalls: 2,
+		},
+		"misses aren't cached": {
+			opts:          []Option{WithNegativeTTL(0)},
+			expectedCalls: 3,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			next := mocks.NewURLStorage(t)
+			next.On("GetURL", mock.Anything, "abc").Times(tt.expectedCalls).Return(nil, storage.ErrNotFound)
+			s, clock := newTestStorage(t, next, tt.opts...)
+
+			for range 2 {
+				_, err := s.GetURL(ctx, "abc")
+				assert.ErrorIs(t, err, storage.ErrNotFound)
+			}
+
+			clock.now = clock.now.Add(time.Second)
+			_, err := s.GetURL(ctx, "abc")
+			assert.ErrorIs(t, err, storage.ErrNotFound)
+		})
+	}
+}
+
+func TestStorage_GetURL_Error(t *testing.T) {
+	ctx := context.Background()
+	storageErr := errors.New("database error")
+
+	next := mocks.NewURLStorage(t)
+	next.On("GetURL", mock.Anything, "abc").Twice().Return(nil, storageErr)
+	s, _ := newTestStorage(t, next)
+
+	for range 2 {
+		_, err := s.GetURL(ctx, "abc")
+		assert.ErrorIs(t, err, storageErr)
+	}
+	assert.Zero(t, s.Stats().Size)
+}
+
+func TestStorage_GetURL_CollapsesLookups(t *testing.T) {
+	ctx := context.Background()
+	url := &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru"}
+
+	release := make(chan struct{})
+	next := mocks.NewURLStorage(t)
+	next.On("GetURL", mock.Anything, "abc").Once().Run(func(mock.Arguments) { <-release }).Return(url, nil)
+	s, _ := newTestStorage(t, next)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := s.GetURL(ctx, "abc")
+			assert.NoError(t, err)
+			assert.Equal(t, url, got)
+		}()
+	}
+
+	require.Eventually(t, func() bool {
+		return s.Stats().Misses == 10
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+}
+
+func TestStorage_GetURL_CanceledCaller(t *testing.T) {
+	url := &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru"}
+
+	release := make(chan struct{})
+	next := mocks.NewURLStorage(t)
+	next.On("GetURL", mock.Anything, "abc").Once().Run(func(mock.Arguments) { <-release }).Return(url, nil)
+	s, _ := newTestStorage(t, next)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := s.GetURL(ctx, "abc")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The lookup started by the canceled caller is completed and cached.
+	close(release)
+	got, err := s.GetURL(context.Background(), "abc")
+	require.NoError(t, err)
+	assert.Equal(t, url, got)
+}
+
+func TestStorage_Invalidation(t *testing.T) {
+	ctx := context.Background()
+	url := &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru"}
+
+	tests := map[string]struct {
+		write func(s *Storage, next *mocks.URLStorage) error
+	}{
+		"set url": {
+			write: func(s *Storage, next *mocks.URLStorage) error {
+				next.On("SetURL", ctx, url, mock.Anything).Once().Return(url, nil)
+				_, err := s.SetURL(ctx, url, storage.DedupeGlobal)
+				return err
+			},
+		},
+		"set urls": {
+			write: func(s *Storage, next *mocks.URLStorage) error {
+				next.On("SetURLs", ctx, []*model.URL{url}, mock.Anything).Once().Return([]*model.URL{url}, nil)
+				_, err := s.SetURLs(ctx, []*model.URL{url}, storage.DedupeGlobal)
+				return err
+			},
+		},
+		"update url": {
+			write: func(s *Storage, next *mocks.URLStorage) error {
+				next.On("UpdateURL", ctx, url, storage.FieldOriginalURL).Once().Return(url, nil)
+				_, err := s.UpdateURL(ctx, url, storage.FieldOriginalURL)
+				return err
+			},
+		},
+		"delete urls": {
+			write: func(s *Storage, next *mocks.URLStorage) error {
+				next.On("DeleteURLs", ctx, []uuid.UUID{url.ID}).Once().Return(nil)
+				return s.DeleteURLs(ctx, []uuid.UUID{url.ID})
+			},
+		},
+		"restore urls": {
+			write: func(s *Storage, next *mocks.URLStorage) error {
+				next.On("RestoreURLs", ctx, []uuid.UUID{url.ID}).Once().Return(nil)
+				return s.RestoreURLs(ctx, []uuid.UUID{url.ID})
+			},
+		},
+		"decrement clicks left": {
+			write: func(s *Storage, next *mocks.URLStorage) error {
+				next.On("DecrementClicksLeft", ctx, url.ID).Once().Return(nil)
+				return s.DecrementClicksLeft(ctx, url.ID)
+			},
+		},
+		"purge deleted urls": {
+			write: func(s *Storage, next *mocks.URLStorage) error {
+				next.On("PurgeDeletedURLs", ctx, mock.Anything, 10).Once().Return(int64(1), nil)
+				_, err := s.PurgeDeletedURLs(ctx, time.Now(), 10)
+				return err
+			},
+		},
+		"invalidate short keys": {
+			write: func(s *Storage, _ *mocks.URLStorage) error {
+				s.InvalidateShortKeys("abc")
+				return nil
+			},
+		},
+		"invalidate all": {
+			write: func(s *Storage, _ *mocks.URLStorage) error {
+				s.InvalidateAll()
+				return nil
+			},
+		},
+		"failed write": {
+			write: func(s *Storage, next *mocks.URLStorage) error {
+				next.On("UpdateURL", ctx, url, storage.FieldOriginalURL).Once().Return(nil, errors.New("database error"))
+				_, err := s.UpdateURL(ctx, url, storage.FieldOriginalURL)
+				assert.Error(t, err)
+				return nil
+			},
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			next := mocks.NewURLStorage(t)
+			next.On("GetURL", mock.Anything, "abc").Twice().Return(url, nil)
+			s, _ := newTestStorage(t, next)
+
+			_, err := s.GetURL(ctx, "abc")
+			require.NoError(t, err)
+
+			require.NoError(t, tt.write(s, next))
+
+			_, err = s.GetURL(ctx, "abc")
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), s.Stats().Misses)
+		})
+	}
+}
+
+func TestStorage_Invalidation_DuringLookup(t *testing.T) {
+	ctx := context.Background()
+	url := &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru"}
+
+	release := make(chan struct{})
+	next := mocks.NewURLStorage(t)
+	next.On("GetURL", mock.Anything, "abc").Once().Run(func(mock.Arguments) { <-release }).Return(nil, storage.ErrNotFound)
+	next.On("GetURL", mock.Anything, "abc").Once().Return(url, nil)
+	next.On("SetURL", ctx, url, mock.Anything).Once().Return(url, nil)
+	s, _ := newTestStorage(t, next)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := s.GetURL(ctx, "abc")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	}()
+
+	require.Eventually(t, func() bool {
+		return s.Stats().Misses == 1
+	}, time.Second, time.Millisecond)
+	_, err := s.SetURL(ctx, url, storage.DedupeGlobal)
+	require.NoError(t, err)
+	close(release)
+	<-done
+
+	// The miss looked up before the URL was saved isn't cached.
+	got, err := s.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, url, got)
+}
+
+func TestStorage_Invalidation_DuringLookup_PerKey(t *testing.T) {
+	ctx := context.Background()
+	url := &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru"}
+
+	tests := map[string]struct {
+		invalidate     func(s *Storage)
+		expectedCached bool
+	}{
+		"other short key": {
+			invalidate:     func(s *Storage) { s.InvalidateShortKeys("other") },
+			expectedCached: true,
+		},
+		"other url id": {
+			invalidate:     func(s *Storage) { s.invalidateIDs(uuid.New()) },
+			expectedCached: true,
+		},
+		"url id": {
+			invalidate:     func(s *Storage) { s.invalidateIDs(url.ID) },
+			expectedCached: false,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			release := make(chan struct{})
+			next := mocks.NewURLStorage(t)
+			next.On("GetURL", mock.Anything, "abc").Once().Run(func(mock.Arguments) { <-release }).Return(url, nil)
+			if !tt.expectedCached {
+				next.On("GetURL", mock.Anything, "abc").Once().Return(url, nil)
+			}
+			s, _ := newTestStorage(t, next)
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				_, err := s.GetURL(ctx, "abc")
+				assert.NoError(t, err)
+			}()
+
+			require.Eventually(t, func() bool {
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				return len(s.loading) == 1
+			}, time.Second, time.Millisecond)
+			tt.invalidate(s)
+			close(release)
+			<-done
+
+			_, err := s.GetURL(ctx, "abc")
+			require.NoError(t, err)
+			// The second lookup is a hit if the first one was cached.
+			assert.Equal(t, tt.expectedCached, s.Stats().Hits == 1)
+
+			// Tombstones are dropped once no lookup is loading.
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			assert.Empty(t, s.invalidatedKeys)
+			assert.Empty(t, s.invalidatedIDs)
+		})
+	}
+}
+
+func TestStorage_PassThrough(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	urlID := uuid.New()
+	urls := []*model.URL{{ID: urlID, ShortKey: "abc", UserID: userID}}
+	click := model.NewClick(urlID, time.Now(), "", "", "")
+
+	next := mocks.NewURLStorage(t)
+	next.On("GetURLs", ctx, []string{"abc"}).Once().Return(urls, nil)
+	next.On("GetURLsByUserID", ctx, userID).Once().Return(urls, nil)
+	next.On("GetDeletedURLsByUserID", ctx, userID).Once().Return(urls, nil)
+	next.On("SaveClick", ctx, click).Once().Return(nil)
+	next.On("GetClickStats", ctx, urlID, time.Hour).Once().Return(&model.ClickStats{Total: 1}, nil)
+	s, _ := newTestStorage(t, next)
+
+	got, err := s.GetURLs(ctx, []string{"abc"})
+	require.NoError(t, err)
+	assert.Equal(t, urls, got)
+
+	got, err = s.GetURLsByUserID(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, urls, got)
+
+	got, err = s.GetDeletedURLsByUserID(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, urls, got)
+
+	require.NoError(t, s.SaveClick(ctx, click))
+
+	stats, err := s.GetClickStats(ctx, urlID, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Total)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"net/netip"
+	"sync/atomic"
+
+	"github.com/dtroode/urlshorter/internal/model"
+)
+
+const (
+	// ipv4PrefixLength is the number of bits of an IPv4 address kept in click events.
+	ipv4PrefixLength = 24
+	// ipv6PrefixLength is the number of bits of an IPv6 address kept in click events.
+	ipv6PrefixLength = 48
+)
+
+// anonymizeIP truncates ip to its network prefix so that stored click events
+// don't identify a single host. Returns an empty string if ip can't be parsed.
+func anonymizeIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	bits := ipv6PrefixLength
+	if addr.Is4() {
+		bits = ipv4PrefixLength
+	}
+
+	prefix, err := addr.WithZone("").Prefix(bits)
+	if err != nil {
+		return ""
+	}
+
+	return prefix.Addr().String()
+}
+
+// clickSaver stores click events.
+type clickSaver interface {
+	SaveClick(ctx context.Context, click *model.Click) error
+}
+
+// clickWriter saves click events in the background with its own workers and queue,
+// so that redirects don't wait for the storage and clicks don't compete
+// with other background jobs of the service.
+type clickWriter struct {
+	saver  clickSaver
+	clicks chan *model.Click
+	// dropped is the number of click events dropped because the queue was full.
+	dropped atomic.Int64
+}
+
+// newClickWriter creates a click writer and starts its workers.
+// A zero queueSize is set based on the number of workers.
+func newClickWriter(saver clickSaver, workers, queueSize int) *clickWriter {
+	if queueSize == 0 {
+		queueSize = workers * 100
+	}
+
+	w := &clickWriter{
+		saver:  saver,
+		clicks: make(chan *model.Click, queueSize),
+	}
+	for range workers {
+		go w.worker()
+	}
+
+	return w
+}
+
+// write queues the click event without blocking.
+// If the queue is full, the event is dropped and counted instead.
+func (w *clickWriter) write(click *model.Click) {
+	select {
+	case w.clicks <- click:
+	default:
+		w.dropped.Add(1)
+	}
+}
+
+// close stops the workers once the queued click events are saved.
+func (w *clickWriter) close() {
+	close(w.clicks)
+}
+
+// worker saves queued click events one at a time.
+func (w *clickWriter) worker() {
+	for click := range w.clicks {
+		ctx, cancel := context.WithTimeout(context.Background(), saveClickTimeout)
+		_ = w.saver.SaveClick(ctx, click)
+		cancel()
+	}
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnonymizeIP(t *testing.T) {
+	tests := map[string]struct {
+		ip       string
+		expected string
+	}{
+		"ipv4":             {ip: "192.168.1.42", expected: "192.168.1.0"},
+		"ipv4 mapped ipv6": {ip: "::ffff:10.0.0.7", expected: "10.0.0.0"},
+		"ipv6":             {ip: "2001:db8:85a3:8d3:1319:8a2e:370:7348", expected: "2001:db8:85a3::"},
+		"ipv6 with zone":   {ip: "fe80::1%eth0", expected: "fe80::"},
+		"invalid":          {ip: "localhost", expected: ""},
+		"empty":            {ip: "", expected: ""},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expected, anonymizeIP(tt.ip))
+		})
+	}
+}
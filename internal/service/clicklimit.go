@@ -0,0 +1,20 @@
+package service
+
+import (
+	"fmt"
+)
+
+// resolveClicksLeft converts the click limit of a shortening request into
+// the number of redirects the URL allows. Returns nil if the number of redirects
+// is not limited. Returns ErrInvalidMaxClicks if maxClicks is negative.
+func resolveClicksLeft(maxClicks int64) (*int64, error) {
+	if maxClicks < 0 {
+		return nil, fmt.Errorf("%w: max_clicks must be positive", ErrInvalidMaxClicks)
+	}
+
+	if maxClicks == 0 {
+		return nil, nil
+	}
+
+	return &maxClicks, nil
+}
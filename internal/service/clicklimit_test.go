@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/request"
+	"github.com/dtroode/urlshorter/internal/service/dto"
+	"github.com/dtroode/urlshorter/internal/service/mocks"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+func clicksLeft(n int64) *int64 {
+	return &n
+}
+
+func TestResolveClicksLeft(t *testing.T) {
+	tests := map[string]struct {
+		maxClicks          int64
+		expectedClicksLeft *int64
+		wantErr            bool
+	}{
+		"not limited": {
+			maxClicks: 0,
+		},
+		"negative": {
+			maxClicks: -1,
+			wantErr:   true,
+		},
+		"one-time": {
+			maxClicks:          1,
+			expectedClicksLeft: clicksLeft(1),
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			left, err := resolveClicksLeft(tt.maxClicks)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidMaxClicks)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedClicksLeft, left)
+		})
+	}
+}
+
+func TestURL_GetOriginalURL_ClickLimited(t *testing.T) {
+	shortKey := "invite"
+	originalURL := "https://yandex.ru"
+
+	tests := map[string]struct {
+		clicksLeft       *int64
+		decrementError   error
+		expectedResponse string
+		expectedError    error
+	}{
+		"clicks left": {
+			clicksLeft:       clicksLeft(1),
+			expectedResponse: originalURL,
+		},
+		"no clicks left": {
+			clicksLeft:    clicksLeft(0),
+			expectedError: ErrGone,
+		},
+		"clicks used up concurrently": {
+			clicksLeft:     clicksLeft(1),
+			decrementError: storage.ErrNoClicksLeft,
+			expectedError:  ErrGone,
+		},
+		"storage error": {
+			clicksLeft:     clicksLeft(1),
+			decrementError: errors.New("storage error"),
+			expectedError:  errors.New("storage error"),
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			urlModel := &model.URL{
+				ID:          uuid.New(),
+				ShortKey:    shortKey,
+				OriginalURL: originalURL,
+				ClicksLeft:  tt.clicksLeft,
+			}
+
+			urlStorage := mocks.NewURLStorage(t)
+			urlStorage.On("GetURL", ctx, shortKey).Once().Return(urlModel, nil)
+			urlStorage.On("DecrementClicksLeft", ctx, urlModel.ID).Maybe().Return(tt.decrementError)
+			clicks := make(chan *model.Click, 1)
+			urlStorage.On("SaveClick", mock.Anything, mock.Anything).Maybe().Run(func(args mock.Arguments) {
+				clicks <- args.Get(1).(*model.Click)
+			}).Return(nil)
+
+			service := URL{
+				storage: urlStorage,
+				clicks:  newTestClickWriter(t, urlStorage),
+			}
+
+			resp, err := service.GetOriginalURL(ctx, dto.NewGetOriginalURL(shortKey, "", "", ""))
+
+			if tt.expectedError != nil {
+				require.Error(t, err)
+				assert.ErrorContains(t, err, tt.expectedError.Error())
+				urlStorage.AssertNotCalled(t, "SaveClick", mock.Anything, mock.Anything)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedResponse, resp)
+			<-clicks
+		})
+	}
+}
+
+func TestURL_CreateShortURL_MaxClicks(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	hasClicksLeft := func(url *model.URL) bool {
+		return url.ClicksLeft != nil && *url.ClicksLeft == 3
+	}
+
+	t.Run("invalid max clicks", func(t *testing.T) {
+		t.Parallel()
+
+		service := URL{
+			baseURL:        "http://localhost",
+			shortKeyLength: 5,
+			keyGenerator:   testKeyGenerator,
+			storage:        mocks.NewURLStorage(t),
+		}
+
+		createDTO := dto.NewCreateShortURL("https://yandex.ru", userID)
+		createDTO.MaxClicks = -1
+		_, err := service.CreateShortURL(ctx, createDTO)
+		require.ErrorIs(t, err, ErrInvalidMaxClicks)
+
+		_, err = service.CreateShortURLBatch(ctx, dto.NewCreateShortURLBatch([]*request.CreateShortURLBatch{
+			{CorrelationID: "1", OriginalURL: "https://yandex.ru", MaxClicks: -1},
+		}, userID))
+		require.ErrorIs(t, err, ErrInvalidMaxClicks)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		urlStorage := mocks.NewURLStorage(t)
+		urlStorage.On("SetURL", mock.Anything, mock.MatchedBy(hasClicksLeft), mock.Anything).Once().Return(func(_ context.Context, url *model.URL, _ storage.DedupeScope) (*model.URL, error) {
+			return url, nil
+		})
+		urlStorage.On("SetURLs", mock.Anything, mock.MatchedBy(func(urls []*model.URL) bool {
+			return len(urls) == 2 && hasClicksLeft(urls[0]) && urls[1].ClicksLeft == nil
+		}), mock.Anything).Once().Return(func(_ context.Context, urls []*model.URL, _ storage.DedupeScope) ([]*model.URL, error) {
+			return urls, nil
+		})
+
+		service := URL{
+			baseURL:        "http://localhost",
+			shortKeyLength: 5,
+			keyGenerator:   testKeyGenerator,
+			storage:        urlStorage,
+		}
+
+		createDTO := dto.NewCreateShortURL("https://yandex.ru", userID)
+		createDTO.MaxClicks = 3
+		_, err := service.CreateShortURL(ctx, createDTO)
+		require.NoError(t, err)
+
+		_, err = service.CreateShortURLBatch(ctx, dto.NewCreateShortURLBatch([]*request.CreateShortURLBatch{
+			{CorrelationID: "1", OriginalURL: "https://yandex.ru", MaxClicks: 3},
+			{CorrelationID: "2", OriginalURL: "https://ya.ru"},
+		}, userID))
+		require.NoError(t, err)
+	})
+
+	t.Run("duplicate original urls in a batch", func(t *testing.T) {
+		t.Parallel()
+
+		var savedURLs []*model.URL
+		urlStorage := mocks.NewURLStorage(t)
+		urlStorage.On("SetURLs", mock.Anything, mock.Anything, storage.DedupeGlobal).Once().Return(func(_ context.Context, urls []*model.URL, _ storage.DedupeScope) ([]*model.URL, error) {
+			// Like the storages, reuse an earlier reusable URL of the batch with the same original URL.
+			savedURLs = make([]*model.URL, len(urls))
+			for i, url := range urls {
+				savedURLs[i] = url
+				for _, saved := range savedURLs[:i] {
+					if saved.CanBeReused() && url.CanBeReused() && saved.OriginalURL == url.OriginalURL {
+						savedURLs[i] = saved
+						break
+					}
+				}
+			}
+			return savedURLs, nil
+		})
+
+		service := URL{
+			baseURL:        "http://localhost",
+			shortKeyLength: 5,
+			keyGenerator:   testKeyGenerator,
+			storage:        urlStorage,
+		}
+
+		resp, err := service.CreateShortURLBatch(ctx, dto.NewCreateShortURLBatch([]*request.CreateShortURLBatch{
+			{CorrelationID: "1", OriginalURL: "https://yandex.ru", MaxClicks: 1},
+			{CorrelationID: "2", OriginalURL: "https://yandex.ru", MaxClicks: 1},
+			{CorrelationID: "3", OriginalURL: "https://yandex.ru"},
+			{CorrelationID: "4", OriginalURL: "https://yandex.ru"},
+		}, userID))
+		require.NoError(t, err)
+
+		// Every item gets the one URL saved for it, click-limited items get URLs of their own.
+		require.Len(t, resp, 4)
+		for i, respURL := range resp {
+			assert.Equal(t, savedURLs[i].ShortKey, strings.TrimPrefix(respURL.ShortURL, "http://localhost/"))
+		}
+		assert.NotEqual(t, resp[0].ShortURL, resp[1].ShortURL)
+		assert.NotEqual(t, resp[0].ShortURL, resp[2].ShortURL)
+		assert.NotEqual(t, resp[1].ShortURL, resp[2].ShortURL)
+		assert.Equal(t, resp[2].ShortURL, resp[3].ShortURL)
+	})
+}
@@ -1,6 +1,8 @@
 package dto
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 
 	"github.com/dtroode/urlshorter/internal/request"
@@ -13,6 +15,19 @@ type CreateShortURL struct {
 	UserID uuid.UUID
 	// OriginalURL is the full URL to be shortened.
 	OriginalURL string
+	// Alias is an optional custom short key requested by the user.
+	// If empty, a short key is generated.
+	Alias string
+	// TTL is an optional lifetime of the URL in seconds.
+	TTL int64
+	// ExpiresAt is an optional moment after which the URL stops working.
+	ExpiresAt *time.Time
+	// ForceNew disables deduplication of the original URL.
+	ForceNew bool
+	// Password is an optional password required to follow the URL.
+	Password string
+	// MaxClicks is an optional number of redirects after which the URL stops working.
+	MaxClicks int64
 }
 
 // NewCreateShortURL creates a new CreateShortURL DTO instance.
@@ -29,6 +44,63 @@ func NewCreateShortURL(originalURL string, userID uuid.UUID) *CreateShortURL {
 	}
 }
 
+// GetOriginalURL represents a data transfer object for resolving a shortened URL.
+// It contains the short key and information about the client used to record a click.
+type GetOriginalURL struct {
+	// ShortKey is the short URL identifier to resolve.
+	ShortKey string
+	// Referrer is the referrer of the request, if any.
+	Referrer string
+	// UserAgent is the user agent of the client.
+	UserAgent string
+	// ClientIP is the IP address of the client.
+	ClientIP string
+	// AccessToken is the token issued by UnlockURL, if the client has one.
+	// It is required to resolve password-protected URLs.
+	AccessToken string
+}
+
+// NewGetOriginalURL creates a new GetOriginalURL DTO instance.
+//
+// Parameters:
+//   - shortKey: The short URL identifier to resolve
+//   - referrer: The referrer of the request
+//   - userAgent: The user agent of the client
+//   - clientIP: The IP address of the client
+//
+// Returns a pointer to the newly created GetOriginalURL instance.
+func NewGetOriginalURL(shortKey, referrer, userAgent, clientIP string) *GetOriginalURL {
+	return &GetOriginalURL{
+		ShortKey:  shortKey,
+		Referrer:  referrer,
+		UserAgent: userAgent,
+		ClientIP:  clientIP,
+	}
+}
+
+// UnlockURL represents a data transfer object for unlocking a password-protected URL.
+// It contains the short key and the password entered by the client.
+type UnlockURL struct {
+	// ShortKey is the short URL identifier to unlock.
+	ShortKey string
+	// Password is the password entered by the client.
+	Password string
+}
+
+// NewUnlockURL creates a new UnlockURL DTO instance.
+//
+// Parameters:
+//   - shortKey: The short URL identifier to unlock
+//   - password: The password entered by the client
+//
+// Returns a pointer to the newly created UnlockURL instance.
+func NewUnlockURL(shortKey, password string) *UnlockURL {
+	return &UnlockURL{
+		ShortKey: shortKey,
+		Password: password,
+	}
+}
+
 // CreateShortURLBatch represents a data transfer object for batch URL shortening operations.
 // It contains a slice of URL requests and the user ID for batch processing.
 type CreateShortURLBatch struct {
@@ -36,6 +108,8 @@ type CreateShortURLBatch struct {
 	URLs []*request.CreateShortURLBatch
 	// UserID is the UUID of the user creating the shortened URLs.
 	UserID uuid.UUID
+	// ForceNew disables deduplication of the original URLs.
+	ForceNew bool
 }
 
 // NewCreateShortURLBatch creates a new CreateShortURLBatch DTO instance.
@@ -74,3 +148,81 @@ func NewDeleteURLs(shortKeys []string, userID uuid.UUID) *DeleteURLs {
 		ShortKeys: shortKeys,
 	}
 }
+
+// RestoreURLs represents a data transfer object for restoring deleted URLs.
+// It contains the user ID and a slice of short keys to be restored.
+type RestoreURLs struct {
+	// UserID is the UUID of the user restoring the URLs.
+	UserID uuid.UUID
+	// ShortKeys is a slice of short URL identifiers to be restored.
+	ShortKeys []string
+}
+
+// NewRestoreURLs creates a new RestoreURLs DTO instance.
+//
+// Parameters:
+//   - shortKeys: A slice of short URL identifiers to restore
+//   - userID: The UUID of the user restoring the URLs
+//
+// Returns a pointer to the newly created RestoreURLs instance.
+func NewRestoreURLs(shortKeys []string, userID uuid.UUID) *RestoreURLs {
+	return &RestoreURLs{
+		UserID:    userID,
+		ShortKeys: shortKeys,
+	}
+}
+
+// GetURLStats represents a data transfer object for retrieving statistics of a shortened URL.
+// It contains the short key, the user requesting statistics and the size of series buckets.
+type GetURLStats struct {
+	// UserID is the UUID of the user requesting statistics.
+	UserID uuid.UUID
+	// ShortKey is the short URL identifier to get statistics for.
+	ShortKey string
+	// Bucket is the size of time series buckets, "hour" or "day".
+	// If empty, "day" is used.
+	Bucket string
+}
+
+// NewGetURLStats creates a new GetURLStats DTO instance.
+//
+// Parameters:
+//   - shortKey: The short URL identifier to get statistics for
+//   - bucket: The size of time series buckets
+//   - userID: The UUID of the user requesting statistics
+//
+// Returns a pointer to the newly created GetURLStats instance.
+func NewGetURLStats(shortKey, bucket string, userID uuid.UUID) *GetURLStats {
+	return &GetURLStats{
+		UserID:   userID,
+		ShortKey: shortKey,
+		Bucket:   bucket,
+	}
+}
+
+// UpdateURL represents a data transfer object for changing a shortened URL.
+// It contains the short key, the user changing the URL and the requested changes.
+type UpdateURL struct {
+	// UserID is the UUID of the user changing the URL.
+	UserID uuid.UUID
+	// ShortKey is the short URL identifier to change.
+	ShortKey string
+	// Changes are the attributes to change. Nil fields are left unchanged.
+	Changes *request.UpdateURL
+}
+
+// NewUpdateURL creates a new UpdateURL DTO instance.
+//
+// Parameters:
+//   - shortKey: The short URL identifier to change
+//   - changes: The attributes to change
+//   - userID: The UUID of the user changing the URL
+//
+// Returns a pointer to the newly created UpdateURL instance.
+func NewUpdateURL(shortKey string, changes *request.UpdateURL, userID uuid.UUID) *UpdateURL {
+	return &UpdateURL{
+		UserID:   userID,
+		ShortKey: shortKey,
+		Changes:  changes,
+	}
+}
@@ -23,3 +23,51 @@ var ErrNoContent = errors.New("no content")
 // ErrGone is returned when a requested resource has been permanently deleted.
 // This error typically indicates a 410 Gone HTTP status.
 var ErrGone = errors.New("gone")
+
+// ErrInvalidAlias is returned when a requested alias does not satisfy alias rules.
+// This error typically indicates a 400 Bad Request HTTP status.
+var ErrInvalidAlias = errors.New("invalid alias")
+
+// ErrAliasTaken is returned when a requested alias is already used by another URL.
+// This error typically indicates a 409 Conflict HTTP status.
+var ErrAliasTaken = errors.New("alias is already taken")
+
+// ErrInvalidExpiration is returned when requested expiration parameters are invalid.
+// This error typically indicates a 400 Bad Request HTTP status.
+var ErrInvalidExpiration = errors.New("invalid expiration")
+
+// ErrForbidden is returned when a user requests a resource owned by another user.
+// This error typically indicates a 403 Forbidden HTTP status.
+var ErrForbidden = errors.New("forbidden")
+
+// ErrInvalidStatsBucket is returned when a requested statistics bucket size is not supported.
+// This error typically indicates a 400 Bad Request HTTP status.
+var ErrInvalidStatsBucket = errors.New("invalid stats bucket")
+
+// ErrInvalidURL is returned when an original URL can't be shortened.
+// This error typically indicates a 400 Bad Request HTTP status.
+var ErrInvalidURL = errors.New("invalid url")
+
+// ErrInvalidMaxClicks is returned when a requested click limit is invalid.
+// This error typically indicates a 400 Bad Request HTTP status.
+var ErrInvalidMaxClicks = errors.New("invalid max clicks")
+
+// ErrInvalidPassword is returned when a requested password does not satisfy password rules.
+// This error typically indicates a 400 Bad Request HTTP status.
+var ErrInvalidPassword = errors.New("invalid password")
+
+// ErrPasswordRequired is returned when a password-protected URL is requested without access.
+// This error typically indicates that a password form should be shown.
+var ErrPasswordRequired = errors.New("password required")
+
+// ErrWrongPassword is returned when a password entered for a URL doesn't match.
+// This error typically indicates a 403 Forbidden HTTP status.
+var ErrWrongPassword = errors.New("wrong password")
+
+// ErrTooManyAttempts is returned when too many wrong passwords have been entered for a URL.
+// This error typically indicates a 429 Too Many Requests HTTP status.
+var ErrTooManyAttempts = errors.New("too many attempts")
+
+// ErrUnavailable is returned when a request can't be served while the storage is unavailable.
+// This error typically indicates a 503 Service Unavailable HTTP status.
+var ErrUnavailable = errors.New("service unavailable")
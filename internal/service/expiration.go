@@ -0,0 +1,38 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// maxTTL is the largest TTL in seconds that can be represented as time.Duration.
+const maxTTL = math.MaxInt64 / int64(time.Second)
+
+// resolveExpiration converts expiration parameters of a shortening request
+// into the moment the URL expires. Returns nil if the URL never expires.
+// Returns ErrInvalidExpiration wrapped with the reason if parameters are invalid.
+func resolveExpiration(now time.Time, ttl int64, expiresAt *time.Time) (*time.Time, error) {
+	if ttl != 0 && expiresAt != nil {
+		return nil, fmt.Errorf("%w: ttl and expires_at are mutually exclusive", ErrInvalidExpiration)
+	}
+
+	if ttl < 0 || ttl > maxTTL {
+		return nil, fmt.Errorf("%w: ttl must be between 1 and %d seconds", ErrInvalidExpiration, maxTTL)
+	}
+
+	if ttl > 0 {
+		t := now.Add(time.Duration(ttl) * time.Second).UTC()
+		return &t, nil
+	}
+
+	if expiresAt != nil {
+		if !expiresAt.After(now) {
+			return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidExpiration)
+		}
+		t := expiresAt.UTC()
+		return &t, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveExpiration(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+
+	tests := map[string]struct {
+		ttl       int64
+		expiresAt *time.Time
+		expected  *time.Time
+		wantErr   bool
+	}{
+		"no expiration": {},
+		"ttl": {
+			ttl:      60,
+			expected: func() *time.Time { t := now.Add(time.Minute); return &t }(),
+		},
+		"expires at": {
+			expiresAt: &future,
+			expected:  &future,
+		},
+		"negative ttl": {
+			ttl:     -1,
+			wantErr: true,
+		},
+		"too large ttl": {
+			ttl:     maxTTL + 1,
+			wantErr: true,
+		},
+		"expires at in the past": {
+			expiresAt: &past,
+			wantErr:   true,
+		},
+		"expires at now": {
+			expiresAt: &now,
+			wantErr:   true,
+		},
+		"both ttl and expires at": {
+			ttl:       60,
+			expiresAt: &future,
+			wantErr:   true,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			expiresAt, err := resolveExpiration(now, tt.ttl, tt.expiresAt)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidExpiration)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, expiresAt)
+		})
+	}
+}
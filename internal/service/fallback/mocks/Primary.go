@@ -0,0 +1,856 @@
+// Code generated by mockery v2.52.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	time "time"
+
+	model "github.com/dtroode/urlshorter/internal/model"
+	storage "github.com/dtroode/urlshorter/internal/storage"
+	uuid "github.com/google/uuid"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Primary is an autogenerated mock type for the Primary type
+type Primary struct {
+	mock.Mock
+}
+
+type Primary_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Primary) EXPECT() *Primary_Expecter {
+	return &Primary_Expecter{mock: &_m.Mock}
+}
+
+// DecrementClicksLeft provides a mock function with given fields: ctx, id
+func (_m *Primary) DecrementClicksLeft(ctx context.Context, id uuid.UUID) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DecrementClicksLeft")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Primary_DecrementClicksLeft_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DecrementClicksLeft'
+type Primary_DecrementClicksLeft_Call struct {
+	*mock.Call
+}
+
+// DecrementClicksLeft is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uuid.UUID
+func (_e *Primary_Expecter) DecrementClicksLeft(ctx interface{}, id interface{}) *Primary_DecrementClicksLeft_Call {
+	return &Primary_DecrementClicksLeft_Call{Call: _e.mock.On("DecrementClicksLeft", ctx, id)}
+}
+
+func (_c *Primary_DecrementClicksLeft_Call) Run(run func(ctx context.Context, id uuid.UUID)) *Primary_DecrementClicksLeft_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uuid.UUID))
+	})
+	return _c
+}
+
+func (_c *Primary_DecrementClicksLeft_Call) Return(_a0 error) *Primary_DecrementClicksLeft_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Primary_DecrementClicksLeft_Call) RunAndReturn(run func(context.Context, uuid.UUID) error) *Primary_DecrementClicksLeft_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteURLs provides a mock function with given fields: ctx, ids
+func (_m *Primary) DeleteURLs(ctx context.Context, ids []uuid.UUID) error {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteURLs")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) error); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Primary_DeleteURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteURLs'
+type Primary_DeleteURLs_Call struct {
+	*mock.Call
+}
+
+// DeleteURLs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - ids []uuid.UUID
+func (_e *Primary_Expecter) DeleteURLs(ctx interface{}, ids interface{}) *Primary_DeleteURLs_Call {
+	return &Primary_DeleteURLs_Call{Call: _e.mock.On("DeleteURLs", ctx, ids)}
+}
+
+func (_c *Primary_DeleteURLs_Call) Run(run func(ctx context.Context, ids []uuid.UUID)) *Primary_DeleteURLs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]uuid.UUID))
+	})
+	return _c
+}
+
+func (_c *Primary_DeleteURLs_Call) Return(_a0 error) *Primary_DeleteURLs_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Primary_DeleteURLs_Call) RunAndReturn(run func(context.Context, []uuid.UUID) error) *Primary_DeleteURLs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ForEachActiveURL provides a mock function with given fields: ctx, fn
+func (_m *Primary) ForEachActiveURL(ctx context.Context, fn func(*model.URL) error) error {
+	ret := _m.Called(ctx, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ForEachActiveURL")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(*model.URL) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Primary_ForEachActiveURL_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ForEachActiveURL'
+type Primary_ForEachActiveURL_Call struct {
+	*mock.Call
+}
+
+// ForEachActiveURL is a helper method to define mock.On call
+//   - ctx context.Context
+//   - fn func(*model.URL) error
+func (_e *Primary_Expecter) ForEachActiveURL(ctx interface{}, fn interface{}) *Primary_ForEachActiveURL_Call {
+	return &Primary_ForEachActiveURL_Call{Call: _e.mock.On("ForEachActiveURL", ctx, fn)}
+}
+
+func (_c *Primary_ForEachActiveURL_Call) Run(run func(ctx context.Context, fn func(*model.URL) error)) *Primary_ForEachActiveURL_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(func(*model.URL) error))
+	})
+	return _c
+}
+
+func (_c *Primary_ForEachActiveURL_Call) Return(_a0 error) *Primary_ForEachActiveURL_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Primary_ForEachActiveURL_Call) RunAndReturn(run func(context.Context, func(*model.URL) error) error) *Primary_ForEachActiveURL_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetClickStats provides a mock function with given fields: ctx, urlID, bucket
+func (_m *Primary) GetClickStats(ctx context.Context, urlID uuid.UUID, bucket time.Duration) (*model.ClickStats, error) {
+	ret := _m.Called(ctx, urlID, bucket)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetClickStats")
+	}
+
+	var r0 *model.ClickStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, time.Duration) (*model.ClickStats, error)); ok {
+		return rf(ctx, urlID, bucket)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, time.Duration) *model.ClickStats); ok {
+		r0 = rf(ctx, urlID, bucket)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.ClickStats)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, time.Duration) error); ok {
+		r1 = rf(ctx, urlID, bucket)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Primary_GetClickStats_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetClickStats'
+type Primary_GetClickStats_Call struct {
+	*mock.Call
+}
+
+// GetClickStats is a helper method to define mock.On call
+//   - ctx context.Context
+//   - urlID uuid.UUID
+//   - bucket time.Duration
+func (_e *Primary_Expecter) GetClickStats(ctx interface{}, urlID interface{}, bucket interface{}) *Primary_GetClickStats_Call {
+	return &Primary_GetClickStats_Call{Call: _e.mock.On("GetClickStats", ctx, urlID, bucket)}
+}
+
+func (_c *Primary_GetClickStats_Call) Run(run func(ctx context.Context, urlID uuid.UUID, bucket time.Duration)) *Primary_GetClickStats_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uuid.UUID), args[2].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *Primary_GetClickStats_Call) Return(_a0 *model.ClickStats, _a1 error) *Primary_GetClickStats_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Primary_GetClickStats_Call) RunAndReturn(run func(context.Context, uuid.UUID, time.Duration) (*model.ClickStats, error)) *Primary_GetClickStats_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetDeletedURLsByUserID provides a mock function with given fields: ctx, userID
+func (_m *Primary) GetDeletedURLsByUserID(ctx context.Context, userID uuid.UUID) ([]*model.URL, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDeletedURLsByUserID")
+	}
+
+	var r0 []*model.URL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ([]*model.URL, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []*model.URL); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.URL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Primary_GetDeletedURLsByUserID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetDeletedURLsByUserID'
+type Primary_GetDeletedURLsByUserID_Call struct {
+	*mock.Call
+}
+
+// GetDeletedURLsByUserID is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userID uuid.UUID
+func (_e *Primary_Expecter) GetDeletedURLsByUserID(ctx interface{}, userID interface{}) *Primary_GetDeletedURLsByUserID_Call {
+	return &Primary_GetDeletedURLsByUserID_Call{Call: _e.mock.On("GetDeletedURLsByUserID", ctx, userID)}
+}
+
+func (_c *Primary_GetDeletedURLsByUserID_Call) Run(run func(ctx context.Context, userID uuid.UUID)) *Primary_GetDeletedURLsByUserID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uuid.UUID))
+	})
+	return _c
+}
+
+func (_c *Primary_GetDeletedURLsByUserID_Call) Return(_a0 []*model.URL, _a1 error) *Primary_GetDeletedURLsByUserID_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Primary_GetDeletedURLsByUserID_Call) RunAndReturn(run func(context.Context, uuid.UUID) ([]*model.URL, error)) *Primary_GetDeletedURLsByUserID_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetURL provides a mock function with given fields: ctx, shortKey
+func (_m *Primary) GetURL(ctx context.Context, shortKey string) (*model.URL, error) {
+	ret := _m.Called(ctx, shortKey)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetURL")
+	}
+
+	var r0 *model.URL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.URL, error)); ok {
+		return rf(ctx, shortKey)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.URL); ok {
+		r0 = rf(ctx, shortKey)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.URL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, shortKey)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Primary_GetURL_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetURL'
+type Primary_GetURL_Call struct {
+	*mock.Call
+}
+
+// GetURL is a helper method to define mock.On call
+//   - ctx context.Context
+//   - shortKey string
+func (_e *Primary_Expecter) GetURL(ctx interface{}, shortKey interface{}) *Primary_GetURL_Call {
+	return &Primary_GetURL_Call{Call: _e.mock.On("GetURL", ctx, shortKey)}
+}
+
+func (_c *Primary_GetURL_Call) Run(run func(ctx context.Context, shortKey string)) *Primary_GetURL_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Primary_GetURL_Call) Return(_a0 *model.URL, _a1 error) *Primary_GetURL_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Primary_GetURL_Call) RunAndReturn(run func(context.Context, string) (*model.URL, error)) *Primary_GetURL_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetURLs provides a mock function with given fields: ctx, shortKeys
+func (_m *Primary) GetURLs(ctx context.Context, shortKeys []string) ([]*model.URL, error) {
+	ret := _m.Called(ctx, shortKeys)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetURLs")
+	}
+
+	var r0 []*model.URL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]*model.URL, error)); ok {
+		return rf(ctx, shortKeys)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []*model.URL); ok {
+		r0 = rf(ctx, shortKeys)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.URL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, shortKeys)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Primary_GetURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetURLs'
+type Primary_GetURLs_Call struct {
+	*mock.Call
+}
+
+// GetURLs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - shortKeys []string
+func (_e *Primary_Expecter) GetURLs(ctx interface{}, shortKeys interface{}) *Primary_GetURLs_Call {
+	return &Primary_GetURLs_Call{Call: _e.mock.On("GetURLs", ctx, shortKeys)}
+}
+
+func (_c *Primary_GetURLs_Call) Run(run func(ctx context.Context, shortKeys []string)) *Primary_GetURLs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]string))
+	})
+	return _c
+}
+
+func (_c *Primary_GetURLs_Call) Return(_a0 []*model.URL, _a1 error) *Primary_GetURLs_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Primary_GetURLs_Call) RunAndReturn(run func(context.Context, []string) ([]*model.URL, error)) *Primary_GetURLs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetURLsByUserID provides a mock function with given fields: ctx, userID
+func (_m *Primary) GetURLsByUserID(ctx context.Context, userID uuid.UUID) ([]*model.URL, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetURLsByUserID")
+	}
+
+	var r0 []*model.URL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ([]*model.URL, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []*model.URL); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.URL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Primary_GetURLsByUserID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetURLsByUserID'
+type Primary_GetURLsByUserID_Call struct {
+	*mock.Call
+}
+
+// GetURLsByUserID is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userID uuid.UUID
+func (_e *Primary_Expecter) GetURLsByUserID(ctx interface{}, userID interface{}) *Primary_GetURLsByUserID_Call {
+	return &Primary_GetURLsByUserID_Call{Call: _e.mock.On("GetURLsByUserID", ctx, userID)}
+}
+
+func (_c *Primary_GetURLsByUserID_Call) Run(run func(ctx context.Context, userID uuid.UUID)) *Primary_GetURLsByUserID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uuid.UUID))
+	})
+	return _c
+}
+
+func (_c *Primary_GetURLsByUserID_Call) Return(_a0 []*model.URL, _a1 error) *Primary_GetURLsByUserID_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Primary_GetURLsByUserID_Call) RunAndReturn(run func(context.Context, uuid.UUID) ([]*model.URL, error)) *Primary_GetURLsByUserID_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Ping provides a mock function with given fields: ctx
+func (_m *Primary) Ping(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Ping")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Primary_Ping_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Ping'
+type Primary_Ping_Call struct {
+	*mock.Call
+}
+
+// Ping is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Primary_Expecter) Ping(ctx interface{}) *Primary_Ping_Call {
+	return &Primary_Ping_Call{Call: _e.mock.On("Ping", ctx)}
+}
+
+func (_c *Primary_Ping_Call) Run(run func(ctx context.Context)) *Primary_Ping_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Primary_Ping_Call) Return(_a0 error) *Primary_Ping_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Primary_Ping_Call) RunAndReturn(run func(context.Context) error) *Primary_Ping_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PurgeDeletedURLs provides a mock function with given fields: ctx, before, limit
+func (_m *Primary) PurgeDeletedURLs(ctx context.Context, before time.Time, limit int) (int64, error) {
+	ret := _m.Called(ctx, before, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeDeletedURLs")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) (int64, error)); ok {
+		return rf(ctx, before, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) int64); ok {
+		r0 = rf(ctx, before, limit)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, before, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Primary_PurgeDeletedURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PurgeDeletedURLs'
+type Primary_PurgeDeletedURLs_Call struct {
+	*mock.Call
+}
+
+// PurgeDeletedURLs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - before time.Time
+//   - limit int
+func (_e *Primary_Expecter) PurgeDeletedURLs(ctx interface{}, before interface{}, limit interface{}) *Primary_PurgeDeletedURLs_Call {
+	return &Primary_PurgeDeletedURLs_Call{Call: _e.mock.On("PurgeDeletedURLs", ctx, before, limit)}
+}
+
+func (_c *Primary_PurgeDeletedURLs_Call) Run(run func(ctx context.Context, before time.Time, limit int)) *Primary_PurgeDeletedURLs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(time.Time), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *Primary_PurgeDeletedURLs_Call) Return(_a0 int64, _a1 error) *Primary_PurgeDeletedURLs_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Primary_PurgeDeletedURLs_Call) RunAndReturn(run func(context.Context, time.Time, int) (int64, error)) *Primary_PurgeDeletedURLs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RestoreURLs provides a mock function with given fields: ctx, ids
+func (_m *Primary) RestoreURLs(ctx context.Context, ids []uuid.UUID) error {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreURLs")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) error); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Primary_RestoreURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RestoreURLs'
+type Primary_RestoreURLs_Call struct {
+	*mock.Call
+}
+
+// RestoreURLs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - ids []uuid.UUID
+func (_e *Primary_Expecter) RestoreURLs(ctx interface{}, ids interface{}) *Primary_RestoreURLs_Call {
+	return &Primary_RestoreURLs_Call{Call: _e.mock.On("RestoreURLs", ctx, ids)}
+}
+
+func (_c *Primary_RestoreURLs_Call) Run(run func(ctx context.Context, ids []uuid.UUID)) *Primary_RestoreURLs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]uuid.UUID))
+	})
+	return _c
+}
+
+func (_c *Primary_RestoreURLs_Call) Return(_a0 error) *Primary_RestoreURLs_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Primary_RestoreURLs_Call) RunAndReturn(run func(context.Context, []uuid.UUID) error) *Primary_RestoreURLs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SaveClick provides a mock function with given fields: ctx, click
+func (_m *Primary) SaveClick(ctx context.Context, click *model.Click) error {
+	ret := _m.Called(ctx, click)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveClick")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Click) error); ok {
+		r0 = rf(ctx, click)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Primary_SaveClick_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveClick'
+type Primary_SaveClick_Call struct {
+	*mock.Call
+}
+
+// SaveClick is a helper method to define mock.On call
+//   - ctx context.Context
+//   - click *model.Click
+func (_e *Primary_Expecter) SaveClick(ctx interface{}, click interface{}) *Primary_SaveClick_Call {
+	return &Primary_SaveClick_Call{Call: _e.mock.On("SaveClick", ctx, click)}
+}
+
+func (_c *Primary_SaveClick_Call) Run(run func(ctx context.Context, click *model.Click)) *Primary_SaveClick_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*model.Click))
+	})
+	return _c
+}
+
+func (_c *Primary_SaveClick_Call) Return(_a0 error) *Primary_SaveClick_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Primary_SaveClick_Call) RunAndReturn(run func(context.Context, *model.Click) error) *Primary_SaveClick_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetURL provides a mock function with given fields: ctx, url, scope
+func (_m *Primary) SetURL(ctx context.Context, url *model.URL, scope storage.DedupeScope) (*model.URL, error) {
+	ret := _m.Called(ctx, url, scope)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetURL")
+	}
+
+	var r0 *model.URL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.URL, storage.DedupeScope) (*model.URL, error)); ok {
+		return rf(ctx, url, scope)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *model.URL, storage.DedupeScope) *model.URL); ok {
+		r0 = rf(ctx, url, scope)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.URL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *model.URL, storage.DedupeScope) error); ok {
+		r1 = rf(ctx, url, scope)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Primary_SetURL_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetURL'
+type Primary_SetURL_Call struct {
+	*mock.Call
+}
+
+// SetURL is a helper method to define mock.On call
+//   - ctx context.Context
+//   - url *model.URL
+//   - scope storage.DedupeScope
+func (_e *Primary_Expecter) SetURL(ctx interface{}, url interface{}, scope interface{}) *Primary_SetURL_Call {
+	return &Primary_SetURL_Call{Call: _e.mock.On("SetURL", ctx, url, scope)}
+}
+
+func (_c *Primary_SetURL_Call) Run(run func(ctx context.Context, url *model.URL, scope storage.DedupeScope)) *Primary_SetURL_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*model.URL), args[2].(storage.DedupeScope))
+	})
+	return _c
+}
+
+func (_c *Primary_SetURL_Call) Return(_a0 *model.URL, _a1 error) *Primary_SetURL_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Primary_SetURL_Call) RunAndReturn(run func(context.Context, *model.URL, storage.DedupeScope) (*model.URL, error)) *Primary_SetURL_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetURLs provides a mock function with given fields: ctx, urls, scope
+func (_m *Primary) SetURLs(ctx context.Context, urls []*model.URL, scope storage.DedupeScope) ([]*model.URL, error) {
+	ret := _m.Called(ctx, urls, scope)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetURLs")
+	}
+
+	var r0 []*model.URL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []*model.URL, storage.DedupeScope) ([]*model.URL, error)); ok {
+		return rf(ctx, urls, scope)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []*model.URL, storage.DedupeScope) []*model.URL); ok {
+		r0 = rf(ctx, urls, scope)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.URL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []*model.URL, storage.DedupeScope) error); ok {
+		r1 = rf(ctx, urls, scope)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Primary_SetURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetURLs'
+type Primary_SetURLs_Call struct {
+	*mock.Call
+}
+
+// SetURLs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - urls []*model.URL
+//   - scope storage.DedupeScope
+func (_e *Primary_Expecter) SetURLs(ctx interface{}, urls interface{}, scope interface{}) *Primary_SetURLs_Call {
+	return &Primary_SetURLs_Call{Call: _e.mock.On("SetURLs", ctx, urls, scope)}
+}
+
+func (_c *Primary_SetURLs_Call) Run(run func(ctx context.Context, urls []*model.URL, scope storage.DedupeScope)) *Primary_SetURLs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]*model.URL), args[2].(storage.DedupeScope))
+	})
+	return _c
+}
+
+func (_c *Primary_SetURLs_Call) Return(savedURLs []*model.URL, err error) *Primary_SetURLs_Call {
+	_c.Call.Return(savedURLs, err)
+	return _c
+}
+
+func (_c *Primary_SetURLs_Call) RunAndReturn(run func(context.Context, []*model.URL, storage.DedupeScope) ([]*model.URL, error)) *Primary_SetURLs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateURL provides a mock function with given fields: ctx, url, fields
+func (_m *Primary) UpdateURL(ctx context.Context, url *model.URL, fields storage.URLFields) (*model.URL, error) {
+	ret := _m.Called(ctx, url, fields)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateURL")
+	}
+
+	var r0 *model.URL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.URL, storage.URLFields) (*model.URL, error)); ok {
+		return rf(ctx, url, fields)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *model.URL, storage.URLFields) *model.URL); ok {
+		r0 = rf(ctx, url, fields)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.URL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *model.URL, storage.URLFields) error); ok {
+		r1 = rf(ctx, url, fields)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Primary_UpdateURL_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateURL'
+type Primary_UpdateURL_Call struct {
+	*mock.Call
+}
+
+// UpdateURL is a helper method to define mock.On call
+//   - ctx context.Context
+//   - url *model.URL
+//   - fields storage.URLFields
+func (_e *Primary_Expecter) UpdateURL(ctx interface{}, url interface{}, fields interface{}) *Primary_UpdateURL_Call {
+	return &Primary_UpdateURL_Call{Call: _e.mock.On("UpdateURL", ctx, url, fields)}
+}
+
+func (_c *Primary_UpdateURL_Call) Run(run func(ctx context.Context, url *model.URL, fields storage.URLFields)) *Primary_UpdateURL_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*model.URL), args[2].(storage.URLFields))
+	})
+	return _c
+}
+
+func (_c *Primary_UpdateURL_Call) Return(_a0 *model.URL, _a1 error) *Primary_UpdateURL_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Primary_UpdateURL_Call) RunAndReturn(run func(context.Context, *model.URL, storage.URLFields) (*model.URL, error)) *Primary_UpdateURL_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewPrimary creates a new instance of Primary. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewPrimary(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Primary {
+	mock := &Primary{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,502 @@
+// Package fallback keeps serving redirects from a local snapshot of active URLs
+// while the primary storage is unavailable.
+package fallback
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dtroode/urlshorter/internal/logger"
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/service"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+const (
+	// primaryName is the name of the primary storage in component statuses
+	// unless the storage reports its own components.
+	primaryName = "primary"
+	// snapshotName is the name of the snapshot in component statuses.
+	snapshotName = "snapshot"
+	// defaultProbeInterval is the time between pings of the primary storage while degraded.
+	defaultProbeInterval = time.Second
+)
+
+var (
+	// errDegraded is returned by operations that need the primary storage while it's unavailable.
+	errDegraded = fmt.Errorf("%w: primary storage is unavailable", service.ErrUnavailable)
+	// errNoSnapshot is reported for the snapshot until one is taken or loaded from disk.
+	errNoSnapshot = errors.New("no snapshot")
+)
+
+// Primary is the storage requests are served from while it's available.
+type Primary interface {
+	service.URLStorage
+
+	// Ping checks if the storage is available.
+	Ping(ctx context.Context) error
+
+	// ForEachActiveURL calls fn with every URL that is neither deleted nor expired.
+	// Returns the first error returned by fn or an error if retrieval fails.
+	ForEachActiveURL(ctx context.Context, fn func(url *model.URL) error) error
+}
+
+// Option configures Storage.
+type Option func(*Storage)
+
+// WithProbeInterval sets the time between pings of the primary storage while it's unavailable.
+func WithProbeInterval(interval time.Duration) Option {
+	return func(s *Storage) {
+		s.probeInterval = interval
+	}
+}
+
+// snapshot holds the URLs that can be followed without the primary storage by short key.
+type snapshot struct {
+	urls    map[string]*model.URL
+	takenAt time.Time
+}
+
+// Storage is a service.URLStorage that serves URL lookups from a snapshot of active URLs
+// while the primary storage fails. The snapshot is refreshed from the primary storage
+// periodically and written to disk, so that it survives restarts.
+// Only URLs that can be followed without a password or a click limit are kept in the snapshot.
+//
+// Once a lookup or a refresh fails, the storage is degraded: lookups are served from the snapshot,
+// and other operations fail with service.ErrUnavailable without calling the primary storage,
+// until the primary storage answers a ping again.
+type Storage struct {
+	primary         Primary
+	filename        string
+	refreshInterval time.Duration
+	probeInterval   time.Duration
+	logger          *logger.Logger
+
+	snapshot atomic.Pointer[snapshot]
+	degraded atomic.Bool
+}
+
+var _ service.URLStorage = (*Storage)(nil)
+
+// NewStorage creates a new Storage instance and loads the snapshot written by a previous run, if any.
+//
+// Parameters:
+//   - primary: The storage requests are served from while it's available
+//   - filename: The file the snapshot is written to
+//   - refreshInterval: The time between refreshes of the snapshot
+//   - logger: The logger failures of the primary storage are reported to
+//   - opts: Options of the storage
+//
+// Returns a pointer to the newly created Storage instance or an error if the snapshot can't be read.
+func NewStorage(primary Primary, filename string, refreshInterval time.Duration, logger *logger.Logger, opts ...Option) (*Storage, error) {
+	s := &Storage{
+		primary:         primary,
+		filename:        filename,
+		refreshInterval: refreshInterval,
+		probeInterval:   defaultProbeInterval,
+		logger:          logger,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	snap, err := readSnapshot(filename)
+	if err != nil {
+		return nil, err
+	}
+	if snap != nil {
+		s.snapshot.Store(snap)
+	}
+
+	return s, nil
+}
+
+// Run refreshes the snapshot every refresh interval and pings the primary storage
+// every probe interval while it's unavailable, until ctx is done.
+// The first refresh is made immediately.
+func (s *Storage) Run(ctx context.Context) {
+	s.refresh(ctx)
+
+	refreshTicker := time.NewTicker(s.refreshInterval)
+	defer refreshTicker.Stop()
+	probeTicker := time.NewTicker(s.probeInterval)
+	defer probeTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-refreshTicker.C:
+			s.refresh(ctx)
+		case <-probeTicker.C:
+			if s.degraded.Load() {
+				s.probe(ctx)
+			}
+		}
+	}
+}
+
+// Degraded reports whether the primary storage is considered unavailable.
+func (s *Storage) Degraded() bool {
+	return s.degraded.Load()
+}
+
+// refresh takes a new snapshot from the primary storage.
+func (s *Storage) refresh(ctx context.Context) {
+	if err := s.Refresh(ctx); err != nil {
+		if ctx.Err() == nil {
+			s.setDegraded(err)
+		}
+		s.logger.Error("failed to refresh fallback snapshot", "error", err, "file", s.filename)
+	}
+}
+
+// probe pings the primary storage and ends the degraded state if it answers.
+func (s *Storage) probe(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, s.probeInterval)
+	defer cancel()
+
+	if err := s.primary.Ping(ctx); err == nil {
+		s.setDegraded(nil)
+	}
+}
+
+// setDegraded starts the degraded state if err is not nil and ends it otherwise.
+func (s *Storage) setDegraded(err error) {
+	degraded := err != nil
+	if s.degraded.Swap(degraded) == degraded {
+		return
+	}
+
+	if degraded {
+		s.logger.Error("primary storage is unavailable, serving redirects from fallback snapshot", "error", err)
+	} else {
+		s.logger.Info("primary storage is available again")
+	}
+}
+
+// Refresh takes a new snapshot of active URLs from the primary storage and writes it to disk.
+// The file isn't rewritten when the snapshot hasn't changed since the previous refresh.
+// The previous snapshot is kept if either fails.
+func (s *Storage) Refresh(ctx context.Context) error {
+	snap := &snapshot{
+		urls:    make(map[string]*model.URL),
+		takenAt: time.Now(),
+	}
+	var urls []*model.URL
+
+	err := s.primary.ForEachActiveURL(ctx, func(url *model.URL) error {
+		// Protected and click-limited URLs can't be followed without the primary storage.
+		if url.IsProtected() || url.ClicksLeft != nil {
+			return nil
+		}
+
+		snapshotURL := &model.URL{
+			ID:          url.ID,
+			ShortKey:    url.ShortKey,
+			OriginalURL: url.OriginalURL,
+			ExpiresAt:   url.ExpiresAt,
+		}
+		snap.urls[url.ShortKey] = snapshotURL
+		urls = append(urls, snapshotURL)
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to take snapshot: %w", err)
+	}
+
+	if !snap.equal(s.snapshot.Load()) {
+		if err := writeSnapshot(s.filename, urls); err != nil {
+			return err
+		}
+	}
+
+	s.snapshot.Store(snap)
+	s.setDegraded(nil)
+
+	return nil
+}
+
+// equal reports whether both snapshots hold the same URLs.
+func (snap *snapshot) equal(other *snapshot) bool {
+	if other == nil || len(snap.urls) != len(other.urls) {
+		return false
+	}
+
+	for shortKey, url := range snap.urls {
+		otherURL, ok := other.urls[shortKey]
+		if !ok || url.ID != otherURL.ID || url.OriginalURL != otherURL.OriginalURL {
+			return false
+		}
+		if (url.ExpiresAt == nil) != (otherURL.ExpiresAt == nil) {
+			return false
+		}
+		if url.ExpiresAt != nil && !url.ExpiresAt.Equal(*otherURL.ExpiresAt) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// writeSnapshot atomically replaces the snapshot file with urls.
+func writeSnapshot(filename string, urls []*model.URL) error {
+	tmpFilename := filename + ".tmp"
+	file, err := os.Create(tmpFilename)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmpFilename)
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, url := range urls {
+		if err := encoder.Encode(url); err != nil {
+			return fmt.Errorf("failed to write snapshot file: %w", err)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync snapshot file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot file: %w", err)
+	}
+	if err := os.Rename(tmpFilename, filename); err != nil {
+		return fmt.Errorf("failed to replace snapshot file: %w", err)
+	}
+
+	return nil
+}
+
+// readSnapshot reads the snapshot from filename. Returns nil if the file doesn't exist.
+func readSnapshot(filename string) (*snapshot, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open snapshot file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat snapshot file: %w", err)
+	}
+
+	snap := &snapshot{
+		urls:    make(map[string]*model.URL),
+		takenAt: info.ModTime(),
+	}
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for {
+		var url model.URL
+		if err := decoder.Decode(&url); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to decode snapshot file: %w", err)
+		}
+
+		snap.urls[url.ShortKey] = &url
+	}
+
+	return snap, nil
+}
+
+// GetURL retrieves a URL by its short key from the primary storage.
+// The URL is looked up in the snapshot if the primary storage fails or is unavailable.
+// Returns service.ErrUnavailable if the URL is not in the snapshot, as it may exist all the same.
+func (s *Storage) GetURL(ctx context.Context, shortKey string) (*model.URL, error) {
+	if !s.degraded.Load() {
+		url, err := s.primary.GetURL(ctx, shortKey)
+		if err == nil || errors.Is(err, storage.ErrNotFound) || ctx.Err() != nil {
+			return url, err
+		}
+		s.setDegraded(err)
+	}
+
+	snap := s.snapshot.Load()
+	if snap == nil {
+		return nil, errDegraded
+	}
+
+	url, ok := snap.urls[shortKey]
+	if !ok {
+		return nil, errDegraded
+	}
+
+	clone := *url
+	return &clone, nil
+}
+
+// GetURLs retrieves multiple URLs by their short keys from the primary storage.
+func (s *Storage) GetURLs(ctx context.Context, shortKeys []string) ([]*model.URL, error) {
+	if s.degraded.Load() {
+		return nil, errDegraded
+	}
+
+	return s.primary.GetURLs(ctx, shortKeys)
+}
+
+// GetURLsByUserID retrieves all URLs created by a specific user from the primary storage.
+func (s *Storage) GetURLsByUserID(ctx context.Context, userID uuid.UUID) ([]*model.URL, error) {
+	if s.degraded.Load() {
+		return nil, errDegraded
+	}
+
+	return s.primary.GetURLsByUserID(ctx, userID)
+}
+
+// GetDeletedURLsByUserID retrieves all deleted URLs created by a specific user from the primary storage.
+func (s *Storage) GetDeletedURLsByUserID(ctx context.Context, userID uuid.UUID) ([]*model.URL, error) {
+	if s.degraded.Load() {
+		return nil, errDegraded
+	}
+
+	return s.primary.GetDeletedURLsByUserID(ctx, userID)
+}
+
+// SetURL stores a single URL in the primary storage.
+func (s *Storage) SetURL(ctx context.Context, url *model.URL, scope storage.DedupeScope) (*model.URL, error) {
+	if s.degraded.Load() {
+		return nil, errDegraded
+	}
+
+	return s.primary.SetURL(ctx, url, scope)
+}
+
+// SetURLs stores multiple URLs in the primary storage.
+func (s *Storage) SetURLs(ctx context.Context, urls []*model.URL, scope storage.DedupeScope) ([]*model.URL, error) {
+	if s.degraded.Load() {
+		return nil, errDegraded
+	}
+
+	return s.primary.SetURLs(ctx, urls, scope)
+}
+
+// UpdateURL updates a URL in the primary storage.
+func (s *Storage) UpdateURL(ctx context.Context, url *model.URL, fields storage.URLFields) (*model.URL, error) {
+	if s.degraded.Load() {
+		return nil, errDegraded
+	}
+
+	return s.primary.UpdateURL(ctx, url, fields)
+}
+
+// DeleteURLs marks URLs as deleted in the primary storage.
+func (s *Storage) DeleteURLs(ctx context.Context, ids []uuid.UUID) error {
+	if s.degraded.Load() {
+		return errDegraded
+	}
+
+	return s.primary.DeleteURLs(ctx, ids)
+}
+
+// RestoreURLs clears the deletion mark of URLs in the primary storage.
+func (s *Storage) RestoreURLs(ctx context.Context, ids []uuid.UUID) error {
+	if s.degraded.Load() {
+		return errDegraded
+	}
+
+	return s.primary.RestoreURLs(ctx, ids)
+}
+
+// PurgeDeletedURLs permanently removes deleted URLs from the primary storage.
+func (s *Storage) PurgeDeletedURLs(ctx context.Context, before time.Time, limit int) (int64, error) {
+	if s.degraded.Load() {
+		return 0, errDegraded
+	}
+
+	return s.primary.PurgeDeletedURLs(ctx, before, limit)
+}
+
+// DecrementClicksLeft uses up one redirect of a click-limited URL in the primary storage.
+func (s *Storage) DecrementClicksLeft(ctx context.Context, id uuid.UUID) error {
+	if s.degraded.Load() {
+		return errDegraded
+	}
+
+	return s.primary.DecrementClicksLeft(ctx, id)
+}
+
+// SaveClick stores a single click event in the primary storage.
+// Clicks made while the storage is degraded are lost.
+func (s *Storage) SaveClick(ctx context.Context, click *model.Click) error {
+	if s.degraded.Load() {
+		return errDegraded
+	}
+
+	return s.primary.SaveClick(ctx, click)
+}
+
+// GetClickStats aggregates the click events of a URL in the primary storage.
+func (s *Storage) GetClickStats(ctx context.Context, urlID uuid.UUID, bucket time.Duration) (*model.ClickStats, error) {
+	if s.degraded.Load() {
+		return nil, errDegraded
+	}
+
+	return s.primary.GetClickStats(ctx, urlID, bucket)
+}
+
+// Ping checks if the primary storage is available. It fails while the primary storage is down
+// even if redirects are served from the snapshot, PingComponents reports the snapshot.
+// The result of the ping starts or ends the degraded state.
+func (s *Storage) Ping(ctx context.Context) error {
+	_, err := s.pingPrimary(ctx)
+
+	return err
+}
+
+// PingComponents pings the primary storage and reports its components along with the snapshot.
+// The storage works without the primary storage as long as there is a snapshot,
+// so an error is returned only if both are unavailable.
+// The result of the ping starts or ends the degraded state.
+func (s *Storage) PingComponents(ctx context.Context) (map[string]error, error) {
+	components, err := s.pingPrimary(ctx)
+
+	if s.snapshot.Load() == nil {
+		components[snapshotName] = errNoSnapshot
+		return components, err
+	}
+
+	components[snapshotName] = nil
+
+	return components, nil
+}
+
+// pingPrimary pings the primary storage and its components and starts or ends the degraded state.
+func (s *Storage) pingPrimary(ctx context.Context) (map[string]error, error) {
+	var (
+		components map[string]error
+		err        error
+	)
+	if componentPinger, ok := s.primary.(service.ComponentPinger); ok {
+		components, err = componentPinger.PingComponents(ctx)
+	} else {
+		err = s.primary.Ping(ctx)
+		components = map[string]error{primaryName: err}
+	}
+
+	if ctx.Err() == nil {
+		s.setDegraded(err)
+	}
+
+	return components, err
+}
@@ -0,0 +1,298 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/logger"
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/service"
+	"github.com/dtroode/urlshorter/internal/service/fallback/mocks"
+	servicemocks "github.com/dtroode/urlshorter/internal/service/mocks"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+var errPrimary = errors.New("connection refused")
+
+// activeURLs returns a ForEachActiveURL implementation that passes urls to fn.
+func activeURLs(urls ...*model.URL) func(context.Context, func(*model.URL) error) error {
+	return func(_ context.Context, fn func(*model.URL) error) error {
+		for _, url := range urls {
+			if err := fn(url); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// newTestStorage creates a storage with a snapshot of urls taken from primary.
+func newTestStorage(t *testing.T, primary *mocks.Primary, urls ...*model.URL) *Storage {
+	t.Helper()
+
+	s, err := NewStorage(primary, filepath.Join(t.TempDir(), "snapshot"), time.Minute, logger.NewLog("ERROR"))
+	require.NoError(t, err)
+
+	if urls != nil {
+		primary.On("ForEachActiveURL", mock.Anything, mock.Anything).Once().Return(activeURLs(urls...))
+		require.NoError(t, s.Refresh(context.Background()))
+	}
+
+	return s
+}
+
+func TestStorage_Refresh(t *testing.T) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	clicksLeft := int64(3)
+	plainURL := &model.URL{ID: uuid.New(), ShortKey: "plain", OriginalURL: "https://ya.ru", UserID: uuid.New(), ExpiresAt: &expiresAt}
+	protectedURL := &model.URL{ID: uuid.New(), ShortKey: "protected", OriginalURL: "https://ya.ru/secret", PasswordHash: "hash"}
+	limitedURL := &model.URL{ID: uuid.New(), ShortKey: "limited", OriginalURL: "https://ya.ru/limited", ClicksLeft: &clicksLeft}
+
+	primary := mocks.NewPrimary(t)
+	s := newTestStorage(t, primary, plainURL, protectedURL, limitedURL)
+
+	// A failed refresh keeps the previous snapshot.
+	primary.On("ForEachActiveURL", mock.Anything, mock.Anything).Once().Return(errPrimary)
+	require.ErrorIs(t, s.Refresh(ctx), errPrimary)
+
+	// The snapshot survives restarts.
+	restarted, err := NewStorage(primary, s.filename, time.Minute, logger.NewLog("ERROR"))
+	require.NoError(t, err)
+
+	for _, s := range []*Storage{s, restarted} {
+		snap := s.snapshot.Load()
+		require.NotNil(t, snap)
+		assert.Equal(t, map[string]*model.URL{
+			"plain": {ID: plainURL.ID, ShortKey: "plain", OriginalURL: "https://ya.ru", ExpiresAt: &expiresAt},
+		}, snap.urls)
+	}
+}
+
+func TestStorage_Refresh_Unchanged(t *testing.T) {
+	ctx := context.Background()
+	url := &model.URL{ID: uuid.New(), ShortKey: "plain", OriginalURL: "https://ya.ru"}
+
+	primary := mocks.NewPrimary(t)
+	s := newTestStorage(t, primary, url)
+
+	info, err := os.Stat(s.filename)
+	require.NoError(t, err)
+
+	// An unchanged snapshot isn't written again.
+	primary.On("ForEachActiveURL", mock.Anything, mock.Anything).Once().Return(activeURLs(url))
+	require.NoError(t, s.Refresh(ctx))
+
+	unchanged, err := os.Stat(s.filename)
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(info, unchanged))
+
+	// A changed snapshot replaces the file.
+	changedURL := &model.URL{ID: url.ID, ShortKey: "plain", OriginalURL: "https://ya.ru/changed"}
+	primary.On("ForEachActiveURL", mock.Anything, mock.Anything).Once().Return(activeURLs(changedURL))
+	require.NoError(t, s.Refresh(ctx))
+
+	changed, err := os.Stat(s.filename)
+	require.NoError(t, err)
+	assert.False(t, os.SameFile(info, changed))
+
+	restarted, err := NewStorage(primary, s.filename, time.Minute, logger.NewLog("ERROR"))
+	require.NoError(t, err)
+	assert.Equal(t, "https://ya.ru/changed", restarted.snapshot.Load().urls["plain"].OriginalURL)
+}
+
+func TestNewStorage_NoSnapshot(t *testing.T) {
+	s, err := NewStorage(mocks.NewPrimary(t), filepath.Join(t.TempDir(), "snapshot"), time.Minute, logger.NewLog("ERROR"))
+	require.NoError(t, err)
+
+	assert.Nil(t, s.snapshot.Load())
+	assert.False(t, s.Degraded())
+}
+
+func TestStorage_GetURL(t *testing.T) {
+	ctx := context.Background()
+	url := &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru"}
+	snapshotURL := &model.URL{ID: url.ID, ShortKey: "abc", OriginalURL: "https://ya.ru"}
+
+	tests := map[string]struct {
+		shortKey      string
+		primaryURL    *model.URL
+		primaryErr    error
+		expectedURL   *model.URL
+		expectedErr   error
+		expectedState bool
+	}{
+		"found in primary": {
+			shortKey:    "abc",
+			primaryURL:  url,
+			expectedURL: url,
+		},
+		"not found in primary": {
+			shortKey:    "def",
+			primaryErr:  storage.ErrNotFound,
+			expectedErr: storage.ErrNotFound,
+		},
+		"primary fails, found in snapshot": {
+			shortKey:      "abc",
+			primaryErr:    errPrimary,
+			expectedURL:   snapshotURL,
+			expectedState: true,
+		},
+		"primary fails, not in snapshot": {
+			shortKey:      "def",
+			primaryErr:    errPrimary,
+			expectedErr:   service.ErrUnavailable,
+			expectedState: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			primary := mocks.NewPrimary(t)
+			s := newTestStorage(t, primary, url)
+			primary.On("GetURL", mock.Anything, tt.shortKey).Once().Return(tt.primaryURL, tt.primaryErr)
+
+			got, err := s.GetURL(ctx, tt.shortKey)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedURL, got)
+			assert.Equal(t, tt.expectedState, s.Degraded())
+		})
+	}
+}
+
+func TestStorage_Degraded(t *testing.T) {
+	ctx := context.Background()
+	url := &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru"}
+
+	primary := mocks.NewPrimary(t)
+	s := newTestStorage(t, primary, url)
+	primary.On("GetURL", mock.Anything, "abc").Once().Return(nil, errPrimary)
+
+	_, err := s.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	require.True(t, s.Degraded())
+
+	// Lookups are served from the snapshot and the rest fails without calling the primary storage.
+	got, err := s.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://ya.ru", got.OriginalURL)
+
+	_, err = s.SetURL(ctx, &model.URL{ShortKey: "def"}, storage.DedupeGlobal)
+	assert.ErrorIs(t, err, service.ErrUnavailable)
+	_, err = s.SetURLs(ctx, []*model.URL{{ShortKey: "def"}}, storage.DedupeGlobal)
+	assert.ErrorIs(t, err, service.ErrUnavailable)
+	_, err = s.UpdateURL(ctx, url, storage.FieldOriginalURL)
+	assert.ErrorIs(t, err, service.ErrUnavailable)
+	_, err = s.GetURLsByUserID(ctx, uuid.New())
+	assert.ErrorIs(t, err, service.ErrUnavailable)
+	assert.ErrorIs(t, s.RestoreURLs(ctx, []uuid.UUID{url.ID}), service.ErrUnavailable)
+	assert.ErrorIs(t, s.DecrementClicksLeft(ctx, url.ID), service.ErrUnavailable)
+
+	// The degraded state lasts while the primary storage doesn't answer pings.
+	primary.On("Ping", mock.Anything).Once().Return(errPrimary)
+	s.probe(ctx)
+	require.True(t, s.Degraded())
+
+	primary.On("Ping", mock.Anything).Once().Return(nil)
+	s.probe(ctx)
+	require.False(t, s.Degraded())
+
+	primary.On("SetURL", mock.Anything, mock.Anything, mock.Anything).Once().Return(url, nil)
+	_, err = s.SetURL(ctx, url, storage.DedupeGlobal)
+	assert.NoError(t, err)
+}
+
+func TestStorage_Ping(t *testing.T) {
+	ctx := context.Background()
+	url := &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru"}
+
+	primary := mocks.NewPrimary(t)
+	s := newTestStorage(t, primary, url)
+
+	// Redirects are served from the snapshot, but the ping reports the primary storage.
+	primary.On("Ping", mock.Anything).Once().Return(errPrimary)
+	assert.ErrorIs(t, s.Ping(ctx), errPrimary)
+	assert.True(t, s.Degraded())
+
+	primary.On("Ping", mock.Anything).Once().Return(nil)
+	assert.NoError(t, s.Ping(ctx))
+	assert.False(t, s.Degraded())
+}
+
+// componentPrimary is a primary storage that reports its own components.
+type componentPrimary struct {
+	*mocks.Primary
+	*servicemocks.ComponentPinger
+}
+
+func TestStorage_PingComponents(t *testing.T) {
+	ctx := context.Background()
+	url := &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru"}
+
+	tests := map[string]struct {
+		primaryErr         error
+		withSnapshot       bool
+		expectedComponents map[string]error
+		expectedErr        error
+	}{
+		"primary is up": {
+			withSnapshot:       true,
+			expectedComponents: map[string]error{"primary": nil, "snapshot": nil},
+		},
+		"primary is down, snapshot serves redirects": {
+			primaryErr:         errPrimary,
+			withSnapshot:       true,
+			expectedComponents: map[string]error{"primary": errPrimary, "snapshot": nil},
+		},
+		"primary is down without snapshot": {
+			primaryErr:         errPrimary,
+			expectedComponents: map[string]error{"primary": errPrimary, "snapshot": errNoSnapshot},
+			expectedErr:        errPrimary,
+		},
+		"primary is up without snapshot": {
+			expectedComponents: map[string]error{"primary": nil, "snapshot": errNoSnapshot},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			primary := mocks.NewPrimary(t)
+			var urls []*model.URL
+			if tt.withSnapshot {
+				urls = []*model.URL{url}
+			}
+			s := newTestStorage(t, primary, urls...)
+			primary.On("Ping", mock.Anything).Once().Return(tt.primaryErr)
+
+			components, err := s.PingComponents(ctx)
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expectedComponents, components)
+			assert.Equal(t, tt.primaryErr != nil, s.Degraded())
+		})
+	}
+
+	t.Run("primary components", func(t *testing.T) {
+		primary := componentPrimary{Primary: mocks.NewPrimary(t), ComponentPinger: servicemocks.NewComponentPinger(t)}
+		primary.ComponentPinger.On("PingComponents", mock.Anything).Once().Return(map[string]error{"primary": nil, "replica-1": errPrimary}, nil)
+
+		s, err := NewStorage(primary, filepath.Join(t.TempDir(), "snapshot"), time.Minute, logger.NewLog("ERROR"))
+		require.NoError(t, err)
+
+		components, err := s.PingComponents(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]error{"primary": nil, "replica-1": errPrimary, "snapshot": errNoSnapshot}, components)
+	})
+}
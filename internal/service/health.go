@@ -2,8 +2,27 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+
+	"github.com/dtroode/urlshorter/internal/response"
+)
+
+// Health statuses of the service and its components.
+const (
+	// HealthStatusOK means every component is up.
+	HealthStatusOK = "ok"
+	// HealthStatusDegraded means some components are down, but the service still works.
+	HealthStatusDegraded = "degraded"
+	// HealthStatusUnavailable means the service doesn't work.
+	HealthStatusUnavailable = "unavailable"
+
+	// ComponentStatusUp means the component is available.
+	ComponentStatusUp = "up"
+	// ComponentStatusDown means the component is unavailable.
+	ComponentStatusDown = "down"
 )
 
 // Pinger defines interface for services that can be pinged.
@@ -11,6 +30,14 @@ type Pinger interface {
 	Ping(ctx context.Context) error
 }
 
+// ComponentPinger defines interface for services made of several components
+// that are pinged separately, like a database with read replicas.
+type ComponentPinger interface {
+	// PingComponents returns the ping error of every component by its name
+	// and an error if the service can't work.
+	PingComponents(ctx context.Context) (map[string]error, error)
+}
+
 // Health represents health check service.
 type Health struct {
 	pingers []Pinger
@@ -33,3 +60,62 @@ func (s *Health) Ping(ctx context.Context) error {
 
 	return nil
 }
+
+// Check checks health of all registered services and reports the status of each of their components.
+// Services that implement ComponentPinger report their components, other services are reported as one
+// component named after their type.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//
+// Returns the health of the service and an error describing failed components, if any.
+// The service is unavailable if any registered service fails and degraded if only some
+// of the components fail.
+func (s *Health) Check(ctx context.Context) (*response.Health, error) {
+	health := &response.Health{
+		Status:     HealthStatusOK,
+		Components: make([]*response.HealthComponent, 0, len(s.pingers)),
+	}
+
+	var errs []error
+	for _, pinger := range s.pingers {
+		name := reflect.TypeOf(pinger).String()
+
+		components := make(map[string]error)
+		var err error
+		if componentPinger, ok := pinger.(ComponentPinger); ok {
+			components, err = componentPinger.PingComponents(ctx)
+		} else {
+			err = pinger.Ping(ctx)
+			components[name] = err
+		}
+
+		if err != nil {
+			health.Status = HealthStatusUnavailable
+		}
+
+		names := make([]string, 0, len(components))
+		for componentName := range components {
+			names = append(names, componentName)
+		}
+		sort.Strings(names)
+
+		for _, componentName := range names {
+			status := ComponentStatusUp
+			if componentErr := components[componentName]; componentErr != nil {
+				status = ComponentStatusDown
+				errs = append(errs, fmt.Errorf("%s: %s: %w", name, componentName, componentErr))
+				if health.Status == HealthStatusOK {
+					health.Status = HealthStatusDegraded
+				}
+			}
+
+			health.Components = append(health.Components, &response.HealthComponent{
+				Name:   componentName,
+				Status: status,
+			})
+		}
+	}
+
+	return health, errors.Join(errs...)
+}
@@ -55,3 +55,85 @@ func TestHealth_Ping(t *testing.T) {
 		})
 	}
 }
+
+func TestHealth_Check(t *testing.T) {
+	newPinger := func(err error) Pinger {
+		pingerMock := mocks.NewPinger(t)
+		pingerMock.On("Ping", mock.Anything).Once().Return(err)
+		return pingerMock
+	}
+	newComponentPinger := func(components map[string]error, err error) Pinger {
+		pingerMock := &componentPinger{
+			Pinger:          mocks.NewPinger(t),
+			ComponentPinger: mocks.NewComponentPinger(t),
+		}
+		pingerMock.ComponentPinger.(*mocks.ComponentPinger).On("PingComponents", mock.Anything).Once().Return(components, err)
+		return pingerMock
+	}
+
+	tests := map[string]struct {
+		pingers          []Pinger
+		expectedStatus   string
+		expectedStatuses map[string]string
+		expectError      bool
+	}{
+		"pinger is up": {
+			pingers:          []Pinger{newPinger(nil)},
+			expectedStatus:   HealthStatusOK,
+			expectedStatuses: map[string]string{"*mocks.Pinger": ComponentStatusUp},
+		},
+		"pinger is down": {
+			pingers:          []Pinger{newPinger(errors.New("database error"))},
+			expectedStatus:   HealthStatusUnavailable,
+			expectedStatuses: map[string]string{"*mocks.Pinger": ComponentStatusDown},
+			expectError:      true,
+		},
+		"components are up": {
+			pingers:          []Pinger{newComponentPinger(map[string]error{"primary": nil, "replica-1": nil}, nil)},
+			expectedStatus:   HealthStatusOK,
+			expectedStatuses: map[string]string{"primary": ComponentStatusUp, "replica-1": ComponentStatusUp},
+		},
+		"optional component is down": {
+			pingers:          []Pinger{newComponentPinger(map[string]error{"primary": nil, "replica-1": errors.New("replica error")}, nil)},
+			expectedStatus:   HealthStatusDegraded,
+			expectedStatuses: map[string]string{"primary": ComponentStatusUp, "replica-1": ComponentStatusDown},
+			expectError:      true,
+		},
+		"required component is down": {
+			pingers: []Pinger{newComponentPinger(
+				map[string]error{"primary": errors.New("database error"), "replica-1": nil},
+				errors.New("database error"),
+			)},
+			expectedStatus:   HealthStatusUnavailable,
+			expectedStatuses: map[string]string{"primary": ComponentStatusDown, "replica-1": ComponentStatusUp},
+			expectError:      true,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			s := NewHealth(tt.pingers...)
+
+			health, err := s.Check(context.Background())
+
+			if tt.expectError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.expectedStatus, health.Status)
+
+			statuses := make(map[string]string)
+			for _, component := range health.Components {
+				statuses[component.Name] = component.Status
+			}
+			require.Equal(t, tt.expectedStatuses, statuses)
+		})
+	}
+}
+
+// componentPinger combines the mocks of Pinger and ComponentPinger.
+type componentPinger struct {
+	Pinger
+	ComponentPinger
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dtroode/urlshorter/internal/service/keygen"
+)
+
+// NewKeyGenerator creates a key generator of the given kind.
+//
+// Parameters:
+//   - kind: One of the keygen.Kind* constants
+//   - salt: The salt of the hashids generator, ignored by other kinds
+//   - sequence: The durable sequence of the hashids generator, ignored by other kinds
+//
+// Returns the generator or an error if the kind is unknown.
+func NewKeyGenerator(kind string, salt string, sequence keygen.Sequence) (KeyGenerator, error) {
+	switch kind {
+	case keygen.KindBase62:
+		return keygen.NewRandom(keygen.Base62Alphabet), nil
+	case keygen.KindCrockford:
+		return keygen.NewRandom(keygen.CrockfordAlphabet), nil
+	case keygen.KindHashids:
+		if sequence == nil {
+			return nil, errors.New("hashids key generator requires a sequence")
+		}
+		return keygen.NewHashids(salt, sequence), nil
+	case keygen.KindCrypto:
+		return keygen.NewCrypto(keygen.Base62Alphabet), nil
+	default:
+		return nil, fmt.Errorf("unknown key generator %q", kind)
+	}
+}
@@ -0,0 +1,224 @@
+package keygen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	hashidsAlphabet   = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890"
+	hashidsSeparators = "cfhistuCFHISTU"
+	// hashidsSepDiv is the target ratio between alphabet and separators sizes.
+	hashidsSepDiv = 3.5
+	// hashidsGuardDiv is the target ratio between alphabet and guards sizes.
+	hashidsGuardDiv = 12
+	// reserveTimeout limits how long Generate waits for a new block of sequence numbers.
+	reserveTimeout = 5 * time.Second
+)
+
+// Hashids generates keys by encoding numbers of a sequence with the hashids algorithm.
+// Numbers are taken from blocks reserved in the sequence, so keys are unique across
+// restarts and across instances sharing the sequence. Different salts produce
+// different keys for the same sequence number.
+type Hashids struct {
+	salt     []byte
+	alphabet []byte
+	seps     []byte
+	guards   []byte
+	sequence Sequence
+
+	mu sync.Mutex
+	// next and end bound the unused part of the reserved block.
+	next uint64
+	end  uint64
+}
+
+// NewHashids creates new Hashids instance taking numbers from the sequence.
+func NewHashids(salt string, sequence Sequence) *Hashids {
+	h := &Hashids{
+		salt:     []byte(salt),
+		sequence: sequence,
+	}
+	h.setup()
+
+	return h
+}
+
+// Generate returns a key of at least length characters.
+// Keys are longer than length once the sequence outgrows the keyspace of length characters.
+func (h *Hashids) Generate(length int) (string, error) {
+	number, err := h.nextNumber()
+	if err != nil {
+		return "", err
+	}
+
+	return h.encode(number, length), nil
+}
+
+// nextNumber returns the next number of the reserved block, reserving a new block when it runs out.
+func (h *Hashids) nextNumber() (uint64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.next == h.end {
+		ctx, cancel := context.WithTimeout(context.Background(), reserveTimeout)
+		defer cancel()
+
+		first, size, err := h.sequence.Reserve(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to reserve sequence numbers: %w", err)
+		}
+		if size == 0 {
+			return 0, errors.New("sequence reserved an empty block")
+		}
+		h.next, h.end = first, first+size
+	}
+
+	number := h.next
+	h.next++
+
+	return number, nil
+}
+
+// setup splits the default alphabet into alphabet, separators and guards
+// the same way the reference hashids implementation does.
+func (h *Hashids) setup() {
+	alphabet := make([]byte, 0, len(hashidsAlphabet))
+	seps := make([]byte, 0, len(hashidsSeparators))
+	for i := range len(hashidsAlphabet) {
+		c := hashidsAlphabet[i]
+		if containsByte(hashidsSeparators, c) {
+			seps = append(seps, c)
+		} else {
+			alphabet = append(alphabet, c)
+		}
+	}
+
+	consistentShuffle(seps, h.salt)
+
+	if len(seps) == 0 || float64(len(alphabet))/float64(len(seps)) > hashidsSepDiv {
+		sepsLength := int(math.Ceil(float64(len(alphabet)) / hashidsSepDiv))
+		if sepsLength == 1 {
+			sepsLength = 2
+		}
+		if sepsLength > len(seps) {
+			diff := sepsLength - len(seps)
+			seps = append(seps, alphabet[:diff]...)
+			alphabet = alphabet[diff:]
+		} else {
+			seps = seps[:sepsLength]
+		}
+	}
+
+	consistentShuffle(alphabet, h.salt)
+
+	guardCount := int(math.Ceil(float64(len(alphabet)) / hashidsGuardDiv))
+	var guards []byte
+	if len(alphabet) < 3 {
+		guards = seps[:guardCount]
+		seps = seps[guardCount:]
+	} else {
+		guards = alphabet[:guardCount]
+		alphabet = alphabet[guardCount:]
+	}
+
+	h.alphabet = alphabet
+	h.seps = seps
+	h.guards = guards
+}
+
+// encode encodes a single number padding the result to minLength.
+func (h *Hashids) encode(number uint64, minLength int) string {
+	alphabet := make([]byte, len(h.alphabet))
+	copy(alphabet, h.alphabet)
+
+	numbersHash := number % 100
+	lottery := alphabet[numbersHash%uint64(len(alphabet))]
+
+	buffer := make([]byte, 0, 1+len(h.salt)+len(alphabet))
+	buffer = append(buffer, lottery)
+	buffer = append(buffer, h.salt...)
+	buffer = append(buffer, alphabet...)
+	consistentShuffle(alphabet, buffer[:len(alphabet)])
+
+	result := []byte{lottery}
+	result = append(result, hashNumber(number, alphabet)...)
+
+	if len(result) < minLength {
+		guardIndex := (numbersHash + uint64(result[0])) % uint64(len(h.guards))
+		result = append([]byte{h.guards[guardIndex]}, result...)
+
+		if len(result) < minLength {
+			guardIndex = (numbersHash + uint64(result[2])) % uint64(len(h.guards))
+			result = append(result, h.guards[guardIndex])
+		}
+	}
+
+	halfLength := len(alphabet) / 2
+	for len(result) < minLength {
+		shuffleSalt := make([]byte, len(alphabet))
+		copy(shuffleSalt, alphabet)
+		consistentShuffle(alphabet, shuffleSalt)
+
+		padded := make([]byte, 0, len(alphabet)+len(result))
+		padded = append(padded, alphabet[halfLength:]...)
+		padded = append(padded, result...)
+		padded = append(padded, alphabet[:halfLength]...)
+		result = padded
+
+		if excess := len(result) - minLength; excess > 0 {
+			result = result[excess/2 : excess/2+minLength]
+		}
+	}
+
+	return string(result)
+}
+
+// hashNumber writes number in the positional system defined by alphabet.
+func hashNumber(number uint64, alphabet []byte) []byte {
+	base := uint64(len(alphabet))
+	hash := make([]byte, 0, 12)
+
+	for {
+		hash = append(hash, alphabet[number%base])
+		number /= base
+		if number == 0 {
+			break
+		}
+	}
+
+	for i, j := 0, len(hash)-1; i < j; i, j = i+1, j-1 {
+		hash[i], hash[j] = hash[j], hash[i]
+	}
+
+	return hash
+}
+
+// consistentShuffle shuffles alphabet in place deterministically depending on salt.
+func consistentShuffle(alphabet []byte, salt []byte) {
+	if len(salt) == 0 {
+		return
+	}
+
+	for i, v, p := len(alphabet)-1, 0, 0; i > 0; i, v = i-1, v+1 {
+		v %= len(salt)
+		integer := int(salt[v])
+		p += integer
+		j := (integer + v + p) % i
+		alphabet[i], alphabet[j] = alphabet[j], alphabet[i]
+	}
+}
+
+func containsByte(s string, c byte) bool {
+	for i := range len(s) {
+		if s[i] == c {
+			return true
+		}
+	}
+
+	return false
+}
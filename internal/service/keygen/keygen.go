@@ -0,0 +1,18 @@
+// Package keygen provides strategies for generating short keys.
+package keygen
+
+const (
+	// Base62Alphabet contains digits and latin letters in both cases.
+	Base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// CrockfordAlphabet is the Crockford base32 alphabet.
+	// It excludes I, L, O and U to avoid visually ambiguous keys.
+	CrockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// Kinds of generators accepted by service.NewKeyGenerator.
+const (
+	KindBase62    = "base62"
+	KindCrockford = "crockford"
+	KindHashids   = "hashids"
+	KindCrypto    = "crypto"
+)
@@ -0,0 +1,128 @@
+package keygen
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandom_Generate(t *testing.T) {
+	tests := map[string]func(length int) (string, error){
+		"base62":    NewRandom(Base62Alphabet).Generate,
+		"crockford": NewRandom(CrockfordAlphabet).Generate,
+		"crypto":    NewCrypto(Base62Alphabet).Generate,
+	}
+
+	for tn, generate := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			for _, length := range []int{1, 8, 32} {
+				key, err := generate(length)
+				require.NoError(t, err)
+				assert.Len(t, key, length)
+			}
+		})
+	}
+}
+
+func TestRandom_Generate_Alphabet(t *testing.T) {
+	key, err := NewRandom(CrockfordAlphabet).Generate(1000)
+	require.NoError(t, err)
+
+	for _, c := range key {
+		assert.True(t, strings.ContainsRune(CrockfordAlphabet, c), "unexpected character %q", c)
+	}
+	assert.NotContains(t, key, "I")
+	assert.NotContains(t, key, "L")
+	assert.NotContains(t, key, "O")
+	assert.NotContains(t, key, "U")
+}
+
+func TestHashids_encode(t *testing.T) {
+	// expected values come from the reference hashids implementation
+	tests := map[string]struct {
+		salt      string
+		number    uint64
+		minLength int
+		expected  string
+	}{
+		"no min length": {
+			salt:     "this is my salt",
+			number:   12345,
+			expected: "NkK9",
+		},
+		"min length": {
+			salt:      "this is my salt",
+			number:    1,
+			minLength: 8,
+			expected:  "gB0NV05e",
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			h := NewHashids(tt.salt, nil)
+			assert.Equal(t, tt.expected, h.encode(tt.number, tt.minLength))
+		})
+	}
+}
+
+func TestHashids_Generate(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "keyseq")
+	sequence, err := NewFileSequence(filename, 100)
+	require.NoError(t, err)
+
+	h := NewHashids("salt", sequence)
+	seen := make(map[string]struct{})
+
+	for range 10000 {
+		key, err := h.Generate(8)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, len(key), 8)
+
+		_, ok := seen[key]
+		require.False(t, ok, "duplicate key %s", key)
+		seen[key] = struct{}{}
+	}
+
+	// a restarted generator continues the sequence instead of repeating keys
+	sequence, err = NewFileSequence(filename, 100)
+	require.NoError(t, err)
+
+	h = NewHashids("salt", sequence)
+	for range 1000 {
+		key, err := h.Generate(8)
+		require.NoError(t, err)
+
+		_, ok := seen[key]
+		require.False(t, ok, "duplicate key %s after restart", key)
+		seen[key] = struct{}{}
+	}
+}
+
+func TestFileSequence_Reserve(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "keyseq")
+	sequence, err := NewFileSequence(filename, 10)
+	require.NoError(t, err)
+
+	first, size, err := sequence.Reserve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), first)
+	assert.Equal(t, uint64(10), size)
+
+	first, _, err = sequence.Reserve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(11), first)
+
+	reopened, err := NewFileSequence(filename, 10)
+	require.NoError(t, err)
+
+	first, _, err = reopened.Reserve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(21), first)
+}
@@ -0,0 +1,73 @@
+package keygen
+
+import (
+	"crypto/rand"
+	"fmt"
+	mathRand "math/rand/v2"
+)
+
+// Random generates keys from uniformly distributed pseudo-random characters.
+// It is fast but its output is predictable.
+type Random struct {
+	alphabet string
+}
+
+// NewRandom creates new Random instance drawing characters from alphabet.
+func NewRandom(alphabet string) *Random {
+	return &Random{
+		alphabet: alphabet,
+	}
+}
+
+// Generate returns a key of exactly length characters.
+func (g *Random) Generate(length int) (string, error) {
+	result := make([]byte, length)
+
+	for i := range result {
+		result[i] = g.alphabet[mathRand.IntN(len(g.alphabet))]
+	}
+
+	return string(result), nil
+}
+
+// Crypto generates keys from a cryptographically secure random source.
+// Keys can't be guessed from previously issued ones.
+type Crypto struct {
+	alphabet string
+	// maxByte is the largest multiple of the alphabet size that fits in a byte.
+	// Random bytes not below it are dropped to keep the distribution uniform.
+	maxByte int
+}
+
+// NewCrypto creates new Crypto instance drawing characters from alphabet.
+// Alphabet must contain at most 256 characters.
+func NewCrypto(alphabet string) *Crypto {
+	return &Crypto{
+		alphabet: alphabet,
+		maxByte:  256 - 256%len(alphabet),
+	}
+}
+
+// Generate returns a key of exactly length characters.
+func (g *Crypto) Generate(length int) (string, error) {
+	result := make([]byte, 0, length)
+	buf := make([]byte, length+length/4)
+
+	for len(result) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("failed to read random bytes: %w", err)
+		}
+
+		for _, b := range buf {
+			if int(b) >= g.maxByte {
+				continue
+			}
+			result = append(result, g.alphabet[int(b)%len(g.alphabet)])
+			if len(result) == length {
+				break
+			}
+		}
+	}
+
+	return string(result), nil
+}
@@ -0,0 +1,123 @@
+package keygen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Sequence reserves blocks of sequence numbers for the hashids generator.
+// A reserved block is never handed out again, neither to another instance
+// sharing the sequence nor after a restart.
+type Sequence interface {
+	// Reserve reserves size consecutive numbers starting from first.
+	Reserve(ctx context.Context) (first uint64, size uint64, err error)
+}
+
+// DefaultSequenceBlockSize is the number of sequence numbers FileSequence reserves at once.
+const DefaultSequenceBlockSize = 100
+
+// FileSequence is a Sequence persisted to a file, for a single instance using file storage.
+// The file holds the first number that hasn't been reserved yet. It is synced to disk
+// before a block is returned, so numbers are not reused after a restart or a crash.
+// Numbers of a block that wasn't used up before a restart are skipped.
+type FileSequence struct {
+	mu        sync.Mutex
+	filename  string
+	blockSize uint64
+	next      uint64
+}
+
+// NewFileSequence creates new FileSequence instance and reads its state from the file.
+// The sequence starts from 1 when the file doesn't exist.
+func NewFileSequence(filename string, blockSize uint64) (*FileSequence, error) {
+	if blockSize == 0 {
+		return nil, errors.New("block size must be positive")
+	}
+
+	s := &FileSequence{
+		filename:  filename,
+		blockSize: blockSize,
+		next:      1,
+	}
+
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sequence file: %w", err)
+	}
+
+	next, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sequence file: %w", err)
+	}
+	s.next = next
+
+	return s, nil
+}
+
+// Reserve reserves the next block of numbers and persists the end of the block.
+func (s *FileSequence) Reserve(ctx context.Context) (uint64, uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	first := s.next
+	if err := s.write(first + s.blockSize); err != nil {
+		return 0, 0, err
+	}
+	s.next = first + s.blockSize
+
+	return first, s.blockSize, nil
+}
+
+// write atomically replaces the file with the given next number.
+func (s *FileSequence) write(next uint64) error {
+	tempFilename := s.filename + ".tmp"
+	tempFile, err := os.OpenFile(tempFilename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open temp file: %w", err)
+	}
+
+	if _, err := tempFile.WriteString(strconv.FormatUint(next, 10) + "\n"); err != nil {
+		tempFile.Close()
+		os.Remove(tempFilename)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tempFile.Sync(); err != nil {
+		tempFile.Close()
+		os.Remove(tempFilename)
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		os.Remove(tempFilename)
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	if err := os.Rename(tempFilename, s.filename); err != nil {
+		os.Remove(tempFilename)
+		return fmt.Errorf("failed to replace sequence file: %w", err)
+	}
+
+	dir, err := os.Open(filepath.Dir(s.filename))
+	if err != nil {
+		return fmt.Errorf("failed to open directory: %w", err)
+	}
+	defer dir.Close()
+
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync directory: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/service/keygen"
+)
+
+func TestNewKeyGenerator(t *testing.T) {
+	sequence, err := keygen.NewFileSequence(filepath.Join(t.TempDir(), "keyseq"), keygen.DefaultSequenceBlockSize)
+	require.NoError(t, err)
+
+	for _, kind := range []string{keygen.KindBase62, keygen.KindCrockford, keygen.KindHashids, keygen.KindCrypto} {
+		g, err := NewKeyGenerator(kind, "salt", sequence)
+		require.NoError(t, err, kind)
+		assert.NotNil(t, g, kind)
+	}
+
+	_, err = NewKeyGenerator(keygen.KindHashids, "salt", nil)
+	assert.Error(t, err)
+
+	_, err = NewKeyGenerator("unknown", "", nil)
+	assert.Error(t, err)
+}
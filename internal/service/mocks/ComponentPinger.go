@@ -0,0 +1,94 @@
+// Code generated by mockery v2.52.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// ComponentPinger is an autogenerated mock type for the ComponentPinger type
+type ComponentPinger struct {
+	mock.Mock
+}
+
+type ComponentPinger_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *ComponentPinger) EXPECT() *ComponentPinger_Expecter {
+	return &ComponentPinger_Expecter{mock: &_m.Mock}
+}
+
+// PingComponents provides a mock function with given fields: ctx
+func (_m *ComponentPinger) PingComponents(ctx context.Context) (map[string]error, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PingComponents")
+	}
+
+	var r0 map[string]error
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (map[string]error, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) map[string]error); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]error)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ComponentPinger_PingComponents_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PingComponents'
+type ComponentPinger_PingComponents_Call struct {
+	*mock.Call
+}
+
+// PingComponents is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *ComponentPinger_Expecter) PingComponents(ctx interface{}) *ComponentPinger_PingComponents_Call {
+	return &ComponentPinger_PingComponents_Call{Call: _e.mock.On("PingComponents", ctx)}
+}
+
+func (_c *ComponentPinger_PingComponents_Call) Run(run func(ctx context.Context)) *ComponentPinger_PingComponents_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *ComponentPinger_PingComponents_Call) Return(_a0 map[string]error, _a1 error) *ComponentPinger_PingComponents_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *ComponentPinger_PingComponents_Call) RunAndReturn(run func(context.Context) (map[string]error, error)) *ComponentPinger_PingComponents_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewComponentPinger creates a new instance of ComponentPinger. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewComponentPinger(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *ComponentPinger {
+	mock := &ComponentPinger{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,165 @@
+// Code generated by mockery v2.52.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+
+	uuid "github.com/google/uuid"
+)
+
+// LinkTokens is an autogenerated mock type for the LinkTokens type
+type LinkTokens struct {
+	mock.Mock
+}
+
+type LinkTokens_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *LinkTokens) EXPECT() *LinkTokens_Expecter {
+	return &LinkTokens_Expecter{mock: &_m.Mock}
+}
+
+// CreateLinkToken provides a mock function with given fields: ctx, urlID, passwordVersion, ttl
+func (_m *LinkTokens) CreateLinkToken(ctx context.Context, urlID uuid.UUID, passwordVersion string, ttl time.Duration) (string, error) {
+	ret := _m.Called(ctx, urlID, passwordVersion, ttl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateLinkToken")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string, time.Duration) (string, error)); ok {
+		return rf(ctx, urlID, passwordVersion, ttl)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string, time.Duration) string); ok {
+		r0 = rf(ctx, urlID, passwordVersion, ttl)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, string, time.Duration) error); ok {
+		r1 = rf(ctx, urlID, passwordVersion, ttl)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LinkTokens_CreateLinkToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateLinkToken'
+type LinkTokens_CreateLinkToken_Call struct {
+	*mock.Call
+}
+
+// CreateLinkToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - urlID uuid.UUID
+//   - passwordVersion string
+//   - ttl time.Duration
+func (_e *LinkTokens_Expecter) CreateLinkToken(ctx interface{}, urlID interface{}, passwordVersion interface{}, ttl interface{}) *LinkTokens_CreateLinkToken_Call {
+	return &LinkTokens_CreateLinkToken_Call{Call: _e.mock.On("CreateLinkToken", ctx, urlID, passwordVersion, ttl)}
+}
+
+func (_c *LinkTokens_CreateLinkToken_Call) Run(run func(ctx context.Context, urlID uuid.UUID, passwordVersion string, ttl time.Duration)) *LinkTokens_CreateLinkToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uuid.UUID), args[2].(string), args[3].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *LinkTokens_CreateLinkToken_Call) Return(_a0 string, _a1 error) *LinkTokens_CreateLinkToken_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *LinkTokens_CreateLinkToken_Call) RunAndReturn(run func(context.Context, uuid.UUID, string, time.Duration) (string, error)) *LinkTokens_CreateLinkToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetLinkID provides a mock function with given fields: ctx, tokenString
+func (_m *LinkTokens) GetLinkID(ctx context.Context, tokenString string) (uuid.UUID, string, error) {
+	ret := _m.Called(ctx, tokenString)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLinkID")
+	}
+
+	var r0 uuid.UUID
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (uuid.UUID, string, error)); ok {
+		return rf(ctx, tokenString)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) uuid.UUID); ok {
+		r0 = rf(ctx, tokenString)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(uuid.UUID)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) string); ok {
+		r1 = rf(ctx, tokenString)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = rf(ctx, tokenString)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// LinkTokens_GetLinkID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetLinkID'
+type LinkTokens_GetLinkID_Call struct {
+	*mock.Call
+}
+
+// GetLinkID is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tokenString string
+func (_e *LinkTokens_Expecter) GetLinkID(ctx interface{}, tokenString interface{}) *LinkTokens_GetLinkID_Call {
+	return &LinkTokens_GetLinkID_Call{Call: _e.mock.On("GetLinkID", ctx, tokenString)}
+}
+
+func (_c *LinkTokens_GetLinkID_Call) Run(run func(ctx context.Context, tokenString string)) *LinkTokens_GetLinkID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *LinkTokens_GetLinkID_Call) Return(_a0 uuid.UUID, _a1 string, _a2 error) *LinkTokens_GetLinkID_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *LinkTokens_GetLinkID_Call) RunAndReturn(run func(context.Context, string) (uuid.UUID, string, error)) *LinkTokens_GetLinkID_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewLinkTokens creates a new instance of LinkTokens. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewLinkTokens(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *LinkTokens {
+	mock := &LinkTokens{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -5,10 +5,12 @@ package mocks
 import (
 	context "context"
 
+	model "github.com/dtroode/urlshorter/internal/model"
 	mock "github.com/stretchr/testify/mock"
 
-	model "github.com/dtroode/urlshorter/internal/model"
+	time "time"
 
+	storage "github.com/dtroode/urlshorter/internal/storage"
 	uuid "github.com/google/uuid"
 )
 
@@ -25,6 +27,53 @@ func (_m *URLStorage) EXPECT() *URLStorage_Expecter {
 	return &URLStorage_Expecter{mock: &_m.Mock}
 }
 
+// DecrementClicksLeft provides a mock function with given fields: ctx, id
+func (_m *URLStorage) DecrementClicksLeft(ctx context.Context, id uuid.UUID) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DecrementClicksLeft")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// URLStorage_DecrementClicksLeft_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DecrementClicksLeft'
+type URLStorage_DecrementClicksLeft_Call struct {
+	*mock.Call
+}
+
+// DecrementClicksLeft is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uuid.UUID
+func (_e *URLStorage_Expecter) DecrementClicksLeft(ctx interface{}, id interface{}) *URLStorage_DecrementClicksLeft_Call {
+	return &URLStorage_DecrementClicksLeft_Call{Call: _e.mock.On("DecrementClicksLeft", ctx, id)}
+}
+
+func (_c *URLStorage_DecrementClicksLeft_Call) Run(run func(ctx context.Context, id uuid.UUID)) *URLStorage_DecrementClicksLeft_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uuid.UUID))
+	})
+	return _c
+}
+
+func (_c *URLStorage_DecrementClicksLeft_Call) Return(_a0 error) *URLStorage_DecrementClicksLeft_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *URLStorage_DecrementClicksLeft_Call) RunAndReturn(run func(context.Context, uuid.UUID) error) *URLStorage_DecrementClicksLeft_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // DeleteURLs provides a mock function with given fields: ctx, ids
 func (_m *URLStorage) DeleteURLs(ctx context.Context, ids []uuid.UUID) error {
 	ret := _m.Called(ctx, ids)
@@ -72,6 +121,125 @@ func (_c *URLStorage_DeleteURLs_Call) RunAndReturn(run func(context.Context, []u
 	return _c
 }
 
+// GetClickStats provides a mock function with given fields: ctx, urlID, bucket
+func (_m *URLStorage) GetClickStats(ctx context.Context, urlID uuid.UUID, bucket time.Duration) (*model.ClickStats, error) {
+	ret := _m.Called(ctx, urlID, bucket)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetClickStats")
+	}
+
+	var r0 *model.ClickStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, time.Duration) (*model.ClickStats, error)); ok {
+		return rf(ctx, urlID, bucket)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, time.Duration) *model.ClickStats); ok {
+		r0 = rf(ctx, urlID, bucket)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.ClickStats)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, time.Duration) error); ok {
+		r1 = rf(ctx, urlID, bucket)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// URLStorage_GetClickStats_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetClickStats'
+type URLStorage_GetClickStats_Call struct {
+	*mock.Call
+}
+
+// GetClickStats is a helper method to define mock.On call
+//   - ctx context.Context
+//   - urlID uuid.UUID
+//   - bucket time.Duration
+func (_e *URLStorage_Expecter) GetClickStats(ctx interface{}, urlID interface{}, bucket interface{}) *URLStorage_GetClickStats_Call {
+	return &URLStorage_GetClickStats_Call{Call: _e.mock.On("GetClickStats", ctx, urlID, bucket)}
+}
+
+func (_c *URLStorage_GetClickStats_Call) Run(run func(ctx context.Context, urlID uuid.UUID, bucket time.Duration)) *URLStorage_GetClickStats_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uuid.UUID), args[2].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *URLStorage_GetClickStats_Call) Return(_a0 *model.ClickStats, _a1 error) *URLStorage_GetClickStats_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *URLStorage_GetClickStats_Call) RunAndReturn(run func(context.Context, uuid.UUID, time.Duration) (*model.ClickStats, error)) *URLStorage_GetClickStats_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetDeletedURLsByUserID provides a mock function with given fields: ctx, userID
+func (_m *URLStorage) GetDeletedURLsByUserID(ctx context.Context, userID uuid.UUID) ([]*model.URL, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDeletedURLsByUserID")
+	}
+
+	var r0 []*model.URL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ([]*model.URL, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []*model.URL); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.URL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// URLStorage_GetDeletedURLsByUserID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetDeletedURLsByUserID'
+type URLStorage_GetDeletedURLsByUserID_Call struct {
+	*mock.Call
+}
+
+// GetDeletedURLsByUserID is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userID uuid.UUID
+func (_e *URLStorage_Expecter) GetDeletedURLsByUserID(ctx interface{}, userID interface{}) *URLStorage_GetDeletedURLsByUserID_Call {
+	return &URLStorage_GetDeletedURLsByUserID_Call{Call: _e.mock.On("GetDeletedURLsByUserID", ctx, userID)}
+}
+
+func (_c *URLStorage_GetDeletedURLsByUserID_Call) Run(run func(ctx context.Context, userID uuid.UUID)) *URLStorage_GetDeletedURLsByUserID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uuid.UUID))
+	})
+	return _c
+}
+
+func (_c *URLStorage_GetDeletedURLsByUserID_Call) Return(_a0 []*model.URL, _a1 error) *URLStorage_GetDeletedURLsByUserID_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *URLStorage_GetDeletedURLsByUserID_Call) RunAndReturn(run func(context.Context, uuid.UUID) ([]*model.URL, error)) *URLStorage_GetDeletedURLsByUserID_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetURL provides a mock function with given fields: ctx, shortKey
 func (_m *URLStorage) GetURL(ctx context.Context, shortKey string) (*model.URL, error) {
 	ret := _m.Called(ctx, shortKey)
@@ -249,9 +417,161 @@ func (_c *URLStorage_GetURLsByUserID_Call) RunAndReturn(run func(context.Context
 	return _c
 }
 
-// SetURL provides a mock function with given fields: ctx, url
-func (_m *URLStorage) SetURL(ctx context.Context, url *model.URL) (*model.URL, error) {
-	ret := _m.Called(ctx, url)
+// PurgeDeletedURLs provides a mock function with given fields: ctx, before, limit
+func (_m *URLStorage) PurgeDeletedURLs(ctx context.Context, before time.Time, limit int) (int64, error) {
+	ret := _m.Called(ctx, before, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeDeletedURLs")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) (int64, error)); ok {
+		return rf(ctx, before, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) int64); ok {
+		r0 = rf(ctx, before, limit)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, before, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// URLStorage_PurgeDeletedURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PurgeDeletedURLs'
+type URLStorage_PurgeDeletedURLs_Call struct {
+	*mock.Call
+}
+
+// PurgeDeletedURLs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - before time.Time
+//   - limit int
+func (_e *URLStorage_Expecter) PurgeDeletedURLs(ctx interface{}, before interface{}, limit interface{}) *URLStorage_PurgeDeletedURLs_Call {
+	return &URLStorage_PurgeDeletedURLs_Call{Call: _e.mock.On("PurgeDeletedURLs", ctx, before, limit)}
+}
+
+func (_c *URLStorage_PurgeDeletedURLs_Call) Run(run func(ctx context.Context, before time.Time, limit int)) *URLStorage_PurgeDeletedURLs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(time.Time), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *URLStorage_PurgeDeletedURLs_Call) Return(_a0 int64, _a1 error) *URLStorage_PurgeDeletedURLs_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *URLStorage_PurgeDeletedURLs_Call) RunAndReturn(run func(context.Context, time.Time, int) (int64, error)) *URLStorage_PurgeDeletedURLs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RestoreURLs provides a mock function with given fields: ctx, ids
+func (_m *URLStorage) RestoreURLs(ctx context.Context, ids []uuid.UUID) error {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreURLs")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) error); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// URLStorage_RestoreURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RestoreURLs'
+type URLStorage_RestoreURLs_Call struct {
+	*mock.Call
+}
+
+// RestoreURLs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - ids []uuid.UUID
+func (_e *URLStorage_Expecter) RestoreURLs(ctx interface{}, ids interface{}) *URLStorage_RestoreURLs_Call {
+	return &URLStorage_RestoreURLs_Call{Call: _e.mock.On("RestoreURLs", ctx, ids)}
+}
+
+func (_c *URLStorage_RestoreURLs_Call) Run(run func(ctx context.Context, ids []uuid.UUID)) *URLStorage_RestoreURLs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]uuid.UUID))
+	})
+	return _c
+}
+
+func (_c *URLStorage_RestoreURLs_Call) Return(_a0 error) *URLStorage_RestoreURLs_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *URLStorage_RestoreURLs_Call) RunAndReturn(run func(context.Context, []uuid.UUID) error) *URLStorage_RestoreURLs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SaveClick provides a mock function with given fields: ctx, click
+func (_m *URLStorage) SaveClick(ctx context.Context, click *model.Click) error {
+	ret := _m.Called(ctx, click)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveClick")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Click) error); ok {
+		r0 = rf(ctx, click)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// URLStorage_SaveClick_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveClick'
+type URLStorage_SaveClick_Call struct {
+	*mock.Call
+}
+
+// SaveClick is a helper method to define mock.On call
+//   - ctx context.Context
+//   - click *model.Click
+func (_e *URLStorage_Expecter) SaveClick(ctx interface{}, click interface{}) *URLStorage_SaveClick_Call {
+	return &URLStorage_SaveClick_Call{Call: _e.mock.On("SaveClick", ctx, click)}
+}
+
+func (_c *URLStorage_SaveClick_Call) Run(run func(ctx context.Context, click *model.Click)) *URLStorage_SaveClick_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*model.Click))
+	})
+	return _c
+}
+
+func (_c *URLStorage_SaveClick_Call) Return(_a0 error) *URLStorage_SaveClick_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *URLStorage_SaveClick_Call) RunAndReturn(run func(context.Context, *model.Click) error) *URLStorage_SaveClick_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetURL provides a mock function with given fields: ctx, url, scope
+func (_m *URLStorage) SetURL(ctx context.Context, url *model.URL, scope storage.DedupeScope) (*model.URL, error) {
+	ret := _m.Called(ctx, url, scope)
 
 	if len(ret) == 0 {
 		panic("no return value specified for SetURL")
@@ -259,19 +579,19 @@ func (_m *URLStorage) SetURL(ctx context.Context, url *model.URL) (*model.URL, e
 
 	var r0 *model.URL
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, *model.URL) (*model.URL, error)); ok {
-		return rf(ctx, url)
+	if rf, ok := ret.Get(0).(func(context.Context, *model.URL, storage.DedupeScope) (*model.URL, error)); ok {
+		return rf(ctx, url, scope)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, *model.URL) *model.URL); ok {
-		r0 = rf(ctx, url)
+	if rf, ok := ret.Get(0).(func(context.Context, *model.URL, storage.DedupeScope) *model.URL); ok {
+		r0 = rf(ctx, url, scope)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*model.URL)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, *model.URL) error); ok {
-		r1 = rf(ctx, url)
+	if rf, ok := ret.Get(1).(func(context.Context, *model.URL, storage.DedupeScope) error); ok {
+		r1 = rf(ctx, url, scope)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -287,13 +607,14 @@ type URLStorage_SetURL_Call struct {
 // SetURL is a helper method to define mock.On call
 //   - ctx context.Context
 //   - url *model.URL
-func (_e *URLStorage_Expecter) SetURL(ctx interface{}, url interface{}) *URLStorage_SetURL_Call {
-	return &URLStorage_SetURL_Call{Call: _e.mock.On("SetURL", ctx, url)}
+//   - scope storage.DedupeScope
+func (_e *URLStorage_Expecter) SetURL(ctx interface{}, url interface{}, scope interface{}) *URLStorage_SetURL_Call {
+	return &URLStorage_SetURL_Call{Call: _e.mock.On("SetURL", ctx, url, scope)}
 }
 
-func (_c *URLStorage_SetURL_Call) Run(run func(ctx context.Context, url *model.URL)) *URLStorage_SetURL_Call {
+func (_c *URLStorage_SetURL_Call) Run(run func(ctx context.Context, url *model.URL, scope storage.DedupeScope)) *URLStorage_SetURL_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(*model.URL))
+		run(args[0].(context.Context), args[1].(*model.URL), args[2].(storage.DedupeScope))
 	})
 	return _c
 }
@@ -303,14 +624,14 @@ func (_c *URLStorage_SetURL_Call) Return(_a0 *model.URL, _a1 error) *URLStorage_
 	return _c
 }
 
-func (_c *URLStorage_SetURL_Call) RunAndReturn(run func(context.Context, *model.URL) (*model.URL, error)) *URLStorage_SetURL_Call {
+func (_c *URLStorage_SetURL_Call) RunAndReturn(run func(context.Context, *model.URL, storage.DedupeScope) (*model.URL, error)) *URLStorage_SetURL_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// SetURLs provides a mock function with given fields: ctx, urls
-func (_m *URLStorage) SetURLs(ctx context.Context, urls []*model.URL) ([]*model.URL, error) {
-	ret := _m.Called(ctx, urls)
+// SetURLs provides a mock function with given fields: ctx, urls, scope
+func (_m *URLStorage) SetURLs(ctx context.Context, urls []*model.URL, scope storage.DedupeScope) ([]*model.URL, error) {
+	ret := _m.Called(ctx, urls, scope)
 
 	if len(ret) == 0 {
 		panic("no return value specified for SetURLs")
@@ -318,19 +639,19 @@ func (_m *URLStorage) SetURLs(ctx context.Context, urls []*model.URL) ([]*model.
 
 	var r0 []*model.URL
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, []*model.URL) ([]*model.URL, error)); ok {
-		return rf(ctx, urls)
+	if rf, ok := ret.Get(0).(func(context.Context, []*model.URL, storage.DedupeScope) ([]*model.URL, error)); ok {
+		return rf(ctx, urls, scope)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, []*model.URL) []*model.URL); ok {
-		r0 = rf(ctx, urls)
+	if rf, ok := ret.Get(0).(func(context.Context, []*model.URL, storage.DedupeScope) []*model.URL); ok {
+		r0 = rf(ctx, urls, scope)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*model.URL)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, []*model.URL) error); ok {
-		r1 = rf(ctx, urls)
+	if rf, ok := ret.Get(1).(func(context.Context, []*model.URL, storage.DedupeScope) error); ok {
+		r1 = rf(ctx, urls, scope)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -346,13 +667,14 @@ type URLStorage_SetURLs_Call struct {
 // SetURLs is a helper method to define mock.On call
 //   - ctx context.Context
 //   - urls []*model.URL
-func (_e *URLStorage_Expecter) SetURLs(ctx interface{}, urls interface{}) *URLStorage_SetURLs_Call {
-	return &URLStorage_SetURLs_Call{Call: _e.mock.On("SetURLs", ctx, urls)}
+//   - scope storage.DedupeScope
+func (_e *URLStorage_Expecter) SetURLs(ctx interface{}, urls interface{}, scope interface{}) *URLStorage_SetURLs_Call {
+	return &URLStorage_SetURLs_Call{Call: _e.mock.On("SetURLs", ctx, urls, scope)}
 }
 
-func (_c *URLStorage_SetURLs_Call) Run(run func(ctx context.Context, urls []*model.URL)) *URLStorage_SetURLs_Call {
+func (_c *URLStorage_SetURLs_Call) Run(run func(ctx context.Context, urls []*model.URL, scope storage.DedupeScope)) *URLStorage_SetURLs_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].([]*model.URL))
+		run(args[0].(context.Context), args[1].([]*model.URL), args[2].(storage.DedupeScope))
 	})
 	return _c
 }
@@ -362,7 +684,67 @@ func (_c *URLStorage_SetURLs_Call) Return(savedURLs []*model.URL, err error) *UR
 	return _c
 }
 
-func (_c *URLStorage_SetURLs_Call) RunAndReturn(run func(context.Context, []*model.URL) ([]*model.URL, error)) *URLStorage_SetURLs_Call {
+func (_c *URLStorage_SetURLs_Call) RunAndReturn(run func(context.Context, []*model.URL, storage.DedupeScope) ([]*model.URL, error)) *URLStorage_SetURLs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateURL provides a mock function with given fields: ctx, url, fields
+func (_m *URLStorage) UpdateURL(ctx context.Context, url *model.URL, fields storage.URLFields) (*model.URL, error) {
+	ret := _m.Called(ctx, url, fields)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateURL")
+	}
+
+	var r0 *model.URL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.URL, storage.URLFields) (*model.URL, error)); ok {
+		return rf(ctx, url, fields)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *model.URL, storage.URLFields) *model.URL); ok {
+		r0 = rf(ctx, url, fields)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.URL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *model.URL, storage.URLFields) error); ok {
+		r1 = rf(ctx, url, fields)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// URLStorage_UpdateURL_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateURL'
+type URLStorage_UpdateURL_Call struct {
+	*mock.Call
+}
+
+// UpdateURL is a helper method to define mock.On call
+//   - ctx context.Context
+//   - url *model.URL
+//   - fields storage.URLFields
+func (_e *URLStorage_Expecter) UpdateURL(ctx interface{}, url interface{}, fields interface{}) *URLStorage_UpdateURL_Call {
+	return &URLStorage_UpdateURL_Call{Call: _e.mock.On("UpdateURL", ctx, url, fields)}
+}
+
+func (_c *URLStorage_UpdateURL_Call) Run(run func(ctx context.Context, url *model.URL, fields storage.URLFields)) *URLStorage_UpdateURL_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*model.URL), args[2].(storage.URLFields))
+	})
+	return _c
+}
+
+func (_c *URLStorage_UpdateURL_Call) Return(_a0 *model.URL, _a1 error) *URLStorage_UpdateURL_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *URLStorage_UpdateURL_Call) RunAndReturn(run func(context.Context, *model.URL, storage.URLFields) (*model.URL, error)) *URLStorage_UpdateURL_Call {
 	_c.Call.Return(run)
 	return _c
 }
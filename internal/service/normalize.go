@@ -0,0 +1,154 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// maxURLLength is the maximum length of a normalized original URL.
+// It matches the size of the original_url column.
+const maxURLLength = 256
+
+// defaultAllowedSchemes are the schemes accepted when no allowlist is configured.
+var defaultAllowedSchemes = []string{"http", "https"}
+
+// defaultPorts maps schemes to ports that are implied by them and can be omitted.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// URLNormalizer validates original URLs and brings them to a canonical form,
+// so that the same destination written differently is stored once.
+// The zero value accepts http and https URLs and keeps fragments.
+type URLNormalizer struct {
+	allowedSchemes map[string]struct{}
+	dropFragment   bool
+}
+
+// NewURLNormalizer creates a new URLNormalizer instance.
+//
+// Parameters:
+//   - allowedSchemes: The URL schemes accepted for shortening, http and https if empty
+//   - dropFragment: Whether the fragment is removed from URLs
+//
+// Returns the newly created URLNormalizer.
+func NewURLNormalizer(allowedSchemes []string, dropFragment bool) URLNormalizer {
+	schemes := make(map[string]struct{}, len(allowedSchemes))
+	for _, scheme := range allowedSchemes {
+		scheme = strings.ToLower(strings.TrimSpace(scheme))
+		if scheme != "" {
+			schemes[scheme] = struct{}{}
+		}
+	}
+
+	return URLNormalizer{
+		allowedSchemes: schemes,
+		dropFragment:   dropFragment,
+	}
+}
+
+// isAllowedScheme reports whether URLs with the scheme can be shortened.
+func (n URLNormalizer) isAllowedScheme(scheme string) bool {
+	if len(n.allowedSchemes) == 0 {
+		for _, s := range defaultAllowedSchemes {
+			if s == scheme {
+				return true
+			}
+		}
+		return false
+	}
+
+	_, ok := n.allowedSchemes[scheme]
+	return ok
+}
+
+// Normalize validates rawURL and returns its canonical form.
+// The host is lowercased and converted to punycode, the default port of the scheme
+// is removed and, if configured, the fragment is dropped.
+// Returns ErrInvalidURL wrapped with the reason if rawURL can't be shortened.
+func (n URLNormalizer) Normalize(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", fmt.Errorf("%w: url is empty", ErrInvalidURL)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidURL, unwrapURLError(err))
+	}
+
+	if u.Scheme == "" {
+		return "", fmt.Errorf("%w: scheme is missing", ErrInvalidURL)
+	}
+	if !n.isAllowedScheme(u.Scheme) {
+		return "", fmt.Errorf("%w: scheme %q is not allowed", ErrInvalidURL, u.Scheme)
+	}
+	if u.Opaque != "" || u.Host == "" {
+		return "", fmt.Errorf("%w: host is missing", ErrInvalidURL)
+	}
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidURL, err)
+	}
+
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	} else {
+		u.Host = host
+	}
+
+	if n.dropFragment {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+
+	normalized := u.String()
+	if len(normalized) > maxURLLength {
+		return "", fmt.Errorf("%w: url is longer than %d characters", ErrInvalidURL, maxURLLength)
+	}
+
+	return normalized, nil
+}
+
+// normalizeHost lowercases host and converts internationalized domain names to punycode.
+// IP addresses are returned in their canonical form.
+func normalizeHost(host string) (string, error) {
+	if host == "" {
+		return "", errors.New("host is missing")
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+
+	asciiHost, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid host %q", host)
+	}
+
+	return strings.ToLower(asciiHost), nil
+}
+
+// unwrapURLError returns the reason of a url.Parse error without the repeated input.
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+
+	return err
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLNormalizer_Normalize(t *testing.T) {
+	tests := map[string]struct {
+		normalizer URLNormalizer
+		rawURL     string
+		expected   string
+		wantErr    bool
+	}{
+		"already normalized": {
+			rawURL:   "https://yandex.ru/search?text=go#results",
+			expected: "https://yandex.ru/search?text=go#results",
+		},
+		"surrounding spaces": {
+			rawURL:   "  https://yandex.ru\n",
+			expected: "https://yandex.ru",
+		},
+		"uppercase scheme and host": {
+			rawURL:   "HTTPS://WWW.Yandex.RU/Path",
+			expected: "https://www.yandex.ru/Path",
+		},
+		"idn host": {
+			rawURL:   "http://пример.рф/путь",
+			expected: "http://xn--e1afmkfd.xn--p1ai/%D0%BF%D1%83%D1%82%D1%8C",
+		},
+		"default http port": {
+			rawURL:   "http://yandex.ru:80/",
+			expected: "http://yandex.ru/",
+		},
+		"default https port": {
+			rawURL:   "https://yandex.ru:443/",
+			expected: "https://yandex.ru/",
+		},
+		"non default port": {
+			rawURL:   "https://yandex.ru:8443/",
+			expected: "https://yandex.ru:8443/",
+		},
+		"https port with http scheme": {
+			rawURL:   "http://yandex.ru:443/",
+			expected: "http://yandex.ru:443/",
+		},
+		"ipv4 host": {
+			rawURL:   "http://127.0.0.1:80/",
+			expected: "http://127.0.0.1/",
+		},
+		"ipv6 host": {
+			rawURL:   "http://[2001:DB8::1]:80/",
+			expected: "http://[2001:db8::1]/",
+		},
+		"ipv6 host with port": {
+			rawURL:   "http://[2001:db8::1]:8080/",
+			expected: "http://[2001:db8::1]:8080/",
+		},
+		"fragment kept": {
+			rawURL:   "https://yandex.ru/#top",
+			expected: "https://yandex.ru/#top",
+		},
+		"fragment dropped": {
+			normalizer: NewURLNormalizer(nil, true),
+			rawURL:     "https://yandex.ru/#top",
+			expected:   "https://yandex.ru/",
+		},
+		"configured scheme": {
+			normalizer: NewURLNormalizer([]string{"https", " FTP "}, false),
+			rawURL:     "ftp://files.example.com/file.txt",
+			expected:   "ftp://files.example.com/file.txt",
+		},
+		"scheme not in configured list": {
+			normalizer: NewURLNormalizer([]string{"https"}, false),
+			rawURL:     "http://yandex.ru",
+			wantErr:    true,
+		},
+		"empty": {
+			rawURL:  "   ",
+			wantErr: true,
+		},
+		"no scheme": {
+			rawURL:  "yandex.ru",
+			wantErr: true,
+		},
+		"not allowed scheme": {
+			rawURL:  "javascript:alert(1)",
+			wantErr: true,
+		},
+		"no host": {
+			rawURL:  "https:///path",
+			wantErr: true,
+		},
+		"opaque": {
+			rawURL:  "http:yandex.ru",
+			wantErr: true,
+		},
+		"unparsable": {
+			rawURL:  "http://[::1",
+			wantErr: true,
+		},
+		"invalid host": {
+			rawURL:  "http://exa_mple.com/",
+			wantErr: true,
+		},
+		"too long": {
+			rawURL:  "https://yandex.ru/" + strings.Repeat("a", maxURLLength),
+			wantErr: true,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			normalized, err := tt.normalizer.Normalize(tt.rawURL)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidURL)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, normalized)
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/service/dto"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+const (
+	// minPasswordLength is the minimum length of a URL password.
+	minPasswordLength = 4
+	// maxPasswordLength is the maximum length of a URL password.
+	// bcrypt ignores everything after the 72nd byte.
+	maxPasswordLength = 72
+	// linkAccessTTL is the time a password-protected URL stays unlocked after a correct password.
+	linkAccessTTL = 10 * time.Minute
+	// maxPasswordAttempts is the number of passwords that can be entered for a URL
+	// within passwordAttemptsWindow.
+	maxPasswordAttempts = 5
+	// passwordAttemptsWindow is the period in which password attempts are counted.
+	passwordAttemptsWindow = 15 * time.Minute
+)
+
+// LinkTokens defines the interface for tokens that give access to password-protected URLs.
+type LinkTokens interface {
+	// CreateLinkToken creates a token that gives access to the URL with the given ID for ttl,
+	// as long as its password has the given version.
+	CreateLinkToken(ctx context.Context, urlID uuid.UUID, passwordVersion string, ttl time.Duration) (string, error)
+
+	// GetLinkID validates a token and returns the ID of the URL it gives access to
+	// and the version of the password it was issued for.
+	GetLinkID(ctx context.Context, tokenString string) (uuid.UUID, string, error)
+}
+
+// hashPassword validates password and returns its salted hash.
+// Returns ErrInvalidPassword wrapped with the reason if the password can't be used.
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return "", fmt.Errorf("%w: length must be between %d and %d", ErrInvalidPassword, minPasswordLength, maxPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return string(hash), nil
+}
+
+// passwordVersion returns the version of a password that link tokens are bound to.
+// It is derived from the salted hash, so it changes whenever the password is set again
+// and tokens issued for a previous password stop giving access.
+func passwordVersion(passwordHash string) string {
+	sum := sha256.Sum256([]byte(passwordHash))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
+
+// hasLinkAccess reports whether accessToken gives access to url.
+// Tokens issued before the password of the URL was changed are rejected.
+func (s *URL) hasLinkAccess(ctx context.Context, url *model.URL, accessToken string) bool {
+	if accessToken == "" {
+		return false
+	}
+
+	urlID, version, err := s.linkTokens.GetLinkID(ctx, accessToken)
+
+	return err == nil && urlID == url.ID && version == passwordVersion(url.PasswordHash)
+}
+
+// UnlockURL checks the password of a password-protected URL.
+// Only maxPasswordAttempts passwords can be entered for a URL within passwordAttemptsWindow.
+//
+// Parameters:
+//   - ctx: The request context
+//   - dto: The DTO containing the short key and the entered password
+//
+// Returns a token that gives access to the URL for linkAccessTTL, or until its password is changed,
+// or an error if the password is wrong.
+// Returns an empty token if the URL is not password-protected.
+// Returns ErrNotFound if the URL doesn't exist.
+// Returns ErrGone if the URL has been deleted, has expired or has no clicks left.
+// Returns ErrWrongPassword if the password doesn't match.
+// Returns ErrTooManyAttempts if the limit of attempts for the URL is reached.
+func (s *URL) UnlockURL(ctx context.Context, dto *dto.UnlockURL) (string, error) {
+	url, err := s.storage.GetURL(ctx, dto.ShortKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("failed to get URL: %w", err)
+	}
+
+	now := time.Now()
+	if url.DeletedAt != nil || url.IsExpired(now) || !url.HasClicksLeft() {
+		return "", ErrGone
+	}
+
+	if !url.IsProtected() {
+		return "", nil
+	}
+
+	if !s.passwordAttempts.take(url.ID, now) {
+		return "", ErrTooManyAttempts
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(url.PasswordHash), []byte(dto.Password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return "", ErrWrongPassword
+		}
+		return "", fmt.Errorf("failed to compare password: %w", err)
+	}
+	s.passwordAttempts.reset(url.ID)
+
+	token, err := s.linkTokens.CreateLinkToken(ctx, url.ID, passwordVersion(url.PasswordHash), linkAccessTTL)
+	if err != nil {
+		return "", fmt.Errorf("failed to create link token: %w", err)
+	}
+
+	return token, nil
+}
+
+// attemptLimiter limits the number of attempts per URL within a fixed window.
+type attemptLimiter struct {
+	mu       sync.Mutex
+	limit    int
+	window   time.Duration
+	attempts map[uuid.UUID]*attemptWindow
+}
+
+// attemptWindow is the number of attempts made since start.
+type attemptWindow struct {
+	start time.Time
+	count int
+}
+
+// newAttemptLimiter creates a limiter that allows limit attempts per URL within window.
+func newAttemptLimiter(limit int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		limit:    limit,
+		window:   window,
+		attempts: make(map[uuid.UUID]*attemptWindow),
+	}
+}
+
+// take records an attempt for the URL with the given ID.
+// Returns false if the limit of attempts has already been reached.
+func (l *attemptLimiter) take(id uuid.UUID, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.removeExpired(now)
+
+	w, ok := l.attempts[id]
+	if !ok {
+		w = &attemptWindow{start: now}
+		l.attempts[id] = w
+	}
+	if w.count >= l.limit {
+		return false
+	}
+	w.count++
+
+	return true
+}
+
+// reset forgets attempts made for the URL with the given ID.
+func (l *attemptLimiter) reset(id uuid.UUID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.attempts, id)
+}
+
+// removeExpired forgets windows that have ended by now, so URLs
+// that are not attacked anymore don't take memory.
+func (l *attemptLimiter) removeExpired(now time.Time) {
+	for id, w := range l.attempts {
+		if now.Sub(w.start) >= l.window {
+			delete(l.attempts, id)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/request"
+	"github.com/dtroode/urlshorter/internal/service/dto"
+	"github.com/dtroode/urlshorter/internal/service/mocks"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+func testPasswordHash(t *testing.T, password string) string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	return string(hash)
+}
+
+func TestHashPassword(t *testing.T) {
+	tests := map[string]struct {
+		password string
+		wantErr  bool
+	}{
+		"too short": {
+			password: "abc",
+			wantErr:  true,
+		},
+		"too long": {
+			password: strings.Repeat("a", maxPasswordLength+1),
+			wantErr:  true,
+		},
+		"shortest": {
+			password: "abcd",
+		},
+		"longest": {
+			password: strings.Repeat("a", maxPasswordLength),
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			hash, err := hashPassword(tt.password)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidPassword)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotEqual(t, tt.password, hash)
+			assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte(tt.password)))
+		})
+	}
+}
+
+func TestAttemptLimiter(t *testing.T) {
+	now := time.Now()
+	id := uuid.New()
+	otherID := uuid.New()
+
+	limiter := newAttemptLimiter(2, time.Minute)
+
+	assert.True(t, limiter.take(id, now))
+	assert.True(t, limiter.take(id, now.Add(time.Second)))
+	assert.False(t, limiter.take(id, now.Add(2*time.Second)), "limit is reached")
+	assert.True(t, limiter.take(otherID, now), "limit is per URL")
+
+	assert.True(t, limiter.take(id, now.Add(time.Minute)), "window has ended")
+
+	limiter.reset(id)
+	assert.True(t, limiter.take(id, now.Add(time.Minute)))
+	assert.True(t, limiter.take(id, now.Add(time.Minute)))
+	assert.False(t, limiter.take(id, now.Add(time.Minute)))
+}
+
+func TestURL_UnlockURL(t *testing.T) {
+	shortKey := "secret"
+	password := "s3cret"
+	passwordHash := testPasswordHash(t, password)
+	deletedAt := time.Now()
+	urlID := uuid.New()
+
+	tests := map[string]struct {
+		password         string
+		storageResponse  *model.URL
+		storageError     error
+		attempts         int
+		expectedResponse string
+		expectedError    error
+	}{
+		"storage error": {
+			storageError:  errors.New("storage error"),
+			expectedError: errors.New("storage error"),
+		},
+		"does not exist": {
+			storageError:  storage.ErrNotFound,
+			expectedError: ErrNotFound,
+		},
+		"deleted": {
+			storageResponse: &model.URL{
+				ID:           urlID,
+				ShortKey:     shortKey,
+				PasswordHash: passwordHash,
+				DeletedAt:    &deletedAt,
+			},
+			expectedError: ErrGone,
+		},
+		"not protected": {
+			storageResponse: &model.URL{
+				ID:       urlID,
+				ShortKey: shortKey,
+			},
+		},
+		"wrong password": {
+			password: "wrong",
+			storageResponse: &model.URL{
+				ID:           urlID,
+				ShortKey:     shortKey,
+				PasswordHash: passwordHash,
+			},
+			expectedError: ErrWrongPassword,
+		},
+		"too many attempts": {
+			password: password,
+			storageResponse: &model.URL{
+				ID:           urlID,
+				ShortKey:     shortKey,
+				PasswordHash: passwordHash,
+			},
+			attempts:      maxPasswordAttempts,
+			expectedError: ErrTooManyAttempts,
+		},
+		"success": {
+			password: password,
+			storageResponse: &model.URL{
+				ID:           urlID,
+				ShortKey:     shortKey,
+				PasswordHash: passwordHash,
+			},
+			attempts:         maxPasswordAttempts - 1,
+			expectedResponse: "token",
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			urlStorage := mocks.NewURLStorage(t)
+			urlStorage.On("GetURL", ctx, shortKey).Once().Return(tt.storageResponse, tt.storageError)
+
+			linkTokens := mocks.NewLinkTokens(t)
+			linkTokens.On("CreateLinkToken", ctx, urlID, mock.Anything, linkAccessTTL).Maybe().Return("token", nil)
+
+			service := URL{
+				linkTokens:       linkTokens,
+				passwordAttempts: newAttemptLimiter(maxPasswordAttempts, passwordAttemptsWindow),
+				storage:          urlStorage,
+			}
+			for range tt.attempts {
+				service.passwordAttempts.take(urlID, time.Now())
+			}
+
+			token, err := service.UnlockURL(ctx, dto.NewUnlockURL(shortKey, tt.password))
+
+			if tt.expectedError != nil {
+				require.Error(t, err)
+				assert.ErrorContains(t, err, tt.expectedError.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedResponse, token)
+		})
+	}
+}
+
+func TestURL_GetOriginalURL_Protected(t *testing.T) {
+	shortKey := "secret"
+	urlModel := &model.URL{
+		ID:           uuid.New(),
+		ShortKey:     shortKey,
+		OriginalURL:  "https://yandex.ru",
+		PasswordHash: testPasswordHash(t, "s3cret"),
+	}
+
+	tests := map[string]struct {
+		accessToken      string
+		tokenURLID       uuid.UUID
+		tokenVersion     string
+		tokenError       error
+		expectedResponse string
+		expectedError    error
+	}{
+		"no token": {
+			expectedError: ErrPasswordRequired,
+		},
+		"invalid token": {
+			accessToken:   "invalid",
+			tokenError:    errors.New("token is invalid"),
+			expectedError: ErrPasswordRequired,
+		},
+		"token of another URL": {
+			accessToken:   "another",
+			tokenURLID:    uuid.New(),
+			tokenVersion:  passwordVersion(urlModel.PasswordHash),
+			expectedError: ErrPasswordRequired,
+		},
+		"token of a previous password": {
+			accessToken:   "previous",
+			tokenURLID:    urlModel.ID,
+			tokenVersion:  passwordVersion(testPasswordHash(t, "s3cret")),
+			expectedError: ErrPasswordRequired,
+		},
+		"valid token": {
+			accessToken:      "valid",
+			tokenURLID:       urlModel.ID,
+			tokenVersion:     passwordVersion(urlModel.PasswordHash),
+			expectedResponse: urlModel.OriginalURL,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			urlStorage := mocks.NewURLStorage(t)
+			urlStorage.On("GetURL", ctx, shortKey).Once().Return(urlModel, nil)
+			clicks := make(chan *model.Click, 1)
+			urlStorage.On("SaveClick", mock.Anything, mock.Anything).Maybe().Run(func(args mock.Arguments) {
+				clicks <- args.Get(1).(*model.Click)
+			}).Return(nil)
+
+			linkTokens := mocks.NewLinkTokens(t)
+			linkTokens.On("GetLinkID", ctx, tt.accessToken).Maybe().Return(tt.tokenURLID, tt.tokenVersion, tt.tokenError)
+
+			service := URL{
+				linkTokens: linkTokens,
+				storage:    urlStorage,
+				clicks:     newTestClickWriter(t, urlStorage),
+			}
+
+			getDTO := dto.NewGetOriginalURL(shortKey, "", "", "")
+			getDTO.AccessToken = tt.accessToken
+			resp, err := service.GetOriginalURL(ctx, getDTO)
+
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedResponse, resp)
+
+			select {
+			case click := <-clicks:
+				assert.Equal(t, urlModel.ID, click.URLID)
+			case <-time.After(time.Second):
+				t.Error("click was not saved")
+			}
+		})
+	}
+}
+
+func TestURL_CreateShortURL_Password(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	t.Run("invalid password", func(t *testing.T) {
+		t.Parallel()
+
+		service := URL{
+			baseURL:        "http://localhost",
+			shortKeyLength: 5,
+			keyGenerator:   testKeyGenerator,
+			storage:        mocks.NewURLStorage(t),
+		}
+
+		createDTO := dto.NewCreateShortURL("https://yandex.ru", userID)
+		createDTO.Password = "abc"
+		_, err := service.CreateShortURL(ctx, createDTO)
+		require.ErrorIs(t, err, ErrInvalidPassword)
+
+		_, err = service.CreateShortURLBatch(ctx, dto.NewCreateShortURLBatch([]*request.CreateShortURLBatch{
+			{CorrelationID: "1", OriginalURL: "https://yandex.ru", Password: "abc"},
+		}, userID))
+		require.ErrorIs(t, err, ErrInvalidPassword)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		hasPassword := func(url *model.URL) bool {
+			return bcrypt.CompareHashAndPassword([]byte(url.PasswordHash), []byte("s3cret")) == nil
+		}
+
+		urlStorage := mocks.NewURLStorage(t)
+		urlStorage.On("SetURL", mock.Anything, mock.MatchedBy(hasPassword), mock.Anything).Once().Return(func(_ context.Context, url *model.URL, _ storage.DedupeScope) (*model.URL, error) {
+			return url, nil
+		})
+		urlStorage.On("SetURLs", mock.Anything, mock.MatchedBy(func(urls []*model.URL) bool {
+			return len(urls) == 2 && hasPassword(urls[0]) && !urls[1].IsProtected()
+		}), mock.Anything).Once().Return(func(_ context.Context, urls []*model.URL, _ storage.DedupeScope) ([]*model.URL, error) {
+			return urls, nil
+		})
+
+		service := URL{
+			baseURL:        "http://localhost",
+			shortKeyLength: 5,
+			keyGenerator:   testKeyGenerator,
+			dedupeScope:    storage.DedupeGlobal,
+			storage:        urlStorage,
+		}
+
+		createDTO := dto.NewCreateShortURL("https://yandex.ru", userID)
+		createDTO.Password = "s3cret"
+		_, err := service.CreateShortURL(ctx, createDTO)
+		require.NoError(t, err)
+
+		_, err = service.CreateShortURLBatch(ctx, dto.NewCreateShortURLBatch([]*request.CreateShortURLBatch{
+			{CorrelationID: "1", OriginalURL: "https://yandex.ru", Password: "s3cret"},
+			{CorrelationID: "2", OriginalURL: "https://ya.ru"},
+		}, userID))
+		require.NoError(t, err)
+	})
+}
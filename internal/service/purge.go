@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dtroode/urlshorter/internal/logger"
+)
+
+const (
+	// purgeBatchSize is the maximum number of URLs removed by a single purge job.
+	purgeBatchSize = 1000
+	// purgeBatchTimeout is the time given to the storage to remove a batch of URLs.
+	purgeBatchTimeout = 30 * time.Second
+)
+
+// PurgeDeletedURLs permanently removes URLs deleted more than retention ago.
+// URLs are removed in batches of purgeBatchSize, each processed as a job on the worker pool,
+// so a large purge doesn't hold the storage for long.
+//
+// Parameters:
+//   - ctx: The context that stops the purge between batches
+//   - retention: The time deleted URLs are kept for restoration
+//
+// Returns the number of removed URLs and an error if a batch fails.
+// URLs removed before the failure are counted.
+func (s *URL) PurgeDeletedURLs(ctx context.Context, retention time.Duration) (int64, error) {
+	before := time.Now().Add(-retention)
+
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		job := s.pool.Submit(ctx, purgeBatchTimeout, s.purgeBatchJob(before), true)
+		res := <-job.ResCh
+		if res.Err != nil {
+			return total, fmt.Errorf("failed to purge urls: %w", res.Err)
+		}
+
+		removed := res.Value.(int64)
+		total += removed
+
+		if removed < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+func (s *URL) purgeBatchJob(before time.Time) func(context.Context) (any, error) {
+	return func(ctx context.Context) (any, error) {
+		return s.storage.PurgeDeletedURLs(ctx, before, purgeBatchSize)
+	}
+}
+
+// Purger periodically removes URLs deleted more than retention ago.
+type Purger struct {
+	service   *URL
+	retention time.Duration
+	interval  time.Duration
+	logger    *logger.Logger
+}
+
+// NewPurger creates a new Purger instance.
+//
+// Parameters:
+//   - service: The URL service whose storage is purged
+//   - retention: The time deleted URLs are kept for restoration
+//   - interval: The time between purges
+//   - logger: The logger purge results are reported to
+//
+// Returns a pointer to the newly created Purger instance.
+func NewPurger(service *URL, retention, interval time.Duration, logger *logger.Logger) *Purger {
+	return &Purger{
+		service:   service,
+		retention: retention,
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+// Run purges deleted URLs every interval until ctx is done.
+// The number of removed URLs is logged after every purge.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.purge(ctx)
+		}
+	}
+}
+
+// purge runs a single purge and logs its result.
+func (p *Purger) purge(ctx context.Context) {
+	removed, err := p.service.PurgeDeletedURLs(ctx, p.retention)
+	if err != nil {
+		p.logger.Error("failed to purge deleted urls", "error", err, "removed", removed)
+		return
+	}
+
+	p.logger.Info("purged deleted urls", "removed", removed, "retention", p.retention.String())
+}
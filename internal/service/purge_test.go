@@ -0,0 +1,145 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/logger"
+	"github.com/dtroode/urlshorter/internal/service/mocks"
+)
+
+func TestURL_PurgeDeletedURLs(t *testing.T) {
+	retention := 24 * time.Hour
+
+	tests := map[string]struct {
+		batches         []int64
+		batchError      error
+		expectedRemoved int64
+		expectedError   error
+	}{
+		"nothing to purge": {
+			batches: []int64{0},
+		},
+		"single batch": {
+			batches:         []int64{10},
+			expectedRemoved: 10,
+		},
+		"several batches": {
+			batches:         []int64{purgeBatchSize, purgeBatchSize, 1},
+			expectedRemoved: 2*purgeBatchSize + 1,
+		},
+		"batch error": {
+			batches:         []int64{purgeBatchSize},
+			batchError:      errors.New("storage error"),
+			expectedRemoved: purgeBatchSize,
+			expectedError:   errors.New("storage error"),
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			isBefore := mock.MatchedBy(func(before time.Time) bool {
+				return before.Before(time.Now().Add(-retention + time.Minute))
+			})
+
+			urlStorage := mocks.NewURLStorage(t)
+			for _, removed := range tt.batches {
+				urlStorage.On("PurgeDeletedURLs", mock.Anything, isBefore, purgeBatchSize).Once().Return(removed, nil)
+			}
+			if tt.batchError != nil {
+				urlStorage.On("PurgeDeletedURLs", mock.Anything, isBefore, purgeBatchSize).Once().Return(int64(0), tt.batchError)
+			}
+
+			service := URL{
+				storage: urlStorage,
+				pool:    newTestPool(t),
+			}
+
+			removed, err := service.PurgeDeletedURLs(ctx, retention)
+			assert.Equal(t, tt.expectedRemoved, removed)
+
+			if tt.expectedError != nil {
+				require.Error(t, err)
+				assert.ErrorContains(t, err, tt.expectedError.Error())
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestURL_PurgeDeletedURLs_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	service := URL{
+		storage: mocks.NewURLStorage(t),
+		pool:    newTestPool(t),
+	}
+
+	removed, err := service.PurgeDeletedURLs(ctx, time.Hour)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, removed)
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func TestPurger_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	urlStorage := mocks.NewURLStorage(t)
+	urlStorage.On("PurgeDeletedURLs", mock.Anything, mock.Anything, purgeBatchSize).Return(int64(3), nil)
+
+	service := &URL{
+		storage: urlStorage,
+		pool:    newTestPool(t),
+	}
+
+	buf := &syncBuffer{}
+	log := &logger.Logger{Logger: slog.New(slog.NewJSONHandler(buf, nil))}
+
+	done := make(chan struct{})
+	go func() {
+		NewPurger(service, time.Hour, time.Millisecond, log).Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(buf.String(), `"msg":"purged deleted urls","removed":3`)
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+}
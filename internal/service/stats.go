@@ -0,0 +1,199 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/response"
+	"github.com/dtroode/urlshorter/internal/service/dto"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+const (
+	// defaultStatsBucket is the bucket size used when none is requested.
+	defaultStatsBucket = "day"
+	// topReferrersLimit is the maximum number of referrers returned in statistics.
+	topReferrersLimit = 10
+	// directReferrer is reported for clicks without referrer.
+	directReferrer = "direct"
+)
+
+// statsBuckets maps supported bucket names to their sizes.
+var statsBuckets = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+}
+
+// userAgentFamilies maps user agent substrings to client families.
+// The order matters, because browsers mention engines and other browsers
+// in their user agents, e.g. every Chrome user agent contains "Safari/".
+var userAgentFamilies = []struct {
+	token  string
+	family string
+}{
+	{token: "bot", family: "Bot"},
+	{token: "spider", family: "Bot"},
+	{token: "crawl", family: "Bot"},
+	{token: "edg/", family: "Edge"},
+	{token: "opr/", family: "Opera"},
+	{token: "yabrowser/", family: "Yandex Browser"},
+	{token: "chrome/", family: "Chrome"},
+	{token: "crios/", family: "Chrome"},
+	{token: "firefox/", family: "Firefox"},
+	{token: "fxios/", family: "Firefox"},
+	{token: "safari/", family: "Safari"},
+	{token: "curl/", family: "curl"},
+}
+
+// GetURLStats retrieves click statistics of a shortened URL owned by the user.
+//
+// Parameters:
+//   - ctx: The request context
+//   - dto: The DTO containing the short key, user ID and bucket size
+//
+// Returns statistics of the URL or an error if retrieval fails.
+// Returns ErrNotFound if the URL doesn't exist.
+// Returns ErrForbidden if the URL belongs to another user.
+// Returns ErrInvalidStatsBucket if the bucket size is not supported.
+func (s *URL) GetURLStats(ctx context.Context, dto *dto.GetURLStats) (*response.URLStats, error) {
+	bucketName := dto.Bucket
+	if bucketName == "" {
+		bucketName = defaultStatsBucket
+	}
+	bucket, ok := statsBuckets[bucketName]
+	if !ok {
+		return nil, ErrInvalidStatsBucket
+	}
+
+	url, err := s.storage.GetURL(ctx, dto.ShortKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get url: %w", err)
+	}
+
+	if url.UserID != dto.UserID {
+		return nil, ErrForbidden
+	}
+
+	clickStats, err := s.storage.GetClickStats(ctx, url.ID, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click stats: %w", err)
+	}
+
+	return buildURLStats(clickStats, bucket), nil
+}
+
+// buildURLStats turns clicks aggregated by the storage into URL statistics.
+// Referrers are grouped by host and user agents by client family.
+func buildURLStats(clickStats *model.ClickStats, bucket time.Duration) *response.URLStats {
+	referrers := make(map[string]int)
+	for referrer, clicks := range clickStats.Referrers {
+		referrers[referrerHost(referrer)] += clicks
+	}
+
+	userAgents := make(map[string]int)
+	for userAgent, clicks := range clickStats.UserAgents {
+		userAgents[userAgentFamily(userAgent)] += clicks
+	}
+
+	topReferrers := sortCounts(referrers)
+	if len(topReferrers) > topReferrersLimit {
+		topReferrers = topReferrers[:topReferrersLimit]
+	}
+
+	return &response.URLStats{
+		TotalClicks:    clickStats.Total,
+		UniqueVisitors: clickStats.UniqueVisitors,
+		Series:         buildSeries(clickStats.Buckets, bucket),
+		TopReferrers:   topReferrers,
+		UserAgents:     sortCounts(userAgents),
+	}
+}
+
+// buildSeries returns consecutive buckets from the earliest to the latest one in counts.
+// Buckets without clicks are included with zero clicks.
+func buildSeries(counts map[time.Time]int, bucket time.Duration) []*response.StatsBucket {
+	series := make([]*response.StatsBucket, 0)
+	if len(counts) == 0 {
+		return series
+	}
+
+	var first, last time.Time
+	for start := range counts {
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if start.After(last) {
+			last = start
+		}
+	}
+
+	for start := first; !start.After(last); start = start.Add(bucket) {
+		series = append(series, &response.StatsBucket{
+			Start:  start,
+			Clicks: counts[start],
+		})
+	}
+
+	return series
+}
+
+// sortCounts returns counts ordered by the number of clicks, most clicked first.
+// Values with the same number of clicks are ordered alphabetically.
+func sortCounts(counts map[string]int) []*response.StatsCount {
+	result := make([]*response.StatsCount, 0, len(counts))
+	for value, clicks := range counts {
+		result = append(result, &response.StatsCount{
+			Value:  value,
+			Clicks: clicks,
+		})
+	}
+
+	slices.SortFunc(result, func(a, b *response.StatsCount) int {
+		if c := cmp.Compare(b.Clicks, a.Clicks); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Value, b.Value)
+	})
+
+	return result
+}
+
+// referrerHost returns the host of the referrer without the "www." prefix.
+func referrerHost(referrer string) string {
+	if referrer == "" {
+		return directReferrer
+	}
+
+	u, err := url.Parse(referrer)
+	if err != nil || u.Host == "" {
+		return referrer
+	}
+
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// userAgentFamily returns the client family of the user agent.
+func userAgentFamily(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown"
+	}
+
+	userAgent = strings.ToLower(userAgent)
+	for _, f := range userAgentFamilies {
+		if strings.Contains(userAgent, f.token) {
+			return f.family
+		}
+	}
+
+	return "Other"
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/response"
+	"github.com/dtroode/urlshorter/internal/service/dto"
+	"github.com/dtroode/urlshorter/internal/service/mocks"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+func TestURL_GetURLStats(t *testing.T) {
+	userID := uuid.New()
+	shortKey := "ABOBA"
+	url := &model.URL{
+		ID:          uuid.New(),
+		ShortKey:    shortKey,
+		OriginalURL: "https://yandex.ru",
+		UserID:      userID,
+	}
+	hourlyStats := &model.ClickStats{
+		Total:          2,
+		UniqueVisitors: 1,
+		Buckets: map[time.Time]int{
+			time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC): 1,
+			time.Date(2025, 10, 1, 14, 0, 0, 0, time.UTC): 1,
+		},
+		Referrers: map[string]int{
+			"https://www.google.com/search": 1,
+			"":                              1,
+		},
+		UserAgents: map[string]int{
+			"Mozilla/5.0 Chrome/120.0 Safari/537.36": 2,
+		},
+	}
+	dailyStats := &model.ClickStats{
+		Total:          hourlyStats.Total,
+		UniqueVisitors: hourlyStats.UniqueVisitors,
+		Buckets: map[time.Time]int{
+			time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC): 2,
+		},
+		Referrers:  hourlyStats.Referrers,
+		UserAgents: hourlyStats.UserAgents,
+	}
+
+	tests := map[string]struct {
+		userID           uuid.UUID
+		bucket           string
+		getURLResponse   *model.URL
+		getURLError      error
+		getClicksError   error
+		expectedResponse *response.URLStats
+		expectedError    error
+	}{
+		"invalid bucket": {
+			userID:        userID,
+			bucket:        "week",
+			expectedError: ErrInvalidStatsBucket,
+		},
+		"not found": {
+			userID:        userID,
+			getURLError:   storage.ErrNotFound,
+			expectedError: ErrNotFound,
+		},
+		"storage error": {
+			userID:        userID,
+			getURLError:   errors.New("storage error"),
+			expectedError: errors.New("storage error"),
+		},
+		"not owner": {
+			userID:         uuid.New(),
+			getURLResponse: url,
+			expectedError:  ErrForbidden,
+		},
+		"get clicks error": {
+			userID:         userID,
+			getURLResponse: url,
+			getClicksError: errors.New("storage error"),
+			expectedError:  errors.New("storage error"),
+		},
+		"success": {
+			userID:         userID,
+			bucket:         "hour",
+			getURLResponse: url,
+			expectedResponse: &response.URLStats{
+				TotalClicks:    2,
+				UniqueVisitors: 1,
+				Series: []*response.StatsBucket{
+					{Start: time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC), Clicks: 1},
+					{Start: time.Date(2025, 10, 1, 13, 0, 0, 0, time.UTC), Clicks: 0},
+					{Start: time.Date(2025, 10, 1, 14, 0, 0, 0, time.UTC), Clicks: 1},
+				},
+				TopReferrers: []*response.StatsCount{
+					{Value: "direct", Clicks: 1},
+					{Value: "google.com", Clicks: 1},
+				},
+				UserAgents: []*response.StatsCount{
+					{Value: "Chrome", Clicks: 2},
+				},
+			},
+		},
+		"success default bucket": {
+			userID:         userID,
+			getURLResponse: url,
+			expectedResponse: &response.URLStats{
+				TotalClicks:    2,
+				UniqueVisitors: 1,
+				Series: []*response.StatsBucket{
+					{Start: time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC), Clicks: 2},
+				},
+				TopReferrers: []*response.StatsCount{
+					{Value: "direct", Clicks: 1},
+					{Value: "google.com", Clicks: 1},
+				},
+				UserAgents: []*response.StatsCount{
+					{Value: "Chrome", Clicks: 2},
+				},
+			},
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			urlStorage := mocks.NewURLStorage(t)
+			urlStorage.On("GetURL", ctx, shortKey).Maybe().Return(tt.getURLResponse, tt.getURLError)
+			urlStorage.On("GetClickStats", ctx, url.ID, time.Hour).Maybe().Return(hourlyStats, tt.getClicksError)
+			urlStorage.On("GetClickStats", ctx, url.ID, 24*time.Hour).Maybe().Return(dailyStats, tt.getClicksError)
+
+			service := URL{
+				storage: urlStorage,
+			}
+
+			stats, err := service.GetURLStats(ctx, dto.NewGetURLStats(shortKey, tt.bucket, tt.userID))
+
+			if tt.expectedError != nil {
+				require.Error(t, err)
+				assert.ErrorContains(t, err, tt.expectedError.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedResponse, stats)
+		})
+	}
+}
+
+func TestBuildURLStats_NoClicks(t *testing.T) {
+	stats := buildURLStats(&model.ClickStats{}, time.Hour)
+
+	assert.Equal(t, &response.URLStats{
+		Series:       []*response.StatsBucket{},
+		TopReferrers: []*response.StatsCount{},
+		UserAgents:   []*response.StatsCount{},
+	}, stats)
+}
+
+func TestBuildURLStats_TopReferrersLimit(t *testing.T) {
+	clickStats := &model.ClickStats{Referrers: make(map[string]int)}
+	for i := range topReferrersLimit + 5 {
+		referrer := "https://site" + string(rune('a'+i)) + ".com/"
+		clickStats.Referrers[referrer] = i + 1
+		// Referrers with the same host are counted together.
+		clickStats.Referrers[referrer+"page"] = i + 1
+	}
+
+	stats := buildURLStats(clickStats, time.Hour)
+
+	require.Len(t, stats.TopReferrers, topReferrersLimit)
+	assert.Equal(t, "siteo.com", stats.TopReferrers[0].Value)
+	assert.Equal(t, 2*(topReferrersLimit+5), stats.TopReferrers[0].Clicks)
+}
+
+func TestReferrerHost(t *testing.T) {
+	tests := map[string]struct {
+		referrer string
+		expected string
+	}{
+		"empty":         {referrer: "", expected: "direct"},
+		"url":           {referrer: "https://google.com/search?q=1", expected: "google.com"},
+		"www":           {referrer: "https://WWW.Google.com/", expected: "google.com"},
+		"with port":     {referrer: "http://localhost:8080/page", expected: "localhost"},
+		"not a url":     {referrer: "android-app", expected: "android-app"},
+		"invalid url":   {referrer: "http://[::1", expected: "http://[::1"},
+		"relative path": {referrer: "/page", expected: "/page"},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expected, referrerHost(tt.referrer))
+		})
+	}
+}
+
+func TestUserAgentFamily(t *testing.T) {
+	tests := map[string]struct {
+		userAgent string
+		expected  string
+	}{
+		"empty":   {userAgent: "", expected: "Unknown"},
+		"chrome":  {userAgent: "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", expected: "Chrome"},
+		"edge":    {userAgent: "Mozilla/5.0 (Windows NT 10.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0", expected: "Edge"},
+		"firefox": {userAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0", expected: "Firefox"},
+		"safari":  {userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_2) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15", expected: "Safari"},
+		"bot":     {userAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", expected: "Bot"},
+		"curl":    {userAgent: "curl/8.4.0", expected: "curl"},
+		"other":   {userAgent: "Wget/1.21", expected: "Other"},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expected, userAgentFamily(tt.userAgent))
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dtroode/urlshorter/internal/response"
+	"github.com/dtroode/urlshorter/internal/service/dto"
+)
+
+// GetDeletedUserURLs retrieves all deleted URLs created by the specified user.
+//
+// Parameters:
+//   - ctx: The request context
+//   - userID: The UUID of the user whose deleted URLs to retrieve
+//
+// Returns a slice of deleted user URLs or an error if retrieval fails.
+// Returns ErrNoContent if the user has no deleted URLs.
+func (s *URL) GetDeletedUserURLs(ctx context.Context, userID uuid.UUID) ([]*response.GetUserURL, error) {
+	urls, err := s.storage.GetDeletedURLsByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deleted urls: %w", err)
+	}
+
+	if len(urls) == 0 {
+		return nil, ErrNoContent
+	}
+
+	resp := make([]*response.GetUserURL, len(urls))
+	now := time.Now()
+
+	for i, u := range urls {
+		respURL, err := s.userURL(u, now)
+		if err != nil {
+			return nil, err
+		}
+		resp[i] = respURL
+	}
+
+	return resp, nil
+}
+
+// RestoreURLs clears the deletion mark of the specified URLs for the given user.
+// Unlike deletion, restoration is performed synchronously,
+// so restored URLs redirect again as soon as the call returns.
+// URLs created by other users and unknown short keys are skipped.
+//
+// Parameters:
+//   - ctx: The request context
+//   - data: The DTO containing the short keys to restore and user ID
+//
+// Returns an error if the restoration fails.
+func (s *URL) RestoreURLs(ctx context.Context, data *dto.RestoreURLs) error {
+	for _, batch := range splitIntoBatches(data.ShortKeys, deleteBatchSize) {
+		urls, err := s.storage.GetURLs(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("failed to get urls: %w", err)
+		}
+
+		ids := ownedURLIDs(urls, data.UserID)
+		if len(ids) == 0 {
+			continue
+		}
+
+		if err := s.storage.RestoreURLs(ctx, ids); err != nil {
+			return fmt.Errorf("failed to restore urls: %w", err)
+		}
+	}
+
+	return nil
+}
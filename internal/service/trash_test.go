@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/response"
+	"github.com/dtroode/urlshorter/internal/service/dto"
+	"github.com/dtroode/urlshorter/internal/service/mocks"
+)
+
+func TestURL_GetDeletedUserURLs(t *testing.T) {
+	userID := uuid.New()
+	deletedAt := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		storageResponse  []*model.URL
+		storageError     error
+		expectedResponse []*response.GetUserURL
+		expectedError    error
+	}{
+		"storage error": {
+			storageError:  errors.New("storage error"),
+			expectedError: fmt.Errorf("failed to get deleted urls: %w", errors.New("storage error")),
+		},
+		"no urls": {
+			storageResponse: make([]*model.URL, 0),
+			expectedError:   ErrNoContent,
+		},
+		"success": {
+			storageResponse: []*model.URL{
+				{
+					ID:          uuid.New(),
+					OriginalURL: "http://yandex.ru",
+					ShortKey:    "ABCDE",
+					UserID:      userID,
+					DeletedAt:   &deletedAt,
+				},
+			},
+			expectedResponse: []*response.GetUserURL{
+				{
+					ShortURL:    "http://localhost/ABCDE",
+					OriginalURL: "http://yandex.ru",
+					DeletedAt:   &deletedAt,
+				},
+			},
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			urlStorage := mocks.NewURLStorage(t)
+			urlStorage.On("GetDeletedURLsByUserID", ctx, userID).Once().Return(tt.storageResponse, tt.storageError)
+
+			service := URL{
+				baseURL: "http://localhost",
+				storage: urlStorage,
+			}
+
+			resp, err := service.GetDeletedUserURLs(ctx, userID)
+
+			if tt.expectedError != nil {
+				require.EqualError(t, err, tt.expectedError.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedResponse, resp)
+		})
+	}
+}
+
+func TestURL_RestoreURLs(t *testing.T) {
+	userID := uuid.New()
+	ownURL := &model.URL{ID: uuid.New(), ShortKey: "own", UserID: userID}
+	otherURL := &model.URL{ID: uuid.New(), ShortKey: "other", UserID: uuid.New()}
+	shortKeys := []string{"own", "other", "unknown"}
+
+	tests := map[string]struct {
+		getURLsResponse []*model.URL
+		getURLsError    error
+		restoreError    error
+		expectedIDs     []uuid.UUID
+		expectedError   error
+	}{
+		"get urls error": {
+			getURLsError:  errors.New("storage error"),
+			expectedError: errors.New("storage error"),
+		},
+		"restore error": {
+			getURLsResponse: []*model.URL{ownURL},
+			restoreError:    errors.New("storage error"),
+			expectedIDs:     []uuid.UUID{ownURL.ID},
+			expectedError:   errors.New("storage error"),
+		},
+		"no own urls": {
+			getURLsResponse: []*model.URL{otherURL},
+		},
+		"success": {
+			getURLsResponse: []*model.URL{ownURL, otherURL},
+			expectedIDs:     []uuid.UUID{ownURL.ID},
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			urlStorage := mocks.NewURLStorage(t)
+			urlStorage.On("GetURLs", ctx, shortKeys).Once().Return(tt.getURLsResponse, tt.getURLsError)
+			if tt.expectedIDs != nil {
+				urlStorage.On("RestoreURLs", ctx, tt.expectedIDs).Once().Return(tt.restoreError)
+			}
+
+			service := URL{
+				storage: urlStorage,
+			}
+
+			err := service.RestoreURLs(ctx, dto.NewRestoreURLs(shortKeys, userID))
+
+			if tt.expectedError != nil {
+				require.Error(t, err)
+				assert.ErrorContains(t, err, tt.expectedError.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			if tt.expectedIDs == nil {
+				urlStorage.AssertNotCalled(t, "RestoreURLs", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestURL_RestoreURLs_Batches(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	shortKeys := make([]string, deleteBatchSize+1)
+	for i := range shortKeys {
+		shortKeys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	urlStorage := mocks.NewURLStorage(t)
+	urlStorage.On("GetURLs", ctx, mock.Anything).Twice().Return(func(_ context.Context, keys []string) ([]*model.URL, error) {
+		urls := make([]*model.URL, len(keys))
+		for i, key := range keys {
+			urls[i] = &model.URL{ID: uuid.New(), ShortKey: key, UserID: userID}
+		}
+		return urls, nil
+	})
+	urlStorage.On("RestoreURLs", ctx, mock.Anything).Twice().Return(nil)
+
+	service := URL{
+		storage: urlStorage,
+	}
+
+	err := service.RestoreURLs(ctx, dto.NewRestoreURLs(shortKeys, userID))
+	require.NoError(t, err)
+}
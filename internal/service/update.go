@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/request"
+	"github.com/dtroode/urlshorter/internal/response"
+	"github.com/dtroode/urlshorter/internal/service/dto"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+// UpdateURL changes attributes of a URL owned by the given user.
+// Only attributes present in dto.Changes are changed.
+//
+// Parameters:
+//   - ctx: The request context
+//   - dto: The DTO containing the short key, the user ID and the requested changes
+//
+// Returns the changed URL or an error if the change fails.
+// Returns ErrNotFound if the URL doesn't exist.
+// Returns ErrForbidden if the URL belongs to another user.
+// Returns ErrGone if the URL has been deleted.
+// Returns ErrInvalidURL, ErrInvalidExpiration, ErrInvalidMaxClicks or ErrInvalidPassword
+// if the requested changes are invalid.
+func (s *URL) UpdateURL(ctx context.Context, dto *dto.UpdateURL) (*response.GetUserURL, error) {
+	url, err := s.storage.GetURL(ctx, dto.ShortKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get url: %w", err)
+	}
+
+	if url.UserID != dto.UserID {
+		return nil, ErrForbidden
+	}
+
+	if url.DeletedAt != nil {
+		return nil, ErrGone
+	}
+
+	now := time.Now()
+	updated, fields, err := s.applyChanges(now, url, dto.Changes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the changed attributes are written, so that clicks used up since url was read aren't given back.
+	savedURL, err := s.storage.UpdateURL(ctx, updated, fields)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrGone
+		}
+		return nil, fmt.Errorf("failed to update url: %w", err)
+	}
+
+	return s.userURL(savedURL, now)
+}
+
+// applyChanges returns a copy of url with changes applied and the set of changed attributes.
+// Returns an error if any of the changes is invalid.
+func (s *URL) applyChanges(now time.Time, url *model.URL, changes *request.UpdateURL) (*model.URL, storage.URLFields, error) {
+	updated := *url
+	var fields storage.URLFields
+	if changes == nil {
+		return &updated, fields, nil
+	}
+
+	if changes.OriginalURL != nil {
+		originalURL, err := s.normalizer.Normalize(*changes.OriginalURL)
+		if err != nil {
+			return nil, 0, err
+		}
+		updated.OriginalURL = originalURL
+		fields |= storage.FieldOriginalURL
+	}
+
+	if changes.TTL != nil && changes.ExpiresAt.Set {
+		return nil, 0, fmt.Errorf("%w: ttl and expires_at are mutually exclusive", ErrInvalidExpiration)
+	}
+	if changes.TTL != nil || changes.ExpiresAt.Set {
+		var ttl int64
+		if changes.TTL != nil {
+			ttl = *changes.TTL
+		}
+		expiresAt, err := resolveExpiration(now, ttl, changes.ExpiresAt.Value)
+		if err != nil {
+			return nil, 0, err
+		}
+		updated.ExpiresAt = expiresAt
+		fields |= storage.FieldExpiresAt
+	}
+
+	if changes.MaxClicks.Set {
+		var maxClicks int64
+		if changes.MaxClicks.Value != nil {
+			maxClicks = *changes.MaxClicks.Value
+		}
+		clicksLeft, err := resolveClicksLeft(maxClicks)
+		if err != nil {
+			return nil, 0, err
+		}
+		updated.ClicksLeft = clicksLeft
+		fields |= storage.FieldClicksLeft
+	}
+
+	if changes.Password != nil {
+		updated.PasswordHash = ""
+		if *changes.Password != "" {
+			passwordHash, err := hashPassword(*changes.Password)
+			if err != nil {
+				return nil, 0, err
+			}
+			updated.PasswordHash = passwordHash
+		}
+		fields |= storage.FieldPasswordHash
+	}
+
+	return &updated, fields, nil
+}
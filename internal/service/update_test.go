@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/request"
+	"github.com/dtroode/urlshorter/internal/service/dto"
+	"github.com/dtroode/urlshorter/internal/service/mocks"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestURL_UpdateURL(t *testing.T) {
+	userID := uuid.New()
+	shortKey := "ABOBA"
+	deletedAt := time.Now().Add(-time.Hour)
+	expiresAt := time.Now().Add(time.Hour)
+	pastExpiresAt := time.Now().Add(-time.Hour)
+
+	newURL := func() *model.URL {
+		return &model.URL{
+			ID:           uuid.New(),
+			ShortKey:     shortKey,
+			OriginalURL:  "https://yandex.ru",
+			UserID:       userID,
+			ExpiresAt:    &expiresAt,
+			PasswordHash: testPasswordHash(t, "s3cret"),
+			ClicksLeft:   clicksLeft(3),
+		}
+	}
+
+	tests := map[string]struct {
+		userID         uuid.UUID
+		changes        *request.UpdateURL
+		getURLResponse *model.URL
+		getURLError    error
+		updateError    error
+		expectedError  error
+		expectedFields storage.URLFields
+		check          func(t *testing.T, url *model.URL)
+	}{
+		"not found": {
+			userID:        userID,
+			changes:       &request.UpdateURL{},
+			getURLError:   storage.ErrNotFound,
+			expectedError: ErrNotFound,
+		},
+		"get url error": {
+			userID:        userID,
+			changes:       &request.UpdateURL{},
+			getURLError:   errors.New("storage error"),
+			expectedError: errors.New("storage error"),
+		},
+		"not owner": {
+			userID:         uuid.New(),
+			changes:        &request.UpdateURL{},
+			getURLResponse: newURL(),
+			expectedError:  ErrForbidden,
+		},
+		"deleted": {
+			userID: userID,
+			changes: &request.UpdateURL{
+				OriginalURL: ptr("https://ya.ru"),
+			},
+			getURLResponse: func() *model.URL {
+				url := newURL()
+				url.DeletedAt = &deletedAt
+				return url
+			}(),
+			expectedError: ErrGone,
+		},
+		"deleted concurrently": {
+			userID: userID,
+			changes: &request.UpdateURL{
+				OriginalURL: ptr("https://ya.ru"),
+			},
+			getURLResponse: newURL(),
+			updateError:    storage.ErrNotFound,
+			expectedError:  ErrGone,
+		},
+		"update error": {
+			userID: userID,
+			changes: &request.UpdateURL{
+				OriginalURL: ptr("https://ya.ru"),
+			},
+			getURLResponse: newURL(),
+			updateError:    errors.New("storage error"),
+			expectedError:  errors.New("storage error"),
+		},
+		"invalid url": {
+			userID: userID,
+			changes: &request.UpdateURL{
+				OriginalURL: ptr("javascript:alert(1)"),
+			},
+			getURLResponse: newURL(),
+			expectedError:  ErrInvalidURL,
+		},
+		"ttl and expires at": {
+			userID: userID,
+			changes: &request.UpdateURL{
+				TTL:       ptr(int64(0)),
+				ExpiresAt: request.Nullable[time.Time]{Set: true, Value: &expiresAt},
+			},
+			getURLResponse: newURL(),
+			expectedError:  ErrInvalidExpiration,
+		},
+		"expires at in the past": {
+			userID: userID,
+			changes: &request.UpdateURL{
+				ExpiresAt: request.Nullable[time.Time]{Set: true, Value: &pastExpiresAt},
+			},
+			getURLResponse: newURL(),
+			expectedError:  ErrInvalidExpiration,
+		},
+		"negative max clicks": {
+			userID: userID,
+			changes: &request.UpdateURL{
+				MaxClicks: request.Nullable[int64]{Set: true, Value: ptr(int64(-1))},
+			},
+			getURLResponse: newURL(),
+			expectedError:  ErrInvalidMaxClicks,
+		},
+		"short password": {
+			userID: userID,
+			changes: &request.UpdateURL{
+				Password: ptr("abc"),
+			},
+			getURLResponse: newURL(),
+			expectedError:  ErrInvalidPassword,
+		},
+		"no changes": {
+			userID:         userID,
+			changes:        &request.UpdateURL{},
+			getURLResponse: newURL(),
+			check: func(t *testing.T, url *model.URL) {
+				assert.Equal(t, "https://yandex.ru", url.OriginalURL)
+				assert.Equal(t, &expiresAt, url.ExpiresAt)
+				assert.Equal(t, clicksLeft(3), url.ClicksLeft)
+				assert.True(t, url.IsProtected())
+			},
+		},
+		"change original url": {
+			userID: userID,
+			changes: &request.UpdateURL{
+				OriginalURL: ptr("HTTPS://Ya.ru/path"),
+			},
+			getURLResponse: newURL(),
+			expectedFields: storage.FieldOriginalURL,
+			check: func(t *testing.T, url *model.URL) {
+				assert.Equal(t, "https://ya.ru/path", url.OriginalURL)
+				assert.Equal(t, clicksLeft(3), url.ClicksLeft)
+			},
+		},
+		"set ttl": {
+			userID: userID,
+			changes: &request.UpdateURL{
+				TTL: ptr(int64(60)),
+			},
+			getURLResponse: newURL(),
+			expectedFields: storage.FieldExpiresAt,
+			check: func(t *testing.T, url *model.URL) {
+				require.NotNil(t, url.ExpiresAt)
+				assert.WithinDuration(t, time.Now().Add(time.Minute), *url.ExpiresAt, 5*time.Second)
+			},
+		},
+		"remove expiration": {
+			userID: userID,
+			changes: &request.UpdateURL{
+				TTL: ptr(int64(0)),
+			},
+			getURLResponse: newURL(),
+			expectedFields: storage.FieldExpiresAt,
+			check: func(t *testing.T, url *model.URL) {
+				assert.Nil(t, url.ExpiresAt)
+			},
+		},
+		"remove expiration with null": {
+			userID: userID,
+			changes: &request.UpdateURL{
+				ExpiresAt: request.Nullable[time.Time]{Set: true},
+			},
+			getURLResponse: newURL(),
+			expectedFields: storage.FieldExpiresAt,
+			check: func(t *testing.T, url *model.URL) {
+				assert.Nil(t, url.ExpiresAt)
+			},
+		},
+		"remove click limit": {
+			userID: userID,
+			changes: &request.UpdateURL{
+				MaxClicks: request.Nullable[int64]{Set: true, Value: ptr(int64(0))},
+			},
+			getURLResponse: newURL(),
+			expectedFields: storage.FieldClicksLeft,
+			check: func(t *testing.T, url *model.URL) {
+				assert.Nil(t, url.ClicksLeft)
+			},
+		},
+		"remove click limit with null": {
+			userID: userID,
+			changes: &request.UpdateURL{
+				MaxClicks: request.Nullable[int64]{Set: true},
+			},
+			getURLResponse: newURL(),
+			expectedFields: storage.FieldClicksLeft,
+			check: func(t *testing.T, url *model.URL) {
+				assert.Nil(t, url.ClicksLeft)
+			},
+		},
+		"change password": {
+			userID: userID,
+			changes: &request.UpdateURL{
+				Password: ptr("n3w-s3cret"),
+			},
+			getURLResponse: newURL(),
+			expectedFields: storage.FieldPasswordHash,
+			check: func(t *testing.T, url *model.URL) {
+				assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(url.PasswordHash), []byte("n3w-s3cret")))
+			},
+		},
+		"remove password": {
+			userID: userID,
+			changes: &request.UpdateURL{
+				Password: ptr(""),
+			},
+			getURLResponse: newURL(),
+			expectedFields: storage.FieldPasswordHash,
+			check: func(t *testing.T, url *model.URL) {
+				assert.False(t, url.IsProtected())
+			},
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			var (
+				updated *model.URL
+				fields  storage.URLFields
+			)
+			urlStorage := mocks.NewURLStorage(t)
+			urlStorage.On("GetURL", ctx, shortKey).Once().Return(tt.getURLResponse, tt.getURLError)
+			urlStorage.On("UpdateURL", ctx, mock.Anything, mock.Anything).Maybe().Return(func(_ context.Context, url *model.URL, f storage.URLFields) (*model.URL, error) {
+				updated = url
+				fields = f
+				if tt.updateError != nil {
+					return nil, tt.updateError
+				}
+				return url, nil
+			})
+
+			service := URL{
+				baseURL:    "http://localhost",
+				normalizer: NewURLNormalizer(nil, false),
+				storage:    urlStorage,
+			}
+
+			resp, err := service.UpdateURL(ctx, dto.NewUpdateURL(shortKey, tt.changes, tt.userID))
+
+			if tt.expectedError != nil {
+				require.Error(t, err)
+				assert.ErrorContains(t, err, tt.expectedError.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			require.NotNil(t, updated)
+			assert.Equal(t, tt.getURLResponse.ID, updated.ID)
+			assert.Equal(t, "http://localhost/"+shortKey, resp.ShortURL)
+			assert.Equal(t, updated.OriginalURL, resp.OriginalURL)
+			assert.Equal(t, tt.expectedFields, fields)
+			tt.check(t, updated)
+		})
+	}
+}
@@ -4,9 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math/rand"
 	"net/url"
-	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,7 +17,26 @@ import (
 	"github.com/dtroode/urlshorter/internal/storage"
 )
 
-const deleteBatchSize = 10
+const (
+	deleteBatchSize = 10
+	// saveClickTimeout is the time given to the storage to save a click event.
+	saveClickTimeout = 5 * time.Second
+	// keyAttemptsPerLength is the number of short key conflicts in a row
+	// after which generated keys become one character longer.
+	keyAttemptsPerLength = 3
+	// maxKeyAttempts is the number of attempts to save a URL with a generated key.
+	maxKeyAttempts = 3 * keyAttemptsPerLength
+	// maxShortKeyLength is the maximum length of a generated key.
+	// It matches the size of the short_key column.
+	maxShortKeyLength = 32
+)
+
+// KeyGenerator defines the interface for short key generation strategies.
+type KeyGenerator interface {
+	// Generate returns a new short key of the given length.
+	// Some strategies may return longer keys when the requested length is exhausted.
+	Generate(length int) (string, error)
+}
 
 // URLStorage defines the interface for URL storage operations.
 // It provides methods for storing, retrieving, and managing URL entities.
@@ -35,17 +53,49 @@ type URLStorage interface {
 	// Returns a slice of URL models or an error if retrieval fails.
 	GetURLsByUserID(ctx context.Context, userID uuid.UUID) ([]*model.URL, error)
 
+	// GetDeletedURLsByUserID retrieves all deleted URLs created by a specific user.
+	// Returns a slice of URL models or an error if retrieval fails.
+	GetDeletedURLsByUserID(ctx context.Context, userID uuid.UUID) ([]*model.URL, error)
+
 	// SetURL stores a single URL in the storage.
+	// Depending on scope, an existing URL with the same original URL may be returned
+	// instead together with storage.ErrConflict.
 	// Returns the saved URL model or an error if storage fails.
-	SetURL(ctx context.Context, url *model.URL) (*model.URL, error)
+	SetURL(ctx context.Context, url *model.URL, scope storage.DedupeScope) (*model.URL, error)
 
 	// SetURLs stores multiple URLs in the storage.
+	// Depending on scope, existing URLs with the same original URLs may be returned
+	// in place of some of urls, in the order of urls.
 	// Returns a slice of saved URL models or an error if storage fails.
-	SetURLs(ctx context.Context, urls []*model.URL) (savedURLs []*model.URL, err error)
+	SetURLs(ctx context.Context, urls []*model.URL, scope storage.DedupeScope) (savedURLs []*model.URL, err error)
 
 	// DeleteURLs marks the specified URLs as deleted.
 	// Returns an error if deletion fails.
 	DeleteURLs(ctx context.Context, ids []uuid.UUID) error
+
+	// RestoreURLs clears the deletion mark of the specified URLs.
+	// Returns an error if restoration fails.
+	RestoreURLs(ctx context.Context, ids []uuid.UUID) error
+
+	// PurgeDeletedURLs permanently removes at most limit URLs deleted before the given moment.
+	// Returns the number of removed URLs or an error if removal fails.
+	PurgeDeletedURLs(ctx context.Context, before time.Time, limit int) (int64, error)
+
+	// UpdateURL replaces the mutable attributes in fields of an existing URL with the ones of url.
+	// Returns the updated URL model or storage.ErrNotFound if the URL doesn't exist or is deleted.
+	UpdateURL(ctx context.Context, url *model.URL, fields storage.URLFields) (*model.URL, error)
+
+	// DecrementClicksLeft atomically uses up one redirect of a click-limited URL.
+	// Returns storage.ErrNoClicksLeft if the URL has no clicks left.
+	DecrementClicksLeft(ctx context.Context, id uuid.UUID) error
+
+	// SaveClick stores a single click event.
+	// Returns an error if storage fails.
+	SaveClick(ctx context.Context, click *model.Click) error
+
+	// GetClickStats aggregates the click events of a URL, counting clicks per bucket of the given size.
+	// Returns the aggregate or an error if retrieval fails.
+	GetClickStats(ctx context.Context, urlID uuid.UUID, bucket time.Duration) (*model.ClickStats, error)
 }
 
 // URL represents the URL shortening service.
@@ -54,35 +104,68 @@ type URLStorage interface {
 type URL struct {
 	// baseURL is the base URL for generating shortened URLs.
 	baseURL string
-	// shortKeyLength is the length of generated short keys.
+	// shortKeyLength is the initial length of generated short keys.
 	shortKeyLength int
+	// grownKeyLength is the length of generated short keys after it has been increased
+	// because of conflicts. Zero means shortKeyLength is used.
+	grownKeyLength atomic.Int64
+	// keyGenerator is the strategy used to generate short keys.
+	keyGenerator KeyGenerator
+	// normalizer validates original URLs and brings them to a canonical form.
+	normalizer URLNormalizer
+	// dedupeScope defines which existing URLs are returned when an original URL is shortened again.
+	dedupeScope storage.DedupeScope
+	// linkTokens issues and validates tokens that give access to password-protected URLs.
+	linkTokens LinkTokens
+	// passwordAttempts limits the number of passwords entered for a URL.
+	passwordAttempts *attemptLimiter
 	// storage is the storage interface for URL persistence.
 	storage URLStorage
 	// pool is the worker pool for background operations.
 	pool *workerpool.Pool
+	// clicks saves click events in the background.
+	clicks *clickWriter
 }
 
 // NewURL creates a new URL service instance with the provided configuration.
 //
 // Parameters:
 //   - baseURL: The base URL for generating shortened URLs
-//   - shortKeyLength: The length of generated short keys
+//   - shortKeyLength: The initial length of generated short keys
+//   - keyGenerator: The strategy used to generate short keys
+//   - normalizer: The validator of original URLs
+//   - dedupeScope: The scope in which original URLs are deduplicated
+//   - linkTokens: The issuer of tokens that give access to password-protected URLs
 //   - concurrencyLimit: The maximum number of concurrent workers
 //   - queueSize: The size of the worker pool queue
+//   - clickWorkers: The number of workers saving click events
+//   - clickQueueSize: The size of the click events queue
 //   - storage: The storage implementation for URL persistence
 //
 // Returns a pointer to the newly created URL service instance.
 func NewURL(
 	baseURL string,
 	shortKeyLength int,
+	keyGenerator KeyGenerator,
+	normalizer URLNormalizer,
+	dedupeScope storage.DedupeScope,
+	linkTokens LinkTokens,
 	concurrencyLimit int,
 	queueSize int,
+	clickWorkers int,
+	clickQueueSize int,
 	storage URLStorage,
 ) *URL {
 	service := &URL{
-		baseURL:        baseURL,
-		shortKeyLength: shortKeyLength,
-		storage:        storage,
+		baseURL:          baseURL,
+		shortKeyLength:   shortKeyLength,
+		keyGenerator:     keyGenerator,
+		normalizer:       normalizer,
+		dedupeScope:      dedupeScope,
+		linkTokens:       linkTokens,
+		passwordAttempts: newAttemptLimiter(maxPasswordAttempts, passwordAttemptsWindow),
+		storage:          storage,
+		clicks:           newClickWriter(storage, clickWorkers, clickQueueSize),
 	}
 
 	pool := workerpool.NewPool(concurrencyLimit, queueSize)
@@ -92,31 +175,81 @@ func NewURL(
 	return service
 }
 
-func (s *URL) generateString() string {
-	var characters = []rune("ABCDEF0123456789")
-	var sb strings.Builder
-	sb.Grow(s.shortKeyLength)
+// keyLength returns the current length of generated short keys.
+func (s *URL) keyLength() int {
+	if length := s.grownKeyLength.Load(); length > 0 {
+		return int(length)
+	}
+
+	return s.shortKeyLength
+}
+
+// growKeyLength increases the length of generated short keys by one
+// unless it has already grown past current or reached maxShortKeyLength.
+func (s *URL) growKeyLength(current int) {
+	if current >= maxShortKeyLength {
+		return
+	}
+
+	for {
+		stored := s.grownKeyLength.Load()
+		if max(int(stored), s.shortKeyLength) > current {
+			return
+		}
+		if s.grownKeyLength.CompareAndSwap(stored, int64(current+1)) {
+			return
+		}
+	}
+}
+
+// generateKey returns a new short key of the given length.
+func (s *URL) generateKey(length int) (string, error) {
+	key, err := s.keyGenerator.Generate(length)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate short key: %w", err)
+	}
+
+	return key, nil
+}
+
+// retryOnKeyConflict calls save until it stops failing with storage.ErrShortKeyConflict
+// or maxKeyAttempts is reached. Save receives the length of keys it should generate.
+// The length grows after keyAttemptsPerLength conflicts in a row, which happens
+// as the keyspace of the current length fills up.
+func (s *URL) retryOnKeyConflict(save func(keyLength int) error) error {
+	var err error
+
+	for attempt := 1; attempt <= maxKeyAttempts; attempt++ {
+		length := s.keyLength()
+
+		err = save(length)
+		if !errors.Is(err, storage.ErrShortKeyConflict) {
+			return err
+		}
 
-	for range s.shortKeyLength {
-		randomIndex := rand.Intn(len(characters))
-		randomChar := characters[randomIndex]
-		sb.WriteRune(randomChar)
+		if attempt%keyAttemptsPerLength == 0 {
+			s.growKeyLength(length)
+		}
 	}
 
-	return sb.String()
+	return err
 }
 
 // GetOriginalURL retrieves the original URL associated with a short key.
+// Every successful lookup is recorded as a click event in the background.
+// Password-protected URLs are resolved only with an access token issued by UnlockURL.
+// Every redirect of a click-limited URL uses up one of its clicks.
 //
 // Parameters:
 //   - ctx: The request context
-//   - shortKey: The short key to look up
+//   - dto: The DTO containing the short key and information about the client
 //
 // Returns the original URL string or an error if not found or deleted.
 // Returns ErrNotFound if the URL doesn't exist.
-// Returns ErrGone if the URL has been deleted.
-func (s *URL) GetOriginalURL(ctx context.Context, shortKey string) (string, error) {
-	url, err := s.storage.GetURL(ctx, shortKey)
+// Returns ErrGone if the URL has been deleted, has expired or has no clicks left.
+// Returns ErrPasswordRequired if the URL is password-protected and the access token is missing or invalid.
+func (s *URL) GetOriginalURL(ctx context.Context, dto *dto.GetOriginalURL) (string, error) {
+	url, err := s.storage.GetURL(ctx, dto.ShortKey)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			return "", ErrNotFound
@@ -124,32 +257,125 @@ func (s *URL) GetOriginalURL(ctx context.Context, shortKey string) (string, erro
 		return "", fmt.Errorf("failed to get original URL: %w", err)
 	}
 
-	if url.DeletedAt != nil {
+	now := time.Now()
+	if url.DeletedAt != nil || url.IsExpired(now) || !url.HasClicksLeft() {
 		return "", ErrGone
 	}
 
+	if url.IsProtected() && !s.hasLinkAccess(ctx, url, dto.AccessToken) {
+		return "", ErrPasswordRequired
+	}
+
+	if url.ClicksLeft != nil {
+		if err := s.storage.DecrementClicksLeft(ctx, url.ID); err != nil {
+			if errors.Is(err, storage.ErrNoClicksLeft) {
+				return "", ErrGone
+			}
+			return "", fmt.Errorf("failed to decrement clicks left: %w", err)
+		}
+	}
+
+	click := model.NewClick(url.ID, now.UTC(), dto.Referrer, dto.UserAgent, anonymizeIP(dto.ClientIP))
+	s.recordClick(click)
+
 	return url.OriginalURL, nil
 }
 
+// recordClick saves the click event using the click writer.
+// It doesn't wait for the event to be saved, so redirects are not slowed down by the storage.
+// If the click events queue is full, the event is dropped and counted instead.
+func (s *URL) recordClick(click *model.Click) {
+	s.clicks.write(click)
+}
+
+// DroppedClicks returns the number of click events dropped because the click events queue was full.
+func (s *URL) DroppedClicks() int64 {
+	return s.clicks.dropped.Load()
+}
+
 // CreateShortURL creates a new shortened URL from the provided DTO.
 // Generates a unique short key and stores the URL mapping.
+// If the original URL has already been shortened within the dedupe scope,
+// the existing shortened URL is returned unless dto.ForceNew is set.
+// Password-protected and click-limited URLs are never reused.
 //
 // Parameters:
 //   - ctx: The request context
 //   - dto: The DTO containing the original URL and user ID
 //
 // Returns the shortened URL string or an error if creation fails.
-// Returns ErrConflict if the URL already exists.
+// Returns ErrConflict if the URL already exists within the dedupe scope.
+// Returns ErrInvalidAlias if the requested alias is malformed.
+// Returns ErrAliasTaken if the requested alias is used by another URL.
+// Returns ErrInvalidExpiration if the requested expiration is invalid.
+// Returns ErrInvalidURL if the original URL can't be shortened.
+// Returns ErrInvalidPassword if the requested password is invalid.
+// Returns ErrInvalidMaxClicks if the requested click limit is invalid.
 func (s *URL) CreateShortURL(ctx context.Context, dto *dto.CreateShortURL) (string, error) {
-	shortKey := s.generateString()
+	var savedURL *model.URL
 	var responseError error
 
-	urlModel := model.NewURL(shortKey, dto.OriginalURL, dto.UserID)
-	savedURL, err := s.storage.SetURL(ctx, urlModel)
+	originalURL, err := s.normalizer.Normalize(dto.OriginalURL)
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt, err := resolveExpiration(time.Now(), dto.TTL, dto.ExpiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	clicksLeft, err := resolveClicksLeft(dto.MaxClicks)
+	if err != nil {
+		return "", err
+	}
+
+	var passwordHash string
+	if dto.Password != "" {
+		passwordHash, err = hashPassword(dto.Password)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	dedupeScope := s.dedupeScopeFor(dto.ForceNew)
+
+	if dto.Alias != "" {
+		if err := validateAlias(dto.Alias); err != nil {
+			return "", err
+		}
+
+		urlModel := model.NewURL(dto.Alias, originalURL, dto.UserID)
+		urlModel.ExpiresAt = expiresAt
+		urlModel.PasswordHash = passwordHash
+		urlModel.ClicksLeft = clicksLeft
+		savedURL, err = s.storage.SetURL(ctx, urlModel, dedupeScope)
+		if errors.Is(err, storage.ErrShortKeyConflict) {
+			savedURL, err = s.resolveAliasConflict(ctx, urlModel)
+		}
+	} else {
+		err = s.retryOnKeyConflict(func(keyLength int) error {
+			shortKey, err := s.generateKey(keyLength)
+			if err != nil {
+				return err
+			}
+
+			urlModel := model.NewURL(shortKey, originalURL, dto.UserID)
+			urlModel.ExpiresAt = expiresAt
+			urlModel.PasswordHash = passwordHash
+			urlModel.ClicksLeft = clicksLeft
+
+			savedURL, err = s.storage.SetURL(ctx, urlModel, dedupeScope)
+			return err
+		})
+	}
 	if err != nil && !errors.Is(err, storage.ErrConflict) {
+		if errors.Is(err, ErrAliasTaken) {
+			return "", err
+		}
 		return "", fmt.Errorf("failed to set URL: %w", err)
 	}
-	shortKey = savedURL.ShortKey
+	shortKey := savedURL.ShortKey
 
 	if errors.Is(err, storage.ErrConflict) {
 		responseError = ErrConflict
@@ -163,47 +389,239 @@ func (s *URL) CreateShortURL(ctx context.Context, dto *dto.CreateShortURL) (stri
 	return shortURL, responseError
 }
 
+// dedupeScopeFor returns the dedupe scope of a request to shorten URLs.
+// Requests with forceNew always create new URLs.
+func (s *URL) dedupeScopeFor(forceNew bool) storage.DedupeScope {
+	if forceNew {
+		return storage.DedupeNone
+	}
+	if s.dedupeScope == "" {
+		return storage.DedupeGlobal
+	}
+
+	return s.dedupeScope
+}
+
+// resolveAliasConflict decides what to report when the alias of urlModel is already taken.
+// Resubmitting the same alias for the same URL by its owner is treated as an ordinary
+// conflict and returns the existing URL, any other use of the alias returns ErrAliasTaken.
+// Only URLs that can be reused are resubmitted this way.
+func (s *URL) resolveAliasConflict(ctx context.Context, urlModel *model.URL) (*model.URL, error) {
+	existing, err := s.storage.GetURL(ctx, urlModel.ShortKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrAliasTaken
+		}
+		return nil, err
+	}
+
+	if canReuseAlias(existing, urlModel) {
+		return existing, storage.ErrConflict
+	}
+
+	return nil, ErrAliasTaken
+}
+
+// canReuseAlias reports whether existing, which has the alias requested for urlModel,
+// is a resubmission of the same URL by its owner and can be returned in place of urlModel.
+func canReuseAlias(existing, urlModel *model.URL) bool {
+	return existing.UserID == urlModel.UserID && existing.OriginalURL == urlModel.OriginalURL && existing.DeletedAt == nil &&
+		existing.CanBeReused() && urlModel.CanBeReused()
+}
+
+// reuseBatchAliases looks up the aliases of a batch and decides what to do with the ones already taken,
+// the way resolveAliasConflict does for a single URL.
+// aliasIndexes maps aliases to indexes of the batch items that request them.
+// Returns the existing URLs to use for batch items, keyed by their indexes,
+// or ErrAliasTaken if an alias is used by another URL.
+func (s *URL) reuseBatchAliases(
+	ctx context.Context,
+	userID uuid.UUID,
+	aliasIndexes map[string]int,
+	originalURLs []string,
+	passwordHashes []string,
+	clicksLeft []*int64,
+) (map[int]*model.URL, error) {
+	reused := make(map[int]*model.URL)
+	if len(aliasIndexes) == 0 {
+		return reused, nil
+	}
+
+	aliases := make([]string, 0, len(aliasIndexes))
+	for alias := range aliasIndexes {
+		aliases = append(aliases, alias)
+	}
+
+	existingURLs, err := s.storage.GetURLs(ctx, aliases)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get urls: %w", err)
+	}
+
+	for _, existing := range existingURLs {
+		i, ok := aliasIndexes[existing.ShortKey]
+		if !ok {
+			continue
+		}
+
+		urlModel := &model.URL{OriginalURL: originalURLs[i], UserID: userID, PasswordHash: passwordHashes[i], ClicksLeft: clicksLeft[i]}
+		if !canReuseAlias(existing, urlModel) {
+			return nil, ErrAliasTaken
+		}
+		reused[i] = existing
+	}
+
+	return reused, nil
+}
+
 // CreateShortURLBatch creates multiple shortened URLs in a single operation.
-// Processes all URLs in the batch and returns results with correlation IDs.
+// Processes all URLs in the batch and returns results with correlation IDs
+// in the order of the batch. Original URLs are deduplicated the way CreateShortURL
+// does it, unless dto.ForceNew is set. Password-protected and click-limited URLs are never reused.
 //
 // Parameters:
 //   - ctx: The request context
 //   - dto: The DTO containing the batch of URLs to shorten
 //
 // Returns a slice of created URLs with their correlation IDs or an error if creation fails.
+// Returns ErrInvalidAlias if any requested alias is malformed.
+// Returns ErrAliasTaken if any requested alias is used by another URL. An alias resubmitted
+// for the same URL by its owner returns the existing URL, like CreateShortURL does.
+// Returns ErrInvalidExpiration if any requested expiration is invalid.
+// Returns ErrInvalidURL if any original URL can't be shortened.
+// Returns ErrInvalidPassword if any requested password is invalid.
+// Returns ErrInvalidMaxClicks if any requested click limit is invalid.
 func (s *URL) CreateShortURLBatch(ctx context.Context, dto *dto.CreateShortURLBatch) ([]*response.CreateShortURLBatch, error) {
-	resp := make([]*response.CreateShortURLBatch, 0)
+	originalURLs := make([]string, len(dto.URLs))
+	for i, reqURL := range dto.URLs {
+		originalURL, err := s.normalizer.Normalize(reqURL.OriginalURL)
+		if err != nil {
+			return nil, fmt.Errorf("correlation id %q: %w", reqURL.CorrelationID, err)
+		}
+		originalURLs[i] = originalURL
+	}
 
-	urlModels := make([]*model.URL, 0)
+	now := time.Now()
+	expirations := make([]*time.Time, len(dto.URLs))
+	for i, reqURL := range dto.URLs {
+		expiresAt, err := resolveExpiration(now, reqURL.TTL, reqURL.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		expirations[i] = expiresAt
+	}
 
-	for _, reqURL := range dto.URLs {
-		shortKey := s.generateString()
+	clicksLeft := make([]*int64, len(dto.URLs))
+	for i, reqURL := range dto.URLs {
+		left, err := resolveClicksLeft(reqURL.MaxClicks)
+		if err != nil {
+			return nil, err
+		}
+		clicksLeft[i] = left
+	}
 
-		urlModel := model.NewURL(shortKey, reqURL.OriginalURL, dto.UserID)
-		urlModels = append(urlModels, urlModel)
+	passwordHashes := make([]string, len(dto.URLs))
+	for i, reqURL := range dto.URLs {
+		if reqURL.Password == "" {
+			continue
+		}
+		passwordHash, err := hashPassword(reqURL.Password)
+		if err != nil {
+			return nil, fmt.Errorf("correlation id %q: %w", reqURL.CorrelationID, err)
+		}
+		passwordHashes[i] = passwordHash
 	}
 
-	savedURLs, err := s.storage.SetURLs(ctx, urlModels)
-	if err != nil {
-		return nil, fmt.Errorf("failed to set urls: %w", err)
+	aliasIndexes := make(map[string]int)
+	for i, reqURL := range dto.URLs {
+		if reqURL.Alias == "" {
+			continue
+		}
+		if err := validateAlias(reqURL.Alias); err != nil {
+			return nil, err
+		}
+		if _, ok := aliasIndexes[reqURL.Alias]; ok {
+			return nil, ErrAliasTaken
+		}
+		aliasIndexes[reqURL.Alias] = i
 	}
 
-	for _, reqURL := range dto.URLs {
-		respURL := response.CreateShortURLBatch{
-			CorrelationID: reqURL.CorrelationID,
+	dedupeScope := s.dedupeScopeFor(dto.ForceNew)
+
+	// batchURLs holds the URLs saved or reused for the items of the batch, in the order of the batch.
+	var batchURLs []*model.URL
+	err := s.retryOnKeyConflict(func(keyLength int) error {
+		// Aliases are checked on every attempt, so that an alias taken since the previous
+		// attempt is reported right away instead of being retried, which can't succeed.
+		reused, err := s.reuseBatchAliases(ctx, dto.UserID, aliasIndexes, originalURLs, passwordHashes, clicksLeft)
+		if err != nil {
+			return err
 		}
 
-		for _, savedURL := range savedURLs {
-			if reqURL.OriginalURL == savedURL.OriginalURL {
-				shortURL, err := url.JoinPath(s.baseURL, savedURL.ShortKey)
+		urlModels := make([]*model.URL, 0, len(dto.URLs))
+
+		for i, reqURL := range dto.URLs {
+			if _, ok := reused[i]; ok {
+				continue
+			}
+
+			shortKey := reqURL.Alias
+			if shortKey == "" {
+				var err error
+				shortKey, err = s.generateKey(keyLength)
 				if err != nil {
-					return nil, ErrInternal
+					return err
 				}
+			}
+
+			urlModel := model.NewURL(shortKey, originalURLs[i], dto.UserID)
+			urlModel.ExpiresAt = expirations[i]
+			urlModel.PasswordHash = passwordHashes[i]
+			urlModel.ClicksLeft = clicksLeft[i]
+			urlModels = append(urlModels, urlModel)
+		}
 
-				respURL.ShortURL = shortURL
-				resp = append(resp, &respURL)
+		var savedURLs []*model.URL
+		if len(urlModels) > 0 {
+			savedURLs, err = s.storage.SetURLs(ctx, urlModels, dedupeScope)
+			if err != nil {
+				return err
 			}
 		}
+
+		// Saved URLs are returned in the order of urlModels.
+		if len(savedURLs) != len(urlModels) {
+			return fmt.Errorf("storage saved %d urls for a batch of %d", len(savedURLs), len(urlModels))
+		}
+		batchURLs = make([]*model.URL, 0, len(dto.URLs))
+		for i := range dto.URLs {
+			if existing, ok := reused[i]; ok {
+				batchURLs = append(batchURLs, existing)
+				continue
+			}
+			batchURLs = append(batchURLs, savedURLs[0])
+			savedURLs = savedURLs[1:]
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrAliasTaken) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to set urls: %w", err)
+	}
+
+	resp := make([]*response.CreateShortURLBatch, len(dto.URLs))
+	for i, reqURL := range dto.URLs {
+		shortURL, err := url.JoinPath(s.baseURL, batchURLs[i].ShortKey)
+		if err != nil {
+			return nil, ErrInternal
+		}
+
+		resp[i] = &response.CreateShortURLBatch{
+			CorrelationID: reqURL.CorrelationID,
+			ShortURL:      shortURL,
+		}
 	}
 
 	return resp, nil
@@ -228,23 +646,37 @@ func (s *URL) GetUserURLs(ctx context.Context, userID uuid.UUID) ([]*response.Ge
 	}
 
 	resp := make([]*response.GetUserURL, len(urls))
+	now := time.Now()
 
 	for i, u := range urls {
-		shortURL, err := url.JoinPath(s.baseURL, u.ShortKey)
+		respURL, err := s.userURL(u, now)
 		if err != nil {
-			return nil, ErrInternal
-		}
-
-		respURL := response.GetUserURL{
-			ShortURL:    shortURL,
-			OriginalURL: u.OriginalURL,
+			return nil, err
 		}
-		resp[i] = &respURL
+		resp[i] = respURL
 	}
 
 	return resp, nil
 }
 
+// userURL converts u into the representation of a URL shown to its owner.
+func (s *URL) userURL(u *model.URL, now time.Time) (*response.GetUserURL, error) {
+	shortURL, err := url.JoinPath(s.baseURL, u.ShortKey)
+	if err != nil {
+		return nil, ErrInternal
+	}
+
+	return &response.GetUserURL{
+		ShortURL:    shortURL,
+		OriginalURL: u.OriginalURL,
+		ExpiresAt:   u.ExpiresAt,
+		Expired:     u.IsExpired(now),
+		Protected:   u.IsProtected(),
+		ClicksLeft:  u.ClicksLeft,
+		DeletedAt:   u.DeletedAt,
+	}, nil
+}
+
 // DeleteURLs marks the specified URLs as deleted for the given user.
 // This operation is performed asynchronously using a worker pool.
 //
@@ -274,14 +706,7 @@ func (s *URL) deleteURLsJob(dto *dto.DeleteURLs) func(context.Context) (any, err
 			return nil, err
 		}
 
-		ids := make([]uuid.UUID, 0)
-		for _, url := range urls {
-			if url.UserID == dto.UserID {
-				ids = append(ids, url.ID)
-			}
-		}
-
-		err = s.storage.DeleteURLs(ctx, ids)
+		err = s.storage.DeleteURLs(ctx, ownedURLIDs(urls, dto.UserID))
 		if err != nil {
 			return nil, err
 		}
@@ -290,6 +715,18 @@ func (s *URL) deleteURLsJob(dto *dto.DeleteURLs) func(context.Context) (any, err
 	}
 }
 
+// ownedURLIDs returns IDs of urls created by the user with the given ID.
+func ownedURLIDs(urls []*model.URL, userID uuid.UUID) []uuid.UUID {
+	ids := make([]uuid.UUID, 0)
+	for _, url := range urls {
+		if url.UserID == userID {
+			ids = append(ids, url.ID)
+		}
+	}
+
+	return ids
+}
+
 func splitIntoBatches[T any](items []T, batchSize int) [][]T {
 	count := (len(items) + batchSize - 1) / batchSize
 	batches := make([][]T, 0, count)
@@ -16,6 +16,7 @@ import (
 	"github.com/dtroode/urlshorter/internal/request"
 	"github.com/dtroode/urlshorter/internal/service/dto"
 	"github.com/dtroode/urlshorter/internal/service/mocks"
+	"github.com/dtroode/urlshorter/internal/storage"
 )
 
 // Оригинальная реализация с math/rand
@@ -200,9 +201,9 @@ func BenchmarkURL_CreateShortURLBatch(b *testing.B) {
 	batchSizes := []int{10, 20, 100}
 	ctx := context.Background()
 	userID := uuid.New()
-	storage := mocks.NewURLStorage(b)
+	urlStorage := mocks.NewURLStorage(b)
 
-	svc := NewURL("http://localhost:8080", 10, 10, 10, storage)
+	svc := NewURL("http://localhost:8080", 10, testKeyGenerator, URLNormalizer{}, storage.DedupeGlobal, nil, 10, 10, 10, 0, urlStorage)
 
 	for _, batchSize := range batchSizes {
 		urls := make([]*request.CreateShortURLBatch, 0)
@@ -223,7 +224,7 @@ func BenchmarkURL_CreateShortURLBatch(b *testing.B) {
 			})
 		}
 
-		storage.On("SetURLs", mock.Anything, mock.Anything).Return(urlModels, nil)
+		urlStorage.On("SetURLs", mock.Anything, mock.Anything, mock.Anything).Return(urlModels, nil)
 		b.Run(fmt.Sprintf("BatchSize_%d", batchSize), func(b *testing.B) {
 			b.ResetTimer()
 
@@ -242,9 +243,9 @@ func BenchmarkURL_DeleteURLs(b *testing.B) {
 	urlsCount := 30
 	ctx := context.Background()
 	userID := uuid.New()
-	storage := mocks.NewURLStorage(b)
+	urlStorage := mocks.NewURLStorage(b)
 
-	svc := NewURL("http://localhost:8080", 10, 10, 10, storage)
+	svc := NewURL("http://localhost:8080", 10, testKeyGenerator, URLNormalizer{}, storage.DedupeGlobal, nil, 10, 10, 10, 0, urlStorage)
 
 	for _, batchSize := range batchSizes {
 		shortKeys := make([]string, 0)
@@ -263,7 +264,7 @@ func BenchmarkURL_DeleteURLs(b *testing.B) {
 			urlMap[shortKey] = urlModel
 		}
 
-		storage.On("GetURLs", mock.Anything, mock.Anything).Return(func(ctx context.Context, keys []string) ([]*model.URL, error) {
+		urlStorage.On("GetURLs", mock.Anything, mock.Anything).Return(func(ctx context.Context, keys []string) ([]*model.URL, error) {
 			result := make([]*model.URL, 0, len(keys))
 			for _, key := range keys {
 				if url, exists := urlMap[key]; exists {
@@ -273,7 +274,7 @@ func BenchmarkURL_DeleteURLs(b *testing.B) {
 			return result, nil
 		}, nil)
 
-		storage.On("DeleteURLs", mock.Anything, mock.Anything).Return(nil)
+		urlStorage.On("DeleteURLs", mock.Anything, mock.Anything).Return(nil)
 
 		b.Run(fmt.Sprintf("BatchSize_%d", batchSize), func(b *testing.B) {
 			b.ResetTimer()
@@ -18,14 +18,20 @@ import (
 	"github.com/dtroode/urlshorter/internal/request"
 	"github.com/dtroode/urlshorter/internal/response"
 	"github.com/dtroode/urlshorter/internal/service/dto"
+	"github.com/dtroode/urlshorter/internal/service/keygen"
 	"github.com/dtroode/urlshorter/internal/service/mocks"
+	"github.com/dtroode/urlshorter/internal/service/workerpool"
 	"github.com/dtroode/urlshorter/internal/storage"
 )
 
+var testKeyGenerator = keygen.NewRandom(keygen.Base62Alphabet)
+
 func TestURL_GetOriginalURL(t *testing.T) {
 	originalURL := "yandex.ru"
 	shortKey := "C69F32242B"
 	deletedAt := time.Now()
+	expiredAt := time.Now().Add(-time.Minute)
+	expiresAt := time.Now().Add(time.Hour)
 
 	tests := map[string]struct {
 		shortKey         string
@@ -54,6 +60,26 @@ func TestURL_GetOriginalURL(t *testing.T) {
 			},
 			expectedError: ErrGone,
 		},
+		"expired": {
+			shortKey: shortKey,
+			storageResponse: &model.URL{
+				ID:          uuid.New(),
+				ShortKey:    shortKey,
+				OriginalURL: originalURL,
+				ExpiresAt:   &expiredAt,
+			},
+			expectedError: ErrGone,
+		},
+		"not expired yet": {
+			shortKey: shortKey,
+			storageResponse: &model.URL{
+				ID:          uuid.New(),
+				ShortKey:    shortKey,
+				OriginalURL: originalURL,
+				ExpiresAt:   &expiresAt,
+			},
+			expectedResponse: originalURL,
+		},
 		"success": {
 			shortKey: shortKey,
 			storageResponse: &model.URL{
@@ -70,25 +96,81 @@ func TestURL_GetOriginalURL(t *testing.T) {
 		t.Run(tn, func(t *testing.T) {
 			ctx := context.Background()
 
+			clicks := make(chan *model.Click, 1)
 			urlStorage := mocks.NewURLStorage(t)
 			urlStorage.On("GetURL", ctx, shortKey).Once().Return(tt.storageResponse, tt.storageError)
+			urlStorage.On("SaveClick", mock.Anything, mock.Anything).Maybe().Run(func(args mock.Arguments) {
+				clicks <- args.Get(1).(*model.Click)
+			}).Return(nil)
 			service := URL{
 				shortKeyLength: 10,
+				keyGenerator:   testKeyGenerator,
 				storage:        urlStorage,
+				clicks:         newTestClickWriter(t, urlStorage),
 			}
 
-			resp, err := service.GetOriginalURL(ctx, shortKey)
+			dto := dto.NewGetOriginalURL(shortKey, "https://ya.ru/", "Mozilla/5.0", "192.168.1.42")
+			resp, err := service.GetOriginalURL(ctx, dto)
 
 			if tt.expectedError != nil {
 				assert.Equal(t, tt.expectedError, err)
 			} else {
 				require.NoError(t, err)
 				assert.Equal(t, tt.expectedResponse, resp)
+
+				select {
+				case click := <-clicks:
+					assert.Equal(t, tt.storageResponse.ID, click.URLID)
+					assert.Equal(t, "https://ya.ru/", click.Referrer)
+					assert.Equal(t, "Mozilla/5.0", click.UserAgent)
+					assert.Equal(t, "192.168.1.0", click.ClientIP)
+				case <-time.After(time.Second):
+					t.Error("click was not saved")
+				}
 			}
 		})
 	}
 }
 
+func TestURL_GetOriginalURL_DropsClicksWhenQueueIsFull(t *testing.T) {
+	ctx := context.Background()
+	url := &model.URL{ID: uuid.New(), ShortKey: "C69F32242B", OriginalURL: "yandex.ru"}
+
+	urlStorage := mocks.NewURLStorage(t)
+	urlStorage.On("GetURL", ctx, url.ShortKey).Return(url, nil)
+	// The click writer has no workers, so the queue fills up after the first click.
+	clicks := newClickWriter(urlStorage, 0, 1)
+	t.Cleanup(clicks.close)
+	service := URL{
+		storage: urlStorage,
+		clicks:  clicks,
+	}
+
+	for range 3 {
+		resp, err := service.GetOriginalURL(ctx, dto.NewGetOriginalURL(url.ShortKey, "", "", ""))
+		require.NoError(t, err)
+		assert.Equal(t, url.OriginalURL, resp)
+	}
+
+	assert.Equal(t, int64(2), service.DroppedClicks())
+	urlStorage.AssertNotCalled(t, "SaveClick", mock.Anything, mock.Anything)
+}
+
+func newTestClickWriter(t *testing.T, saver clickSaver) *clickWriter {
+	clicks := newClickWriter(saver, 1, 0)
+	t.Cleanup(clicks.close)
+
+	return clicks
+}
+
+func newTestPool(t *testing.T) *workerpool.Pool {
+	pool := workerpool.NewPool(1, 0)
+	pool.Start()
+	t.Cleanup(pool.Close)
+
+	return pool
+}
+
 func TestURL_CreateShortURL(t *testing.T) {
 	userID := uuid.New()
 
@@ -103,27 +185,27 @@ func TestURL_CreateShortURL(t *testing.T) {
 		expectedError        error
 	}{
 		"storage error": {
-			originalURL:   "yandex.ru",
+			originalURL:   "https://yandex.ru",
 			setURLError:   errors.New("storage error"),
 			expectedError: fmt.Errorf("failed to set URL: %w", errors.New("storage error")),
 		},
 		// ascii control character used here as base URL
 		// this causes url.JoinPath to fail
 		"failed to join path": {
-			originalURL: "yandex.ru",
+			originalURL: "https://yandex.ru",
 			baseURL:     string(rune(0x7f)),
 			setURLResponse: &model.URL{
-				OriginalURL: "yandex.ru",
+				OriginalURL: "https://yandex.ru",
 				ShortKey:    "ABCDE",
 			},
 			shortKeyLength: 10,
 			expectedError:  ErrInternal,
 		},
 		"url already exists": {
-			originalURL: "yandex.ru",
+			originalURL: "https://yandex.ru",
 			baseURL:     "http://localhost",
 			setURLResponse: &model.URL{
-				OriginalURL: "yandex.ru",
+				OriginalURL: "https://yandex.ru",
 				ShortKey:    "ABCDE",
 			},
 			setURLError:          storage.ErrConflict,
@@ -133,10 +215,10 @@ func TestURL_CreateShortURL(t *testing.T) {
 			expectedError:        storage.ErrConflict,
 		},
 		"base url without last slash": {
-			originalURL: "yandex.ru",
+			originalURL: "https://yandex.ru",
 			baseURL:     "http://localhost",
 			setURLResponse: &model.URL{
-				OriginalURL: "yandex.ru",
+				OriginalURL: "https://yandex.ru",
 				ShortKey:    "ABCDE",
 			},
 			shortKeyLength:       10,
@@ -144,10 +226,10 @@ func TestURL_CreateShortURL(t *testing.T) {
 			expectedUrlmapLength: 1,
 		},
 		"base url with last slash": {
-			originalURL: "yandex.ru",
+			originalURL: "https://yandex.ru",
 			baseURL:     "http://localhost/",
 			setURLResponse: &model.URL{
-				OriginalURL: "yandex.ru",
+				OriginalURL: "https://yandex.ru",
 				ShortKey:    "ABCDE",
 			},
 			shortKeyLength:       10,
@@ -163,10 +245,11 @@ func TestURL_CreateShortURL(t *testing.T) {
 			ctx := context.Background()
 
 			urlStorage := mocks.NewURLStorage(t)
-			urlStorage.On("SetURL", mock.Anything, mock.Anything).Once().Return(tt.setURLResponse, tt.setURLError)
+			urlStorage.On("SetURL", mock.Anything, mock.Anything, mock.Anything).Once().Return(tt.setURLResponse, tt.setURLError)
 			service := URL{
 				baseURL:        tt.baseURL,
 				shortKeyLength: tt.shortKeyLength,
+				keyGenerator:   testKeyGenerator,
 				storage:        urlStorage,
 			}
 
@@ -177,10 +260,10 @@ func TestURL_CreateShortURL(t *testing.T) {
 			if tt.expectedError == nil {
 				assert.Equal(t, tt.expectedError, err)
 
-				urlStorage.AssertCalled(t, "SetURL", ctx,
+				urlStorage.AssertCalled(t, "SetURL", mock.Anything,
 					mock.MatchedBy(func(url *model.URL) bool {
 						return url.OriginalURL == tt.originalURL && url.UserID == userID
-					}))
+					}), mock.Anything)
 
 				assert.Len(t, shortURL, tt.expectedLength)
 				assert.True(t, strings.HasPrefix(shortURL, tt.baseURL))
@@ -209,11 +292,11 @@ func TestURL_CreateShortURLBatch(t *testing.T) {
 			originalURLs: []*request.CreateShortURLBatch{
 				{
 					CorrelationID: "1",
-					OriginalURL:   "yandex.ru",
+					OriginalURL:   "https://yandex.ru",
 				},
 				{
 					CorrelationID: "2",
-					OriginalURL:   "google.com",
+					OriginalURL:   "https://google.com",
 				},
 			},
 			baseURL:       "http://localhost/",
@@ -224,18 +307,18 @@ func TestURL_CreateShortURLBatch(t *testing.T) {
 			originalURLs: []*request.CreateShortURLBatch{
 				{
 					CorrelationID: "1",
-					OriginalURL:   "yandex.ru",
+					OriginalURL:   "https://yandex.ru",
 				},
 			},
 			baseURL: "http://localhost/",
 			savedURLs: []*model.URL{
 				{
-					OriginalURL: "yandex.ru",
+					OriginalURL: "https://yandex.ru",
 					ShortKey:    "ABOBA",
 				},
 			},
 			existingURL: &model.URL{
-				OriginalURL: "yandex.ru",
+				OriginalURL: "https://yandex.ru",
 				ShortKey:    "ABOBA",
 			},
 			shortKeyLength: 5,
@@ -251,13 +334,13 @@ func TestURL_CreateShortURLBatch(t *testing.T) {
 			originalURLs: []*request.CreateShortURLBatch{
 				{
 					CorrelationID: "1",
-					OriginalURL:   "yandex.ru",
+					OriginalURL:   "https://yandex.ru",
 				},
 			},
 			baseURL: "http://localhost/",
 			savedURLs: []*model.URL{
 				{
-					OriginalURL: "yandex.ru",
+					OriginalURL: "https://yandex.ru",
 					ShortKey:    "ABCDE",
 				},
 			},
@@ -276,19 +359,23 @@ func TestURL_CreateShortURLBatch(t *testing.T) {
 			originalURLs: []*request.CreateShortURLBatch{
 				{
 					CorrelationID: "1",
-					OriginalURL:   "yandex.ru",
+					OriginalURL:   "https://yandex.ru",
 				},
 				{
 					CorrelationID: "2",
-					OriginalURL:   "google.com",
+					OriginalURL:   "https://google.com",
 				},
 			},
 			baseURL: string(rune(0x7f)),
 			savedURLs: []*model.URL{
 				{
-					OriginalURL: "yandex.ru",
+					OriginalURL: "https://yandex.ru",
 					ShortKey:    "ABCDE",
 				},
+				{
+					OriginalURL: "https://google.com",
+					ShortKey:    "FGHIJ",
+				},
 			},
 			shortKeyLength: 5,
 			expectedError:  ErrInternal,
@@ -298,13 +385,13 @@ func TestURL_CreateShortURLBatch(t *testing.T) {
 			originalURLs: []*request.CreateShortURLBatch{
 				{
 					CorrelationID: "1",
-					OriginalURL:   "yandex.ru",
+					OriginalURL:   "https://yandex.ru",
 				},
 			},
 			baseURL: "http://localhost",
 			savedURLs: []*model.URL{
 				{
-					OriginalURL: "yandex.ru",
+					OriginalURL: "https://yandex.ru",
 					ShortKey:    "ABCDE",
 				},
 			},
@@ -326,11 +413,12 @@ func TestURL_CreateShortURLBatch(t *testing.T) {
 			ctx := context.Background()
 
 			urlStorage := mocks.NewURLStorage(t)
-			urlStorage.On("SetURLs", mock.Anything, mock.Anything).Maybe().Return(tt.savedURLs, tt.setURLsError)
+			urlStorage.On("SetURLs", mock.Anything, mock.Anything, mock.Anything).Maybe().Return(tt.savedURLs, tt.setURLsError)
 			urlStorage.On("GetURLByOriginal", mock.Anything, mock.Anything).Maybe().Return(tt.existingURL, tt.getURLByOriginalError)
 			service := URL{
 				baseURL:        tt.baseURL,
 				shortKeyLength: tt.shortKeyLength,
+				keyGenerator:   testKeyGenerator,
 				storage:        urlStorage,
 			}
 
@@ -353,13 +441,13 @@ func TestURL_CreateShortURLBatch(t *testing.T) {
 					shortKeys[i] = shortKey
 				}
 
-				urlStorage.AssertCalled(t, "SetURLs", ctx,
+				urlStorage.AssertCalled(t, "SetURLs", mock.Anything,
 					mock.MatchedBy(func(urls []*model.URL) bool {
 						for i, u := range urls {
 							return u.OriginalURL == tt.originalURLs[i].OriginalURL && u.UserID == userID
 						}
 						return true
-					}))
+					}), mock.Anything)
 			}
 		})
 	}
@@ -474,6 +562,7 @@ func TestURL_GetUserURLs(t *testing.T) {
 			service := URL{
 				baseURL:        tt.baseURL,
 				shortKeyLength: 5,
+				keyGenerator:   testKeyGenerator,
 				storage:        urlStorage,
 			}
 
@@ -518,7 +607,7 @@ func TestURL_DeleteURLs(t *testing.T) {
 			}).
 			Return(nil, errors.New("service error"))
 
-		service := NewURL("base", 3, 3, 15, urlStorage)
+		service := NewURL("base", 3, testKeyGenerator, URLNormalizer{}, storage.DedupeGlobal, nil, 3, 15, 1, 0, urlStorage)
 		service.DeleteURLs(context.Background(), dto)
 
 		wg.Wait()
@@ -544,10 +633,540 @@ func TestURL_DeleteURLs(t *testing.T) {
 			}).
 			Return(nil)
 
-		service := NewURL("base", 3, 3, 15, urlStorage)
+		service := NewURL("base", 3, testKeyGenerator, URLNormalizer{}, storage.DedupeGlobal, nil, 3, 15, 1, 0, urlStorage)
 		service.DeleteURLs(context.Background(), dto)
 
 		wg.Wait()
 		urlStorage.AssertExpectations(t)
 	})
 }
+
+func TestURL_CreateShortURL_Alias(t *testing.T) {
+	userID := uuid.New()
+	alias := "q3-launch"
+
+	tests := map[string]struct {
+		alias           string
+		setURLError     error
+		existingURL     *model.URL
+		expectedURL     string
+		expectedError   error
+		expectedErrorIs error
+	}{
+		"invalid alias": {
+			alias:           "a b",
+			expectedErrorIs: ErrInvalidAlias,
+		},
+		"alias saved": {
+			alias:       alias,
+			expectedURL: "http://localhost/q3-launch",
+		},
+		"alias taken by another user": {
+			alias:       alias,
+			setURLError: storage.ErrShortKeyConflict,
+			existingURL: &model.URL{
+				ShortKey:    alias,
+				OriginalURL: "https://example.com",
+				UserID:      uuid.New(),
+			},
+			expectedErrorIs: ErrAliasTaken,
+		},
+		"alias resubmitted by owner": {
+			alias:       alias,
+			setURLError: storage.ErrShortKeyConflict,
+			existingURL: &model.URL{
+				ShortKey:    alias,
+				OriginalURL: "https://yandex.ru",
+				UserID:      userID,
+			},
+			expectedURL:   "http://localhost/q3-launch",
+			expectedError: ErrConflict,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			urlStorage := mocks.NewURLStorage(t)
+			urlStorage.On("SetURL", mock.Anything, mock.MatchedBy(func(url *model.URL) bool {
+				return url.ShortKey == tt.alias
+			}), mock.Anything).Maybe().Return(func(_ context.Context, url *model.URL, _ storage.DedupeScope) (*model.URL, error) {
+				if tt.setURLError != nil {
+					return nil, tt.setURLError
+				}
+				return url, nil
+			})
+			urlStorage.On("GetURL", mock.Anything, tt.alias).Maybe().Return(tt.existingURL, nil)
+
+			service := URL{
+				baseURL:        "http://localhost",
+				shortKeyLength: 8,
+				keyGenerator:   testKeyGenerator,
+				storage:        urlStorage,
+			}
+
+			dto := dto.NewCreateShortURL("https://yandex.ru", userID)
+			dto.Alias = tt.alias
+			shortURL, err := service.CreateShortURL(ctx, dto)
+
+			if tt.expectedErrorIs != nil {
+				assert.ErrorIs(t, err, tt.expectedErrorIs)
+				return
+			}
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedURL, shortURL)
+		})
+	}
+}
+
+func TestURL_CreateShortURL_Expiration(t *testing.T) {
+	userID := uuid.New()
+	expiresAt := time.Now().Add(time.Hour).UTC()
+
+	tests := map[string]struct {
+		ttl           int64
+		expiresAt     *time.Time
+		expectedError error
+		check         func(t *testing.T, url *model.URL)
+	}{
+		"ttl": {
+			ttl: 60,
+			check: func(t *testing.T, url *model.URL) {
+				require.NotNil(t, url.ExpiresAt)
+				assert.WithinDuration(t, time.Now().Add(time.Minute), *url.ExpiresAt, 5*time.Second)
+			},
+		},
+		"expires at": {
+			expiresAt: &expiresAt,
+			check: func(t *testing.T, url *model.URL) {
+				assert.Equal(t, &expiresAt, url.ExpiresAt)
+			},
+		},
+		"no expiration": {
+			check: func(t *testing.T, url *model.URL) {
+				assert.Nil(t, url.ExpiresAt)
+			},
+		},
+		"invalid": {
+			ttl:           60,
+			expiresAt:     &expiresAt,
+			expectedError: ErrInvalidExpiration,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			urlStorage := mocks.NewURLStorage(t)
+			urlStorage.On("SetURL", mock.Anything, mock.Anything, mock.Anything).Maybe().Return(func(_ context.Context, url *model.URL, _ storage.DedupeScope) (*model.URL, error) {
+				return url, nil
+			})
+
+			service := URL{
+				baseURL:        "http://localhost",
+				shortKeyLength: 5,
+				keyGenerator:   testKeyGenerator,
+				storage:        urlStorage,
+			}
+
+			dto := dto.NewCreateShortURL("https://yandex.ru", userID)
+			dto.TTL = tt.ttl
+			dto.ExpiresAt = tt.expiresAt
+			_, err := service.CreateShortURL(ctx, dto)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				urlStorage.AssertNotCalled(t, "SetURL", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			require.NoError(t, err)
+			urlStorage.AssertCalled(t, "SetURL", mock.Anything, mock.MatchedBy(func(url *model.URL) bool {
+				tt.check(t, url)
+				return true
+			}), mock.Anything)
+		})
+	}
+}
+
+func TestURL_GetUserURLs_Expired(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	expiredAt := time.Now().Add(-time.Minute)
+	expiresAt := time.Now().Add(time.Hour)
+
+	urlStorage := mocks.NewURLStorage(t)
+	urlStorage.On("GetURLsByUserID", ctx, userID).Once().Return([]*model.URL{
+		{ShortKey: "ABCDE", OriginalURL: "http://yandex.ru", UserID: userID, ExpiresAt: &expiredAt},
+		{ShortKey: "ABOBA", OriginalURL: "http://google.com", UserID: userID, ExpiresAt: &expiresAt},
+	}, nil)
+
+	service := URL{
+		baseURL: "http://localhost",
+		storage: urlStorage,
+	}
+
+	urls, err := service.GetUserURLs(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []*response.GetUserURL{
+		{ShortURL: "http://localhost/ABCDE", OriginalURL: "http://yandex.ru", ExpiresAt: &expiredAt, Expired: true},
+		{ShortURL: "http://localhost/ABOBA", OriginalURL: "http://google.com", ExpiresAt: &expiresAt},
+	}, urls)
+}
+
+func TestURL_CreateShortURL_InvalidURL(t *testing.T) {
+	tests := map[string]string{
+		"empty":       "",
+		"no scheme":   "yandex.ru",
+		"not allowed": "ftp://yandex.ru",
+	}
+
+	for tn, originalURL := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			urlStorage := mocks.NewURLStorage(t)
+			service := URL{
+				baseURL:        "http://localhost",
+				shortKeyLength: 5,
+				keyGenerator:   testKeyGenerator,
+				storage:        urlStorage,
+			}
+
+			_, err := service.CreateShortURL(context.Background(), dto.NewCreateShortURL(originalURL, uuid.New()))
+			assert.ErrorIs(t, err, ErrInvalidURL)
+
+			_, err = service.CreateShortURLBatch(context.Background(), dto.NewCreateShortURLBatch([]*request.CreateShortURLBatch{
+				{CorrelationID: "1", OriginalURL: "https://yandex.ru"},
+				{CorrelationID: "2", OriginalURL: originalURL},
+			}, uuid.New()))
+			assert.ErrorIs(t, err, ErrInvalidURL)
+		})
+	}
+}
+
+func TestURL_CreateShortURL_NormalizesURL(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	urlStorage := mocks.NewURLStorage(t)
+	urlStorage.On("SetURL", mock.Anything, mock.Anything, mock.Anything).Once().Return(func(_ context.Context, url *model.URL, _ storage.DedupeScope) (*model.URL, error) {
+		return url, nil
+	})
+	urlStorage.On("SetURLs", mock.Anything, mock.Anything, mock.Anything).Once().Return(func(_ context.Context, urls []*model.URL, _ storage.DedupeScope) ([]*model.URL, error) {
+		return urls, nil
+	})
+
+	service := URL{
+		baseURL:        "http://localhost",
+		shortKeyLength: 5,
+		keyGenerator:   testKeyGenerator,
+		normalizer:     NewURLNormalizer(nil, true),
+		storage:        urlStorage,
+	}
+
+	_, err := service.CreateShortURL(ctx, dto.NewCreateShortURL("HTTPS://Yandex.RU:443/path#top", userID))
+	require.NoError(t, err)
+	urlStorage.AssertCalled(t, "SetURL", mock.Anything, mock.MatchedBy(func(url *model.URL) bool {
+		return url.OriginalURL == "https://yandex.ru/path"
+	}), mock.Anything)
+
+	resp, err := service.CreateShortURLBatch(ctx, dto.NewCreateShortURLBatch([]*request.CreateShortURLBatch{
+		{CorrelationID: "1", OriginalURL: "HTTP://Google.com:80"},
+	}, userID))
+	require.NoError(t, err)
+	require.Len(t, resp, 1)
+	assert.Equal(t, "1", resp[0].CorrelationID)
+	urlStorage.AssertCalled(t, "SetURLs", mock.Anything, mock.MatchedBy(func(urls []*model.URL) bool {
+		return len(urls) == 1 && urls[0].OriginalURL == "http://google.com"
+	}), mock.Anything)
+}
+
+func TestURL_CreateShortURL_DedupeScope(t *testing.T) {
+	tests := map[string]struct {
+		dedupeScope   storage.DedupeScope
+		forceNew      bool
+		expectedScope storage.DedupeScope
+	}{
+		"default": {
+			expectedScope: storage.DedupeGlobal,
+		},
+		"global": {
+			dedupeScope:   storage.DedupeGlobal,
+			expectedScope: storage.DedupeGlobal,
+		},
+		"user": {
+			dedupeScope:   storage.DedupeUser,
+			expectedScope: storage.DedupeUser,
+		},
+		"none": {
+			dedupeScope:   storage.DedupeNone,
+			expectedScope: storage.DedupeNone,
+		},
+		"force new": {
+			dedupeScope:   storage.DedupeUser,
+			forceNew:      true,
+			expectedScope: storage.DedupeNone,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			urlStorage := mocks.NewURLStorage(t)
+			urlStorage.On("SetURL", mock.Anything, mock.Anything, tt.expectedScope).Once().Return(func(_ context.Context, url *model.URL, _ storage.DedupeScope) (*model.URL, error) {
+				return url, nil
+			})
+
+			service := URL{
+				baseURL:        "http://localhost",
+				shortKeyLength: 5,
+				keyGenerator:   testKeyGenerator,
+				dedupeScope:    tt.dedupeScope,
+				storage:        urlStorage,
+			}
+
+			createDTO := dto.NewCreateShortURL("https://yandex.ru", uuid.New())
+			createDTO.ForceNew = tt.forceNew
+			_, err := service.CreateShortURL(ctx, createDTO)
+			require.NoError(t, err)
+
+			urlStorage.On("SetURLs", mock.Anything, mock.Anything, tt.expectedScope).Once().Return(func(_ context.Context, urls []*model.URL, _ storage.DedupeScope) ([]*model.URL, error) {
+				return urls, nil
+			})
+
+			batchDTO := dto.NewCreateShortURLBatch([]*request.CreateShortURLBatch{
+				{CorrelationID: "1", OriginalURL: "https://yandex.ru"},
+			}, uuid.New())
+			batchDTO.ForceNew = tt.forceNew
+			_, err = service.CreateShortURLBatch(ctx, batchDTO)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestURL_CreateShortURLBatch_DuplicateOriginalURLs(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	urlStorage := mocks.NewURLStorage(t)
+	urlStorage.On("SetURLs", mock.Anything, mock.Anything, storage.DedupeNone).Once().Return(func(_ context.Context, urls []*model.URL, _ storage.DedupeScope) ([]*model.URL, error) {
+		return urls, nil
+	})
+
+	service := URL{
+		baseURL:        "http://localhost",
+		shortKeyLength: 5,
+		keyGenerator:   testKeyGenerator,
+		storage:        urlStorage,
+	}
+
+	batchDTO := dto.NewCreateShortURLBatch([]*request.CreateShortURLBatch{
+		{CorrelationID: "1", OriginalURL: "https://yandex.ru"},
+		{CorrelationID: "2", OriginalURL: "https://yandex.ru"},
+		{CorrelationID: "3", OriginalURL: "https://google.com"},
+	}, userID)
+	batchDTO.ForceNew = true
+	resp, err := service.CreateShortURLBatch(ctx, batchDTO)
+	require.NoError(t, err)
+
+	// Every item gets exactly one short URL, the one saved for it.
+	require.Len(t, resp, 3)
+	shortURLs := make(map[string]struct{})
+	for i, respURL := range resp {
+		assert.Equal(t, batchDTO.URLs[i].CorrelationID, respURL.CorrelationID)
+		shortURLs[respURL.ShortURL] = struct{}{}
+	}
+	assert.Len(t, shortURLs, 3)
+}
+
+func TestURL_CreateShortURL_KeyConflict(t *testing.T) {
+	userID := uuid.New()
+
+	tests := map[string]struct {
+		conflicts         int
+		expectedKeyLength int
+		expectedError     bool
+	}{
+		"no conflicts": {
+			conflicts:         0,
+			expectedKeyLength: 5,
+		},
+		"retry with the same length": {
+			conflicts:         keyAttemptsPerLength - 1,
+			expectedKeyLength: 5,
+		},
+		"grow length": {
+			conflicts:         keyAttemptsPerLength,
+			expectedKeyLength: 6,
+		},
+		"grow length twice": {
+			conflicts:         2 * keyAttemptsPerLength,
+			expectedKeyLength: 7,
+		},
+		"attempts exhausted": {
+			conflicts:     maxKeyAttempts,
+			expectedError: true,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			var savedKey string
+			calls := 0
+			urlStorage := mocks.NewURLStorage(t)
+			urlStorage.On("SetURL", mock.Anything, mock.Anything, mock.Anything).Return(func(_ context.Context, url *model.URL, _ storage.DedupeScope) (*model.URL, error) {
+				calls++
+				if calls <= tt.conflicts {
+					return nil, storage.ErrShortKeyConflict
+				}
+				savedKey = url.ShortKey
+				return url, nil
+			})
+
+			service := URL{
+				baseURL:        "http://localhost",
+				shortKeyLength: 5,
+				keyGenerator:   testKeyGenerator,
+				storage:        urlStorage,
+			}
+
+			dto := dto.NewCreateShortURL("https://yandex.ru", userID)
+			_, err := service.CreateShortURL(ctx, dto)
+
+			if tt.expectedError {
+				assert.ErrorIs(t, err, storage.ErrShortKeyConflict)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Len(t, savedKey, tt.expectedKeyLength)
+			assert.Equal(t, tt.expectedKeyLength, service.keyLength())
+		})
+	}
+}
+
+func TestURL_CreateShortURLBatch_KeyConflict(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	calls := 0
+	urlStorage := mocks.NewURLStorage(t)
+	urlStorage.On("SetURLs", mock.Anything, mock.Anything, mock.Anything).Return(func(_ context.Context, urls []*model.URL, _ storage.DedupeScope) ([]*model.URL, error) {
+		calls++
+		if calls == 1 {
+			return nil, storage.ErrShortKeyConflict
+		}
+		return urls, nil
+	})
+
+	service := URL{
+		baseURL:        "http://localhost",
+		shortKeyLength: 5,
+		keyGenerator:   testKeyGenerator,
+		storage:        urlStorage,
+	}
+
+	dto := dto.NewCreateShortURLBatch([]*request.CreateShortURLBatch{
+		{CorrelationID: "1", OriginalURL: "https://yandex.ru"},
+	}, userID)
+	resp, err := service.CreateShortURLBatch(ctx, dto)
+	require.NoError(t, err)
+	require.Len(t, resp, 1)
+	assert.Equal(t, 2, calls)
+}
+
+func TestURL_CreateShortURLBatch_Alias(t *testing.T) {
+	userID := uuid.New()
+	alias := "q3-launch"
+	owned := &model.URL{ID: uuid.New(), ShortKey: alias, OriginalURL: "https://yandex.ru", UserID: userID}
+	foreign := &model.URL{ID: uuid.New(), ShortKey: alias, OriginalURL: "https://example.com", UserID: uuid.New()}
+
+	tests := map[string]struct {
+		existingURLs    [][]*model.URL
+		setURLsError    error
+		expectedSetURLs int
+		expectedURLs    []string
+		expectedError   error
+	}{
+		"alias saved": {
+			existingURLs:    [][]*model.URL{nil},
+			expectedSetURLs: 1,
+			expectedURLs:    []string{"http://localhost/q3-launch"},
+		},
+		"alias resubmitted by owner": {
+			existingURLs:    [][]*model.URL{{owned}},
+			expectedSetURLs: 1,
+			expectedURLs:    []string{"http://localhost/q3-launch"},
+		},
+		"alias taken by another user": {
+			existingURLs:  [][]*model.URL{{foreign}},
+			expectedError: ErrAliasTaken,
+		},
+		"alias taken while saving": {
+			existingURLs:    [][]*model.URL{nil, {foreign}},
+			setURLsError:    storage.ErrShortKeyConflict,
+			expectedSetURLs: 1,
+			expectedError:   ErrAliasTaken,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			urlStorage := mocks.NewURLStorage(t)
+			for _, existing := range tt.existingURLs {
+				urlStorage.On("GetURLs", mock.Anything, []string{alias}).Once().Return(existing, nil)
+			}
+			setURLs := 0
+			urlStorage.On("SetURLs", mock.Anything, mock.Anything, mock.Anything).Maybe().Return(func(_ context.Context, urls []*model.URL, _ storage.DedupeScope) ([]*model.URL, error) {
+				setURLs++
+				if tt.setURLsError != nil {
+					return nil, tt.setURLsError
+				}
+				return urls, nil
+			})
+
+			service := URL{
+				baseURL:        "http://localhost",
+				shortKeyLength: 8,
+				keyGenerator:   testKeyGenerator,
+				storage:        urlStorage,
+			}
+
+			batchDTO := dto.NewCreateShortURLBatch([]*request.CreateShortURLBatch{
+				{CorrelationID: "1", OriginalURL: "https://yandex.ru", Alias: alias},
+				{CorrelationID: "2", OriginalURL: "https://google.com"},
+			}, userID)
+			resp, err := service.CreateShortURLBatch(ctx, batchDTO)
+			assert.Equal(t, tt.expectedSetURLs, setURLs)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, resp, 2)
+			assert.Equal(t, tt.expectedURLs[0], resp[0].ShortURL)
+			assert.NotEqual(t, resp[0].ShortURL, resp[1].ShortURL)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"fmt"
+)
+
+// DedupeScope defines which existing URLs are reused when the same original URL is saved again.
+type DedupeScope string
+
+const (
+	// DedupeGlobal reuses an active URL with the same original URL created by any user.
+	DedupeGlobal DedupeScope = "global"
+	// DedupeUser reuses an active URL with the same original URL created by the same user.
+	DedupeUser DedupeScope = "user"
+	// DedupeNone always saves a new URL.
+	DedupeNone DedupeScope = "none"
+)
+
+// ParseDedupeScope converts a configuration value into DedupeScope.
+// Returns an error if the value is not a known scope.
+func ParseDedupeScope(value string) (DedupeScope, error) {
+	switch scope := DedupeScope(value); scope {
+	case DedupeGlobal, DedupeUser, DedupeNone:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("unknown dedupe scope %q", value)
+	}
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDedupeScope(t *testing.T) {
+	tests := map[string]struct {
+		value         string
+		expectedScope DedupeScope
+		wantErr       bool
+	}{
+		"global": {
+			value:         "global",
+			expectedScope: DedupeGlobal,
+		},
+		"user": {
+			value:         "user",
+			expectedScope: DedupeUser,
+		},
+		"none": {
+			value:         "none",
+			expectedScope: DedupeNone,
+		},
+		"empty": {
+			value:   "",
+			wantErr: true,
+		},
+		"unknown": {
+			value:   "tenant",
+			wantErr: true,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+
+			scope, err := ParseDedupeScope(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedScope, scope)
+		})
+	}
+}
@@ -5,5 +5,11 @@ import "errors"
 // ErrConflict is returned when there is a conflict during URL creation.
 var ErrConflict = errors.New("conflict")
 
+// ErrShortKeyConflict is returned when the short key is already taken by another URL.
+var ErrShortKeyConflict = errors.New("short key conflict")
+
 // ErrNotFound is returned when a URL is not found.
 var ErrNotFound = errors.New("not found")
+
+// ErrNoClicksLeft is returned when a click-limited URL has used up all its redirects.
+var ErrNoClicksLeft = errors.New("no clicks left")
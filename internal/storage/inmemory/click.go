@@ -0,0 +1,137 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dtroode/urlshorter/internal/model"
+)
+
+// loadClicks reads click events from the clicks file and groups them by URL ID.
+func loadClicks(filename string) (map[uuid.UUID][]*model.Click, error) {
+	clicks := make(map[uuid.UUID][]*model.Click)
+
+	err := readRecords(filename, func(line []byte) error {
+		click := &model.Click{}
+		if err := decodeRecord(line, click); err != nil {
+			return fmt.Errorf("failed to unmarshall clicks entry: %w", err)
+		}
+		clicks[click.URLID] = append(clicks[click.URLID], click)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return clicks, nil
+}
+
+// SaveClick appends a single click event to the clicks file.
+func (s *Storage) SaveClick(_ context.Context, click *model.Click) error {
+	record, err := appendRecord(nil, click)
+	if err != nil {
+		return fmt.Errorf("failed to marshal click: %w", err)
+	}
+
+	return commit(&s.clicksMu, func() (pendingSync, error) {
+		pending, err := s.clicksFile.write(record)
+		if err != nil {
+			return pendingSync{}, fmt.Errorf("failed to write click to file: %w", err)
+		}
+
+		s.clicks[click.URLID] = append(s.clicks[click.URLID], click)
+		s.clickCounters.add(click)
+
+		return pending, nil
+	})
+}
+
+// GetClickStats aggregates the click events of a URL from counters kept up to date as clicks are saved,
+// so it doesn't go through the events. Clicks are counted per hour, so bucket must be a whole number of hours.
+func (s *Storage) GetClickStats(_ context.Context, urlID uuid.UUID, bucket time.Duration) (*model.ClickStats, error) {
+	s.clicksMu.RLock()
+	defer s.clicksMu.RUnlock()
+
+	return s.clickCounters[urlID].stats(bucket), nil
+}
+
+// visitor identifies a client of a URL by its address and user agent.
+type visitor struct {
+	clientIP  string
+	userAgent string
+}
+
+// urlClickCounters counts the click events of a URL by their attributes.
+type urlClickCounters struct {
+	total      int
+	hours      map[time.Time]int
+	referrers  map[string]int
+	userAgents map[string]int
+	visitors   map[visitor]struct{}
+}
+
+// stats returns the counters with the hours grouped into buckets.
+// Counters of a URL without clicks are nil and return empty statistics.
+func (c *urlClickCounters) stats(bucket time.Duration) *model.ClickStats {
+	stats := &model.ClickStats{
+		Buckets:    make(map[time.Time]int),
+		Referrers:  make(map[string]int),
+		UserAgents: make(map[string]int),
+	}
+	if c == nil {
+		return stats
+	}
+
+	stats.Total = c.total
+	stats.UniqueVisitors = len(c.visitors)
+	for hour, clicks := range c.hours {
+		stats.Buckets[hour.Truncate(bucket)] += clicks
+	}
+	for referrer, clicks := range c.referrers {
+		stats.Referrers[referrer] = clicks
+	}
+	for userAgent, clicks := range c.userAgents {
+		stats.UserAgents[userAgent] = clicks
+	}
+
+	return stats
+}
+
+// clickCounters holds the click counters of URLs by URL ID.
+type clickCounters map[uuid.UUID]*urlClickCounters
+
+// countClicks builds the counters of loaded click events.
+func countClicks(clicks map[uuid.UUID][]*model.Click) clickCounters {
+	counters := make(clickCounters, len(clicks))
+	for _, urlClicks := range clicks {
+		for _, click := range urlClicks {
+			counters.add(click)
+		}
+	}
+
+	return counters
+}
+
+// add counts the click.
+func (c clickCounters) add(click *model.Click) {
+	counters, ok := c[click.URLID]
+	if !ok {
+		counters = &urlClickCounters{
+			hours:      make(map[time.Time]int),
+			referrers:  make(map[string]int),
+			userAgents: make(map[string]int),
+			visitors:   make(map[visitor]struct{}),
+		}
+		c[click.URLID] = counters
+	}
+
+	counters.total++
+	counters.hours[click.CreatedAt.UTC().Truncate(time.Hour)]++
+	counters.referrers[click.Referrer]++
+	counters.userAgents[click.UserAgent]++
+	counters.visitors[visitor{clientIP: click.ClientIP, userAgent: click.UserAgent}] = struct{}{}
+}
@@ -0,0 +1,69 @@
+package inmemory
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/model"
+)
+
+func TestStorage_SaveClick(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	s := Storage{
+		clicks:        make(map[uuid.UUID][]*model.Click),
+		clickCounters: make(clickCounters),
+		clicksFile:    &recordFile{file: &dummyFile{Buffer: buf}},
+	}
+
+	urlID := uuid.New()
+	clicks := []*model.Click{
+		model.NewClick(urlID, time.Now().UTC(), "https://ya.ru", "Mozilla/5.0", "192.168.1.0"),
+		model.NewClick(urlID, time.Now().UTC(), "", "curl/8.0", "2001:db8::"),
+	}
+
+	for _, click := range clicks {
+		err := s.SaveClick(context.Background(), click)
+		require.NoError(t, err)
+	}
+
+	for _, click := range clicks {
+		line, err := buf.ReadBytes('\n')
+		require.NoError(t, err)
+
+		writtenData := &model.Click{}
+		err = decodeRecord(line, writtenData)
+		require.NoError(t, err)
+
+		assert.Equal(t, click, writtenData)
+	}
+
+	assert.Equal(t, clicks, s.clicks[urlID])
+}
+
+func TestStorage_NewStorage_RestoresClicks(t *testing.T) {
+	filename := t.TempDir() + "/urls"
+	click := model.NewClick(uuid.New(), time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC), "https://ya.ru", "Mozilla/5.0", "192.168.1.0")
+
+	s, err := NewStorage(filename)
+	require.NoError(t, err)
+	err = s.SaveClick(context.Background(), click)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	s, err = NewStorage(filename)
+	require.NoError(t, err)
+	defer s.Close()
+
+	assert.Equal(t, []*model.Click{click}, s.clicks[click.URLID])
+
+	stats, err := s.GetClickStats(context.Background(), click.URLID, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Total)
+	assert.Equal(t, map[time.Time]int{click.CreatedAt: 1}, stats.Buckets)
+}
@@ -0,0 +1,225 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dtroode/urlshorter/internal/logger"
+	"github.com/dtroode/urlshorter/internal/model"
+)
+
+// tailFileSuffix is appended to the storage file name to get the name of the file
+// changes are appended to between compactions. The storage file itself is a snapshot.
+// Sealed tails, that are being compacted, get a sequence number after the suffix.
+const tailFileSuffix = ".tail"
+
+// tailFilename returns the name of the file changes are appended to.
+func (s *Storage) tailFilename() string {
+	return s.filename + tailFileSuffix
+}
+
+// sealedTails returns sequence numbers of sealed tails of the storage file in ascending order.
+func sealedTails(filename string) ([]int, error) {
+	prefix := filename + tailFileSuffix + "."
+
+	matches, err := filepath.Glob(prefix + "*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sealed tails: %w", err)
+	}
+
+	seqs := make([]int, 0, len(matches))
+	for _, match := range matches {
+		seq, err := strconv.Atoi(strings.TrimPrefix(match, prefix))
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	slices.Sort(seqs)
+
+	return seqs, nil
+}
+
+// sealedTailFilename returns the name of the sealed tail with the given sequence number.
+func sealedTailFilename(filename string, seq int) string {
+	return filename + tailFileSuffix + "." + strconv.Itoa(seq)
+}
+
+// loadURLs loads the snapshot and replays sealed tails and the tail on top of it.
+// Returns the loaded URLs and the greatest sequence number of sealed tails.
+func loadURLs(filename string) (URLMap, int, error) {
+	urlmap := URLMap{}
+
+	if err := replayFile(filename, urlmap); err != nil {
+		return nil, 0, err
+	}
+
+	seqs, err := sealedTails(filename)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	lastSeq := 0
+	for _, seq := range seqs {
+		if err := replayFile(sealedTailFilename(filename, seq), urlmap); err != nil {
+			return nil, 0, err
+		}
+		lastSeq = seq
+	}
+
+	if err := replayFile(filename+tailFileSuffix, urlmap); err != nil {
+		return nil, 0, err
+	}
+
+	return urlmap, lastSeq, nil
+}
+
+// Compact replaces the storage file with a snapshot of the stored URLs
+// and drops the changes already contained in it, so the next start
+// replays only changes made after the compaction.
+// Writers are blocked only while the tail is switched, not while the snapshot is written.
+func (s *Storage) Compact(_ context.Context) error {
+	s.compactMu.Lock()
+	defer s.compactMu.Unlock()
+
+	_, err := s.compact(nil)
+
+	return err
+}
+
+// compact seals the tail and writes a snapshot of the URLs stored at that moment,
+// leaving out the ones for which exclude returns true.
+// Returns the left out URLs by short key. Callers must hold s.compactMu.
+func (s *Storage) compact(exclude func(url *model.URL) bool) (URLMap, error) {
+	s.mu.Lock()
+
+	kept := make([]*model.URL, 0, len(s.urlmap))
+	excluded := URLMap{}
+	for shortKey, url := range s.urlmap {
+		if exclude != nil && exclude(url) {
+			excluded[shortKey] = url
+			continue
+		}
+		kept = append(kept, url)
+	}
+
+	seq, err := s.sealTail()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeSnapshot(s.filename, kept, seq); err != nil {
+		return nil, err
+	}
+
+	return excluded, nil
+}
+
+// sealTail renames the tail into a sealed tail and starts a new one.
+// Returns the sequence number of the sealed tail. Callers must hold s.mu.
+func (s *Storage) sealTail() (int, error) {
+	seq := s.lastSealedTail + 1
+
+	file, err := sealTailFile(s.filename, seq, s.syncPolicy)
+	if err != nil {
+		return 0, err
+	}
+
+	// The sealed tail is removed once the snapshot is written, and the snapshot
+	// is taken from memory, so records the sealed tail failed to sync are not lost.
+	s.file.retire()
+	s.file = file
+	s.lastSealedTail = seq
+
+	return seq, nil
+}
+
+// sealTailFile renames the tail of the storage file into the sealed tail
+// with the given sequence number and opens a new tail.
+// The caller is responsible for closing the file of the sealed tail.
+func sealTailFile(filename string, seq int, policy SyncPolicy) (*recordFile, error) {
+	tail := filename + tailFileSuffix
+	sealed := sealedTailFilename(filename, seq)
+
+	if err := os.Rename(tail, sealed); err != nil {
+		return nil, fmt.Errorf("failed to seal tail: %w", err)
+	}
+
+	file, err := openRecordFile(tail, policy)
+	if err != nil {
+		if renameErr := os.Rename(sealed, tail); renameErr != nil {
+			return nil, fmt.Errorf("failed to open tail: %w, and to restore it: %w", err, renameErr)
+		}
+		return nil, fmt.Errorf("failed to open tail: %w", err)
+	}
+
+	if policy != SyncNever {
+		if err := syncDir(filepath.Dir(filename)); err != nil {
+			file.close()
+			return nil, err
+		}
+	}
+
+	return file, nil
+}
+
+// writeSnapshot replaces the storage file with urls and removes sealed tails
+// with sequence numbers up to seq, which are contained in the snapshot.
+func writeSnapshot(filename string, urls []*model.URL, seq int) error {
+	if err := replaceFile(filename, urls); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	// Sealed tails left by failed compactions are older than the snapshot too.
+	seqs, err := sealedTails(filename)
+	if err != nil {
+		return err
+	}
+	for _, sealed := range seqs {
+		if sealed > seq {
+			continue
+		}
+		if err := os.Remove(sealedTailFilename(filename, sealed)); err != nil {
+			return fmt.Errorf("failed to remove sealed tail: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// compactor is implemented by storages that can be compacted.
+type compactor interface {
+	Compact(ctx context.Context) error
+}
+
+// RunCompaction compacts the storage every interval until ctx is done.
+// Failed compactions are logged and retried after the next interval.
+func (s *Storage) RunCompaction(ctx context.Context, interval time.Duration, logger *logger.Logger) {
+	runCompaction(ctx, s, s.filename, interval, logger)
+}
+
+// runCompaction compacts storage every interval until ctx is done.
+func runCompaction(ctx context.Context, storage compactor, filename string, interval time.Duration, logger *logger.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := storage.Compact(ctx); err != nil {
+				logger.Error("failed to compact storage file", "error", err, "file", filename)
+				continue
+			}
+			logger.Debug("compacted storage file", "file", filename)
+		}
+	}
+}
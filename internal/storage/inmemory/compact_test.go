@@ -0,0 +1,188 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/logger"
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+func countLines(t *testing.T, filename string) int {
+	t.Helper()
+
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return 0
+	}
+	require.NoError(t, err)
+
+	lines := 0
+	for _, b := range data {
+		if b == '\n' {
+			lines++
+		}
+	}
+
+	return lines
+}
+
+func TestStorage_Compact(t *testing.T) {
+	ctx := context.Background()
+	filename := t.TempDir() + "/urls"
+
+	s, err := NewStorage(filename)
+	require.NoError(t, err)
+
+	url := &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru"}
+	_, err = s.SetURL(ctx, url, storage.DedupeGlobal)
+	require.NoError(t, err)
+	for i := range 3 {
+		updated := *url
+		updated.OriginalURL = fmt.Sprintf("https://ya.ru/%d", i)
+		_, err = s.UpdateURL(ctx, &updated, storage.FieldOriginalURL)
+		require.NoError(t, err)
+	}
+	require.NoError(t, s.DeleteURLs(ctx, []uuid.UUID{url.ID}))
+	assert.Equal(t, 5, countLines(t, filename+tailFileSuffix))
+
+	require.NoError(t, s.Compact(ctx))
+
+	assert.Equal(t, 1, countLines(t, filename))
+	assert.Zero(t, countLines(t, filename+tailFileSuffix))
+	seqs, err := sealedTails(filename)
+	require.NoError(t, err)
+	assert.Empty(t, seqs)
+	_, err = os.Stat(filename + tempFileSuffix)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// Changes made after the compaction go to the new tail.
+	_, err = s.SetURL(ctx, &model.URL{ID: uuid.New(), ShortKey: "def", OriginalURL: "https://google.com"}, storage.DedupeGlobal)
+	require.NoError(t, err)
+	require.NoError(t, s.RestoreURLs(ctx, []uuid.UUID{url.ID}))
+	assert.Equal(t, 2, countLines(t, filename+tailFileSuffix))
+	require.NoError(t, s.Close())
+
+	s, err = NewStorage(filename)
+	require.NoError(t, err)
+	defer s.Close()
+
+	got, err := s.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://ya.ru/2", got.OriginalURL)
+	assert.Nil(t, got.DeletedAt)
+
+	got, err = s.GetURL(ctx, "def")
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.com", got.OriginalURL)
+}
+
+func TestStorage_Compact_ReplaysLeftoverSealedTails(t *testing.T) {
+	ctx := context.Background()
+	filename := t.TempDir() + "/urls"
+
+	// A crash during compaction leaves sealed tails that are newer than the snapshot.
+	writeLines(t, filename, `{"id":"`+uuid.NewString()+`","short_key":"abc","original_url":"https://old.ru"}`)
+	writeLines(t, sealedTailFilename(filename, 1), `{"id":"`+uuid.NewString()+`","short_key":"abc","original_url":"https://ya.ru"}`)
+	writeLines(t, sealedTailFilename(filename, 2), `{"id":"`+uuid.NewString()+`","short_key":"def","original_url":"https://google.com"}`)
+
+	s, err := NewStorage(filename)
+	require.NoError(t, err)
+	defer s.Close()
+
+	got, err := s.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://ya.ru", got.OriginalURL)
+	_, err = s.GetURL(ctx, "def")
+	require.NoError(t, err)
+
+	require.NoError(t, s.Compact(ctx))
+
+	seqs, err := sealedTails(filename)
+	require.NoError(t, err)
+	assert.Empty(t, seqs)
+	assert.Equal(t, 2, countLines(t, filename))
+}
+
+func TestStorage_Compact_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	filename := t.TempDir() + "/urls"
+
+	s, err := NewStorage(filename)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				_, err := s.SetURL(ctx, &model.URL{
+					ID:          uuid.New(),
+					ShortKey:    fmt.Sprintf("key-%d-%d", w, i),
+					OriginalURL: fmt.Sprintf("https://ya.ru/%d/%d", w, i),
+				}, storage.DedupeGlobal)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	for range 10 {
+		require.NoError(t, s.Compact(ctx))
+	}
+	wg.Wait()
+	require.NoError(t, s.Close())
+
+	s, err = NewStorage(filename)
+	require.NoError(t, err)
+	defer s.Close()
+
+	assert.Len(t, s.urlmap, 200)
+}
+
+func TestStorage_RunCompaction(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	filename := t.TempDir() + "/urls"
+
+	s, err := NewStorage(filename)
+	require.NoError(t, err)
+	defer s.Close()
+
+	_, err = s.SetURL(ctx, &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru"}, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.RunCompaction(ctx, 10*time.Millisecond, logger.NewLog("error"))
+	}()
+
+	assert.Eventually(t, func() bool {
+		s.compactMu.Lock()
+		defer s.compactMu.Unlock()
+
+		return countLines(t, filename) == 1 && countLines(t, filename+tailFileSuffix) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func writeLines(t *testing.T, filename string, lines ...string) {
+	t.Helper()
+
+	data := ""
+	for _, line := range lines {
+		data += line + "\n"
+	}
+	require.NoError(t, os.WriteFile(filename, []byte(data), 0600))
+}
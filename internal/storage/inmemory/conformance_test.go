@@ -0,0 +1,34 @@
+package inmemory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/storage"
+	"github.com/dtroode/urlshorter/internal/storage/storagetest"
+)
+
+func TestStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, err := NewStorage(t.TempDir() + "/urls")
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, s.Close())
+		})
+
+		return s
+	})
+}
+
+func TestShardedStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, err := NewShardedStorage(t.TempDir()+"/urls", 8)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, s.Close())
+		})
+
+		return s
+	})
+}
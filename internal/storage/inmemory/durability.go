@@ -0,0 +1,282 @@
+package inmemory
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SyncPolicy defines when records written to the storage files are synced to disk.
+type SyncPolicy string
+
+const (
+	// SyncAlways syncs every write before it is acknowledged.
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval syncs written records periodically, so a crash loses at most one interval of writes.
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever leaves syncing to the operating system.
+	SyncNever SyncPolicy = "never"
+)
+
+// ParseSyncPolicy converts a configuration value into SyncPolicy.
+// Returns an error if the value is not a known policy.
+func ParseSyncPolicy(value string) (SyncPolicy, error) {
+	switch policy := SyncPolicy(value); policy {
+	case SyncAlways, SyncInterval, SyncNever:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown sync policy %q", value)
+	}
+}
+
+// options holds settings shared by Storage and ShardedStorage.
+type options struct {
+	syncPolicy   SyncPolicy
+	syncInterval time.Duration
+}
+
+// Option configures Storage and ShardedStorage.
+type Option func(*options)
+
+// WithSync sets the policy of syncing written records to disk.
+// interval is used only by SyncInterval.
+func WithSync(policy SyncPolicy, interval time.Duration) Option {
+	return func(o *options) {
+		o.syncPolicy = policy
+		o.syncInterval = interval
+	}
+}
+
+// newOptions applies opts over the defaults. Records are synced with SyncAlways by default.
+func newOptions(opts []Option) (options, error) {
+	o := options{syncPolicy: SyncAlways}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.syncPolicy == SyncInterval && o.syncInterval <= 0 {
+		return options{}, fmt.Errorf("sync interval must be positive, got %s", o.syncInterval)
+	}
+
+	return o, nil
+}
+
+// truncater is implemented by files that can be cut back to a given size.
+type truncater interface {
+	Truncate(size int64) error
+}
+
+// recordFile appends records to a file and syncs them according to the sync policy.
+// Records are written under the lock of the owner of the file, while syncs
+// may run concurrently with writes: waitSync syncs records written by many callers at once.
+type recordFile struct {
+	file File
+	// size is the size of the file after the last write. It is guarded by the lock of the owner.
+	size   int64
+	policy SyncPolicy
+	// written is the sequence number of the last write.
+	written atomic.Uint64
+	// syncMu serializes syncs. synced is the sequence number of the last write known to be synced.
+	syncMu sync.Mutex
+	synced uint64
+	// err is set when the file failed to sync. Records written before it
+	// may be lost, so no more records are accepted.
+	err atomic.Pointer[error]
+}
+
+// pendingSync is a write to a record file that may not be synced to disk yet.
+type pendingSync struct {
+	file *recordFile
+	seq  uint64
+}
+
+// wait waits until the write is synced to disk if the sync policy of the file is SyncAlways.
+// The zero pendingSync has nothing to wait for.
+func (p pendingSync) wait() error {
+	if p.file == nil || p.file.policy != SyncAlways {
+		return nil
+	}
+
+	return p.file.waitSync(p.seq)
+}
+
+// openRecordFile opens the file for appending records.
+func openRecordFile(filename string, policy SyncPolicy) (*recordFile, error) {
+	file, err := openAppend(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	return &recordFile{
+		file:   file,
+		size:   info.Size(),
+		policy: policy,
+	}, nil
+}
+
+// failure returns the error the file failed to sync with, if any.
+func (f *recordFile) failure() error {
+	if err := f.err.Load(); err != nil {
+		return *err
+	}
+
+	return nil
+}
+
+// write writes records in a single write without syncing them. If the write fails,
+// the file is cut back to its previous size, so no partial record is left in front of the next one.
+// Callers must hold the lock of the owner of the file, and with SyncAlways wait for
+// the returned pendingSync after releasing it and before acknowledging the write.
+func (f *recordFile) write(records []byte) (pendingSync, error) {
+	if err := f.failure(); err != nil {
+		return pendingSync{}, err
+	}
+
+	if _, err := f.file.Write(records); err != nil {
+		return pendingSync{}, errors.Join(err, f.rollback())
+	}
+	f.size += int64(len(records))
+
+	return pendingSync{file: f, seq: f.written.Add(1)}, nil
+}
+
+// append writes records in a single write. With SyncAlways the records are synced
+// before append returns. If the write or the sync fails, the file is cut back
+// to its previous size, so no partial record is left in front of the next one.
+// Unlike write, append must not run concurrently with syncs.
+func (f *recordFile) append(records []byte) error {
+	pending, err := f.write(records)
+	if err != nil {
+		return err
+	}
+
+	if err := pending.wait(); err != nil {
+		f.size -= int64(len(records))
+		return errors.Join(err, f.rollback())
+	}
+
+	return nil
+}
+
+// rollback cuts the file back to the size it had before the last write.
+func (f *recordFile) rollback() error {
+	file, ok := f.file.(truncater)
+	if !ok {
+		return nil
+	}
+
+	if err := file.Truncate(f.size); err != nil {
+		return fmt.Errorf("failed to truncate partial write: %w", err)
+	}
+
+	return nil
+}
+
+// waitSync waits until the write with sequence number seq is synced, syncing the file
+// unless a sync that started after the write has already done it. Callers that wait
+// while a sync is running are all served by the next one.
+func (f *recordFile) waitSync(seq uint64) error {
+	f.syncMu.Lock()
+	defer f.syncMu.Unlock()
+
+	if err := f.failure(); err != nil {
+		return err
+	}
+	if f.synced >= seq {
+		return nil
+	}
+
+	written := f.written.Load()
+	if err := f.file.Sync(); err != nil {
+		err = fmt.Errorf("failed to sync file: %w", err)
+		f.err.Store(&err)
+		return err
+	}
+	f.synced = written
+
+	return nil
+}
+
+// sync syncs records written since the last sync.
+func (f *recordFile) sync() error {
+	return f.waitSync(f.written.Load())
+}
+
+// dirty reports whether records were written since the last sync.
+func (f *recordFile) dirty() bool {
+	f.syncMu.Lock()
+	defer f.syncMu.Unlock()
+
+	return f.written.Load() != f.synced
+}
+
+// close syncs records written since the last sync, unless the policy is SyncNever, and closes the file.
+func (f *recordFile) close() error {
+	var err error
+	if f.policy != SyncNever {
+		err = f.sync()
+	}
+
+	return errors.Join(err, f.file.Close())
+}
+
+// retire closes the file after it has been replaced by another one. With SyncAlways
+// the records written to it are synced first, so that callers still waiting for them
+// are not failed by the close.
+func (f *recordFile) retire() {
+	if f.policy == SyncAlways {
+		_ = f.sync()
+	}
+	_ = f.file.Close()
+}
+
+// commit runs fn under mu and waits for the write fn returns to be synced after releasing mu,
+// so that writers don't hold the lock while the file is synced.
+func commit(mu sync.Locker, fn func() (pendingSync, error)) error {
+	pending, err := func() (pendingSync, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return fn()
+	}()
+	if err != nil {
+		return err
+	}
+
+	return pending.wait()
+}
+
+// runSync syncs the storage files every sync interval until the storage is closed.
+// The files are synced without holding the storage locks, so writes don't wait for syncs.
+// A failed sync makes following writes to the file fail.
+func (s *Storage) runSync() {
+	defer close(s.syncDone)
+
+	ticker := time.NewTicker(s.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopSync:
+			return
+		case <-ticker.C:
+			s.mu.RLock()
+			file := s.file
+			s.mu.RUnlock()
+			_ = file.sync()
+
+			s.clicksMu.RLock()
+			clicksFile := s.clicksFile
+			s.clicksMu.RUnlock()
+			_ = clicksFile.sync()
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package inmemory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+// faultyFile is a File that counts syncs and fails writes or syncs on demand.
+type faultyFile struct {
+	bytes.Buffer
+	syncs     int
+	writeErr  error
+	syncErr   error
+	truncated []int64
+}
+
+func (f *faultyFile) Write(p []byte) (int, error) {
+	if f.writeErr != nil {
+		n, _ := f.Buffer.Write(p[:len(p)/2])
+		return n, f.writeErr
+	}
+	return f.Buffer.Write(p)
+}
+
+func (f *faultyFile) Sync() error {
+	f.syncs++
+	return f.syncErr
+}
+
+func (f *faultyFile) Truncate(size int64) error {
+	f.truncated = append(f.truncated, size)
+	f.Buffer.Truncate(int(size))
+	return nil
+}
+
+func (f *faultyFile) Close() error {
+	return nil
+}
+
+func TestParseSyncPolicy(t *testing.T) {
+	tests := map[string]struct {
+		value          string
+		expectedPolicy SyncPolicy
+		expectedError  bool
+	}{
+		"always": {
+			value:          "always",
+			expectedPolicy: SyncAlways,
+		},
+		"interval": {
+			value:          "interval",
+			expectedPolicy: SyncInterval,
+		},
+		"never": {
+			value:          "never",
+			expectedPolicy: SyncNever,
+		},
+		"unknown": {
+			value:         "sometimes",
+			expectedError: true,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			policy, err := ParseSyncPolicy(tt.value)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedPolicy, policy)
+		})
+	}
+}
+
+func TestRecordFile_Append(t *testing.T) {
+	tests := map[string]struct {
+		policy        SyncPolicy
+		expectedSyncs int
+	}{
+		"always": {
+			policy:        SyncAlways,
+			expectedSyncs: 2,
+		},
+		"interval": {
+			policy:        SyncInterval,
+			expectedSyncs: 1,
+		},
+		"never": {
+			policy:        SyncNever,
+			expectedSyncs: 0,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			file := &faultyFile{}
+			f := &recordFile{file: file, policy: tt.policy}
+
+			require.NoError(t, f.append([]byte("a\n")))
+			require.NoError(t, f.append([]byte("b\n")))
+			if tt.policy == SyncInterval {
+				require.NoError(t, f.sync())
+				require.NoError(t, f.sync())
+			}
+
+			assert.Equal(t, tt.expectedSyncs, file.syncs)
+			assert.Equal(t, int64(4), f.size)
+		})
+	}
+}
+
+func TestRecordFile_Append_Failures(t *testing.T) {
+	t.Run("write error", func(t *testing.T) {
+		file := &faultyFile{}
+		f := &recordFile{file: file, policy: SyncAlways}
+		require.NoError(t, f.append([]byte("a\n")))
+
+		file.writeErr = errors.New("disk full")
+		assert.Error(t, f.append([]byte("bbbb\n")))
+		assert.Equal(t, []int64{2}, file.truncated)
+		assert.Equal(t, "a\n", file.String())
+
+		// The file accepts records again once writes succeed.
+		file.writeErr = nil
+		require.NoError(t, f.append([]byte("c\n")))
+		assert.Equal(t, "a\nc\n", file.String())
+	})
+
+	t.Run("sync error", func(t *testing.T) {
+		file := &faultyFile{}
+		f := &recordFile{file: file, policy: SyncAlways}
+		require.NoError(t, f.append([]byte("a\n")))
+
+		file.syncErr = errors.New("io error")
+		assert.Error(t, f.append([]byte("b\n")))
+		assert.Equal(t, "a\n", file.String())
+
+		// Records written before a failed sync may be lost, so the file stays failed.
+		file.syncErr = nil
+		assert.Error(t, f.append([]byte("c\n")))
+		assert.Error(t, f.close())
+	})
+}
+
+func TestStorage_WriteFailure_KeepsMap(t *testing.T) {
+	ctx := context.Background()
+	file := &faultyFile{writeErr: errors.New("disk full")}
+	existing := &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru", UserID: uuid.New()}
+
+	s := &Storage{
+		urlmap: URLMap{"abc": existing},
+		file:   &recordFile{file: file},
+	}
+	s.indexURLs()
+
+	_, err := s.SetURL(ctx, &model.URL{ID: uuid.New(), ShortKey: "new", OriginalURL: "https://new.ru"}, storage.DedupeGlobal)
+	assert.Error(t, err)
+
+	_, err = s.SetURLs(ctx, []*model.URL{{ID: uuid.New(), ShortKey: "new", OriginalURL: "https://new.ru"}}, storage.DedupeGlobal)
+	assert.Error(t, err)
+
+	updated := *existing
+	updated.OriginalURL = "https://google.com"
+	_, err = s.UpdateURL(ctx, &updated, storage.FieldOriginalURL)
+	assert.Error(t, err)
+
+	assert.Error(t, s.DeleteURLs(ctx, []uuid.UUID{existing.ID}))
+
+	_, err = s.GetURL(ctx, "new")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	got, err := s.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Same(t, existing, got)
+	assert.Zero(t, file.Len())
+}
+
+func TestStorage_NewStorage_SyncInterval(t *testing.T) {
+	ctx := context.Background()
+	filename := t.TempDir() + "/urls"
+
+	_, err := NewStorage(filename, WithSync(SyncInterval, 0))
+	assert.Error(t, err)
+
+	s, err := NewStorage(filename, WithSync(SyncInterval, 10*time.Millisecond))
+	require.NoError(t, err)
+
+	_, err = s.SetURL(ctx, &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru"}, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		return !s.file.dirty()
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, s.Close())
+
+	s, err = NewStorage(filename, WithSync(SyncNever, 0))
+	require.NoError(t, err)
+	defer s.Close()
+
+	_, err = s.GetURL(ctx, "abc")
+	assert.NoError(t, err)
+}
+
+// blockingFile is a File whose syncs wait until release is closed.
+type blockingFile struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	syncs   atomic.Int32
+	syncing chan struct{}
+	release chan struct{}
+}
+
+func (f *blockingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.buf.Write(p)
+}
+
+func (f *blockingFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *blockingFile) Sync() error {
+	f.syncs.Add(1)
+	select {
+	case f.syncing <- struct{}{}:
+	default:
+	}
+	<-f.release
+	return nil
+}
+
+func (f *blockingFile) Close() error {
+	return nil
+}
+
+func TestStorage_SyncAlways_SyncsOutsideLock(t *testing.T) {
+	ctx := context.Background()
+	file := &blockingFile{syncing: make(chan struct{}, 1), release: make(chan struct{})}
+
+	s := &Storage{
+		urlmap: URLMap{},
+		file:   &recordFile{file: file, policy: SyncAlways},
+	}
+	s.indexURLs()
+
+	var wg sync.WaitGroup
+	setURL := func(shortKey string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.SetURL(ctx, &model.URL{ID: uuid.New(), ShortKey: shortKey, OriginalURL: "https://" + shortKey + ".ru"}, storage.DedupeGlobal)
+			assert.NoError(t, err)
+		}()
+	}
+
+	setURL("first")
+	<-file.syncing
+
+	// Writes are not blocked by the running sync and wait for the next one together.
+	setURL("second")
+	setURL("third")
+	require.Eventually(t, func() bool {
+		return s.file.written.Load() == 3
+	}, time.Second, time.Millisecond)
+
+	_, err := s.GetURL(ctx, "third")
+	require.NoError(t, err)
+
+	close(file.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(2), file.syncs.Load())
+	assert.False(t, s.file.dirty())
+}
@@ -0,0 +1,98 @@
+package inmemory
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dtroode/urlshorter/internal/model"
+)
+
+// tempFileSuffix is appended to a file name to get the name of the file it is rewritten into.
+const tempFileSuffix = ".tmp"
+
+// replayFile reads URL records from the file into urlmap.
+// Records are applied in order, so the last record of a short key wins.
+// A missing file is treated as an empty one.
+func replayFile(filename string, urlmap URLMap) error {
+	return readRecords(filename, func(line []byte) error {
+		entry := &model.URL{}
+		if err := decodeRecord(line, entry); err != nil {
+			return fmt.Errorf("failed to unmarshall urls entry: %w", err)
+		}
+		urlmap[entry.ShortKey] = entry
+
+		return nil
+	})
+}
+
+// openAppend opens the file for appending, creating it if it doesn't exist.
+func openAppend(filename string) (*os.File, error) {
+	return os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+}
+
+// replaceFile replaces the content of the file with entries, one JSON document per line.
+// Entries are written to a temporary file that is synced to disk and renamed over
+// the original one, so a failure or a crash never leaves a partially written file.
+func replaceFile[T any](filename string, entries []T) error {
+	tempFilename := filename + tempFileSuffix
+
+	tempFile, err := os.OpenFile(tempFilename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open temp file: %w", err)
+	}
+
+	writer := bufio.NewWriter(tempFile)
+	var buf []byte
+	for _, entry := range entries {
+		buf, err = appendRecord(buf[:0], entry)
+		if err != nil {
+			tempFile.Close()
+			os.Remove(tempFilename)
+			return fmt.Errorf("failed to encode entry: %w", err)
+		}
+		if _, err := writer.Write(buf); err != nil {
+			tempFile.Close()
+			os.Remove(tempFilename)
+			return fmt.Errorf("failed to write temp file: %w", err)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		tempFile.Close()
+		os.Remove(tempFilename)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tempFile.Sync(); err != nil {
+		tempFile.Close()
+		os.Remove(tempFilename)
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		os.Remove(tempFilename)
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	if err := os.Rename(tempFilename, filename); err != nil {
+		os.Remove(tempFilename)
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+
+	return syncDir(filepath.Dir(filename))
+}
+
+// syncDir syncs the directory to disk, so that renames inside it survive a crash.
+func syncDir(dirname string) error {
+	dir, err := os.Open(dirname)
+	if err != nil {
+		return fmt.Errorf("failed to open directory: %w", err)
+	}
+	defer dir.Close()
+
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync directory: %w", err)
+	}
+
+	return nil
+}
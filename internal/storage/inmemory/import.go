@@ -0,0 +1,154 @@
+package inmemory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+// ForEachURL calls fn with every stored URL, including deleted and expired ones, in ID order.
+// The URLs are collected before fn is called, so fn may use the storage.
+// Returns the first error returned by fn.
+func (s *Storage) ForEachURL(_ context.Context, fn func(url *model.URL) error) error {
+	s.mu.RLock()
+	urls := make([]*model.URL, 0, len(s.urlmap))
+	for _, url := range s.urlmap {
+		urls = append(urls, url)
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(urls, func(a, b *model.URL) int {
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+
+	for _, url := range urls {
+		if err := fn(url); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ImportURLs saves urls with all of their attributes, including the deletion mark, as they are.
+// URLs with IDs that are already stored are skipped, so importing the same URLs again is safe.
+// Returns the number of saved URLs, or storage.ErrShortKeyConflict and saves nothing
+// if a short key is taken by a URL with another ID.
+func (s *Storage) ImportURLs(ctx context.Context, urls []*model.URL) (int64, error) {
+	newURLs := make([]*model.URL, 0, len(urls))
+
+	err := commit(&s.mu, func() (pendingSync, error) {
+		batchIDs := make(map[uuid.UUID]struct{}, len(urls))
+		batchKeys := make(map[string]struct{}, len(urls))
+
+		for _, url := range urls {
+			if _, ok := s.urlIDs[url.ID]; ok {
+				continue
+			}
+			if _, ok := batchIDs[url.ID]; ok {
+				continue
+			}
+
+			_, inStorage := s.urlmap[url.ShortKey]
+			_, inBatch := batchKeys[url.ShortKey]
+			if inStorage || inBatch {
+				return pendingSync{}, storage.ErrShortKeyConflict
+			}
+			batchIDs[url.ID] = struct{}{}
+			batchKeys[url.ShortKey] = struct{}{}
+
+			newURLs = append(newURLs, url)
+		}
+
+		if len(newURLs) == 0 {
+			return pendingSync{}, nil
+		}
+
+		pending, err := s.saveToFileBatch(ctx, newURLs)
+		if err != nil {
+			return pendingSync{}, fmt.Errorf("failed to encode urls to file: %w", err)
+		}
+
+		for _, url := range newURLs {
+			s.addURL(url)
+		}
+
+		return pending, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(newURLs)), nil
+}
+
+// SyncURLs replaces the mutable attributes of stored URLs with the ones of urls with the same IDs:
+// the original URL, the deletion mark, the expiration, the password hash and the clicks left.
+// Changed URLs are appended to the file in a single write and replace the stored ones.
+// URLs that are not stored are ignored. Returns the number of changed URLs.
+func (s *Storage) SyncURLs(ctx context.Context, urls []*model.URL) (int64, error) {
+	changed := make([]*model.URL, 0, len(urls))
+
+	err := commit(&s.mu, func() (pendingSync, error) {
+		for _, url := range urls {
+			stored := s.urlByID(url.ID)
+			if stored == nil || sameMutableAttributes(stored, url) {
+				continue
+			}
+
+			updated := *stored
+			updated.OriginalURL = url.OriginalURL
+			updated.DeletedAt = url.DeletedAt
+			updated.ExpiresAt = url.ExpiresAt
+			updated.PasswordHash = url.PasswordHash
+			updated.ClicksLeft = url.ClicksLeft
+			changed = append(changed, &updated)
+		}
+
+		if len(changed) == 0 {
+			return pendingSync{}, nil
+		}
+
+		pending, err := s.saveToFileBatch(ctx, changed)
+		if err != nil {
+			return pendingSync{}, fmt.Errorf("failed to encode urls to file: %w", err)
+		}
+
+		for _, url := range changed {
+			s.replaceURL(url)
+		}
+
+		return pending, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(changed)), nil
+}
+
+// sameMutableAttributes reports whether a and b have the same attributes that SyncURLs replaces.
+func sameMutableAttributes(a, b *model.URL) bool {
+	return a.OriginalURL == b.OriginalURL &&
+		sameTime(a.DeletedAt, b.DeletedAt) &&
+		sameTime(a.ExpiresAt, b.ExpiresAt) &&
+		a.PasswordHash == b.PasswordHash &&
+		(a.ClicksLeft == nil) == (b.ClicksLeft == nil) &&
+		(a.ClicksLeft == nil || *a.ClicksLeft == *b.ClicksLeft)
+}
+
+// sameTime reports whether a and b are both nil or the same moment.
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}
@@ -0,0 +1,105 @@
+package inmemory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+func TestStorage_ImportURLs(t *testing.T) {
+	ctx := context.Background()
+	filename := t.TempDir() + "/urls"
+	deletedAt := time.Now().UTC()
+
+	alive := &model.URL{ID: uuid.New(), ShortKey: "alive", OriginalURL: "https://alive.ru", UserID: uuid.New()}
+	deleted := &model.URL{ID: uuid.New(), ShortKey: "deleted", OriginalURL: "https://alive.ru", UserID: uuid.New(), DeletedAt: &deletedAt}
+
+	s, err := NewStorage(filename)
+	require.NoError(t, err)
+
+	imported, err := s.ImportURLs(ctx, []*model.URL{alive, deleted, alive})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), imported)
+
+	// Importing the same URLs again is safe.
+	imported, err = s.ImportURLs(ctx, []*model.URL{alive, deleted})
+	require.NoError(t, err)
+	assert.Zero(t, imported)
+
+	taken := &model.URL{ID: uuid.New(), ShortKey: "alive", OriginalURL: "https://taken.ru"}
+	other := &model.URL{ID: uuid.New(), ShortKey: "other", OriginalURL: "https://other.ru"}
+	_, err = s.ImportURLs(ctx, []*model.URL{other, taken})
+	require.ErrorIs(t, err, storage.ErrShortKeyConflict)
+	_, err = s.GetURL(ctx, "other")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	require.NoError(t, s.Close())
+
+	// Imported URLs, including the deletion mark, survive a restart.
+	s, err = NewStorage(filename)
+	require.NoError(t, err)
+	defer s.Close()
+
+	urls := make(map[string]*model.URL)
+	err = s.ForEachURL(ctx, func(url *model.URL) error {
+		urls[url.ShortKey] = url
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, urls, 2)
+	assert.Equal(t, alive.UserID, urls["alive"].UserID)
+	require.NotNil(t, urls["deleted"].DeletedAt)
+	assert.True(t, deletedAt.Equal(*urls["deleted"].DeletedAt))
+}
+
+func TestStorage_SyncURLs(t *testing.T) {
+	ctx := context.Background()
+	filename := t.TempDir() + "/urls"
+	deletedAt := time.Now().UTC()
+	clicksLeft := int64(3)
+
+	url := &model.URL{ID: uuid.New(), ShortKey: "url", OriginalURL: "https://url.ru", UserID: uuid.New()}
+	unknown := &model.URL{ID: uuid.New(), ShortKey: "unknown", OriginalURL: "https://unknown.ru"}
+
+	s, err := NewStorage(filename)
+	require.NoError(t, err)
+
+	_, err = s.ImportURLs(ctx, []*model.URL{url})
+	require.NoError(t, err)
+
+	changed := *url
+	changed.OriginalURL = "https://changed.ru"
+	changed.DeletedAt = &deletedAt
+	changed.ClicksLeft = &clicksLeft
+
+	synced, err := s.SyncURLs(ctx, []*model.URL{&changed, unknown})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), synced)
+
+	// Syncing URLs that didn't change changes nothing.
+	synced, err = s.SyncURLs(ctx, []*model.URL{&changed})
+	require.NoError(t, err)
+	assert.Zero(t, synced)
+
+	_, err = s.GetURL(ctx, "unknown")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	require.NoError(t, s.Close())
+
+	// Synced URLs survive a restart.
+	s, err = NewStorage(filename)
+	require.NoError(t, err)
+	defer s.Close()
+
+	stored, err := s.GetURL(ctx, "url")
+	require.NoError(t, err)
+	assert.Equal(t, "https://changed.ru", stored.OriginalURL)
+	require.NotNil(t, stored.DeletedAt)
+	assert.True(t, deletedAt.Equal(*stored.DeletedAt))
+	assert.Equal(t, &clicksLeft, stored.ClicksLeft)
+}
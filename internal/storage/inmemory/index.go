@@ -0,0 +1,169 @@
+package inmemory
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+// keyIndex maps an attribute of stored URLs to the short keys of the URLs having it.
+type keyIndex[K comparable] map[K]map[string]struct{}
+
+// add indexes shortKey under key.
+func (idx keyIndex[K]) add(key K, shortKey string) {
+	keys, ok := idx[key]
+	if !ok {
+		keys = make(map[string]struct{}, 1)
+		idx[key] = keys
+	}
+	keys[shortKey] = struct{}{}
+}
+
+// remove removes shortKey from the keys indexed under key.
+func (idx keyIndex[K]) remove(key K, shortKey string) {
+	keys := idx[key]
+	delete(keys, shortKey)
+	if len(keys) == 0 {
+		delete(idx, key)
+	}
+}
+
+// indexURLs rebuilds the secondary indexes of the stored URLs. Callers must hold s.mu.
+func (s *Storage) indexURLs() {
+	s.originalURLs = make(keyIndex[string], len(s.urlmap))
+	s.userURLs = make(keyIndex[uuid.UUID])
+	s.urlIDs = make(map[uuid.UUID]string, len(s.urlmap))
+
+	for _, url := range s.urlmap {
+		s.indexURL(url)
+	}
+}
+
+// indexURL adds url to the secondary indexes. Callers must hold s.mu.
+func (s *Storage) indexURL(url *model.URL) {
+	s.originalURLs.add(url.OriginalURL, url.ShortKey)
+	s.userURLs.add(url.UserID, url.ShortKey)
+	s.urlIDs[url.ID] = url.ShortKey
+}
+
+// unindexURL removes url from the secondary indexes. Callers must hold s.mu.
+func (s *Storage) unindexURL(url *model.URL) {
+	s.originalURLs.remove(url.OriginalURL, url.ShortKey)
+	s.userURLs.remove(url.UserID, url.ShortKey)
+	if s.urlIDs[url.ID] == url.ShortKey {
+		delete(s.urlIDs, url.ID)
+	}
+}
+
+// addURL puts url into the map and the indexes. Callers must hold s.mu.
+func (s *Storage) addURL(url *model.URL) {
+	if s.urlIDs == nil {
+		s.indexURLs()
+	}
+
+	s.urlmap[url.ShortKey] = url
+	s.indexURL(url)
+}
+
+// replaceURL replaces the stored URL with the same short key by url. Callers must hold s.mu.
+func (s *Storage) replaceURL(url *model.URL) {
+	if stored, ok := s.urlmap[url.ShortKey]; ok && s.urlIDs != nil {
+		s.unindexURL(stored)
+	}
+
+	s.addURL(url)
+}
+
+// removeURL removes url from the map and the indexes. Callers must hold s.mu.
+func (s *Storage) removeURL(url *model.URL) {
+	if s.urlIDs != nil {
+		s.unindexURL(url)
+	}
+
+	delete(s.urlmap, url.ShortKey)
+}
+
+// urlByID returns the stored URL with the given ID or nil if there is no such URL.
+// Callers must hold s.mu.
+func (s *Storage) urlByID(id uuid.UUID) *model.URL {
+	shortKey, ok := s.urlIDs[id]
+	if !ok {
+		return nil
+	}
+
+	return s.urlmap[shortKey]
+}
+
+// canReuse reports whether existing can be returned instead of saving url
+// within scope. Only reusable URLs that are neither deleted nor expired by now
+// are reused, which matches the PostgreSQL storage.
+func canReuse(existing, url *model.URL, scope storage.DedupeScope, now time.Time) bool {
+	if scope == storage.DedupeNone || !url.CanBeReused() || !existing.CanBeReused() {
+		return false
+	}
+	if existing.DeletedAt != nil || existing.IsExpired(now) {
+		return false
+	}
+
+	return scope == storage.DedupeGlobal || existing.UserID == url.UserID
+}
+
+// findReusable returns the first of candidates that can be reused instead of saving url within scope.
+// Returns nil if there is no such URL.
+func findReusable(candidates []*model.URL, url *model.URL, scope storage.DedupeScope, now time.Time) *model.URL {
+	for _, candidate := range candidates {
+		if canReuse(candidate, url, scope, now) {
+			return candidate
+		}
+	}
+
+	return nil
+}
+
+// findActiveURL returns a stored URL that can be reused instead of saving url within scope.
+// Returns nil if there is no such URL. Callers must hold s.mu.
+func (s *Storage) findActiveURL(url *model.URL, scope storage.DedupeScope, now time.Time) *model.URL {
+	for shortKey := range s.originalURLs[url.OriginalURL] {
+		existing := s.urlmap[shortKey]
+		if existing != nil && canReuse(existing, url, scope, now) {
+			return existing
+		}
+	}
+
+	return nil
+}
+
+// TakenShortKeys returns the short keys among shortKeys that are taken by stored URLs, including deleted ones.
+func (s *Storage) TakenShortKeys(_ context.Context, shortKeys []string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	taken := make([]string, 0)
+	for _, shortKey := range shortKeys {
+		if _, ok := s.urlmap[shortKey]; ok {
+			taken = append(taken, shortKey)
+		}
+	}
+
+	return taken, nil
+}
+
+// FindActiveURLs returns the active URLs that would be reused instead of saving urls within scope,
+// at the indexes of urls. Indexes of urls without such a URL are nil.
+func (s *Storage) FindActiveURLs(_ context.Context, urls []*model.URL, scope storage.DedupeScope) ([]*model.URL, error) {
+	now := time.Now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := make([]*model.URL, len(urls))
+	for i, url := range urls {
+		found[i] = s.findActiveURL(url, scope, now)
+	}
+
+	return found, nil
+}
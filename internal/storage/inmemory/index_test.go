@@ -0,0 +1,57 @@
+package inmemory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+func TestStorage_Indexes(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	s, err := NewStorage(t.TempDir() + "/urls")
+	require.NoError(t, err)
+	defer s.Close()
+
+	first := &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru", UserID: userID}
+	second := &model.URL{ID: uuid.New(), ShortKey: "def", OriginalURL: "https://google.com", UserID: userID}
+	_, err = s.SetURLs(ctx, []*model.URL{first, second}, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	updated := *first
+	updated.OriginalURL = "https://yandex.ru"
+	_, err = s.UpdateURL(ctx, &updated, storage.FieldOriginalURL)
+	require.NoError(t, err)
+
+	assert.NotContains(t, s.originalURLs, "https://ya.ru")
+	assert.Contains(t, s.originalURLs["https://yandex.ru"], "abc")
+
+	require.NoError(t, s.DeleteURLs(ctx, []uuid.UUID{second.ID, second.ID}))
+
+	urls, err := s.GetURLsByUserID(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "https://yandex.ru", urls[0].OriginalURL)
+
+	urls, err = s.GetDeletedURLsByUserID(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, second.ID, urls[0].ID)
+
+	removed, err := s.PurgeDeletedURLs(ctx, time.Now().Add(time.Hour), 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	assert.Nil(t, s.urlByID(second.ID))
+	assert.NotContains(t, s.originalURLs, "https://google.com")
+	assert.Len(t, s.userURLs[userID], 1)
+	assert.Equal(t, "abc", s.urlByID(first.ID).ShortKey)
+}
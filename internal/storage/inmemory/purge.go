@@ -0,0 +1,129 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dtroode/urlshorter/internal/model"
+)
+
+// PurgeDeletedURLs permanently removes at most limit URLs deleted before the given moment
+// together with their click events.
+// The storage is compacted without the removed URLs, and the stored URLs are changed
+// only after the snapshot has been written. URLs changed while the snapshot was written,
+// for example restored ones, are not removed.
+// Returns the number of removed URLs.
+func (s *Storage) PurgeDeletedURLs(_ context.Context, before time.Time, limit int) (int64, error) {
+	s.compactMu.Lock()
+	defer s.compactMu.Unlock()
+
+	s.mu.RLock()
+	found := 0
+	for _, url := range s.urlmap {
+		if isPurgeable(url, before) {
+			found++
+		}
+	}
+	s.mu.RUnlock()
+
+	if found == 0 {
+		return 0, nil
+	}
+
+	excluded, err := s.compact(purgeSelector(before, limit))
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	removed := make(map[uuid.UUID]struct{}, len(excluded))
+	for shortKey, url := range excluded {
+		if s.urlmap[shortKey] != url {
+			continue
+		}
+		s.removeURL(url)
+		removed[url.ID] = struct{}{}
+	}
+	s.mu.Unlock()
+
+	if err := s.purgeClicks(removed); err != nil {
+		return int64(len(removed)), err
+	}
+
+	return int64(len(removed)), nil
+}
+
+// isPurgeable reports whether url was deleted before the given moment.
+func isPurgeable(url *model.URL, before time.Time) bool {
+	return url.DeletedAt != nil && url.DeletedAt.Before(before)
+}
+
+// purgeSelector returns a function selecting at most limit URLs deleted before the given moment.
+func purgeSelector(before time.Time, limit int) func(url *model.URL) bool {
+	selected := 0
+
+	return func(url *model.URL) bool {
+		if selected >= limit || !isPurgeable(url, before) {
+			return false
+		}
+		selected++
+		return true
+	}
+}
+
+// purgeClicks removes click events of the URLs with the given IDs
+// and rewrites the clicks file without them.
+func (s *Storage) purgeClicks(urlIDs map[uuid.UUID]struct{}) error {
+	s.clicksMu.Lock()
+	defer s.clicksMu.Unlock()
+
+	hasClicks := false
+	for id := range urlIDs {
+		if _, ok := s.clicks[id]; ok {
+			hasClicks = true
+			break
+		}
+	}
+	if !hasClicks {
+		return nil
+	}
+
+	kept := make([]*model.Click, 0)
+	for urlID, clicks := range s.clicks {
+		if _, ok := urlIDs[urlID]; !ok {
+			kept = append(kept, clicks...)
+		}
+	}
+
+	file, err := rewriteClicksFile(s.filename+clicksFileSuffix, kept, s.syncPolicy)
+	if err != nil {
+		return err
+	}
+	s.clicksFile.retire()
+	s.clicksFile = file
+
+	for id := range urlIDs {
+		delete(s.clicks, id)
+		delete(s.clickCounters, id)
+	}
+
+	return nil
+}
+
+// rewriteClicksFile replaces the content of the clicks file with clicks
+// and opens it for appending.
+func rewriteClicksFile(filename string, clicks []*model.Click, policy SyncPolicy) (*recordFile, error) {
+	if err := replaceFile(filename, clicks); err != nil {
+		return nil, fmt.Errorf("failed to rewrite clicks file: %w", err)
+	}
+
+	file, err := openRecordFile(filename, policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open clicks file for append: %w", err)
+	}
+
+	return file, nil
+}
@@ -0,0 +1,78 @@
+package inmemory
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+func TestStorage_PurgeDeletedURLs(t *testing.T) {
+	ctx := context.Background()
+	filename := t.TempDir() + "/urls"
+	now := time.Now().UTC()
+	longAgo := now.Add(-48 * time.Hour)
+	recently := now.Add(-time.Hour)
+
+	old1 := &model.URL{ID: uuid.New(), ShortKey: "old1", OriginalURL: "https://old1.ru", DeletedAt: &longAgo}
+	old2 := &model.URL{ID: uuid.New(), ShortKey: "old2", OriginalURL: "https://old2.ru", DeletedAt: &longAgo}
+	recent := &model.URL{ID: uuid.New(), ShortKey: "recent", OriginalURL: "https://recent.ru", DeletedAt: &recently}
+	alive := &model.URL{ID: uuid.New(), ShortKey: "alive", OriginalURL: "https://alive.ru"}
+
+	s, err := NewStorage(filename)
+	require.NoError(t, err)
+	_, err = s.SetURLs(ctx, []*model.URL{old1, old2, recent, alive}, storage.DedupeGlobal)
+	require.NoError(t, err)
+	require.NoError(t, s.SaveClick(ctx, model.NewClick(old1.ID, now, "", "", "")))
+	require.NoError(t, s.SaveClick(ctx, model.NewClick(alive.ID, now, "", "", "")))
+
+	before := now.Add(-24 * time.Hour)
+
+	removed, err := s.PurgeDeletedURLs(ctx, before, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	removed, err = s.PurgeDeletedURLs(ctx, before, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	removed, err = s.PurgeDeletedURLs(ctx, before, 10)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+
+	// Entries written after the purge are appended to the rewritten file.
+	_, err = s.SetURL(ctx, &model.URL{ID: uuid.New(), ShortKey: "new", OriginalURL: "https://new.ru"}, storage.DedupeGlobal)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	_, err = os.Stat(filename + tempFileSuffix)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	s, err = NewStorage(filename)
+	require.NoError(t, err)
+	defer s.Close()
+
+	for _, shortKey := range []string{"old1", "old2"} {
+		_, err = s.GetURL(ctx, shortKey)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	}
+	for _, shortKey := range []string{"recent", "alive", "new"} {
+		_, err = s.GetURL(ctx, shortKey)
+		assert.NoError(t, err)
+	}
+
+	stats, err := s.GetClickStats(ctx, old1.ID, time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, stats.Total)
+
+	stats, err = s.GetClickStats(ctx, alive.ID, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Total)
+}
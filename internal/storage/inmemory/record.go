@@ -0,0 +1,130 @@
+package inmemory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"github.com/dtroode/urlshorter/internal/model"
+)
+
+// Records are stored one per line as the hex encoded CRC-32C checksum of the JSON document,
+// a space and the JSON document itself. Lines starting with '{' are records written
+// before checksums were introduced and are read without verification.
+const (
+	checksumLen = 8
+	recordSep   = ' '
+)
+
+// errChecksumMismatch is returned when the checksum of a record doesn't match its content.
+var errChecksumMismatch = errors.New("record checksum mismatch")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// appendRecord appends the record of v to buf.
+func appendRecord(buf []byte, v any) ([]byte, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	buf = hex.AppendEncode(buf, binary.BigEndian.AppendUint32(nil, crc32.Checksum(payload, crcTable)))
+	buf = append(buf, recordSep)
+	buf = append(buf, payload...)
+	buf = append(buf, '\n')
+
+	return buf, nil
+}
+
+// encodeURLs returns the records of urls.
+func encodeURLs(urls []*model.URL) ([]byte, error) {
+	var records []byte
+	for _, url := range urls {
+		var err error
+		records, err = appendRecord(records, url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal url: %w", err)
+		}
+	}
+
+	return records, nil
+}
+
+// decodeRecord verifies the checksum of the record line and unmarshals its JSON document into v.
+func decodeRecord(line []byte, v any) error {
+	line = bytes.TrimSuffix(line, []byte{'\n'})
+
+	if len(line) > 0 && line[0] == '{' {
+		return json.Unmarshal(line, v)
+	}
+
+	if len(line) <= checksumLen || line[checksumLen] != recordSep {
+		return errChecksumMismatch
+	}
+
+	checksum := make([]byte, crc32.Size)
+	if _, err := hex.Decode(checksum, line[:checksumLen]); err != nil {
+		return errChecksumMismatch
+	}
+
+	payload := line[checksumLen+1:]
+	if binary.BigEndian.Uint32(checksum) != crc32.Checksum(payload, crcTable) {
+		return errChecksumMismatch
+	}
+
+	return json.Unmarshal(payload, v)
+}
+
+// readRecords calls decode for every record line of the file in order.
+// A missing file is treated as an empty one.
+// A broken last record, left by a crash in the middle of a write, is a torn tail:
+// the file is truncated to the last complete record, so appends continue after it.
+// A broken record followed by other records is reported as an error.
+func readRecords(filename string, decode func(line []byte) error) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open file for read: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		if len(line) == 0 {
+			return nil
+		}
+
+		complete := line[len(line)-1] == '\n'
+		var decodeErr error
+		if complete {
+			decodeErr = decode(line)
+		}
+
+		if !complete || decodeErr != nil {
+			if _, peekErr := reader.Peek(1); !errors.Is(peekErr, io.EOF) {
+				return fmt.Errorf("broken record at offset %d of %s: %w", offset, filename, decodeErr)
+			}
+			if err := os.Truncate(filename, offset); err != nil {
+				return fmt.Errorf("failed to truncate torn tail: %w", err)
+			}
+			return nil
+		}
+
+		offset += int64(len(line))
+	}
+}
@@ -0,0 +1,143 @@
+package inmemory
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+func TestDecodeRecord(t *testing.T) {
+	url := &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru"}
+
+	record, err := appendRecord(nil, url)
+	require.NoError(t, err)
+
+	legacy, err := json.Marshal(url)
+	require.NoError(t, err)
+
+	corrupted := []byte(string(record))
+	corrupted[len(corrupted)-3] = 'X'
+
+	tests := map[string]struct {
+		line          []byte
+		expectedError bool
+	}{
+		"valid record": {
+			line: record,
+		},
+		"record without checksum": {
+			line: append(legacy, '\n'),
+		},
+		"checksum mismatch": {
+			line:          corrupted,
+			expectedError: true,
+		},
+		"truncated record": {
+			line:          record[:len(record)/2],
+			expectedError: true,
+		},
+		"invalid checksum": {
+			line:          []byte("zzzzzzzz " + string(record[checksumLen+1:])),
+			expectedError: true,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			decoded := &model.URL{}
+			err := decodeRecord(tt.line, decoded)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, url, decoded)
+		})
+	}
+}
+
+func TestStorage_NewStorage_TornTail(t *testing.T) {
+	tests := map[string]struct {
+		tail string
+	}{
+		"partial record": {
+			tail: `1234abcd {"id":"`,
+		},
+		"record without newline": {
+			tail: `{"id":"` + uuid.NewString() + `","short_key":"torn","original_url":"https://torn.ru"}`,
+		},
+		"checksum mismatch": {
+			tail: `00000000 {"id":"` + uuid.NewString() + `","short_key":"torn","original_url":"https://torn.ru"}` + "\n",
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			ctx := context.Background()
+			filename := t.TempDir() + "/urls"
+
+			s, err := NewStorage(filename)
+			require.NoError(t, err)
+			_, err = s.SetURL(ctx, &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru"}, storage.DedupeGlobal)
+			require.NoError(t, err)
+			require.NoError(t, s.Close())
+
+			file, err := os.OpenFile(filename+tailFileSuffix, os.O_WRONLY|os.O_APPEND, 0600)
+			require.NoError(t, err)
+			_, err = file.WriteString(tt.tail)
+			require.NoError(t, err)
+			require.NoError(t, file.Close())
+
+			s, err = NewStorage(filename)
+			require.NoError(t, err)
+
+			_, err = s.GetURL(ctx, "abc")
+			require.NoError(t, err)
+			_, err = s.GetURL(ctx, "torn")
+			assert.Error(t, err)
+
+			// Records written after recovery follow the last complete record.
+			_, err = s.SetURL(ctx, &model.URL{ID: uuid.New(), ShortKey: "def", OriginalURL: "https://google.com"}, storage.DedupeGlobal)
+			require.NoError(t, err)
+			require.NoError(t, s.Close())
+
+			s, err = NewStorage(filename)
+			require.NoError(t, err)
+			defer s.Close()
+
+			for _, shortKey := range []string{"abc", "def"} {
+				_, err = s.GetURL(ctx, shortKey)
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestStorage_NewStorage_BrokenRecord(t *testing.T) {
+	ctx := context.Background()
+	filename := t.TempDir() + "/urls"
+
+	s, err := NewStorage(filename)
+	require.NoError(t, err)
+	_, err = s.SetURL(ctx, &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru"}, storage.DedupeGlobal)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	data, err := os.ReadFile(filename + tailFileSuffix)
+	require.NoError(t, err)
+	broken := "00000000 " + string(data[checksumLen+1:])
+	require.NoError(t, os.WriteFile(filename+tailFileSuffix, []byte(broken+string(data)), 0600))
+
+	_, err = NewStorage(filename)
+	assert.ErrorIs(t, err, errChecksumMismatch)
+}
@@ -0,0 +1,806 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dtroode/urlshorter/internal/logger"
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+// ShardedStorage represents in-memory storage implementation partitioned into shards
+// by a hash of the short key. Every shard has its own lock, so operations on URLs
+// in different shards don't block each other. Secondary indexes are partitioned
+// into stripes with their own locks.
+//
+// URLs are persisted to the same files as Storage uses by a single writer
+// that appends changes in order, so either of the storages can open the files.
+//
+// To avoid deadlocks locks are acquired in a fixed order: original URL stripes,
+// then shards, both in ascending order. User and ID stripes are held only
+// while the index is read or changed.
+type ShardedStorage struct {
+	filename string
+	seed     maphash.Seed
+
+	shards []urlShard
+	// originalURLs index stored URLs by original URL to find URLs that can be reused.
+	// The stripe of an original URL is held while URLs with it are saved or changed.
+	originalURLs []indexStripe[string]
+	// userURLs index stored URLs by user ID.
+	userURLs []indexStripe[uuid.UUID]
+	// urlIDs map IDs of stored URLs to their short keys.
+	urlIDs []idStripe
+	file   *fileWriter
+
+	// compactMu serializes compactions and purges.
+	compactMu sync.Mutex
+	// lastSealedTail is the sequence number of the last sealed tail.
+	lastSealedTail int
+
+	clickShards []clickShard
+	clicksFile  *fileWriter
+
+	syncPolicy SyncPolicy
+}
+
+// urlShard holds URLs whose short keys hash to the shard.
+type urlShard struct {
+	mu   sync.RWMutex
+	urls URLMap
+}
+
+// indexStripe holds a part of a secondary index.
+type indexStripe[K comparable] struct {
+	mu   sync.Mutex
+	keys keyIndex[K]
+}
+
+// idStripe holds a part of the ID index.
+type idStripe struct {
+	mu  sync.RWMutex
+	ids map[uuid.UUID]string
+}
+
+// clickShard holds click events of URLs whose IDs hash to the shard.
+type clickShard struct {
+	mu     sync.RWMutex
+	clicks map[uuid.UUID][]*model.Click
+	// counters aggregate clicks for statistics.
+	counters clickCounters
+}
+
+// NewShardedStorage creates new sharded in-memory storage instance with the given number of shards.
+// The files are loaded the same way as by NewStorage.
+func NewShardedStorage(filename string, shards int, opts ...Option) (*ShardedStorage, error) {
+	if shards <= 0 {
+		return nil, fmt.Errorf("number of shards must be positive, got %d", shards)
+	}
+
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	urlmap, lastSealedTail, err := loadURLs(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	clicks, err := loadClicks(filename + clicksFileSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	writeFile, err := openRecordFile(filename+tailFileSuffix, o.syncPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file for append: %w", err)
+	}
+
+	clicksFile, err := openRecordFile(filename+clicksFileSuffix, o.syncPolicy)
+	if err != nil {
+		writeFile.close()
+		return nil, fmt.Errorf("failed to open clicks file for append: %w", err)
+	}
+
+	if o.syncPolicy != SyncNever {
+		if err := syncDir(filepath.Dir(filename)); err != nil {
+			writeFile.close()
+			clicksFile.close()
+			return nil, err
+		}
+	}
+
+	s := &ShardedStorage{
+		filename:       filename,
+		seed:           maphash.MakeSeed(),
+		shards:         make([]urlShard, shards),
+		originalURLs:   make([]indexStripe[string], shards),
+		userURLs:       make([]indexStripe[uuid.UUID], shards),
+		urlIDs:         make([]idStripe, shards),
+		lastSealedTail: lastSealedTail,
+		clickShards:    make([]clickShard, shards),
+		syncPolicy:     o.syncPolicy,
+	}
+	for i := 0; i < shards; i++ {
+		s.shards[i].urls = URLMap{}
+		s.originalURLs[i].keys = keyIndex[string]{}
+		s.userURLs[i].keys = keyIndex[uuid.UUID]{}
+		s.urlIDs[i].ids = map[uuid.UUID]string{}
+		s.clickShards[i].clicks = map[uuid.UUID][]*model.Click{}
+		s.clickShards[i].counters = clickCounters{}
+	}
+
+	for _, url := range urlmap {
+		s.addURL(url)
+	}
+	for urlID, urlClicks := range clicks {
+		shard := &s.clickShards[s.uuidIndex(urlID)]
+		shard.clicks[urlID] = urlClicks
+		for _, click := range urlClicks {
+			shard.counters.add(click)
+		}
+	}
+
+	var syncInterval time.Duration
+	if o.syncPolicy == SyncInterval {
+		syncInterval = o.syncInterval
+	}
+	s.file = newFileWriter(writeFile, syncInterval)
+	s.clicksFile = newFileWriter(clicksFile, syncInterval)
+
+	return s, nil
+}
+
+// Ping checks if the storage is available.
+func (s *ShardedStorage) Ping(_ context.Context) error {
+	return nil
+}
+
+// Close syncs unsynced records, unless the sync policy is SyncNever, and closes the underlying files.
+func (s *ShardedStorage) Close() error {
+	return errors.Join(s.file.close(), s.clicksFile.close())
+}
+
+// stringIndex returns the index of the shard or stripe of value.
+func (s *ShardedStorage) stringIndex(value string) int {
+	return int(maphash.String(s.seed, value) % uint64(len(s.shards)))
+}
+
+// uuidIndex returns the index of the shard or stripe of id.
+func (s *ShardedStorage) uuidIndex(id uuid.UUID) int {
+	return int(maphash.Bytes(s.seed, id[:]) % uint64(len(s.shards)))
+}
+
+// shardOf returns the shard of shortKey.
+func (s *ShardedStorage) shardOf(shortKey string) *urlShard {
+	return &s.shards[s.stringIndex(shortKey)]
+}
+
+// sortedIndexes returns distinct indexes of values in ascending order.
+func (s *ShardedStorage) sortedIndexes(values []string) []int {
+	indexes := make([]int, 0, len(values))
+	for _, value := range values {
+		indexes = append(indexes, s.stringIndex(value))
+	}
+	slices.Sort(indexes)
+
+	return slices.Compact(indexes)
+}
+
+// lockStripes locks the stripes of originalURLs and returns a function unlocking them.
+func (s *ShardedStorage) lockStripes(originalURLs ...string) func() {
+	indexes := s.sortedIndexes(originalURLs)
+	for _, i := range indexes {
+		s.originalURLs[i].mu.Lock()
+	}
+
+	return func() {
+		for _, i := range indexes {
+			s.originalURLs[i].mu.Unlock()
+		}
+	}
+}
+
+// lockShards locks the shards of shortKeys for writing and returns a function unlocking them.
+func (s *ShardedStorage) lockShards(shortKeys ...string) func() {
+	indexes := s.sortedIndexes(shortKeys)
+	for _, i := range indexes {
+		s.shards[i].mu.Lock()
+	}
+
+	return func() {
+		for _, i := range indexes {
+			s.shards[i].mu.Unlock()
+		}
+	}
+}
+
+// lockAllShards locks all shards for writing and returns a function unlocking them.
+// No URLs are written while all shards are locked, as writers hold their shards until the write completes.
+func (s *ShardedStorage) lockAllShards() func() {
+	for i := range s.shards {
+		s.shards[i].mu.Lock()
+	}
+
+	return func() {
+		for i := range s.shards {
+			s.shards[i].mu.Unlock()
+		}
+	}
+}
+
+// loadURL returns the stored URL with the short key or nil if there is no such URL.
+func (s *ShardedStorage) loadURL(shortKey string) *model.URL {
+	shard := s.shardOf(shortKey)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	return shard.urls[shortKey]
+}
+
+// shortKeyByID returns the short key of the stored URL with the given ID.
+func (s *ShardedStorage) shortKeyByID(id uuid.UUID) (string, bool) {
+	stripe := &s.urlIDs[s.uuidIndex(id)]
+	stripe.mu.RLock()
+	defer stripe.mu.RUnlock()
+
+	shortKey, ok := stripe.ids[id]
+
+	return shortKey, ok
+}
+
+// addURL puts url into its shard and the indexes.
+// Callers must hold the original URL stripe and the shard of url.
+func (s *ShardedStorage) addURL(url *model.URL) {
+	s.shardOf(url.ShortKey).urls[url.ShortKey] = url
+	s.originalURLs[s.stringIndex(url.OriginalURL)].keys.add(url.OriginalURL, url.ShortKey)
+
+	users := &s.userURLs[s.uuidIndex(url.UserID)]
+	users.mu.Lock()
+	users.keys.add(url.UserID, url.ShortKey)
+	users.mu.Unlock()
+
+	ids := &s.urlIDs[s.uuidIndex(url.ID)]
+	ids.mu.Lock()
+	ids.ids[url.ID] = url.ShortKey
+	ids.mu.Unlock()
+}
+
+// removeURL removes url from its shard and the indexes.
+// Callers must hold the original URL stripe and the shard of url.
+func (s *ShardedStorage) removeURL(url *model.URL) {
+	delete(s.shardOf(url.ShortKey).urls, url.ShortKey)
+	s.originalURLs[s.stringIndex(url.OriginalURL)].keys.remove(url.OriginalURL, url.ShortKey)
+
+	users := &s.userURLs[s.uuidIndex(url.UserID)]
+	users.mu.Lock()
+	users.keys.remove(url.UserID, url.ShortKey)
+	users.mu.Unlock()
+
+	ids := &s.urlIDs[s.uuidIndex(url.ID)]
+	ids.mu.Lock()
+	if ids.ids[url.ID] == url.ShortKey {
+		delete(ids.ids, url.ID)
+	}
+	ids.mu.Unlock()
+}
+
+// findActiveURL returns a stored URL that can be reused instead of saving url within scope.
+// Returns nil if there is no such URL. Callers must hold the original URL stripe of url
+// and must not hold any shards.
+func (s *ShardedStorage) findActiveURL(url *model.URL, scope storage.DedupeScope, now time.Time) *model.URL {
+	for shortKey := range s.originalURLs[s.stringIndex(url.OriginalURL)].keys[url.OriginalURL] {
+		existing := s.loadURL(shortKey)
+		if existing != nil && canReuse(existing, url, scope, now) {
+			return existing
+		}
+	}
+
+	return nil
+}
+
+// saveURLs appends urls to the file in a single write.
+func (s *ShardedStorage) saveURLs(urls []*model.URL) error {
+	records, err := encodeURLs(urls)
+	if err != nil {
+		return err
+	}
+
+	return s.file.write(records)
+}
+
+// GetURL retrieves a URL by its short key.
+func (s *ShardedStorage) GetURL(_ context.Context, shortKey string) (*model.URL, error) {
+	url := s.loadURL(shortKey)
+	if url == nil {
+		return nil, storage.ErrNotFound
+	}
+
+	return url, nil
+}
+
+// GetURLs retrieves multiple URLs by their short keys.
+func (s *ShardedStorage) GetURLs(_ context.Context, shortKeys []string) ([]*model.URL, error) {
+	urls := make([]*model.URL, 0, len(shortKeys))
+
+	for _, shortKey := range shortKeys {
+		if url := s.loadURL(shortKey); url != nil {
+			urls = append(urls, url)
+		}
+	}
+
+	return urls, nil
+}
+
+// GetURLsByUserID retrieves all URLs created by a specific user.
+// Deleted URLs are not included.
+func (s *ShardedStorage) GetURLsByUserID(_ context.Context, userID uuid.UUID) ([]*model.URL, error) {
+	return s.userURLsByDeletion(userID, false), nil
+}
+
+// GetDeletedURLsByUserID retrieves all deleted URLs created by a specific user.
+func (s *ShardedStorage) GetDeletedURLsByUserID(_ context.Context, userID uuid.UUID) ([]*model.URL, error) {
+	return s.userURLsByDeletion(userID, true), nil
+}
+
+// userURLsByDeletion returns URLs of the user that are deleted or not deleted.
+func (s *ShardedStorage) userURLsByDeletion(userID uuid.UUID, deleted bool) []*model.URL {
+	users := &s.userURLs[s.uuidIndex(userID)]
+	users.mu.Lock()
+	shortKeys := make([]string, 0, len(users.keys[userID]))
+	for shortKey := range users.keys[userID] {
+		shortKeys = append(shortKeys, shortKey)
+	}
+	users.mu.Unlock()
+
+	urls := make([]*model.URL, 0, len(shortKeys))
+	for _, shortKey := range shortKeys {
+		url := s.loadURL(shortKey)
+		if url != nil && url.UserID == userID && (url.DeletedAt != nil) == deleted {
+			urls = append(urls, url)
+		}
+	}
+
+	return urls
+}
+
+// SetURL stores a single URL in the storage.
+// Depending on scope, an active URL with the same original URL
+// may be returned instead together with storage.ErrConflict.
+// Returns storage.ErrShortKeyConflict if the short key is already taken.
+func (s *ShardedStorage) SetURL(ctx context.Context, url *model.URL, scope storage.DedupeScope) (*model.URL, error) {
+	unlockStripes := s.lockStripes(url.OriginalURL)
+	defer unlockStripes()
+
+	if existing := s.findActiveURL(url, scope, time.Now()); existing != nil {
+		return existing, storage.ErrConflict
+	}
+
+	unlockShards := s.lockShards(url.ShortKey)
+	defer unlockShards()
+
+	if _, ok := s.shardOf(url.ShortKey).urls[url.ShortKey]; ok {
+		return nil, storage.ErrShortKeyConflict
+	}
+
+	if err := s.saveURLs([]*model.URL{url}); err != nil {
+		return nil, fmt.Errorf("failed to encode url to file: %w", err)
+	}
+
+	s.addURL(url)
+
+	return url, nil
+}
+
+// SetURLs stores multiple URLs in the storage.
+// Depending on scope, active URLs with the same original URLs,
+// including ones saved earlier in the same batch, may be returned in place of some of urls.
+// Returns storage.ErrShortKeyConflict and saves nothing if a short key is already taken.
+func (s *ShardedStorage) SetURLs(ctx context.Context, urls []*model.URL, scope storage.DedupeScope) ([]*model.URL, error) {
+	now := time.Now()
+
+	originalURLs := make([]string, 0, len(urls))
+	for _, url := range urls {
+		originalURLs = append(originalURLs, url.OriginalURL)
+	}
+	unlockStripes := s.lockStripes(originalURLs...)
+	defer unlockStripes()
+
+	savedURLs := make([]*model.URL, len(urls))
+	newURLs := make([]*model.URL, 0, len(urls))
+	shortKeys := make([]string, 0, len(urls))
+	batchKeys := make(map[string]struct{}, len(urls))
+	batchURLs := make(map[string][]*model.URL)
+
+	for i, url := range urls {
+		if existing := s.findActiveURL(url, scope, now); existing != nil {
+			savedURLs[i] = existing
+			continue
+		}
+		if existing := findReusable(batchURLs[url.OriginalURL], url, scope, now); existing != nil {
+			savedURLs[i] = existing
+			continue
+		}
+
+		if _, ok := batchKeys[url.ShortKey]; ok {
+			return nil, storage.ErrShortKeyConflict
+		}
+		batchKeys[url.ShortKey] = struct{}{}
+		batchURLs[url.OriginalURL] = append(batchURLs[url.OriginalURL], url)
+
+		savedURLs[i] = url
+		newURLs = append(newURLs, url)
+		shortKeys = append(shortKeys, url.ShortKey)
+	}
+
+	unlockShards := s.lockShards(shortKeys...)
+	defer unlockShards()
+
+	for _, url := range newURLs {
+		if _, ok := s.shardOf(url.ShortKey).urls[url.ShortKey]; ok {
+			return nil, storage.ErrShortKeyConflict
+		}
+	}
+
+	if err := s.saveURLs(newURLs); err != nil {
+		return nil, fmt.Errorf("failed to encode urls to file: %w", err)
+	}
+
+	for _, url := range newURLs {
+		s.addURL(url)
+	}
+
+	return savedURLs, nil
+}
+
+// UpdateURL replaces the mutable attributes in fields of an existing URL with the ones of url.
+// Other attributes keep their stored values. The short key, the owner and the creation data can't be changed.
+// Returns storage.ErrNotFound if the URL doesn't exist or is deleted.
+func (s *ShardedStorage) UpdateURL(_ context.Context, url *model.URL, fields storage.URLFields) (*model.URL, error) {
+	for {
+		current := s.loadURL(url.ShortKey)
+		if current == nil || current.ID != url.ID || current.DeletedAt != nil {
+			return nil, storage.ErrNotFound
+		}
+
+		updated, done, err := s.updateURL(current.OriginalURL, url, fields)
+		if done {
+			return updated, err
+		}
+	}
+}
+
+// updateURL updates the stored URL if its original URL is still originalURL.
+// Returns false if the original URL has changed meanwhile and the update must be retried
+// with the stripe of the new one.
+func (s *ShardedStorage) updateURL(originalURL string, url *model.URL, fields storage.URLFields) (*model.URL, bool, error) {
+	newOriginalURL := originalURL
+	if fields.Has(storage.FieldOriginalURL) {
+		newOriginalURL = url.OriginalURL
+	}
+
+	unlockStripes := s.lockStripes(originalURL, newOriginalURL)
+	defer unlockStripes()
+	unlockShards := s.lockShards(url.ShortKey)
+	defer unlockShards()
+
+	shard := s.shardOf(url.ShortKey)
+	stored, ok := shard.urls[url.ShortKey]
+	if !ok || stored.ID != url.ID || stored.DeletedAt != nil {
+		return nil, true, storage.ErrNotFound
+	}
+	if stored.OriginalURL != originalURL {
+		return nil, false, nil
+	}
+
+	updated := fields.Apply(stored, url)
+
+	if err := s.saveURLs([]*model.URL{updated}); err != nil {
+		return nil, true, fmt.Errorf("failed to encode url to file: %w", err)
+	}
+
+	shard.urls[updated.ShortKey] = updated
+	s.originalURLs[s.stringIndex(stored.OriginalURL)].keys.remove(stored.OriginalURL, stored.ShortKey)
+	s.originalURLs[s.stringIndex(updated.OriginalURL)].keys.add(updated.OriginalURL, updated.ShortKey)
+
+	return updated, true, nil
+}
+
+// DeleteURLs marks the specified URLs as deleted.
+// URLs that are already deleted keep the moment of their first deletion.
+func (s *ShardedStorage) DeleteURLs(_ context.Context, ids []uuid.UUID) error {
+	deletedAt := time.Now().UTC()
+
+	return s.setDeletedAt(ids, &deletedAt)
+}
+
+// RestoreURLs clears the deletion mark of the specified URLs.
+func (s *ShardedStorage) RestoreURLs(_ context.Context, ids []uuid.UUID) error {
+	return s.setDeletedAt(ids, nil)
+}
+
+// setDeletedAt sets DeletedAt of the URLs with the given IDs that are not already
+// in the requested deletion state. Changed URLs are appended to the file in a single write
+// and replace the stored ones only after the write succeeds.
+func (s *ShardedStorage) setDeletedAt(ids []uuid.UUID, deletedAt *time.Time) error {
+	shortKeys := make(map[uuid.UUID]string, len(ids))
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := shortKeys[id]; ok {
+			continue
+		}
+		if shortKey, ok := s.shortKeyByID(id); ok {
+			shortKeys[id] = shortKey
+			keys = append(keys, shortKey)
+		}
+	}
+
+	unlockShards := s.lockShards(keys...)
+	defer unlockShards()
+
+	changed := make([]*model.URL, 0, len(shortKeys))
+	for id, shortKey := range shortKeys {
+		url := s.shardOf(shortKey).urls[shortKey]
+		if url == nil || url.ID != id || (url.DeletedAt == nil) == (deletedAt == nil) {
+			continue
+		}
+
+		updated := *url
+		updated.DeletedAt = deletedAt
+		changed = append(changed, &updated)
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if err := s.saveURLs(changed); err != nil {
+		return fmt.Errorf("failed to write urls to file: %w", err)
+	}
+
+	for _, url := range changed {
+		s.shardOf(url.ShortKey).urls[url.ShortKey] = url
+	}
+
+	return nil
+}
+
+// DecrementClicksLeft uses up one redirect of a click-limited URL.
+// Returns storage.ErrNoClicksLeft if the URL doesn't exist, has no clicks left or is not click-limited.
+func (s *ShardedStorage) DecrementClicksLeft(_ context.Context, id uuid.UUID) error {
+	shortKey, ok := s.shortKeyByID(id)
+	if !ok {
+		return storage.ErrNoClicksLeft
+	}
+
+	unlockShards := s.lockShards(shortKey)
+	defer unlockShards()
+
+	shard := s.shardOf(shortKey)
+	url := shard.urls[shortKey]
+	if url == nil || url.ID != id || url.ClicksLeft == nil || *url.ClicksLeft <= 0 {
+		return storage.ErrNoClicksLeft
+	}
+
+	updated := *url
+	clicksLeft := *url.ClicksLeft - 1
+	updated.ClicksLeft = &clicksLeft
+
+	if err := s.saveURLs([]*model.URL{&updated}); err != nil {
+		return fmt.Errorf("failed to encode url to file: %w", err)
+	}
+
+	shard.urls[updated.ShortKey] = &updated
+
+	return nil
+}
+
+// Compact replaces the storage file with a snapshot of the stored URLs
+// and drops the changes already contained in it.
+// Writers are blocked only while the tail is switched, not while the snapshot is written.
+func (s *ShardedStorage) Compact(_ context.Context) error {
+	s.compactMu.Lock()
+	defer s.compactMu.Unlock()
+
+	_, err := s.compact(nil)
+
+	return err
+}
+
+// compact seals the tail and writes a snapshot of the URLs stored at that moment,
+// leaving out the ones for which exclude returns true.
+// Returns the left out URLs by short key. Callers must hold s.compactMu.
+func (s *ShardedStorage) compact(exclude func(url *model.URL) bool) (URLMap, error) {
+	unlockShards := s.lockAllShards()
+
+	kept := make([]*model.URL, 0)
+	excluded := URLMap{}
+	for i := range s.shards {
+		for shortKey, url := range s.shards[i].urls {
+			if exclude != nil && exclude(url) {
+				excluded[shortKey] = url
+				continue
+			}
+			kept = append(kept, url)
+		}
+	}
+
+	seq := s.lastSealedTail + 1
+	err := s.file.replace(func() (*recordFile, error) {
+		return sealTailFile(s.filename, seq, s.syncPolicy)
+	})
+	unlockShards()
+	if err != nil {
+		return nil, err
+	}
+	s.lastSealedTail = seq
+
+	if err := writeSnapshot(s.filename, kept, seq); err != nil {
+		return nil, err
+	}
+
+	return excluded, nil
+}
+
+// RunCompaction compacts the storage every interval until ctx is done.
+// Failed compactions are logged and retried after the next interval.
+func (s *ShardedStorage) RunCompaction(ctx context.Context, interval time.Duration, logger *logger.Logger) {
+	runCompaction(ctx, s, s.filename, interval, logger)
+}
+
+// PurgeDeletedURLs permanently removes at most limit URLs deleted before the given moment
+// together with their click events.
+// The storage is compacted without the removed URLs, and the stored URLs are changed
+// only after the snapshot has been written. URLs changed while the snapshot was written,
+// for example restored ones, are not removed.
+// Returns the number of removed URLs.
+func (s *ShardedStorage) PurgeDeletedURLs(_ context.Context, before time.Time, limit int) (int64, error) {
+	s.compactMu.Lock()
+	defer s.compactMu.Unlock()
+
+	found := false
+	for i := range s.shards {
+		s.shards[i].mu.RLock()
+		for _, url := range s.shards[i].urls {
+			if isPurgeable(url, before) {
+				found = true
+				break
+			}
+		}
+		s.shards[i].mu.RUnlock()
+
+		if found {
+			break
+		}
+	}
+	if !found {
+		return 0, nil
+	}
+
+	excluded, err := s.compact(purgeSelector(before, limit))
+	if err != nil {
+		return 0, err
+	}
+
+	removed := make(map[uuid.UUID]struct{}, len(excluded))
+	for _, url := range excluded {
+		if s.removeUnchangedURL(url) {
+			removed[url.ID] = struct{}{}
+		}
+	}
+
+	if err := s.purgeClicks(removed); err != nil {
+		return int64(len(removed)), err
+	}
+
+	return int64(len(removed)), nil
+}
+
+// removeUnchangedURL removes url if it is still the stored one.
+func (s *ShardedStorage) removeUnchangedURL(url *model.URL) bool {
+	unlockStripes := s.lockStripes(url.OriginalURL)
+	defer unlockStripes()
+	unlockShards := s.lockShards(url.ShortKey)
+	defer unlockShards()
+
+	if s.shardOf(url.ShortKey).urls[url.ShortKey] != url {
+		return false
+	}
+	s.removeURL(url)
+
+	return true
+}
+
+// purgeClicks removes click events of the URLs with the given IDs
+// and rewrites the clicks file without them.
+func (s *ShardedStorage) purgeClicks(urlIDs map[uuid.UUID]struct{}) error {
+	hasClicks := false
+	for id := range urlIDs {
+		shard := &s.clickShards[s.uuidIndex(id)]
+		shard.mu.RLock()
+		_, ok := shard.clicks[id]
+		shard.mu.RUnlock()
+
+		if ok {
+			hasClicks = true
+			break
+		}
+	}
+	if !hasClicks {
+		return nil
+	}
+
+	for i := range s.clickShards {
+		s.clickShards[i].mu.Lock()
+	}
+	defer func() {
+		for i := range s.clickShards {
+			s.clickShards[i].mu.Unlock()
+		}
+	}()
+
+	kept := make([]*model.Click, 0)
+	for i := range s.clickShards {
+		for urlID, clicks := range s.clickShards[i].clicks {
+			if _, ok := urlIDs[urlID]; !ok {
+				kept = append(kept, clicks...)
+			}
+		}
+	}
+
+	err := s.clicksFile.replace(func() (*recordFile, error) {
+		return rewriteClicksFile(s.filename+clicksFileSuffix, kept, s.syncPolicy)
+	})
+	if err != nil {
+		return err
+	}
+
+	for id := range urlIDs {
+		shard := &s.clickShards[s.uuidIndex(id)]
+		delete(shard.clicks, id)
+		delete(shard.counters, id)
+	}
+
+	return nil
+}
+
+// SaveClick appends a single click event to the clicks file.
+func (s *ShardedStorage) SaveClick(_ context.Context, click *model.Click) error {
+	shard := &s.clickShards[s.uuidIndex(click.URLID)]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	record, err := appendRecord(nil, click)
+	if err != nil {
+		return fmt.Errorf("failed to marshal click: %w", err)
+	}
+
+	if err := s.clicksFile.write(record); err != nil {
+		return fmt.Errorf("failed to write click to file: %w", err)
+	}
+
+	shard.clicks[click.URLID] = append(shard.clicks[click.URLID], click)
+	shard.counters.add(click)
+
+	return nil
+}
+
+// GetClickStats aggregates the click events of a URL from counters kept up to date as clicks are saved,
+// so it doesn't go through the events. Clicks are counted per hour, so bucket must be a whole number of hours.
+func (s *ShardedStorage) GetClickStats(_ context.Context, urlID uuid.UUID, bucket time.Duration) (*model.ClickStats, error) {
+	shard := &s.clickShards[s.uuidIndex(urlID)]
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	return shard.counters[urlID].stats(bucket), nil
+}
@@ -0,0 +1,144 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+// benchStorages returns constructors of the storages compared by the parallel benchmarks.
+func benchStorages() map[string]func(b *testing.B) storage.Storage {
+	return map[string]func(b *testing.B) storage.Storage{
+		"Storage": func(b *testing.B) storage.Storage {
+			s, err := NewStorage(b.TempDir()+"/urls", WithSync(SyncNever, 0))
+			if err != nil {
+				b.Fatal(err)
+			}
+			return s
+		},
+		"Sharded_16": func(b *testing.B) storage.Storage {
+			s, err := NewShardedStorage(b.TempDir()+"/urls", 16, WithSync(SyncNever, 0))
+			if err != nil {
+				b.Fatal(err)
+			}
+			return s
+		},
+	}
+}
+
+// fillBenchStorage saves size URLs to s and returns their short keys.
+func fillBenchStorage(b *testing.B, s storage.Storage, size int) []string {
+	b.Helper()
+
+	urls := make([]*model.URL, size)
+	shortKeys := make([]string, size)
+	for i := range urls {
+		urls[i] = &model.URL{
+			ID:          uuid.New(),
+			ShortKey:    fmt.Sprintf("key%d", i),
+			OriginalURL: fmt.Sprintf("https://example.com/%d", i),
+			UserID:      uuid.New(),
+		}
+		shortKeys[i] = urls[i].ShortKey
+	}
+
+	if _, err := s.SetURLs(context.Background(), urls, storage.DedupeGlobal); err != nil {
+		b.Fatal(err)
+	}
+
+	return shortKeys
+}
+
+func BenchmarkParallel_GetURL(b *testing.B) {
+	ctx := context.Background()
+
+	for name, newStorage := range benchStorages() {
+		b.Run(name, func(b *testing.B) {
+			s := newStorage(b)
+			defer s.Close()
+			shortKeys := fillBenchStorage(b, s, 10_000)
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					if _, err := s.GetURL(ctx, shortKeys[i%len(shortKeys)]); err != nil {
+						b.Error(err)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkParallel_SetURL(b *testing.B) {
+	ctx := context.Background()
+
+	for name, newStorage := range benchStorages() {
+		b.Run(name, func(b *testing.B) {
+			s := newStorage(b)
+			defer s.Close()
+			var counter atomic.Int64
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					n := counter.Add(1)
+					url := &model.URL{
+						ID:          uuid.New(),
+						ShortKey:    fmt.Sprintf("key%d", n),
+						OriginalURL: fmt.Sprintf("https://example.com/%d", n),
+					}
+					if _, err := s.SetURL(ctx, url, storage.DedupeNone); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkParallel_Mixed runs nine reads for every write.
+func BenchmarkParallel_Mixed(b *testing.B) {
+	ctx := context.Background()
+
+	for name, newStorage := range benchStorages() {
+		b.Run(name, func(b *testing.B) {
+			s := newStorage(b)
+			defer s.Close()
+			shortKeys := fillBenchStorage(b, s, 10_000)
+			var counter atomic.Int64
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					i++
+					if i%10 != 0 {
+						if _, err := s.GetURL(ctx, shortKeys[i%len(shortKeys)]); err != nil {
+							b.Error(err)
+						}
+						continue
+					}
+
+					n := counter.Add(1)
+					url := &model.URL{
+						ID:          uuid.New(),
+						ShortKey:    fmt.Sprintf("new%d", n),
+						OriginalURL: fmt.Sprintf("https://example.com/new/%d", n),
+					}
+					if _, err := s.SetURL(ctx, url, storage.DedupeNone); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+		})
+	}
+}
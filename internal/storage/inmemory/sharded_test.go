@@ -0,0 +1,184 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+func TestNewShardedStorage_InvalidShards(t *testing.T) {
+	_, err := NewShardedStorage(t.TempDir()+"/urls", 0)
+	assert.Error(t, err)
+}
+
+func TestShardedStorage_SharesFilesWithStorage(t *testing.T) {
+	ctx := context.Background()
+	filename := t.TempDir() + "/urls"
+	userID := uuid.New()
+
+	plain, err := NewStorage(filename)
+	require.NoError(t, err)
+	first := &model.URL{ID: uuid.New(), ShortKey: "abc", OriginalURL: "https://ya.ru", UserID: userID}
+	_, err = plain.SetURL(ctx, first, storage.DedupeGlobal)
+	require.NoError(t, err)
+	require.NoError(t, plain.SaveClick(ctx, model.NewClick(first.ID, time.Now().UTC(), "", "", "")))
+	require.NoError(t, plain.Close())
+
+	sharded, err := NewShardedStorage(filename, 4)
+	require.NoError(t, err)
+	second := &model.URL{ID: uuid.New(), ShortKey: "def", OriginalURL: "https://google.com", UserID: userID}
+	_, err = sharded.SetURL(ctx, second, storage.DedupeGlobal)
+	require.NoError(t, err)
+	require.NoError(t, sharded.DeleteURLs(ctx, []uuid.UUID{first.ID}))
+
+	// The URL loaded from the file is deduped against.
+	existing, err := sharded.SetURL(ctx, &model.URL{ID: uuid.New(), ShortKey: "ghi", OriginalURL: "https://google.com"}, storage.DedupeGlobal)
+	assert.ErrorIs(t, err, storage.ErrConflict)
+	assert.Equal(t, second.ID, existing.ID)
+	require.NoError(t, sharded.Close())
+
+	plain, err = NewStorage(filename)
+	require.NoError(t, err)
+	defer plain.Close()
+
+	urls, err := plain.GetURLsByUserID(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, second.ID, urls[0].ID)
+
+	urls, err = plain.GetDeletedURLsByUserID(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, first.ID, urls[0].ID)
+
+	stats, err := plain.GetClickStats(ctx, first.ID, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Total)
+}
+
+func TestShardedStorage_PurgeDeletedURLs(t *testing.T) {
+	ctx := context.Background()
+	filename := t.TempDir() + "/urls"
+	now := time.Now().UTC()
+	longAgo := now.Add(-48 * time.Hour)
+
+	old := &model.URL{ID: uuid.New(), ShortKey: "old", OriginalURL: "https://old.ru", DeletedAt: &longAgo}
+	alive := &model.URL{ID: uuid.New(), ShortKey: "alive", OriginalURL: "https://alive.ru"}
+
+	s, err := NewShardedStorage(filename, 4)
+	require.NoError(t, err)
+	_, err = s.SetURLs(ctx, []*model.URL{old, alive}, storage.DedupeGlobal)
+	require.NoError(t, err)
+	require.NoError(t, s.SaveClick(ctx, model.NewClick(old.ID, now, "", "", "")))
+	require.NoError(t, s.SaveClick(ctx, model.NewClick(alive.ID, now, "", "", "")))
+
+	removed, err := s.PurgeDeletedURLs(ctx, now.Add(-24*time.Hour), 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	// Changes made after the purge are appended to the new tail.
+	_, err = s.SetURL(ctx, &model.URL{ID: uuid.New(), ShortKey: "new", OriginalURL: "https://new.ru"}, storage.DedupeGlobal)
+	require.NoError(t, err)
+	require.NoError(t, s.SaveClick(ctx, model.NewClick(alive.ID, now, "", "", "")))
+	require.NoError(t, s.Close())
+
+	s, err = NewShardedStorage(filename, 2)
+	require.NoError(t, err)
+	defer s.Close()
+
+	_, err = s.GetURL(ctx, "old")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	for _, shortKey := range []string{"alive", "new"} {
+		_, err = s.GetURL(ctx, shortKey)
+		assert.NoError(t, err)
+	}
+
+	stats, err := s.GetClickStats(ctx, old.ID, time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, stats.Total)
+
+	stats, err = s.GetClickStats(ctx, alive.ID, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Total)
+}
+
+func TestShardedStorage_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	filename := t.TempDir() + "/urls"
+	userID := uuid.New()
+
+	s, err := NewShardedStorage(filename, 4, WithSync(SyncNever, 0))
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				url := &model.URL{
+					ID:          uuid.New(),
+					ShortKey:    fmt.Sprintf("key-%d-%d", w, i),
+					OriginalURL: fmt.Sprintf("https://ya.ru/%d/%d", w, i),
+					UserID:      userID,
+				}
+				_, err := s.SetURL(ctx, url, storage.DedupeGlobal)
+				assert.NoError(t, err)
+
+				updated := *url
+				updated.OriginalURL += "/updated"
+				_, err = s.UpdateURL(ctx, &updated, storage.FieldOriginalURL)
+				assert.NoError(t, err)
+
+				if i%2 == 0 {
+					assert.NoError(t, s.DeleteURLs(ctx, []uuid.UUID{url.ID}))
+				}
+
+				// A URL with the same original URL saved concurrently is always deduped.
+				_, err = s.SetURL(ctx, &model.URL{ID: uuid.New(), ShortKey: url.ShortKey + "-dup", OriginalURL: "https://shared.ru"}, storage.DedupeGlobal)
+				if err != nil {
+					assert.ErrorIs(t, err, storage.ErrConflict)
+				}
+			}
+		}()
+	}
+	for range 5 {
+		require.NoError(t, s.Compact(ctx))
+	}
+	wg.Wait()
+
+	urls, err := s.GetURLsByUserID(ctx, userID)
+	require.NoError(t, err)
+	assert.Len(t, urls, 200)
+	require.NoError(t, s.Close())
+
+	s, err = NewShardedStorage(filename, 4)
+	require.NoError(t, err)
+	defer s.Close()
+
+	urls, err = s.GetURLsByUserID(ctx, userID)
+	require.NoError(t, err)
+	assert.Len(t, urls, 200)
+	for _, url := range urls {
+		assert.Contains(t, url.OriginalURL, "/updated")
+	}
+
+	shared := 0
+	for i := range s.shards {
+		for _, url := range s.shards[i].urls {
+			if url.OriginalURL == "https://shared.ru" {
+				shared++
+			}
+		}
+	}
+	assert.Equal(t, 1, shared)
+}
@@ -1,14 +1,14 @@
 package inmemory
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"os"
-	"strings"
+	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -20,6 +20,7 @@ import (
 type File interface {
 	io.WriteCloser
 	io.StringWriter
+	Sync() error
 }
 
 // URLMap represents a map of short keys to URL models.
@@ -41,12 +42,41 @@ func (m URLMap) UnmarshalJSON(d []byte) error {
 	return nil
 }
 
+// clicksFileSuffix is appended to the storage file name to get the name of the click events file.
+const clicksFileSuffix = ".clicks"
+
 // Storage represents in-memory storage implementation.
+// URLs are persisted to a snapshot file and a tail file with changes made since
+// the last compaction. On start the snapshot is loaded and the tail is replayed on top of it.
 type Storage struct {
-	urlmap  URLMap
-	mu      sync.RWMutex
-	file    File
-	encoder *json.Encoder
+	filename string
+	urlmap   URLMap
+	// originalURLs indexes urlmap by original URL to find URLs that can be reused.
+	originalURLs keyIndex[string]
+	// userURLs indexes urlmap by user ID.
+	userURLs keyIndex[uuid.UUID]
+	// urlIDs maps IDs of stored URLs to their short keys.
+	urlIDs map[uuid.UUID]string
+	mu     sync.RWMutex
+	// file is the tail file changes are appended to.
+	file *recordFile
+
+	// compactMu serializes compactions and purges.
+	compactMu sync.Mutex
+	// lastSealedTail is the sequence number of the last sealed tail.
+	lastSealedTail int
+
+	clicks map[uuid.UUID][]*model.Click
+	// clickCounters aggregate clicks for statistics.
+	clickCounters clickCounters
+	clicksMu      sync.RWMutex
+	clicksFile    *recordFile
+
+	syncPolicy   SyncPolicy
+	syncInterval time.Duration
+	// stopSync stops periodic syncing of SyncInterval, syncDone is closed when it has stopped.
+	stopSync chan struct{}
+	syncDone chan struct{}
 }
 
 // Ping checks if the storage is available.
@@ -55,43 +85,81 @@ func (s *Storage) Ping(ctx context.Context) error {
 }
 
 // NewStorage creates new in-memory storage instance.
-func NewStorage(filename string) (*Storage, error) {
-	readFile, err := os.OpenFile(filename, os.O_RDONLY|os.O_CREATE, 0600)
+// filename is the snapshot file. Files written before snapshots were introduced
+// contain all changes and are loaded as a snapshot.
+// Records torn by a crash in the middle of a write are dropped.
+// Written records are synced to disk with SyncAlways unless WithSync is passed.
+func NewStorage(filename string, opts ...Option) (*Storage, error) {
+	o, err := newOptions(opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file for read: %w", err)
+		return nil, err
 	}
-	defer readFile.Close()
-
-	scanner := bufio.NewScanner(readFile)
 
-	urlmap := URLMap{}
+	s := &Storage{
+		filename:     filename,
+		syncPolicy:   o.syncPolicy,
+		syncInterval: o.syncInterval,
+	}
 
-	for scanner.Scan() {
-		entry := &model.URL{}
-		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
-			return nil, fmt.Errorf("failed to unmarshall urls entry: %w", err)
-		}
-		urlmap[entry.ShortKey] = entry
+	urlmap, lastSealedTail, err := loadURLs(filename)
+	if err != nil {
+		return nil, err
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("scanner error: %w", err)
+
+	clicks, err := loadClicks(filename + clicksFileSuffix)
+	if err != nil {
+		return nil, err
 	}
 
-	writeFile, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	writeFile, err := openRecordFile(filename+tailFileSuffix, s.syncPolicy)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file for append: %w", err)
 	}
 
-	return &Storage{
-		urlmap:  urlmap,
-		file:    writeFile,
-		encoder: json.NewEncoder(writeFile),
-	}, nil
+	clicksFile, err := openRecordFile(filename+clicksFileSuffix, s.syncPolicy)
+	if err != nil {
+		writeFile.close()
+		return nil, fmt.Errorf("failed to open clicks file for append: %w", err)
+	}
+
+	if s.syncPolicy != SyncNever {
+		if err := syncDir(filepath.Dir(filename)); err != nil {
+			writeFile.close()
+			clicksFile.close()
+			return nil, err
+		}
+	}
+
+	s.urlmap = urlmap
+	s.indexURLs()
+	s.file = writeFile
+	s.lastSealedTail = lastSealedTail
+	s.clicks = clicks
+	s.clickCounters = countClicks(clicks)
+	s.clicksFile = clicksFile
+
+	if s.syncPolicy == SyncInterval {
+		s.stopSync = make(chan struct{})
+		s.syncDone = make(chan struct{})
+		go s.runSync()
+	}
+
+	return s, nil
 }
 
-// Close closes the storage and underlying file.
+// Close syncs unsynced records, unless the sync policy is SyncNever, and closes the underlying files.
 func (s *Storage) Close() error {
-	return s.file.Close()
+	if s.stopSync != nil {
+		close(s.stopSync)
+		<-s.syncDone
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clicksMu.Lock()
+	defer s.clicksMu.Unlock()
+
+	return errors.Join(s.file.close(), s.clicksFile.close())
 }
 
 // GetURL retrieves a URL by its short key.
@@ -113,13 +181,11 @@ func (s *Storage) GetURLs(_ context.Context, shortKeys []string) ([]*model.URL,
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	urls := make([]*model.URL, 0)
+	urls := make([]*model.URL, 0, len(shortKeys))
 
 	for _, shortKey := range shortKeys {
-		for _, u := range s.urlmap {
-			if u.ShortKey == shortKey {
-				urls = append(urls, u)
-			}
+		if url, ok := s.urlmap[shortKey]; ok {
+			urls = append(urls, url)
 		}
 	}
 
@@ -127,74 +193,250 @@ func (s *Storage) GetURLs(_ context.Context, shortKeys []string) ([]*model.URL,
 }
 
 // GetURLsByUserID retrieves all URLs created by a specific user.
+// Deleted URLs are not included.
 func (s *Storage) GetURLsByUserID(ctx context.Context, userID uuid.UUID) ([]*model.URL, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	return s.userURLsByDeletion(userID, false), nil
+}
+
+// GetDeletedURLsByUserID retrieves all deleted URLs created by a specific user.
+func (s *Storage) GetDeletedURLsByUserID(_ context.Context, userID uuid.UUID) ([]*model.URL, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.userURLsByDeletion(userID, true), nil
+}
+
+// userURLsByDeletion returns URLs of the user that are deleted or not deleted.
+// Callers must hold s.mu.
+func (s *Storage) userURLsByDeletion(userID uuid.UUID, deleted bool) []*model.URL {
 	urls := make([]*model.URL, 0)
 
-	for _, url := range s.urlmap {
-		if url.UserID == userID {
+	for shortKey := range s.userURLs[userID] {
+		url := s.urlmap[shortKey]
+		if url != nil && (url.DeletedAt != nil) == deleted {
 			urls = append(urls, url)
 		}
 	}
 
-	return urls, nil
+	return urls
 }
 
 // saveToFile saves a URL to the underlying file.
-func (s *Storage) saveToFile(_ context.Context, url *model.URL) error {
-	return s.encoder.Encode(url)
+func (s *Storage) saveToFile(ctx context.Context, url *model.URL) (pendingSync, error) {
+	return s.saveToFileBatch(ctx, []*model.URL{url})
 }
 
-// saveToFileBatch saves multiple URLs to the underlying file.
-func (s *Storage) saveToFileBatch(_ context.Context, urls string) error {
-	_, err := s.file.WriteString(urls)
-	return err
+// saveToFileBatch saves multiple URLs to the underlying file in a single write.
+// Either all of the URLs are saved or none of them. Callers must hold s.mu
+// and wait for the returned pendingSync after releasing it.
+func (s *Storage) saveToFileBatch(_ context.Context, urls []*model.URL) (pendingSync, error) {
+	records, err := encodeURLs(urls)
+	if err != nil {
+		return pendingSync{}, err
+	}
+
+	return s.file.write(records)
 }
 
 // SetURL stores a single URL in the storage.
-func (s *Storage) SetURL(ctx context.Context, url *model.URL) (*model.URL, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// Depending on scope, an active URL with the same original URL
+// may be returned instead together with storage.ErrConflict.
+// Returns storage.ErrShortKeyConflict if the short key is already taken.
+func (s *Storage) SetURL(ctx context.Context, url *model.URL, scope storage.DedupeScope) (*model.URL, error) {
+	var existing *model.URL
+	err := commit(&s.mu, func() (pendingSync, error) {
+		if existing = s.findActiveURL(url, scope, time.Now()); existing != nil {
+			return pendingSync{}, storage.ErrConflict
+		}
+
+		if _, ok := s.urlmap[url.ShortKey]; ok {
+			return pendingSync{}, storage.ErrShortKeyConflict
+		}
 
-	s.urlmap[url.ShortKey] = url
+		pending, err := s.saveToFile(ctx, url)
+		if err != nil {
+			return pendingSync{}, fmt.Errorf("failed to encode url to file: %w", err)
+		}
 
-	if err := s.saveToFile(ctx, url); err != nil {
-		return nil, fmt.Errorf("failed to encode url to file: %w", err)
+		s.addURL(url)
+
+		return pending, nil
+	})
+	if errors.Is(err, storage.ErrConflict) {
+		return existing, err
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	return url, nil
 }
 
 // SetURLs stores multiple URLs in the storage.
-func (s *Storage) SetURLs(ctx context.Context, urls []*model.URL) ([]*model.URL, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// Depending on scope, active URLs with the same original URLs,
+// including ones saved earlier in the same batch, may be returned in place of some of urls.
+// Returns storage.ErrShortKeyConflict and saves nothing if a short key is already taken.
+func (s *Storage) SetURLs(ctx context.Context, urls []*model.URL, scope storage.DedupeScope) ([]*model.URL, error) {
+	now := time.Now()
+	savedURLs := make([]*model.URL, len(urls))
+
+	err := commit(&s.mu, func() (pendingSync, error) {
+		newURLs := make([]*model.URL, 0, len(urls))
+		batchKeys := make(map[string]struct{}, len(urls))
+		batchURLs := make(map[string][]*model.URL)
+
+		for i, url := range urls {
+			if existing := s.findActiveURL(url, scope, now); existing != nil {
+				savedURLs[i] = existing
+				continue
+			}
+			if existing := findReusable(batchURLs[url.OriginalURL], url, scope, now); existing != nil {
+				savedURLs[i] = existing
+				continue
+			}
 
-	var builder strings.Builder
+			_, inStorage := s.urlmap[url.ShortKey]
+			_, inBatch := batchKeys[url.ShortKey]
+			if inStorage || inBatch {
+				return pendingSync{}, storage.ErrShortKeyConflict
+			}
+			batchKeys[url.ShortKey] = struct{}{}
+			batchURLs[url.OriginalURL] = append(batchURLs[url.OriginalURL], url)
 
-	for _, url := range urls {
-		s.urlmap[url.ShortKey] = url
+			savedURLs[i] = url
+			newURLs = append(newURLs, url)
+		}
 
-		b, err := json.Marshal(url)
+		pending, err := s.saveToFileBatch(ctx, newURLs)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal url: %w", err)
+			return pendingSync{}, fmt.Errorf("failed to encode urls to file: %w", err)
 		}
 
-		if _, err := builder.WriteString(string(b) + "\n"); err != nil {
-			return nil, fmt.Errorf("failed write url to buffer: %w", err)
+		for _, url := range newURLs {
+			s.addURL(url)
 		}
+
+		return pending, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.saveToFileBatch(ctx, builder.String()); err != nil {
-		return nil, fmt.Errorf("failed to encode urls to file: %w", err)
+	return savedURLs, nil
+}
+
+// UpdateURL replaces the mutable attributes in fields of an existing URL with the ones of url.
+// Other attributes keep their stored values. The short key, the owner and the creation data can't be changed.
+// Returns storage.ErrNotFound if the URL doesn't exist or is deleted.
+func (s *Storage) UpdateURL(ctx context.Context, url *model.URL, fields storage.URLFields) (*model.URL, error) {
+	var updated *model.URL
+	err := commit(&s.mu, func() (pendingSync, error) {
+		stored, ok := s.urlmap[url.ShortKey]
+		if !ok || stored.ID != url.ID || stored.DeletedAt != nil {
+			return pendingSync{}, storage.ErrNotFound
+		}
+
+		updated = fields.Apply(stored, url)
+
+		pending, err := s.saveToFile(ctx, updated)
+		if err != nil {
+			return pendingSync{}, fmt.Errorf("failed to encode url to file: %w", err)
+		}
+
+		s.replaceURL(updated)
+
+		return pending, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return urls, nil
+	return updated, nil
 }
 
 // DeleteURLs marks the specified URLs as deleted.
-func (s *Storage) DeleteURLs(_ context.Context, _ []uuid.UUID) error {
-	return nil
+// Deleted URLs are appended to the file as tombstones with DeletedAt set,
+// so they stay deleted after a restart. URLs that are already deleted keep
+// the moment of their first deletion.
+func (s *Storage) DeleteURLs(ctx context.Context, ids []uuid.UUID) error {
+	deletedAt := time.Now().UTC()
+
+	return s.setDeletedAt(ctx, ids, &deletedAt)
+}
+
+// RestoreURLs clears the deletion mark of the specified URLs.
+// Restored URLs are appended to the file and replace the stored ones.
+func (s *Storage) RestoreURLs(ctx context.Context, ids []uuid.UUID) error {
+	return s.setDeletedAt(ctx, ids, nil)
+}
+
+// setDeletedAt sets DeletedAt of the URLs with the given IDs that are not already
+// in the requested deletion state. Changed URLs are appended to the file in a single write
+// and replace the stored ones only after the write succeeds.
+// When the file is loaded, the last record of a short key wins.
+func (s *Storage) setDeletedAt(ctx context.Context, ids []uuid.UUID, deletedAt *time.Time) error {
+	return commit(&s.mu, func() (pendingSync, error) {
+		seen := make(map[uuid.UUID]struct{}, len(ids))
+		changed := make([]*model.URL, 0, len(ids))
+
+		for _, id := range ids {
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+
+			url := s.urlByID(id)
+			if url == nil || (url.DeletedAt == nil) == (deletedAt == nil) {
+				continue
+			}
+
+			updated := *url
+			updated.DeletedAt = deletedAt
+			changed = append(changed, &updated)
+		}
+
+		if len(changed) == 0 {
+			return pendingSync{}, nil
+		}
+
+		pending, err := s.saveToFileBatch(ctx, changed)
+		if err != nil {
+			return pendingSync{}, fmt.Errorf("failed to write urls to file: %w", err)
+		}
+
+		for _, url := range changed {
+			s.replaceURL(url)
+		}
+
+		return pending, nil
+	})
+}
+
+// DecrementClicksLeft uses up one redirect of a click-limited URL.
+// The updated URL is appended to the file and replaces the stored one,
+// so concurrent readers never see a partially updated URL.
+// Returns storage.ErrNoClicksLeft if the URL doesn't exist, has no clicks left or is not click-limited.
+func (s *Storage) DecrementClicksLeft(ctx context.Context, id uuid.UUID) error {
+	return commit(&s.mu, func() (pendingSync, error) {
+		url := s.urlByID(id)
+		if url == nil || url.ClicksLeft == nil || *url.ClicksLeft <= 0 {
+			return pendingSync{}, storage.ErrNoClicksLeft
+		}
+
+		updated := *url
+		clicksLeft := *url.ClicksLeft - 1
+		updated.ClicksLeft = &clicksLeft
+
+		pending, err := s.saveToFile(ctx, &updated)
+		if err != nil {
+			return pendingSync{}, fmt.Errorf("failed to encode url to file: %w", err)
+		}
+
+		s.replaceURL(&updated)
+
+		return pending, nil
+	})
 }
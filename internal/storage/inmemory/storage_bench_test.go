@@ -0,0 +1,138 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/dtroode/urlshorter/internal/model"
+)
+
+// discardFile is a File that drops everything written to it.
+type discardFile struct{}
+
+func (discardFile) Write(p []byte) (int, error)       { return len(p), nil }
+func (discardFile) WriteString(s string) (int, error) { return len(s), nil }
+func (discardFile) Sync() error                       { return nil }
+func (discardFile) Close() error                      { return nil }
+
+// storageSizes are the numbers of stored URLs benchmarks are run with.
+var storageSizes = []int{1_000, 10_000, 100_000}
+
+// urlsPerUser is the number of URLs every user of a benchmark storage has.
+const urlsPerUser = 10
+
+// newBenchStorage returns a storage with size URLs, urlsPerUser for each user,
+// and all of the stored URLs in the order they were created.
+func newBenchStorage(b *testing.B, size int) (*Storage, []*model.URL) {
+	b.Helper()
+
+	s := &Storage{
+		urlmap: make(URLMap, size),
+		file:   &recordFile{file: discardFile{}},
+	}
+
+	urls := make([]*model.URL, size)
+	var userID uuid.UUID
+	for i := range urls {
+		if i%urlsPerUser == 0 {
+			userID = uuid.New()
+		}
+		clicksLeft := int64(b.N + 1)
+		urls[i] = &model.URL{
+			ID:          uuid.New(),
+			ShortKey:    fmt.Sprintf("key%d", i),
+			OriginalURL: fmt.Sprintf("https://example.com/%d", i),
+			UserID:      userID,
+			ClicksLeft:  &clicksLeft,
+		}
+		s.urlmap[urls[i].ShortKey] = urls[i]
+	}
+	s.indexURLs()
+
+	return s, urls
+}
+
+func BenchmarkStorage_GetURLs(b *testing.B) {
+	ctx := context.Background()
+
+	for _, size := range storageSizes {
+		b.Run(fmt.Sprintf("Size_%d", size), func(b *testing.B) {
+			s, urls := newBenchStorage(b, size)
+			shortKeys := make([]string, 0, 10)
+			for _, url := range urls[:10] {
+				shortKeys = append(shortKeys, url.ShortKey)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := s.GetURLs(ctx, shortKeys); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkStorage_GetURLsByUserID(b *testing.B) {
+	ctx := context.Background()
+
+	for _, size := range storageSizes {
+		b.Run(fmt.Sprintf("Size_%d", size), func(b *testing.B) {
+			s, urls := newBenchStorage(b, size)
+			userID := urls[size/2].UserID
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := s.GetURLsByUserID(ctx, userID); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkStorage_DeleteURLs(b *testing.B) {
+	ctx := context.Background()
+
+	for _, size := range storageSizes {
+		b.Run(fmt.Sprintf("Size_%d", size), func(b *testing.B) {
+			s, urls := newBenchStorage(b, size)
+			ids := make([]uuid.UUID, 0, 100)
+			for _, url := range urls[:100] {
+				ids = append(ids, url.ID)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Deleting URLs that are already deleted is a no-op, so URLs are restored in between.
+				if err := s.DeleteURLs(ctx, ids); err != nil {
+					b.Fatal(err)
+				}
+				if err := s.RestoreURLs(ctx, ids); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkStorage_DecrementClicksLeft(b *testing.B) {
+	ctx := context.Background()
+
+	for _, size := range storageSizes {
+		b.Run(fmt.Sprintf("Size_%d", size), func(b *testing.B) {
+			s, urls := newBenchStorage(b, size)
+			id := urls[size/2].ID
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := s.DecrementClicksLeft(ctx, id); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
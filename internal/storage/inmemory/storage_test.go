@@ -3,10 +3,13 @@ package inmemory
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -146,6 +149,12 @@ func TestStorage_GetURLsByUserID(t *testing.T) {
 					OriginalURL: "google.com",
 					UserID:      uuid.Max,
 				},
+				"del": &model.URL{
+					ShortKey:    "del",
+					OriginalURL: "deleted.com",
+					UserID:      uuid.Max,
+					DeletedAt:   &time.Time{},
+				},
 			},
 			userID: uuid.Max,
 			expectedResponse: []*model.URL{
@@ -165,6 +174,7 @@ func TestStorage_GetURLsByUserID(t *testing.T) {
 			s := Storage{
 				urlmap: tt.urlmap,
 			}
+			s.indexURLs()
 
 			urls, err := s.GetURLsByUserID(context.Background(), tt.userID)
 			assert.NoError(t, err)
@@ -181,6 +191,10 @@ func (f *dummyFile) Close() error {
 	return nil
 }
 
+func (f *dummyFile) Sync() error {
+	return nil
+}
+
 func TestURL_SetURL(t *testing.T) {
 	buf := bytes.NewBuffer(nil)
 
@@ -224,12 +238,11 @@ func TestURL_SetURL(t *testing.T) {
 	for tn, tt := range tests {
 		t.Run(tn, func(t *testing.T) {
 			s := Storage{
-				urlmap:  tt.urlmap,
-				file:    &dummyFile{Buffer: buf},
-				encoder: json.NewEncoder(buf),
+				urlmap: tt.urlmap,
+				file:   &recordFile{file: &dummyFile{Buffer: buf}},
 			}
 
-			url, err := s.SetURL(context.Background(), tt.url)
+			url, err := s.SetURL(context.Background(), tt.url, storage.DedupeGlobal)
 			require.NoError(t, err)
 
 			assert.Equal(t, tt.url, (tt.urlmap)[tt.url.ShortKey])
@@ -239,7 +252,7 @@ func TestURL_SetURL(t *testing.T) {
 			require.NoError(t, err)
 
 			writtenData := &model.URL{}
-			err = json.Unmarshal(line, writtenData)
+			err = decodeRecord(line, writtenData)
 			require.NoError(t, err)
 
 			assert.Equal(t, tt.url, writtenData)
@@ -273,12 +286,11 @@ func TestURL_SetURLs(t *testing.T) {
 	for tn, tt := range tests {
 		t.Run(tn, func(t *testing.T) {
 			s := Storage{
-				urlmap:  tt.urlmap,
-				file:    &dummyFile{Buffer: buf},
-				encoder: json.NewEncoder(buf),
+				urlmap: tt.urlmap,
+				file:   &recordFile{file: &dummyFile{Buffer: buf}},
 			}
 
-			savedURLs, err := s.SetURLs(context.Background(), tt.urls)
+			savedURLs, err := s.SetURLs(context.Background(), tt.urls, storage.DedupeGlobal)
 			require.NoError(t, err)
 
 			assert.Equal(t, tt.urls, savedURLs)
@@ -293,7 +305,7 @@ func TestURL_SetURLs(t *testing.T) {
 				require.NoError(t, err)
 
 				writtenData := &model.URL{}
-				err = json.Unmarshal(line, writtenData)
+				err = decodeRecord(line, writtenData)
 				require.NoError(t, err)
 
 				assert.Equal(t, u, writtenData)
@@ -302,8 +314,75 @@ func TestURL_SetURLs(t *testing.T) {
 	}
 }
 
+func TestURL_SetURL_ShortKeyConflict(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	s := Storage{
+		urlmap: URLMap{
+			"abcd1": &model.URL{
+				ShortKey:    "abcd1",
+				OriginalURL: "yandex.ru",
+			},
+		},
+		file: &recordFile{file: &dummyFile{Buffer: buf}},
+	}
+
+	_, err := s.SetURL(context.Background(), &model.URL{
+		ShortKey:    "abcd1",
+		OriginalURL: "google.com",
+	}, storage.DedupeGlobal)
+	assert.ErrorIs(t, err, storage.ErrShortKeyConflict)
+	assert.Equal(t, "yandex.ru", s.urlmap["abcd1"].OriginalURL)
+	assert.Zero(t, buf.Len())
+}
+
+func TestURL_SetURLs_ShortKeyConflict(t *testing.T) {
+	tests := map[string]struct {
+		urlmap URLMap
+		urls   []*model.URL
+	}{
+		"key exists in storage": {
+			urlmap: URLMap{
+				"abcd1": &model.URL{
+					ShortKey:    "abcd1",
+					OriginalURL: "yandex.ru",
+				},
+			},
+			urls: []*model.URL{
+				{ShortKey: "abcd2", OriginalURL: "google.com"},
+				{ShortKey: "abcd1", OriginalURL: "ya.ru"},
+			},
+		},
+		"key repeated in batch": {
+			urlmap: URLMap{},
+			urls: []*model.URL{
+				{ShortKey: "abcd2", OriginalURL: "google.com"},
+				{ShortKey: "abcd2", OriginalURL: "ya.ru"},
+			},
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			s := Storage{
+				urlmap: tt.urlmap,
+				file:   &recordFile{file: &dummyFile{Buffer: buf}},
+			}
+			size := len(tt.urlmap)
+
+			_, err := s.SetURLs(context.Background(), tt.urls, storage.DedupeGlobal)
+			assert.ErrorIs(t, err, storage.ErrShortKeyConflict)
+			assert.Len(t, s.urlmap, size)
+			assert.Zero(t, buf.Len())
+		})
+	}
+}
+
 func TestURL_DeleteURLs(t *testing.T) {
 	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	deletedAt := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	buf := bytes.NewBuffer(nil)
 	s := Storage{
 		urlmap: URLMap{
 			"ydx": &model.URL{
@@ -311,10 +390,63 @@ func TestURL_DeleteURLs(t *testing.T) {
 				ShortKey:    "ydx",
 				OriginalURL: "yandex.ru",
 			},
+			"ggl": &model.URL{
+				ID:          ids[1],
+				ShortKey:    "ggl",
+				OriginalURL: "google.com",
+				DeletedAt:   &deletedAt,
+			},
+			"ya": &model.URL{
+				ID:          uuid.New(),
+				ShortKey:    "ya",
+				OriginalURL: "ya.ru",
+			},
 		},
+		file: &recordFile{file: &dummyFile{Buffer: buf}},
 	}
+	s.indexURLs()
 	err := s.DeleteURLs(context.Background(), ids)
 	require.NoError(t, err)
+
+	require.NotNil(t, s.urlmap["ydx"].DeletedAt)
+	assert.Equal(t, &deletedAt, s.urlmap["ggl"].DeletedAt)
+	assert.Nil(t, s.urlmap["ya"].DeletedAt)
+	assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+}
+
+func TestStorage_DeleteURLs_Persists(t *testing.T) {
+	ctx := context.Background()
+	filename := t.TempDir() + "/urls"
+	userID := uuid.New()
+	url := &model.URL{
+		ID:          uuid.New(),
+		ShortKey:    "abc",
+		OriginalURL: "https://ya.ru",
+		UserID:      userID,
+	}
+
+	s, err := NewStorage(filename)
+	require.NoError(t, err)
+	_, err = s.SetURL(ctx, url, storage.DedupeGlobal)
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteURLs(ctx, []uuid.UUID{url.ID}))
+	require.NoError(t, s.Close())
+
+	s, err = NewStorage(filename)
+	require.NoError(t, err)
+	defer s.Close()
+
+	deleted, err := s.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.NotNil(t, deleted.DeletedAt)
+
+	userURLs, err := s.GetURLsByUserID(ctx, userID)
+	require.NoError(t, err)
+	assert.Empty(t, userURLs)
+
+	deletedURLs, err := s.GetDeletedURLsByUserID(ctx, userID)
+	require.NoError(t, err)
+	assert.Len(t, deletedURLs, 1)
 }
 
 func TestURLMap_UnmarshalJSON(t *testing.T) {
@@ -335,7 +467,11 @@ func TestStorage_Ping(t *testing.T) {
 
 func TestStorage_NewStorage_And_Close(t *testing.T) {
 	filename := "test_storage_file.json"
-	defer func() { _ = os.Remove(filename) }()
+	defer func() {
+		_ = os.Remove(filename)
+		_ = os.Remove(filename + tailFileSuffix)
+		_ = os.Remove(filename + clicksFileSuffix)
+	}()
 
 	s, err := NewStorage(filename)
 	require.NoError(t, err)
@@ -344,3 +480,304 @@ func TestStorage_NewStorage_And_Close(t *testing.T) {
 	err = s.Close()
 	assert.NoError(t, err)
 }
+
+func TestStorage_NewStorage_RestoresURLs(t *testing.T) {
+	filename := t.TempDir() + "/urls"
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	url := &model.URL{
+		ID:          uuid.New(),
+		ShortKey:    "abc",
+		OriginalURL: "https://ya.ru",
+		UserID:      uuid.New(),
+		ExpiresAt:   &expiresAt,
+	}
+
+	s, err := NewStorage(filename)
+	require.NoError(t, err)
+	_, err = s.SetURL(context.Background(), url, storage.DedupeGlobal)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	s, err = NewStorage(filename)
+	require.NoError(t, err)
+	defer s.Close()
+
+	restored, err := s.GetURL(context.Background(), "abc")
+	require.NoError(t, err)
+	assert.Equal(t, url, restored)
+}
+
+func TestStorage_DecrementClicksLeft(t *testing.T) {
+	clicksLeft := int64(1)
+	noClicksLeft := int64(0)
+
+	tests := map[string]struct {
+		url                *model.URL
+		expectedClicksLeft *int64
+		expectedError      error
+	}{
+		"not found": {
+			expectedError: storage.ErrNoClicksLeft,
+		},
+		"not limited": {
+			url:           &model.URL{ID: uuid.New(), ShortKey: "abc"},
+			expectedError: storage.ErrNoClicksLeft,
+		},
+		"no clicks left": {
+			url:                &model.URL{ID: uuid.New(), ShortKey: "abc", ClicksLeft: &noClicksLeft},
+			expectedClicksLeft: &noClicksLeft,
+			expectedError:      storage.ErrNoClicksLeft,
+		},
+		"clicks left": {
+			url:                &model.URL{ID: uuid.New(), ShortKey: "abc", ClicksLeft: &clicksLeft},
+			expectedClicksLeft: &noClicksLeft,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			s := Storage{
+				urlmap: URLMap{},
+				file:   &recordFile{file: &dummyFile{Buffer: buf}},
+			}
+			id := uuid.New()
+			if tt.url != nil {
+				s.addURL(tt.url)
+				id = tt.url.ID
+			}
+
+			err := s.DecrementClicksLeft(context.Background(), id)
+			assert.ErrorIs(t, err, tt.expectedError)
+
+			if tt.url != nil {
+				assert.Equal(t, tt.expectedClicksLeft, s.urlmap["abc"].ClicksLeft)
+			}
+			if tt.expectedError != nil {
+				assert.Zero(t, buf.Len())
+			} else {
+				assert.NotZero(t, buf.Len())
+			}
+		})
+	}
+}
+
+func TestStorage_DecrementClicksLeft_Concurrent(t *testing.T) {
+	filename := t.TempDir() + "/urls"
+	clicksLeft := int64(10)
+	url := &model.URL{
+		ID:          uuid.New(),
+		ShortKey:    "abc",
+		OriginalURL: "https://ya.ru",
+		ClicksLeft:  &clicksLeft,
+	}
+
+	s, err := NewStorage(filename)
+	require.NoError(t, err)
+	_, err = s.SetURL(context.Background(), url, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	var used atomic.Int64
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := s.DecrementClicksLeft(context.Background(), url.ID); err == nil {
+				used.Add(1)
+			} else {
+				assert.ErrorIs(t, err, storage.ErrNoClicksLeft)
+			}
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, s.Close())
+
+	assert.Equal(t, clicksLeft, used.Load())
+
+	s, err = NewStorage(filename)
+	require.NoError(t, err)
+	defer s.Close()
+
+	restored, err := s.GetURL(context.Background(), "abc")
+	require.NoError(t, err)
+	require.NotNil(t, restored.ClicksLeft)
+	assert.Zero(t, *restored.ClicksLeft)
+}
+
+func TestStorage_UpdateURL(t *testing.T) {
+	deletedAt := time.Now()
+	stored := func() *model.URL {
+		return &model.URL{
+			ID:          uuid.New(),
+			ShortKey:    "abc",
+			OriginalURL: "https://ya.ru",
+			UserID:      uuid.New(),
+		}
+	}
+
+	tests := map[string]struct {
+		stored        *model.URL
+		otherID       bool
+		expectedError error
+	}{
+		"not found": {
+			expectedError: storage.ErrNotFound,
+		},
+		"other url with the same short key": {
+			stored:        stored(),
+			otherID:       true,
+			expectedError: storage.ErrNotFound,
+		},
+		"deleted": {
+			stored: func() *model.URL {
+				url := stored()
+				url.DeletedAt = &deletedAt
+				return url
+			}(),
+			expectedError: storage.ErrNotFound,
+		},
+		"success": {
+			stored: stored(),
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			s := Storage{
+				urlmap: URLMap{},
+				file:   &recordFile{file: &dummyFile{Buffer: buf}},
+			}
+
+			expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+			clicksLeft := int64(5)
+			changed := &model.URL{
+				ID:           uuid.New(),
+				ShortKey:     "abc",
+				OriginalURL:  "https://yandex.ru",
+				UserID:       uuid.New(),
+				ExpiresAt:    &expiresAt,
+				PasswordHash: "hash",
+				ClicksLeft:   &clicksLeft,
+			}
+			if tt.stored != nil {
+				s.addURL(tt.stored)
+				if !tt.otherID {
+					changed.ID = tt.stored.ID
+				}
+			}
+
+			updated, err := s.UpdateURL(context.Background(), changed, storage.FieldOriginalURL|storage.FieldExpiresAt|storage.FieldPasswordHash|storage.FieldClicksLeft)
+			if tt.expectedError != nil {
+				require.ErrorIs(t, err, tt.expectedError)
+				assert.Zero(t, buf.Len())
+				if tt.stored != nil {
+					assert.Equal(t, "https://ya.ru", s.urlmap["abc"].OriginalURL)
+				}
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.stored.UserID, updated.UserID)
+			assert.Equal(t, "https://yandex.ru", updated.OriginalURL)
+			assert.Equal(t, &expiresAt, updated.ExpiresAt)
+			assert.Equal(t, "hash", updated.PasswordHash)
+			assert.Equal(t, &clicksLeft, updated.ClicksLeft)
+			assert.Equal(t, updated, s.urlmap["abc"])
+			assert.NotZero(t, buf.Len())
+		})
+	}
+}
+
+func TestStorage_UpdateURL_Persists(t *testing.T) {
+	filename := t.TempDir() + "/urls"
+	url := &model.URL{
+		ID:          uuid.New(),
+		ShortKey:    "abc",
+		OriginalURL: "https://ya.ru",
+		UserID:      uuid.New(),
+	}
+
+	s, err := NewStorage(filename)
+	require.NoError(t, err)
+	_, err = s.SetURL(context.Background(), url, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	changed := *url
+	changed.OriginalURL = "https://yandex.ru"
+	_, err = s.UpdateURL(context.Background(), &changed, storage.FieldOriginalURL)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	s, err = NewStorage(filename)
+	require.NoError(t, err)
+	defer s.Close()
+
+	restored, err := s.GetURL(context.Background(), "abc")
+	require.NoError(t, err)
+	assert.Equal(t, &changed, restored)
+}
+
+func TestStorage_GetDeletedURLsByUserID(t *testing.T) {
+	userID := uuid.New()
+	deletedAt := time.Now()
+	deleted := &model.URL{ID: uuid.New(), ShortKey: "del", UserID: userID, DeletedAt: &deletedAt}
+
+	s := Storage{
+		urlmap: URLMap{
+			"del":   deleted,
+			"alive": {ID: uuid.New(), ShortKey: "alive", UserID: userID},
+			"other": {ID: uuid.New(), ShortKey: "other", UserID: uuid.New(), DeletedAt: &deletedAt},
+		},
+	}
+	s.indexURLs()
+
+	urls, err := s.GetDeletedURLsByUserID(context.Background(), userID)
+	require.NoError(t, err)
+	assert.Equal(t, []*model.URL{deleted}, urls)
+}
+
+func TestStorage_RestoreURLs(t *testing.T) {
+	filename := t.TempDir() + "/urls"
+	deletedAt := time.Now().UTC()
+	deleted := &model.URL{
+		ID:          uuid.New(),
+		ShortKey:    "del",
+		OriginalURL: "https://ya.ru",
+		UserID:      uuid.New(),
+		DeletedAt:   &deletedAt,
+	}
+	alive := &model.URL{
+		ID:          uuid.New(),
+		ShortKey:    "alive",
+		OriginalURL: "https://yandex.ru",
+		UserID:      uuid.New(),
+	}
+
+	s, err := NewStorage(filename)
+	require.NoError(t, err)
+	_, err = s.SetURLs(context.Background(), []*model.URL{deleted, alive}, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	err = s.RestoreURLs(context.Background(), []uuid.UUID{deleted.ID, alive.ID, uuid.New()})
+	require.NoError(t, err)
+
+	restored, err := s.GetURL(context.Background(), "del")
+	require.NoError(t, err)
+	assert.Nil(t, restored.DeletedAt)
+	require.NoError(t, s.Close())
+
+	s, err = NewStorage(filename)
+	require.NoError(t, err)
+	defer s.Close()
+
+	restored, err = s.GetURL(context.Background(), "del")
+	require.NoError(t, err)
+	assert.Nil(t, restored.DeletedAt)
+
+	notChanged, err := s.GetURL(context.Background(), "alive")
+	require.NoError(t, err)
+	assert.Equal(t, alive, notChanged)
+}
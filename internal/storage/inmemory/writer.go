@@ -0,0 +1,145 @@
+package inmemory
+
+import (
+	"errors"
+	"time"
+)
+
+// maxWriteBatch is the maximum number of writes fileWriter appends to the file at once.
+const maxWriteBatch = 256
+
+// errWriterClosed is returned by writes to a closed fileWriter.
+var errWriterClosed = errors.New("storage file is closed")
+
+// writeRequest is a write of records or an operation on the file queued to fileWriter.
+type writeRequest struct {
+	records []byte
+	op      func() error
+	// stop stops the writer once op has run.
+	stop bool
+	done chan error
+}
+
+// fileWriter appends records to a record file from a single goroutine.
+// Writes are appended in the order they are received. Writes queued while
+// the previous ones were written are appended together, so that a single write
+// and a single sync serve all of them.
+type fileWriter struct {
+	file     *recordFile
+	interval time.Duration
+	requests chan writeRequest
+	ops      chan writeRequest
+	closed   chan struct{}
+}
+
+// newFileWriter starts a writer of the file. With a positive interval
+// the file is synced every interval.
+func newFileWriter(file *recordFile, interval time.Duration) *fileWriter {
+	w := &fileWriter{
+		file:     file,
+		interval: interval,
+		requests: make(chan writeRequest),
+		ops:      make(chan writeRequest),
+		closed:   make(chan struct{}),
+	}
+
+	go w.run()
+
+	return w
+}
+
+// write appends records to the file and waits until they are written.
+// Either all of the records are written or none of them.
+func (w *fileWriter) write(records []byte) error {
+	return w.send(w.requests, writeRequest{records: records, done: make(chan error, 1)})
+}
+
+// do runs op on the writer goroutine, so that op has exclusive access to w.file.
+// op is not ordered with concurrent writes, callers that need it to be must wait for them.
+func (w *fileWriter) do(op func() error) error {
+	return w.send(w.ops, writeRequest{op: op, done: make(chan error, 1)})
+}
+
+// replace replaces the file by the one returned by open, that runs on the writer goroutine.
+// The replaced file is closed without syncing: callers replace files whose content
+// is already persisted elsewhere.
+func (w *fileWriter) replace(open func() (*recordFile, error)) error {
+	return w.do(func() error {
+		file, err := open()
+		if err != nil {
+			return err
+		}
+
+		_ = w.file.file.Close()
+		w.file = file
+
+		return nil
+	})
+}
+
+// close closes the file and stops the writer. Writes after close fail.
+func (w *fileWriter) close() error {
+	err := w.send(w.ops, writeRequest{op: func() error { return w.file.close() }, stop: true, done: make(chan error, 1)})
+	<-w.closed
+
+	return err
+}
+
+// send queues request and waits for its result.
+func (w *fileWriter) send(requests chan<- writeRequest, request writeRequest) error {
+	select {
+	case requests <- request:
+	case <-w.closed:
+		return errWriterClosed
+	}
+
+	return <-request.done
+}
+
+// run serves requests until the writer is closed.
+func (w *fileWriter) run() {
+	defer close(w.closed)
+
+	var tick <-chan time.Time
+	if w.interval > 0 {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	batch := make([]writeRequest, 0, maxWriteBatch)
+	var records []byte
+
+	for {
+		select {
+		case <-tick:
+			_ = w.file.sync()
+		case request := <-w.ops:
+			request.done <- request.op()
+			if request.stop {
+				return
+			}
+		case request := <-w.requests:
+			batch = append(batch[:0], request)
+		drain:
+			for len(batch) < maxWriteBatch {
+				select {
+				case request := <-w.requests:
+					batch = append(batch, request)
+				default:
+					break drain
+				}
+			}
+
+			records = records[:0]
+			for _, request := range batch {
+				records = append(records, request.records...)
+			}
+
+			err := w.file.append(records)
+			for _, request := range batch {
+				request.done <- err
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package inmemory
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileWriter_Write(t *testing.T) {
+	file := &faultyFile{}
+	w := newFileWriter(&recordFile{file: file, policy: SyncAlways}, 0)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, w.write([]byte("record\n")))
+		}()
+	}
+	wg.Wait()
+
+	require.NoError(t, w.close())
+
+	assert.Equal(t, strings.Repeat("record\n", 20), file.String())
+	// Writes queued together are synced together.
+	assert.LessOrEqual(t, file.syncs, 20)
+	assert.ErrorIs(t, w.write([]byte("late\n")), errWriterClosed)
+}
+
+func TestFileWriter_Write_Error(t *testing.T) {
+	file := &faultyFile{writeErr: errors.New("disk full")}
+	w := newFileWriter(&recordFile{file: file}, 0)
+	defer w.close()
+
+	assert.Error(t, w.write([]byte("record\n")))
+	assert.Zero(t, file.Len())
+
+	file.writeErr = nil
+	require.NoError(t, w.write([]byte("record\n")))
+	assert.Equal(t, "record\n", file.String())
+}
@@ -0,0 +1,265 @@
+// Package migrate copies URLs between storages.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+// defaultBatchSize is the number of URLs imported at once if none is set.
+const defaultBatchSize = 1000
+
+// Source is a storage URLs are migrated from.
+type Source interface {
+	// ForEachURL calls fn with every stored URL, including deleted and expired ones.
+	ForEachURL(ctx context.Context, fn func(url *model.URL) error) error
+}
+
+// Target is a storage URLs are migrated to.
+type Target interface {
+	// GetURLs retrieves the stored URLs with the given short keys.
+	GetURLs(ctx context.Context, shortKeys []string) ([]*model.URL, error)
+
+	// ImportURLs saves urls as they are, skipping URLs with IDs that are already stored.
+	// Returns the number of saved URLs or storage.ErrShortKeyConflict if a short key is taken.
+	ImportURLs(ctx context.Context, urls []*model.URL) (int64, error)
+
+	// SyncURLs replaces the mutable attributes of stored URLs with the ones of urls with the same IDs.
+	// Returns the number of URLs that changed.
+	SyncURLs(ctx context.Context, urls []*model.URL) (int64, error)
+}
+
+// Progress holds the counters of a migration.
+type Progress struct {
+	// Read is the number of URLs read from the source.
+	Read int64
+	// Imported is the number of URLs saved to the target, or that would be saved in a dry run.
+	Imported int64
+	// Updated is the number of URLs the target already has with other mutable attributes,
+	// e.g. deleted or restored in the source since a previous run, that are updated to match the source.
+	Updated int64
+	// Skipped is the number of URLs the target already has as they are, e.g. from a previous run.
+	Skipped int64
+	// Conflicts is the number of URLs whose short keys are taken in the target by other URLs.
+	Conflicts int64
+}
+
+// Option configures a migration.
+type Option func(*options)
+
+type options struct {
+	batchSize  int
+	dryRun     bool
+	onProgress func(Progress)
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		batchSize: defaultBatchSize,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithBatchSize sets the number of URLs imported at once. A non-positive size keeps the default of 1000.
+func WithBatchSize(size int) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.batchSize = size
+		}
+	}
+}
+
+// WithDryRun makes the migration only look up URLs in the target and count what would be imported.
+func WithDryRun() Option {
+	return func(o *options) {
+		o.dryRun = true
+	}
+}
+
+// WithProgress makes the migration report its counters to fn after every batch.
+func WithProgress(fn func(Progress)) Option {
+	return func(o *options) {
+		o.onProgress = fn
+	}
+}
+
+// Migrate streams every URL of src, including deleted ones, to dst in batches.
+// URLs the target already has get the mutable attributes of the source: the original URL,
+// the deletion mark, the expiration, the password hash and the clicks left. So migrating again
+// is safe and brings the target up to date with the source. URLs whose short keys are taken
+// in the target by other URLs are counted as conflicts and left out.
+// Click events are not migrated.
+//
+// Returns the final counters or an error if reading or importing fails.
+func Migrate(ctx context.Context, src Source, dst Target, opts ...Option) (Progress, error) {
+	o := newOptions(opts)
+	m := &migration{dst: dst, options: o}
+
+	batch := make([]*model.URL, 0, o.batchSize)
+	err := src.ForEachURL(ctx, func(url *model.URL) error {
+		m.progress.Read++
+		batch = append(batch, url)
+		if len(batch) < o.batchSize {
+			return nil
+		}
+
+		err := m.migrateBatch(ctx, batch)
+		batch = batch[:0]
+
+		return err
+	})
+	if err != nil {
+		return m.progress, err
+	}
+
+	if len(batch) > 0 {
+		if err := m.migrateBatch(ctx, batch); err != nil {
+			return m.progress, err
+		}
+	}
+
+	return m.progress, nil
+}
+
+// migration is the state of a running migration.
+type migration struct {
+	*options
+	dst      Target
+	progress Progress
+}
+
+// migrateBatch imports or, in a dry run, classifies a batch and reports progress.
+func (m *migration) migrateBatch(ctx context.Context, batch []*model.URL) error {
+	var err error
+	if m.dryRun {
+		err = m.classifyBatch(ctx, batch)
+	} else {
+		err = m.importBatch(ctx, batch)
+	}
+	if err != nil {
+		return err
+	}
+
+	if m.onProgress != nil {
+		m.onProgress(m.progress)
+	}
+
+	return nil
+}
+
+// importBatch imports the batch and syncs the URLs the target already has.
+// If a short key is taken in the target, URLs are imported one by one,
+// so that the rest of the batch is imported.
+func (m *migration) importBatch(ctx context.Context, batch []*model.URL) error {
+	imported, conflicts, err := m.importURLs(ctx, batch)
+	if err != nil {
+		return err
+	}
+
+	// Imported URLs match the source, so only the ones stored before are counted as updated.
+	updated, err := m.dst.SyncURLs(ctx, batch)
+	if err != nil {
+		return fmt.Errorf("failed to sync urls: %w", err)
+	}
+
+	m.progress.Imported += imported
+	m.progress.Updated += updated
+	m.progress.Conflicts += conflicts
+	m.progress.Skipped += int64(len(batch)) - imported - updated - conflicts
+
+	return nil
+}
+
+// importURLs imports the batch, falling back to importing URLs one by one on a short key conflict.
+// Returns the number of imported URLs and the number of URLs left out because of conflicts.
+func (m *migration) importURLs(ctx context.Context, batch []*model.URL) (imported, conflicts int64, err error) {
+	imported, err = m.dst.ImportURLs(ctx, batch)
+	if err == nil {
+		return imported, 0, nil
+	}
+	if !errors.Is(err, storage.ErrShortKeyConflict) {
+		return 0, 0, fmt.Errorf("failed to import urls: %w", err)
+	}
+
+	imported = 0
+	for _, url := range batch {
+		n, err := m.dst.ImportURLs(ctx, []*model.URL{url})
+		if errors.Is(err, storage.ErrShortKeyConflict) {
+			conflicts++
+			continue
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to import url: %w", err)
+		}
+
+		imported += n
+	}
+
+	return imported, conflicts, nil
+}
+
+// classifyBatch looks up the URLs of the batch in the target by short key
+// and counts the ones that would be imported, updated, skipped or left out.
+func (m *migration) classifyBatch(ctx context.Context, batch []*model.URL) error {
+	shortKeys := make([]string, len(batch))
+	for i, url := range batch {
+		shortKeys[i] = url.ShortKey
+	}
+
+	stored, err := m.dst.GetURLs(ctx, shortKeys)
+	if err != nil {
+		return fmt.Errorf("failed to get urls: %w", err)
+	}
+
+	storedURLs := make(map[string]*model.URL, len(stored))
+	for _, url := range stored {
+		storedURLs[url.ShortKey] = url
+	}
+
+	for _, url := range batch {
+		existing, ok := storedURLs[url.ShortKey]
+		switch {
+		case !ok:
+			m.progress.Imported++
+			// Later URLs of the batch with the same short key would conflict with this one.
+			storedURLs[url.ShortKey] = url
+		case existing.ID != url.ID:
+			m.progress.Conflicts++
+		case sameMutableAttributes(existing, url):
+			m.progress.Skipped++
+		default:
+			m.progress.Updated++
+		}
+	}
+
+	return nil
+}
+
+// sameMutableAttributes reports whether a and b have the same attributes that SyncURLs replaces.
+func sameMutableAttributes(a, b *model.URL) bool {
+	return a.OriginalURL == b.OriginalURL &&
+		sameTime(a.DeletedAt, b.DeletedAt) &&
+		sameTime(a.ExpiresAt, b.ExpiresAt) &&
+		a.PasswordHash == b.PasswordHash &&
+		(a.ClicksLeft == nil) == (b.ClicksLeft == nil) &&
+		(a.ClicksLeft == nil || *a.ClicksLeft == *b.ClicksLeft)
+}
+
+// sameTime reports whether a and b are both nil or the same moment.
+// Moments are compared to the microsecond, the precision PostgreSQL stores them with.
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Truncate(time.Microsecond).Equal(b.Truncate(time.Microsecond))
+}
@@ -0,0 +1,177 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/storage"
+	"github.com/dtroode/urlshorter/internal/storage/inmemory"
+)
+
+func newTestStorage(t *testing.T, urls ...*model.URL) *inmemory.Storage {
+	t.Helper()
+
+	s, err := inmemory.NewStorage(t.TempDir()+"/urls", inmemory.WithSync(inmemory.SyncNever, 0))
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+
+	_, err = s.ImportURLs(context.Background(), urls)
+	require.NoError(t, err)
+
+	return s
+}
+
+func newURLs(n int) []*model.URL {
+	deletedAt := time.Now().UTC()
+	urls := make([]*model.URL, n)
+	for i := range urls {
+		urls[i] = &model.URL{
+			ID:          uuid.New(),
+			ShortKey:    fmt.Sprintf("key%d", i),
+			OriginalURL: fmt.Sprintf("https://example.com/%d", i),
+			UserID:      uuid.New(),
+		}
+		if i%3 == 0 {
+			urls[i].DeletedAt = &deletedAt
+		}
+	}
+
+	return urls
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	urls := newURLs(10)
+	src := newTestStorage(t, urls...)
+	dst := newTestStorage(t)
+
+	var reports []Progress
+	progress, err := Migrate(ctx, src, dst, WithBatchSize(4), WithProgress(func(p Progress) {
+		reports = append(reports, p)
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, Progress{Read: 10, Imported: 10}, progress)
+	assert.Equal(t, []Progress{
+		{Read: 4, Imported: 4},
+		{Read: 8, Imported: 8},
+		{Read: 10, Imported: 10},
+	}, reports)
+
+	for _, url := range urls {
+		migrated, err := dst.GetURL(ctx, url.ShortKey)
+		require.NoError(t, err)
+		assert.Equal(t, url, migrated)
+	}
+
+	// Running the migration again is safe.
+	progress, err = Migrate(ctx, src, dst, WithBatchSize(4))
+	require.NoError(t, err)
+	assert.Equal(t, Progress{Read: 10, Skipped: 10}, progress)
+}
+
+func TestMigrate_SyncsChangedURLs(t *testing.T) {
+	ctx := context.Background()
+	urls := newURLs(4)
+	src := newTestStorage(t, urls...)
+	dst := newTestStorage(t, urls...)
+
+	// URLs change in the source after they were migrated.
+	require.NoError(t, src.DeleteURLs(ctx, []uuid.UUID{urls[1].ID}))
+	require.NoError(t, src.RestoreURLs(ctx, []uuid.UUID{urls[0].ID}))
+	clicksLeft := int64(5)
+	changed := *urls[2]
+	changed.ClicksLeft = &clicksLeft
+	_, err := src.UpdateURL(ctx, &changed, storage.FieldClicksLeft)
+	require.NoError(t, err)
+
+	progress, err := Migrate(ctx, src, dst, WithDryRun())
+	require.NoError(t, err)
+	assert.Equal(t, Progress{Read: 4, Updated: 3, Skipped: 1}, progress)
+
+	progress, err = Migrate(ctx, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, Progress{Read: 4, Updated: 3, Skipped: 1}, progress)
+
+	for _, url := range urls {
+		expected, err := src.GetURL(ctx, url.ShortKey)
+		require.NoError(t, err)
+		migrated, err := dst.GetURL(ctx, url.ShortKey)
+		require.NoError(t, err)
+		assert.Equal(t, expected, migrated)
+	}
+
+	progress, err = Migrate(ctx, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, Progress{Read: 4, Skipped: 4}, progress)
+}
+
+func TestMigrate_Conflicts(t *testing.T) {
+	ctx := context.Background()
+	urls := newURLs(5)
+	taken := &model.URL{ID: uuid.New(), ShortKey: urls[2].ShortKey, OriginalURL: "https://taken.com"}
+
+	tests := map[string]struct {
+		opts             []Option
+		expectedProgress Progress
+		expectedStored   int
+	}{
+		"import": {
+			expectedProgress: Progress{Read: 5, Imported: 3, Skipped: 1, Conflicts: 1},
+			expectedStored:   5,
+		},
+		"dry run": {
+			opts:             []Option{WithDryRun()},
+			expectedProgress: Progress{Read: 5, Imported: 3, Skipped: 1, Conflicts: 1},
+			expectedStored:   2,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			src := newTestStorage(t, urls...)
+			dst := newTestStorage(t, urls[0], taken)
+
+			progress, err := Migrate(ctx, src, dst, tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedProgress, progress)
+
+			stored := 0
+			require.NoError(t, dst.ForEachURL(ctx, func(*model.URL) error {
+				stored++
+				return nil
+			}))
+			assert.Equal(t, tt.expectedStored, stored)
+
+			url, err := dst.GetURL(ctx, taken.ShortKey)
+			require.NoError(t, err)
+			assert.Equal(t, taken.ID, url.ID)
+		})
+	}
+}
+
+// failingTarget is a Target that fails every import.
+type failingTarget struct {
+	Target
+}
+
+func (failingTarget) ImportURLs(context.Context, []*model.URL) (int64, error) {
+	return 0, errors.New("connection refused")
+}
+
+func TestMigrate_Error(t *testing.T) {
+	ctx := context.Background()
+	src := newTestStorage(t, newURLs(5)...)
+
+	progress, err := Migrate(ctx, src, failingTarget{}, WithBatchSize(2))
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, storage.ErrShortKeyConflict)
+	assert.Equal(t, Progress{Read: 2}, progress)
+}
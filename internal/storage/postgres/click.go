@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dtroode/urlshorter/internal/model"
+)
+
+// SaveClick stores a single click event.
+func (s *Storage) SaveClick(ctx context.Context, click *model.Click) error {
+	query := `
+	INSERT INTO clicks (id, url_id, created_at, referrer, user_agent, client_ip)
+	VALUES (@id, @urlID, @createdAt, @referrer, @userAgent, @clientIP)`
+	args := pgx.NamedArgs{
+		"id":        click.ID,
+		"urlID":     click.URLID,
+		"createdAt": click.CreatedAt,
+		"referrer":  click.Referrer,
+		"userAgent": click.UserAgent,
+		"clientIP":  click.ClientIP,
+	}
+	_, err := s.db.Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to save click: %w", err)
+	}
+
+	return nil
+}
+
+// GetClickStats aggregates the click events of a URL in the database, so that the events
+// aren't transferred. Buckets are aligned to the Unix epoch in UTC.
+func (s *Storage) GetClickStats(ctx context.Context, urlID uuid.UUID, bucket time.Duration) (*model.ClickStats, error) {
+	stats := &model.ClickStats{
+		Buckets:    make(map[time.Time]int),
+		Referrers:  make(map[string]int),
+		UserAgents: make(map[string]int),
+	}
+
+	query := `
+	SELECT count(*), count(DISTINCT (client_ip, user_agent)) FROM clicks
+	WHERE url_id = $1`
+	if err := s.db.QueryRow(ctx, query, urlID).Scan(&stats.Total, &stats.UniqueVisitors); err != nil {
+		return nil, fmt.Errorf("failed to count clicks: %w", err)
+	}
+
+	// Every grouping set leaves the columns of the other sets NULL.
+	query = `
+	SELECT date_bin(@bucket::interval, created_at, timestamptz '1970-01-01 00:00:00+00'), referrer, user_agent, count(*)
+	FROM clicks
+	WHERE url_id = @urlID
+	GROUP BY GROUPING SETS (
+		(date_bin(@bucket::interval, created_at, timestamptz '1970-01-01 00:00:00+00')),
+		(referrer),
+		(user_agent)
+	)`
+	args := pgx.NamedArgs{
+		"urlID":  urlID,
+		"bucket": bucket,
+	}
+	rows, err := s.db.Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rows: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			start     *time.Time
+			referrer  *string
+			userAgent *string
+			clicks    int
+		)
+		if err := rows.Scan(&start, &referrer, &userAgent, &clicks); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		switch {
+		case start != nil:
+			stats.Buckets[start.UTC()] = clicks
+		case referrer != nil:
+			stats.Referrers[*referrer] = clicks
+		case userAgent != nil:
+			stats.UserAgents[*userAgent] = clicks
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,25 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/storage"
+	"github.com/dtroode/urlshorter/internal/storage/postgres"
+	"github.com/dtroode/urlshorter/internal/storage/storagetest"
+)
+
+func TestStorage_Conformance(t *testing.T) {
+	s, err := postgres.NewStorage(dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, s.Close())
+	})
+
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return s
+	})
+}
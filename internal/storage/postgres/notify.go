@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dtroode/urlshorter/internal/logger"
+)
+
+const (
+	// urlChangesChannel is the notification channel short keys of changed URLs are sent to.
+	urlChangesChannel = "url_changes"
+	// minListenBackoff is the time before the first attempt to reconnect the listener.
+	minListenBackoff = 100 * time.Millisecond
+	// maxListenBackoff is the maximum time between attempts to reconnect the listener.
+	maxListenBackoff = 30 * time.Second
+	// listenPingInterval is the time without notifications after which the listener
+	// connection is pinged, so that a connection dropped silently is detected.
+	listenPingInterval = 30 * time.Second
+)
+
+// URLChangeHandler handles changes of URLs made by any storage sharing the database.
+type URLChangeHandler interface {
+	// InvalidateShortKeys is called with the short keys of changed URLs.
+	InvalidateShortKeys(shortKeys ...string)
+	// InvalidateAll is called when changes may have been missed,
+	// because the listener wasn't connected.
+	InvalidateAll()
+}
+
+// ListenURLChanges passes the short keys of URLs updated, deleted, restored, purged or redirected
+// with a click limit by any storage sharing the database to h until ctx is done.
+// Changed URLs are read from the primary for a while, as replicas may lag behind.
+// The listener keeps a dedicated connection, which is reconnected with exponential backoff if it drops.
+func (s *Storage) ListenURLChanges(ctx context.Context, h URLChangeHandler, logger *logger.Logger) {
+	backoff := minListenBackoff
+
+	for {
+		err := s.listenURLChanges(ctx, h, func() {
+			backoff = minListenBackoff
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		logger.Error("url changes listener failed", "error", err, "retry_in", backoff)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		backoff = nextListenBackoff(backoff)
+	}
+}
+
+// nextListenBackoff returns the time before the next attempt to reconnect the listener.
+func nextListenBackoff(backoff time.Duration) time.Duration {
+	return min(2*backoff, maxListenBackoff)
+}
+
+// listenURLChanges connects the listener and passes notifications to h until the connection fails
+// or ctx is done. connected is called once the listener is connected.
+func (s *Storage) listenURLChanges(ctx context.Context, h URLChangeHandler, connected func()) error {
+	conn, err := pgx.ConnectConfig(ctx, s.db.Config().ConnConfig.Copy())
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	if _, err := conn.Exec(ctx, "LISTEN "+urlChangesChannel); err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	// Changes made while the listener wasn't connected are unknown.
+	if len(s.replicas) > 0 {
+		s.changes.addAll(time.Now())
+	}
+	h.InvalidateAll()
+	connected()
+
+	for {
+		waitCtx, cancel := context.WithTimeout(ctx, listenPingInterval)
+		notification, err := conn.WaitForNotification(waitCtx)
+		cancel()
+
+		if err != nil {
+			if ctx.Err() != nil || !errors.Is(err, context.DeadlineExceeded) {
+				return fmt.Errorf("failed to wait for notification: %w", err)
+			}
+			if err := conn.Ping(ctx); err != nil {
+				return fmt.Errorf("failed to ping: %w", err)
+			}
+			continue
+		}
+
+		// The change may not have reached the replicas yet, so the URL is read from the primary
+		// by the lookup that caches it again.
+		s.markChanged(notification.Payload)
+		h.InvalidateShortKeys(notification.Payload)
+	}
+}
@@ -0,0 +1,23 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextListenBackoff(t *testing.T) {
+	backoff := minListenBackoff
+	var backoffs []time.Duration
+	for range 12 {
+		backoffs = append(backoffs, backoff)
+		backoff = nextListenBackoff(backoff)
+	}
+
+	assert.Equal(t, []time.Duration{
+		100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond,
+		1600 * time.Millisecond, 3200 * time.Millisecond, 6400 * time.Millisecond, 12800 * time.Millisecond,
+		25600 * time.Millisecond, 30 * time.Second, 30 * time.Second, 30 * time.Second,
+	}, backoffs)
+}
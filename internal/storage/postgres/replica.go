@@ -0,0 +1,267 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+const (
+	// primaryName is the name of the primary pool in component statuses.
+	primaryName = "primary"
+	// defaultReplicaCheckInterval is the time between health checks of replicas if none is set.
+	defaultReplicaCheckInterval = 5 * time.Second
+	// replicaLagWindow is the time URLs are read from the primary after they change,
+	// so that lookups don't get URLs from replicas that haven't caught up with the change yet.
+	replicaLagWindow = 5 * time.Second
+)
+
+// Option configures Storage.
+type Option func(*options)
+
+type options struct {
+	replicaDSNs          []string
+	replicaCheckInterval time.Duration
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		replicaCheckInterval: defaultReplicaCheckInterval,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithReplicas makes the storage read URLs from replicas with the given DSNs.
+// Replicas are pinged every checkInterval, reads go to the primary while none of them is healthy.
+// A non-positive checkInterval keeps the default of 5 seconds.
+func WithReplicas(dsns []string, checkInterval time.Duration) Option {
+	return func(o *options) {
+		o.replicaDSNs = dsns
+		if checkInterval > 0 {
+			o.replicaCheckInterval = checkInterval
+		}
+	}
+}
+
+// replica is a connection pool of a read replica.
+type replica struct {
+	name    string
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+}
+
+// newReplicas opens connection pools of replicas with dsns.
+// Pools connect lazily, so replicas that are down don't fail the storage creation.
+func newReplicas(ctx context.Context, dsns []string) ([]*replica, error) {
+	replicas := make([]*replica, 0, len(dsns))
+	for i, dsn := range dsns {
+		conf, err := pgxpool.ParseConfig(dsn)
+		if err == nil {
+			var pool *pgxpool.Pool
+			pool, err = pgxpool.NewWithConfig(ctx, conf)
+			if err == nil {
+				replicas = append(replicas, &replica{name: fmt.Sprintf("replica-%d", i+1), pool: pool})
+				continue
+			}
+		}
+
+		for _, r := range replicas {
+			r.pool.Close()
+		}
+
+		return nil, fmt.Errorf("failed to open replica %d connection pool: %w", i+1, err)
+	}
+
+	return replicas, nil
+}
+
+// check pings the replica and records whether it's healthy.
+func (r *replica) check(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := r.pool.Ping(ctx)
+	r.healthy.Store(err == nil)
+
+	return err
+}
+
+// checkReplicas checks every replica concurrently.
+func (s *Storage) checkReplicas(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, r := range s.replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = r.check(ctx, s.replicaCheckInterval)
+		}()
+	}
+	wg.Wait()
+}
+
+// runReplicaChecks checks replicas every s.replicaCheckInterval until the storage is closed.
+func (s *Storage) runReplicaChecks() {
+	defer close(s.checksDone)
+
+	ticker := time.NewTicker(s.replicaCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopChecks:
+			return
+		case <-ticker.C:
+			s.checkReplicas(context.Background())
+		}
+	}
+}
+
+// pickReplica returns the next healthy replica in round robin order.
+// Returns nil if no replica is healthy.
+func (s *Storage) pickReplica() *replica {
+	n := len(s.replicas)
+	if n == 0 {
+		return nil
+	}
+
+	start := int(s.nextReplica.Add(1) % uint32(n))
+	for i := range n {
+		if r := s.replicas[(start+i)%n]; r.healthy.Load() {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// readFromReplica runs query on a healthy replica and falls back to the primary
+// if there is none or the replica fails. A replica that fails is considered unhealthy
+// until its next successful check.
+// Replicas may lag behind the primary, so the query is also repeated on the primary
+// when the replica doesn't find the URL or stale reports that the result is incomplete.
+func readFromReplica[T any](ctx context.Context, s *Storage, query func(db *pgxpool.Pool) (T, error), stale func(T) bool) (T, error) {
+	if r := s.pickReplica(); r != nil {
+		result, err := query(r.pool)
+		if err == nil && (stale == nil || !stale(result)) {
+			return result, nil
+		}
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			if ctx.Err() != nil {
+				return result, err
+			}
+			r.healthy.Store(false)
+		}
+	}
+
+	return query(s.db)
+}
+
+// PingComponents pings the primary and every replica concurrently.
+// Returns the ping error of every pool by its name and the error of the primary,
+// as the storage can serve reads and writes without replicas.
+func (s *Storage) PingComponents(ctx context.Context) (map[string]error, error) {
+	statuses := make(map[string]error, len(s.replicas)+1)
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, r := range s.replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := r.check(ctx, s.replicaCheckInterval)
+
+			mu.Lock()
+			statuses[r.name] = err
+			mu.Unlock()
+		}()
+	}
+
+	err := s.db.Ping(ctx)
+	wg.Wait()
+	statuses[primaryName] = err
+
+	return statuses, err
+}
+
+// recentChanges holds the short keys of URLs changed within replicaLagWindow by the time they were changed.
+type recentChanges struct {
+	mu   sync.Mutex
+	keys map[string]time.Time
+	// all is the time all URLs were considered changed, because changes may have been missed.
+	all time.Time
+	// pruned is the time expired keys were last removed.
+	pruned time.Time
+}
+
+// add records that the URLs with shortKeys changed at now.
+func (c *recentChanges) add(now time.Time, shortKeys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.keys == nil {
+		c.keys = make(map[string]time.Time)
+	}
+	for _, shortKey := range shortKeys {
+		c.keys[shortKey] = now
+	}
+
+	if now.Sub(c.pruned) < replicaLagWindow {
+		return
+	}
+	for shortKey, changedAt := range c.keys {
+		if now.Sub(changedAt) >= replicaLagWindow {
+			delete(c.keys, shortKey)
+		}
+	}
+	c.pruned = now
+}
+
+// addAll records that any URL may have changed at now.
+func (c *recentChanges) addAll(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.all = now
+}
+
+// contains reports whether any of the URLs with shortKeys changed within replicaLagWindow before now.
+func (c *recentChanges) contains(now time.Time, shortKeys ...string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.all) < replicaLagWindow {
+		return true
+	}
+	for _, shortKey := range shortKeys {
+		if changedAt, ok := c.keys[shortKey]; ok && now.Sub(changedAt) < replicaLagWindow {
+			return true
+		}
+	}
+
+	return false
+}
+
+// markChanged makes lookups of the URLs with shortKeys read from the primary for replicaLagWindow.
+func (s *Storage) markChanged(shortKeys ...string) {
+	if len(s.replicas) > 0 && len(shortKeys) > 0 {
+		s.changes.add(time.Now(), shortKeys...)
+	}
+}
+
+// changedRecently reports whether any of the URLs with shortKeys has to be read from the primary.
+func (s *Storage) changedRecently(shortKeys ...string) bool {
+	return len(s.replicas) > 0 && s.changes.contains(time.Now(), shortKeys...)
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+func newReplicaTestStorage(healthy ...bool) *Storage {
+	s := &Storage{db: new(pgxpool.Pool)}
+	for i, h := range healthy {
+		r := &replica{name: string(rune('a' + i)), pool: new(pgxpool.Pool)}
+		r.healthy.Store(h)
+		s.replicas = append(s.replicas, r)
+	}
+
+	return s
+}
+
+func TestStorage_PickReplica(t *testing.T) {
+	s := newReplicaTestStorage(true, false, true)
+
+	picked := make(map[string]int)
+	for range 6 {
+		r := s.pickReplica()
+		require.NotNil(t, r)
+		picked[r.name]++
+	}
+	// Unhealthy replicas are skipped, healthy ones take turns.
+	assert.NotContains(t, picked, "b")
+	assert.Len(t, picked, 2)
+
+	s.replicas[0].healthy.Store(false)
+	s.replicas[2].healthy.Store(false)
+	assert.Nil(t, s.pickReplica())
+
+	assert.Nil(t, newReplicaTestStorage().pickReplica())
+}
+
+func TestReadFromReplica(t *testing.T) {
+	replicaErr := errors.New("connection refused")
+
+	tests := map[string]struct {
+		replicaResult   []string
+		replicaErr      error
+		stale           func([]string) bool
+		expectedResult  []string
+		expectedPrimary bool
+		expectedHealthy bool
+	}{
+		"read from replica": {
+			replicaResult:   []string{"replica"},
+			expectedResult:  []string{"replica"},
+			expectedHealthy: true,
+		},
+		"replica fails": {
+			replicaErr:      replicaErr,
+			expectedResult:  []string{"primary"},
+			expectedPrimary: true,
+			expectedHealthy: false,
+		},
+		"not found on replica": {
+			replicaErr:      storage.ErrNotFound,
+			expectedResult:  []string{"primary"},
+			expectedPrimary: true,
+			expectedHealthy: true,
+		},
+		"stale result on replica": {
+			replicaResult:   []string{"replica"},
+			stale:           func(result []string) bool { return len(result) < 2 },
+			expectedResult:  []string{"primary"},
+			expectedPrimary: true,
+			expectedHealthy: true,
+		},
+	}
+
+	for tn, tt := range tests {
+		t.Run(tn, func(t *testing.T) {
+			s := newReplicaTestStorage(true)
+
+			primaryRead := false
+			result, err := readFromReplica(context.Background(), s, func(db *pgxpool.Pool) ([]string, error) {
+				if db == s.db {
+					primaryRead = true
+					return []string{"primary"}, nil
+				}
+				return tt.replicaResult, tt.replicaErr
+			}, tt.stale)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedResult, result)
+			assert.Equal(t, tt.expectedPrimary, primaryRead)
+			assert.Equal(t, tt.expectedHealthy, s.replicas[0].healthy.Load())
+		})
+	}
+}
+
+func TestReadFromReplica_NoHealthyReplica(t *testing.T) {
+	s := newReplicaTestStorage(false)
+
+	result, err := readFromReplica(context.Background(), s, func(db *pgxpool.Pool) (string, error) {
+		if db == s.db {
+			return "primary", nil
+		}
+		return "replica", nil
+	}, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, "primary", result)
+}
+
+func TestRecentChanges(t *testing.T) {
+	now := time.Now()
+	var c recentChanges
+
+	assert.False(t, c.contains(now, "abc"))
+
+	c.add(now, "abc")
+	assert.True(t, c.contains(now.Add(replicaLagWindow/2), "abc"))
+	assert.True(t, c.contains(now, "other", "abc"))
+	assert.False(t, c.contains(now, "other"))
+	assert.False(t, c.contains(now.Add(replicaLagWindow), "abc"))
+
+	// Expired keys are removed when keys are added later.
+	c.add(now.Add(replicaLagWindow), "other")
+	assert.NotContains(t, c.keys, "abc")
+
+	c.addAll(now)
+	assert.True(t, c.contains(now, "missing"))
+	assert.False(t, c.contains(now.Add(replicaLagWindow), "missing"))
+}
+
+func TestStorage_MarkChanged_WithoutReplicas(t *testing.T) {
+	s := newReplicaTestStorage()
+	s.markChanged("abc")
+
+	assert.False(t, s.changedRecently("abc"))
+	assert.Empty(t, s.changes.keys)
+}
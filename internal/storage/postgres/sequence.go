@@ -0,0 +1,32 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// KeySequence reserves blocks of hashids sequence numbers from the short_key_sequence
+// database sequence, which is shared by all instances using the database.
+type KeySequence struct {
+	db *pgxpool.Pool
+}
+
+// KeySequence returns the short key sequence of the database.
+func (s *Storage) KeySequence() *KeySequence {
+	return &KeySequence{db: s.db}
+}
+
+// Reserve reserves the next block of numbers. The block size is the increment of the sequence.
+func (s *KeySequence) Reserve(ctx context.Context) (uint64, uint64, error) {
+	query := `
+	SELECT nextval('short_key_sequence'), seqincrement FROM pg_sequence
+	WHERE seqrelid = 'short_key_sequence'::regclass`
+	var first, size int64
+	if err := s.db.QueryRow(ctx, query).Scan(&first, &size); err != nil {
+		return 0, 0, fmt.Errorf("failed to reserve short key sequence: %w", err)
+	}
+
+	return uint64(first), uint64(size), nil
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/pgtype"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/dtroode/urlshorter/database"
@@ -15,14 +18,49 @@ import (
 	"github.com/dtroode/urlshorter/internal/storage"
 )
 
+const (
+	// uniqueViolationCode is the PostgreSQL error code for unique constraint violations.
+	uniqueViolationCode = "23505"
+	// shortKeyConstraint is the name of the unique constraint on urls.short_key.
+	shortKeyConstraint = "urls_short_key_key"
+	// urlColumns is the list of urls columns scanned by scanURL.
+	urlColumns = "id, short_key, original_url, user_id, deleted_at, expires_at, password_hash, clicks_left"
+)
+
+// scanner is implemented by pgx.Row and pgx.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanURL scans a row selected with urlColumns into a URL model.
+func scanURL(row scanner) (*model.URL, error) {
+	var url model.URL
+	err := row.Scan(&url.ID, &url.ShortKey, &url.OriginalURL, &url.UserID, &url.DeletedAt, &url.ExpiresAt, &url.PasswordHash, &url.ClicksLeft)
+	if err != nil {
+		return nil, err
+	}
+
+	return &url, nil
+}
+
 // Storage represents PostgreSQL storage implementation.
+// Writes go to the primary database, lookups of URLs may be served by read replicas.
 type Storage struct {
 	db *pgxpool.Pool
+
+	replicas             []*replica
+	nextReplica          atomic.Uint32
+	replicaCheckInterval time.Duration
+	stopChecks           chan struct{}
+	checksDone           chan struct{}
+	// changes holds URLs changed recently, which are read from the primary.
+	changes recentChanges
 }
 
 // NewStorage creates new PostgreSQL storage instance.
-func NewStorage(dsn string) (*Storage, error) {
+func NewStorage(dsn string, opts ...Option) (*Storage, error) {
 	ctx := context.Background()
+	o := newOptions(opts)
 
 	conf, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
@@ -35,31 +73,69 @@ func NewStorage(dsn string) (*Storage, error) {
 	}
 
 	if err := database.Migrate(ctx, dsn); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
-	return &Storage{
-		db: pool,
-	}, nil
+	replicas, err := newReplicas(ctx, o.replicaDSNs)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	s := &Storage{
+		db:                   pool,
+		replicas:             replicas,
+		replicaCheckInterval: o.replicaCheckInterval,
+	}
+
+	if len(replicas) > 0 {
+		s.checkReplicas(ctx)
+
+		s.stopChecks = make(chan struct{})
+		s.checksDone = make(chan struct{})
+		go s.runReplicaChecks()
+	}
+
+	return s, nil
 }
 
-// Close closes the storage and database connection pool.
+// Close closes the storage and database connection pools.
 func (s *Storage) Close() error {
+	if s.stopChecks != nil {
+		close(s.stopChecks)
+		<-s.checksDone
+	}
+
+	for _, r := range s.replicas {
+		r.pool.Close()
+	}
 	s.db.Close()
 
 	return nil
 }
 
-// Ping checks if the database is available.
+// Ping checks if the primary database is available.
 func (s *Storage) Ping(ctx context.Context) error {
 	return s.db.Ping(ctx)
 }
 
 // GetURL retrieves a URL by its short key.
+// URLs changed recently are read from the primary, as replicas may not have the change yet.
 func (s *Storage) GetURL(ctx context.Context, shortKey string) (*model.URL, error) {
-	var url model.URL
-	query := `SELECT id, short_key, original_url, user_id, deleted_at FROM urls WHERE short_key = $1`
-	err := s.db.QueryRow(ctx, query, shortKey).Scan(&url.ID, &url.ShortKey, &url.OriginalURL, &url.UserID, &url.DeletedAt)
+	if s.changedRecently(shortKey) {
+		return getURL(ctx, s.db, shortKey)
+	}
+
+	return readFromReplica(ctx, s, func(db *pgxpool.Pool) (*model.URL, error) {
+		return getURL(ctx, db, shortKey)
+	}, nil)
+}
+
+// getURL retrieves a URL by its short key from db.
+func getURL(ctx context.Context, db *pgxpool.Pool, shortKey string) (*model.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE short_key = $1`
+	url, err := scanURL(db.QueryRow(ctx, query, shortKey))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, storage.ErrNotFound
@@ -67,16 +143,40 @@ func (s *Storage) GetURL(ctx context.Context, shortKey string) (*model.URL, erro
 		return nil, fmt.Errorf("failed to get url: %w", err)
 	}
 
-	return &url, nil
+	return url, nil
 }
 
 // GetURLs retrieves multiple URLs by their short keys.
+// URLs are read from the primary if any of them changed recently.
 func (s *Storage) GetURLs(ctx context.Context, shortKeys []string) ([]*model.URL, error) {
-	query := `SELECT id, short_key, original_url, user_id, deleted_at FROM urls WHERE short_key = ANY ($1)`
+	if s.changedRecently(shortKeys...) {
+		return getURLs(ctx, s.db, shortKeys)
+	}
+
+	return readFromReplica(ctx, s, func(db *pgxpool.Pool) ([]*model.URL, error) {
+		return getURLs(ctx, db, shortKeys)
+	}, func(urls []*model.URL) bool {
+		return len(urls) < countDistinct(shortKeys)
+	})
+}
+
+// countDistinct returns the number of distinct values.
+func countDistinct(values []string) int {
+	distinct := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		distinct[value] = struct{}{}
+	}
+
+	return len(distinct)
+}
+
+// getURLs retrieves multiple URLs by their short keys from db.
+func getURLs(ctx context.Context, db *pgxpool.Pool, shortKeys []string) ([]*model.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE short_key = ANY ($1)`
 
 	keys := &pgtype.TextArray{}
 	keys.Set(shortKeys)
-	rows, err := s.db.Query(ctx, query, keys)
+	rows, err := db.Query(ctx, query, keys)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query rows: %w", err)
 	}
@@ -85,21 +185,51 @@ func (s *Storage) GetURLs(ctx context.Context, shortKeys []string) ([]*model.URL
 	urls := make([]*model.URL, 0)
 
 	for rows.Next() {
-		var url model.URL
-		err := rows.Scan(&url.ID, &url.ShortKey, &url.OriginalURL, &url.UserID, &url.DeletedAt)
+		url, err := scanURL(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
-		urls = append(urls, &url)
+		urls = append(urls, url)
 	}
 
 	return urls, nil
 }
 
 // GetURLsByUserID retrieves all URLs created by a specific user.
+// URLs created just before the call may be missing if they are read from a lagging replica.
 func (s *Storage) GetURLsByUserID(ctx context.Context, userID uuid.UUID) ([]*model.URL, error) {
-	query := `SELECT id, short_key, original_url, user_id, deleted_at FROM urls WHERE user_id = $1 AND deleted_at IS NULL`
+	return readFromReplica(ctx, s, func(db *pgxpool.Pool) ([]*model.URL, error) {
+		return getURLsByUserID(ctx, db, userID)
+	}, nil)
+}
+
+// getURLsByUserID retrieves all URLs created by a specific user from db.
+func getURLsByUserID(ctx context.Context, db *pgxpool.Pool, userID uuid.UUID) ([]*model.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE user_id = $1 AND deleted_at IS NULL`
+	rows, err := db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rows: %w", err)
+	}
+	defer rows.Close()
+
+	urls := make([]*model.URL, 0)
+
+	for rows.Next() {
+		url, err := scanURL(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		urls = append(urls, url)
+	}
+
+	return urls, nil
+}
+
+// GetDeletedURLsByUserID retrieves all deleted URLs created by a specific user.
+func (s *Storage) GetDeletedURLsByUserID(ctx context.Context, userID uuid.UUID) ([]*model.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE user_id = $1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC`
 	rows, err := s.db.Query(ctx, query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query rows: %w", err)
@@ -109,89 +239,665 @@ func (s *Storage) GetURLsByUserID(ctx context.Context, userID uuid.UUID) ([]*mod
 	urls := make([]*model.URL, 0)
 
 	for rows.Next() {
-		var url model.URL
-		err := rows.Scan(&url.ID, &url.ShortKey, &url.OriginalURL, &url.UserID, &url.DeletedAt)
+		url, err := scanURL(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
-		urls = append(urls, &url)
+		urls = append(urls, url)
 	}
 
 	return urls, nil
 }
 
+// ForEachActiveURL calls fn with every URL that is neither deleted nor expired.
+// URLs are streamed from the primary, so that the result is up to date and isn't held in memory.
+// Returns the first error returned by fn or an error if retrieval fails.
+func (s *Storage) ForEachActiveURL(ctx context.Context, fn func(url *model.URL) error) error {
+	return s.forEachURL(ctx, `WHERE deleted_at IS NULL AND (expires_at IS NULL OR expires_at > now())`, fn)
+}
+
+// ForEachURL calls fn with every stored URL, including deleted and expired ones, in ID order.
+// URLs are streamed from the primary.
+// Returns the first error returned by fn or an error if retrieval fails.
+func (s *Storage) ForEachURL(ctx context.Context, fn func(url *model.URL) error) error {
+	return s.forEachURL(ctx, `ORDER BY id`, fn)
+}
+
+// forEachURL streams URLs selected with the query suffix from the primary to fn.
+func (s *Storage) forEachURL(ctx context.Context, suffix string, fn func(url *model.URL) error) error {
+	query := `SELECT ` + urlColumns + ` FROM urls ` + suffix
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to query rows: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		url, err := scanURL(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if err := fn(url); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return nil
+}
+
 // SetURL stores a single URL in the storage.
-func (s *Storage) SetURL(ctx context.Context, url *model.URL) (*model.URL, error) {
-	var savedURL model.URL
-	query := `
-	INSERT INTO urls (id, short_key, original_url, user_id) VALUES (@id, @shortKey, @originalURL, @userID)
-	ON CONFLICT (original_url) DO UPDATE SET short_key = urls.short_key
-	RETURNING id, short_key, original_url, user_id`
-	args := pgx.NamedArgs{
-		"id":          url.ID,
-		"shortKey":    url.ShortKey,
-		"originalURL": url.OriginalURL,
-		"userID":      url.UserID,
+// Depending on scope, an active URL with the same original URL
+// may be returned instead together with storage.ErrConflict.
+func (s *Storage) SetURL(ctx context.Context, url *model.URL, scope storage.DedupeScope) (*model.URL, error) {
+	if scope == storage.DedupeNone {
+		return insertURL(ctx, s.db, url)
 	}
-	err := s.db.QueryRow(ctx, query, args).Scan(&savedURL.ID, &savedURL.ShortKey, &savedURL.OriginalURL, &savedURL.UserID)
+
+	tx, err := s.db.Begin(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to save url: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockOriginalURLs(ctx, tx, []string{url.OriginalURL}); err != nil {
+		return nil, err
+	}
+
+	savedURL, err := saveURL(ctx, tx, url, scope)
+	if err != nil && !errors.Is(err, storage.ErrConflict) {
+		return nil, err
 	}
 
-	if url.ID != savedURL.ID {
-		err = storage.ErrConflict
+	if commitErr := tx.Commit(ctx); commitErr != nil {
+		return nil, fmt.Errorf("failed to commit transcation: %w", commitErr)
 	}
 
-	return &savedURL, err
+	return savedURL, err
 }
 
 // SetURLs stores multiple URLs in the storage.
-func (s *Storage) SetURLs(ctx context.Context, urls []*model.URL) (savedURLs []*model.URL, err error) {
+// Depending on scope, active URLs with the same original URLs,
+// including ones saved earlier in the same batch, may be returned in place of some of urls.
+// Returns storage.ErrShortKeyConflict and saves nothing if a short key is already taken.
+// Existing URLs are looked up and new URLs are inserted with a fixed number of statements,
+// so the number of round trips doesn't grow with the size of the batch.
+func (s *Storage) SetURLs(ctx context.Context, urls []*model.URL, scope storage.DedupeScope) ([]*model.URL, error) {
+	now := time.Now()
+
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
+	savedURLs := make([]*model.URL, len(urls))
+
+	if scope != storage.DedupeNone {
+		originalURLs := make([]string, len(urls))
+		for i, url := range urls {
+			originalURLs[i] = url.OriginalURL
+		}
+		if err := lockOriginalURLs(ctx, tx, originalURLs); err != nil {
+			return nil, err
+		}
+		if err := findActiveURLs(ctx, tx, urls, scope, savedURLs); err != nil {
+			return nil, err
+		}
+	}
+
+	// reused maps indexes of urls deduped against URLs saved earlier in the batch to indexes of the latter.
+	reused := make(map[int]int)
+	batchURLs := make(map[string][]int)
+	newURLs := make([]*model.URL, 0, len(urls))
+
+	for i, url := range urls {
+		if savedURLs[i] != nil {
+			continue
+		}
+		if j, ok := findReusable(urls, batchURLs[url.OriginalURL], url, scope, now); ok {
+			reused[i] = j
+			continue
+		}
+
+		batchURLs[url.OriginalURL] = append(batchURLs[url.OriginalURL], i)
+		newURLs = append(newURLs, url)
+	}
+
+	insertedURLs, err := insertURLs(ctx, tx, newURLs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, url := range urls {
+		if j, ok := reused[i]; ok {
+			savedURLs[i] = savedURLs[j]
+		} else if savedURLs[i] == nil {
+			savedURLs[i] = insertedURLs[url.ID]
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transcation: %w", err)
+	}
+
+	return savedURLs, nil
+}
+
+// findReusable returns the index of the first URL of urls at candidates that can be
+// reused instead of saving url within scope. The candidates are new URLs of the same batch,
+// so they are never deleted.
+func findReusable(urls []*model.URL, candidates []int, url *model.URL, scope storage.DedupeScope, now time.Time) (int, bool) {
+	if scope == storage.DedupeNone || !url.CanBeReused() {
+		return 0, false
+	}
+
+	for _, i := range candidates {
+		candidate := urls[i]
+		if !candidate.CanBeReused() || candidate.IsExpired(now) {
+			continue
+		}
+		if scope == storage.DedupeGlobal || candidate.UserID == url.UserID {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// querier is implemented by pgxpool.Pool and pgx.Tx.
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// lockOriginalURLs takes transaction level advisory locks on original URLs,
+// so that concurrent transactions can't save the same original URL twice.
+// Locks are taken in a fixed order to avoid deadlocks between batches.
+func lockOriginalURLs(ctx context.Context, tx pgx.Tx, originalURLs []string) error {
 	query := `
-	INSERT INTO urls (id, short_key, original_url, user_id) VALUES (@id, @shortKey, @originalURL, @userID)
-	ON CONFLICT (original_url) DO UPDATE SET short_key = urls.short_key
-	RETURNING id, short_key, original_url, user_id`
-
-	for _, url := range urls {
-		args := pgx.NamedArgs{
-			"id":          url.ID,
-			"shortKey":    url.ShortKey,
-			"originalURL": url.OriginalURL,
-			"userID":      url.UserID,
-		}
-		var savedURL model.URL
-		err := tx.QueryRow(ctx, query, args).Scan(&savedURL.ID, &savedURL.ShortKey, &savedURL.OriginalURL, &savedURL.UserID)
+	SELECT pg_advisory_xact_lock(key) FROM (
+		SELECT DISTINCT hashtext(original_url) AS key FROM unnest($1::text[]) AS original_url ORDER BY key
+	) AS keys`
+	if _, err := tx.Exec(ctx, query, originalURLs); err != nil {
+		return fmt.Errorf("failed to lock original urls: %w", err)
+	}
+
+	return nil
+}
+
+// saveURL inserts url unless an active URL with the same original URL exists within scope.
+// Returns the existing URL and storage.ErrConflict in that case.
+// URLs that can't be reused are always inserted.
+// Callers must hold the lock on the original URL.
+func saveURL(ctx context.Context, q querier, url *model.URL, scope storage.DedupeScope) (*model.URL, error) {
+	if scope != storage.DedupeNone && url.CanBeReused() {
+		existing, err := findActiveURL(ctx, q, url, scope)
 		if err != nil {
-			tx.Rollback(ctx)
+			return nil, err
+		}
+		if existing != nil {
+			return existing, storage.ErrConflict
+		}
+	}
 
-			return nil, fmt.Errorf("failed to save url: %w", err)
+	return insertURL(ctx, q, url)
+}
+
+// findActiveURL returns a reusable URL that is neither deleted nor expired and has
+// the same original URL as url. With storage.DedupeUser only URLs of the same user are considered.
+// Returns nil if there is no such URL.
+func findActiveURL(ctx context.Context, q querier, url *model.URL, scope storage.DedupeScope) (*model.URL, error) {
+	query := `
+	SELECT ` + urlColumns + ` FROM urls
+	WHERE original_url = @originalURL
+		AND (@anyUser OR user_id = @userID)
+		AND deleted_at IS NULL
+		AND (expires_at IS NULL OR expires_at > now())
+		AND password_hash = ''
+		AND clicks_left IS NULL
+	LIMIT 1`
+	args := pgx.NamedArgs{
+		"originalURL": url.OriginalURL,
+		"userID":      url.UserID,
+		"anyUser":     scope == storage.DedupeGlobal,
+	}
+	existing, err := scanURL(q.QueryRow(ctx, query, args))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
 		}
+		return nil, fmt.Errorf("failed to find url: %w", err)
+	}
+
+	return existing, nil
+}
 
-		savedURLs = append(savedURLs, &savedURL)
+// FindActiveURLs returns the active URLs that would be reused instead of saving urls within scope,
+// at the indexes of urls. Indexes of urls without such a URL are nil.
+// URLs are looked up on the primary, so that URLs saved just now are found.
+func (s *Storage) FindActiveURLs(ctx context.Context, urls []*model.URL, scope storage.DedupeScope) ([]*model.URL, error) {
+	found := make([]*model.URL, len(urls))
+	if scope == storage.DedupeNone {
+		return found, nil
 	}
 
-	err = tx.Commit(ctx)
+	if err := findActiveURLs(ctx, s.db, urls, scope, found); err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// TakenShortKeys returns the short keys among shortKeys that are taken by stored URLs, including deleted ones.
+// Short keys are looked up on the primary, so that URLs saved just now are found.
+func (s *Storage) TakenShortKeys(ctx context.Context, shortKeys []string) ([]string, error) {
+	rows, err := s.db.Query(ctx, `SELECT short_key FROM urls WHERE short_key = ANY($1)`, shortKeys)
 	if err != nil {
-		return nil, fmt.Errorf("failed to commit transcation: %w", err)
+		return nil, fmt.Errorf("failed to query rows: %w", err)
+	}
+
+	taken, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows: %w", err)
+	}
+
+	return taken, nil
+}
+
+// rowsQuerier is implemented by pools and transactions that can run queries returning rows.
+type rowsQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// findActiveURLs looks up active URLs for all reusable urls with a single query,
+// the way findActiveURL does for a single URL, and puts them into found at the indexes of urls.
+// Callers saving urls must hold the locks on the original URLs.
+func findActiveURLs(ctx context.Context, q rowsQuerier, urls []*model.URL, scope storage.DedupeScope, found []*model.URL) error {
+	ords := make([]int32, 0, len(urls))
+	originalURLs := make([]string, 0, len(urls))
+	userIDs := make([]uuid.UUID, 0, len(urls))
+	for i, url := range urls {
+		if url.CanBeReused() {
+			ords = append(ords, int32(i))
+			originalURLs = append(originalURLs, url.OriginalURL)
+			userIDs = append(userIDs, url.UserID)
+		}
+	}
+	if len(ords) == 0 {
+		return nil
+	}
+
+	query := `
+	SELECT DISTINCT ON (batch.ord) batch.ord, ` + urlColumns + `
+	FROM unnest(@ords::integer[], @originalURLs::text[], @userIDs::uuid[]) AS batch(ord, batch_original_url, batch_user_id)
+	JOIN urls ON original_url = batch_original_url
+	WHERE (@anyUser OR user_id = batch_user_id)
+		AND deleted_at IS NULL
+		AND (expires_at IS NULL OR expires_at > now())
+		AND password_hash = ''
+		AND clicks_left IS NULL
+	ORDER BY batch.ord`
+	args := pgx.NamedArgs{
+		"ords":         ords,
+		"originalURLs": originalURLs,
+		"userIDs":      userIDs,
+		"anyUser":      scope == storage.DedupeGlobal,
+	}
+	rows, err := q.Query(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to find urls: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ord int32
+		url, err := scanURL(prefixedScanner{row: rows, prefix: &ord})
+		if err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		found[ord] = url
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to find urls: %w", err)
+	}
+
+	return nil
+}
+
+// prefixedScanner scans the first column of a row into prefix and the rest into the destinations
+// passed to Scan, so that scanURL can scan rows with an extra leading column.
+type prefixedScanner struct {
+	row    scanner
+	prefix any
+}
+
+// Scan implements scanner.
+func (s prefixedScanner) Scan(dest ...any) error {
+	return s.row.Scan(append([]any{s.prefix}, dest...)...)
+}
+
+// insertURLs inserts urls as new rows. The URLs are copied to a temporary table
+// and inserted from it with a single statement.
+// Returns the inserted URLs by ID, or storage.ErrShortKeyConflict if a short key is already taken.
+func insertURLs(ctx context.Context, tx pgx.Tx, urls []*model.URL) (map[uuid.UUID]*model.URL, error) {
+	insertedURLs := make(map[uuid.UUID]*model.URL, len(urls))
+	if len(urls) == 0 {
+		return insertedURLs, nil
+	}
+
+	if _, err := tx.Exec(ctx, `CREATE TEMPORARY TABLE urls_batch (LIKE urls INCLUDING DEFAULTS) ON COMMIT DROP`); err != nil {
+		return nil, fmt.Errorf("failed to create batch table: %w", err)
+	}
+
+	columns := []string{"id", "short_key", "original_url", "user_id", "expires_at", "password_hash", "clicks_left"}
+	_, err := tx.CopyFrom(ctx, pgx.Identifier{"urls_batch"}, columns, pgx.CopyFromSlice(len(urls), func(i int) ([]any, error) {
+		url := urls[i]
+		return []any{url.ID, url.ShortKey, url.OriginalURL, url.UserID, url.ExpiresAt, url.PasswordHash, url.ClicksLeft}, nil
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy urls: %w", err)
+	}
+
+	query := `
+	INSERT INTO urls (id, short_key, original_url, user_id, expires_at, password_hash, clicks_left)
+	SELECT id, short_key, original_url, user_id, expires_at, password_hash, clicks_left FROM urls_batch
+	RETURNING ` + urlColumns
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save urls: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		url, err := scanURL(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		insertedURLs[url.ID] = url
+	}
+	if err := rows.Err(); err != nil {
+		if isShortKeyConflict(err) {
+			return nil, storage.ErrShortKeyConflict
+		}
+		return nil, fmt.Errorf("failed to save urls: %w", err)
+	}
+
+	return insertedURLs, nil
+}
+
+// ImportURLs inserts urls with all of their attributes, including the deletion mark, as they are.
+// URLs with IDs that are already stored are skipped, so importing the same URLs again is safe.
+// Returns the number of inserted URLs, or storage.ErrShortKeyConflict and inserts nothing
+// if a short key is taken by a URL with another ID.
+func (s *Storage) ImportURLs(ctx context.Context, urls []*model.URL) (int64, error) {
+	if len(urls) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `CREATE TEMPORARY TABLE urls_import (LIKE urls INCLUDING DEFAULTS) ON COMMIT DROP`); err != nil {
+		return 0, fmt.Errorf("failed to create import table: %w", err)
+	}
+
+	columns := []string{"id", "short_key", "original_url", "user_id", "deleted_at", "expires_at", "password_hash", "clicks_left"}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"urls_import"}, columns, pgx.CopyFromSlice(len(urls), func(i int) ([]any, error) {
+		url := urls[i]
+		return []any{url.ID, url.ShortKey, url.OriginalURL, url.UserID, url.DeletedAt, url.ExpiresAt, url.PasswordHash, url.ClicksLeft}, nil
+	}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy urls: %w", err)
+	}
+
+	query := `
+	INSERT INTO urls (id, short_key, original_url, user_id, deleted_at, expires_at, password_hash, clicks_left)
+	SELECT id, short_key, original_url, user_id, deleted_at, expires_at, password_hash, clicks_left FROM urls_import
+	ON CONFLICT (id) DO NOTHING`
+	tag, err := tx.Exec(ctx, query)
+	if err != nil {
+		if isShortKeyConflict(err) {
+			return 0, storage.ErrShortKeyConflict
+		}
+		return 0, fmt.Errorf("failed to import urls: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return
+	return tag.RowsAffected(), nil
 }
 
-// DeleteURLs marks the specified URLs as deleted.
+// SyncURLs replaces the mutable attributes of stored URLs with the ones of urls with the same IDs:
+// the original URL, the deletion mark, the expiration, the password hash and the clicks left.
+// URLs that are not stored are ignored. Returns the number of changed URLs.
+// The short keys of changed URLs are sent to the url changes channel.
+func (s *Storage) SyncURLs(ctx context.Context, urls []*model.URL) (int64, error) {
+	if len(urls) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `CREATE TEMPORARY TABLE urls_sync (LIKE urls INCLUDING DEFAULTS) ON COMMIT DROP`); err != nil {
+		return 0, fmt.Errorf("failed to create sync table: %w", err)
+	}
+
+	columns := []string{"id", "short_key", "original_url", "user_id", "deleted_at", "expires_at", "password_hash", "clicks_left"}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"urls_sync"}, columns, pgx.CopyFromSlice(len(urls), func(i int) ([]any, error) {
+		url := urls[i]
+		return []any{url.ID, url.ShortKey, url.OriginalURL, url.UserID, url.DeletedAt, url.ExpiresAt, url.PasswordHash, url.ClicksLeft}, nil
+	}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy urls: %w", err)
+	}
+
+	query := `
+	WITH synced AS (
+		UPDATE urls SET
+			original_url = s.original_url,
+			deleted_at = s.deleted_at,
+			expires_at = s.expires_at,
+			password_hash = s.password_hash,
+			clicks_left = s.clicks_left
+		FROM urls_sync s
+		WHERE urls.id = s.id
+			AND (urls.original_url, urls.deleted_at, urls.expires_at, urls.password_hash, urls.clicks_left)
+			IS DISTINCT FROM (s.original_url, s.deleted_at, s.expires_at, s.password_hash, s.clicks_left)
+		RETURNING urls.short_key
+	)
+	SELECT short_key FROM synced, pg_notify($1, short_key)`
+	rows, err := tx.Query(ctx, query, urlChangesChannel)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sync urls: %w", err)
+	}
+
+	shortKeys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, fmt.Errorf("failed to sync urls: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.markChanged(shortKeys...)
+
+	return int64(len(shortKeys)), nil
+}
+
+// insertURL inserts url as a new row.
+// Returns storage.ErrShortKeyConflict if the short key is already taken.
+func insertURL(ctx context.Context, q querier, url *model.URL) (*model.URL, error) {
+	query := `
+	INSERT INTO urls (id, short_key, original_url, user_id, expires_at, password_hash, clicks_left)
+	VALUES (@id, @shortKey, @originalURL, @userID, @expiresAt, @passwordHash, @clicksLeft)
+	RETURNING ` + urlColumns
+	args := pgx.NamedArgs{
+		"id":           url.ID,
+		"shortKey":     url.ShortKey,
+		"originalURL":  url.OriginalURL,
+		"userID":       url.UserID,
+		"expiresAt":    url.ExpiresAt,
+		"passwordHash": url.PasswordHash,
+		"clicksLeft":   url.ClicksLeft,
+	}
+	savedURL, err := scanURL(q.QueryRow(ctx, query, args))
+	if err != nil {
+		if isShortKeyConflict(err) {
+			return nil, storage.ErrShortKeyConflict
+		}
+		return nil, fmt.Errorf("failed to save url: %w", err)
+	}
+
+	return savedURL, nil
+}
+
+// UpdateURL replaces the mutable attributes in fields of an existing URL with the ones of url.
+// Other columns keep their stored values, so that clicks used up concurrently aren't overwritten.
+// The short key, the owner and the creation data can't be changed.
+// Returns storage.ErrNotFound if the URL doesn't exist or is deleted.
+// The short key of the URL is sent to the url changes channel.
+func (s *Storage) UpdateURL(ctx context.Context, url *model.URL, fields storage.URLFields) (*model.URL, error) {
+	query := `
+	WITH updated AS (
+		UPDATE urls SET
+			original_url = CASE WHEN @setOriginalURL THEN @originalURL::text ELSE original_url END,
+			expires_at = CASE WHEN @setExpiresAt THEN @expiresAt::timestamptz ELSE expires_at END,
+			password_hash = CASE WHEN @setPasswordHash THEN @passwordHash::text ELSE password_hash END,
+			clicks_left = CASE WHEN @setClicksLeft THEN @clicksLeft::bigint ELSE clicks_left END
+		WHERE id = @id AND deleted_at IS NULL
+		RETURNING ` + urlColumns + `
+	)
+	SELECT ` + urlColumns + ` FROM updated, pg_notify(@channel, updated.short_key)`
+	args := pgx.NamedArgs{
+		"id":              url.ID,
+		"setOriginalURL":  fields.Has(storage.FieldOriginalURL),
+		"originalURL":     url.OriginalURL,
+		"setExpiresAt":    fields.Has(storage.FieldExpiresAt),
+		"expiresAt":       url.ExpiresAt,
+		"setPasswordHash": fields.Has(storage.FieldPasswordHash),
+		"passwordHash":    url.PasswordHash,
+		"setClicksLeft":   fields.Has(storage.FieldClicksLeft),
+		"clicksLeft":      url.ClicksLeft,
+		"channel":         urlChangesChannel,
+	}
+	savedURL, err := scanURL(s.db.QueryRow(ctx, query, args))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, storage.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to update url: %w", err)
+	}
+	s.markChanged(savedURL.ShortKey)
+
+	return savedURL, nil
+}
+
+// DeleteURLs marks the specified URLs as deleted. URLs that are already deleted
+// keep the moment of their first deletion.
+// The short keys of the deleted URLs are sent to the url changes channel.
 func (s *Storage) DeleteURLs(ctx context.Context, ids []uuid.UUID) error {
-	query := `UPDATE urls SET deleted_at = now() WHERE id = ANY($1)`
-	_, err := s.db.Exec(ctx, query, ids)
+	query := `
+	WITH deleted AS (
+		UPDATE urls SET deleted_at = now() WHERE id = ANY($1) AND deleted_at IS NULL RETURNING short_key
+	)
+	SELECT short_key FROM deleted, pg_notify($2, short_key)`
+	_, err := s.changeURLs(ctx, query, ids, urlChangesChannel)
+
+	return err
+}
+
+// RestoreURLs clears the deletion mark of the specified URLs.
+// The short keys of the restored URLs are sent to the url changes channel.
+func (s *Storage) RestoreURLs(ctx context.Context, ids []uuid.UUID) error {
+	query := `
+	WITH restored AS (
+		UPDATE urls SET deleted_at = NULL WHERE id = ANY($1) AND deleted_at IS NOT NULL RETURNING short_key
+	)
+	SELECT short_key FROM restored, pg_notify($2, short_key)`
+	_, err := s.changeURLs(ctx, query, ids, urlChangesChannel)
+
+	return err
+}
+
+// PurgeDeletedURLs permanently removes at most limit URLs deleted before the given moment.
+// Click events of removed URLs are removed by the foreign key cascade.
+// The short keys of the removed URLs are sent to the url changes channel.
+// Returns the number of removed URLs.
+func (s *Storage) PurgeDeletedURLs(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `
+	WITH purged AS (
+		DELETE FROM urls WHERE id IN (
+			SELECT id FROM urls WHERE deleted_at < $1 ORDER BY deleted_at LIMIT $2
+		) RETURNING short_key
+	)
+	SELECT short_key FROM purged, pg_notify($3, short_key)`
+	shortKeys, err := s.changeURLs(ctx, query, before, limit, urlChangesChannel)
+
+	return int64(len(shortKeys)), err
+}
+
+// DecrementClicksLeft uses up one redirect of a click-limited URL.
+// The check and the decrement are a single statement, so concurrent redirects
+// can't use more redirects than the URL allows.
+// The short key of the URL is sent to the url changes channel.
+// Returns storage.ErrNoClicksLeft if the URL doesn't exist, has no clicks left or is not click-limited.
+func (s *Storage) DecrementClicksLeft(ctx context.Context, id uuid.UUID) error {
+	query := `
+	WITH decremented AS (
+		UPDATE urls SET clicks_left = clicks_left - 1 WHERE id = $1 AND clicks_left > 0 RETURNING short_key
+	)
+	SELECT short_key FROM decremented, pg_notify($2, short_key)`
+	shortKeys, err := s.changeURLs(ctx, query, id, urlChangesChannel)
 	if err != nil {
-		return fmt.Errorf("failed to exec query: %w", err)
+		return err
+	}
+
+	if len(shortKeys) == 0 {
+		return storage.ErrNoClicksLeft
 	}
 
 	return nil
 }
+
+// changeURLs runs a query that changes URLs and selects their short keys,
+// and makes the changed URLs read from the primary. Returns the short keys.
+func (s *Storage) changeURLs(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	shortKeys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+	s.markChanged(shortKeys...)
+
+	return shortKeys, nil
+}
+
+// isShortKeyConflict reports whether err is a unique violation of the short key constraint.
+func isShortKeyConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	return pgErr.Code == uniqueViolationCode && pgErr.ConstraintName == shortKeyConstraint
+}
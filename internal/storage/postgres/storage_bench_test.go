@@ -0,0 +1,69 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/storage"
+	"github.com/dtroode/urlshorter/internal/storage/postgres"
+)
+
+// newBenchURLs returns size new URLs with unique short keys and original URLs.
+func newBenchURLs(prefix string, size int) []*model.URL {
+	urls := make([]*model.URL, size)
+	for i := range urls {
+		urls[i] = &model.URL{
+			ID:          uuid.New(),
+			ShortKey:    fmt.Sprintf("%s%d", prefix, i),
+			OriginalURL: fmt.Sprintf("https://bench.com/%s/%d", prefix, i),
+			UserID:      uuid.New(),
+		}
+	}
+
+	return urls
+}
+
+func BenchmarkStorage_SetURLs(b *testing.B) {
+	s, err := postgres.NewStorage(dsn)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	run := 0
+
+	for _, size := range []int{10, 100, 1_000, 5_000} {
+		b.Run(fmt.Sprintf("new_%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				run++
+				urls := newBenchURLs(fmt.Sprintf("bn%d-", run), size)
+				if _, err := s.SetURLs(ctx, urls, storage.DedupeGlobal); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		// Every URL of the batch is already stored and is reported instead.
+		b.Run(fmt.Sprintf("existing_%d", size), func(b *testing.B) {
+			run++
+			urls := newBenchURLs(fmt.Sprintf("be%d-", run), size)
+			if _, err := s.SetURLs(ctx, urls, storage.DedupeGlobal); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := s.SetURLs(ctx, urls, storage.DedupeGlobal); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
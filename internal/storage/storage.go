@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -13,9 +14,16 @@ type Storage interface {
 	Ping(ctx context.Context) error
 	GetURL(ctx context.Context, shortKey string) (*model.URL, error)
 	GetURLs(ctx context.Context, shortKeys []string) ([]*model.URL, error)
-	SetURL(ctx context.Context, url *model.URL) (*model.URL, error)
-	SetURLs(ctx context.Context, urls []*model.URL) (savedURLs []*model.URL, err error)
+	SetURL(ctx context.Context, url *model.URL, scope DedupeScope) (*model.URL, error)
+	SetURLs(ctx context.Context, urls []*model.URL, scope DedupeScope) (savedURLs []*model.URL, err error)
 	GetURLsByUserID(ctx context.Context, userID uuid.UUID) ([]*model.URL, error)
+	GetDeletedURLsByUserID(ctx context.Context, userID uuid.UUID) ([]*model.URL, error)
+	UpdateURL(ctx context.Context, url *model.URL, fields URLFields) (*model.URL, error)
 	DeleteURLs(ctx context.Context, ids []uuid.UUID) error
+	RestoreURLs(ctx context.Context, ids []uuid.UUID) error
+	PurgeDeletedURLs(ctx context.Context, before time.Time, limit int) (int64, error)
+	DecrementClicksLeft(ctx context.Context, id uuid.UUID) error
+	SaveClick(ctx context.Context, click *model.Click) error
+	GetClickStats(ctx context.Context, urlID uuid.UUID, bucket time.Duration) (*model.ClickStats, error)
 	Close() error
 }
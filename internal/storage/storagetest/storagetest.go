@@ -0,0 +1,468 @@
+// Package storagetest provides a conformance test suite for storage.Storage implementations.
+//
+// Every storage backend must pass the suite, so that handlers and services
+// behave the same regardless of the configured backend.
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/storage"
+)
+
+// Run runs the conformance test suite against the storage returned by newStorage.
+// newStorage is called once per test and may return the same storage every time:
+// tests only use URLs with unique short keys and original URLs.
+func Run(t *testing.T, newStorage func(t *testing.T) storage.Storage) {
+	tests := map[string]func(t *testing.T, s storage.Storage){
+		"set and get url":                      testSetAndGetURL,
+		"short key conflict":                   testShortKeyConflict,
+		"conflict in global scope":             testConflictGlobalScope,
+		"conflict in user scope":               testConflictUserScope,
+		"no conflict in none scope":            testNoConflictNoneScope,
+		"no conflict for urls not reused":      testNoConflictNotReused,
+		"no conflict for inactive urls":        testNoConflictInactive,
+		"conflict after original url update":   testConflictAfterUpdate,
+		"batch keeps order and dedupes":        testBatchDedupe,
+		"batch short key conflict saves none":  testBatchShortKeyConflict,
+		"delete and restore urls":              testDeleteAndRestore,
+		"delete keeps first deletion time":     testDeleteKeepsFirstDeletion,
+		"update deleted url":                   testUpdateDeleted,
+		"update keeps concurrent decrement":    testUpdateKeepsConcurrentDecrement,
+		"decrement clicks left":                testDecrementClicksLeft,
+		"purge deleted urls":                   testPurgeDeleted,
+		"get urls by user id excludes deleted": testGetURLsByUserIDExcludesDeleted,
+		"click stats":                          testClickStats,
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, newStorage(t))
+		})
+	}
+}
+
+// newURL returns a reusable URL with a unique short key and original URL.
+func newURL(userID uuid.UUID) *model.URL {
+	id := uuid.New()
+
+	return &model.URL{
+		ID:          id,
+		ShortKey:    id.String()[:8] + id.String()[24:],
+		OriginalURL: "https://" + id.String() + ".example.com",
+		UserID:      userID,
+	}
+}
+
+// withOriginalURL returns a new URL of userID with the same original URL as url.
+func withOriginalURL(url *model.URL, userID uuid.UUID) *model.URL {
+	other := newURL(userID)
+	other.OriginalURL = url.OriginalURL
+
+	return other
+}
+
+func testSetAndGetURL(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	url := newURL(uuid.New())
+
+	saved, err := s.SetURL(ctx, url, storage.DedupeGlobal)
+	require.NoError(t, err)
+	assert.Equal(t, url.ID, saved.ID)
+
+	got, err := s.GetURL(ctx, url.ShortKey)
+	require.NoError(t, err)
+	assert.Equal(t, url.ID, got.ID)
+	assert.Equal(t, url.OriginalURL, got.OriginalURL)
+	assert.Equal(t, url.UserID, got.UserID)
+	assert.Nil(t, got.DeletedAt)
+
+	_, err = s.GetURL(ctx, "missing-"+url.ShortKey)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func testShortKeyConflict(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	url := newURL(uuid.New())
+
+	_, err := s.SetURL(ctx, url, storage.DedupeNone)
+	require.NoError(t, err)
+
+	other := newURL(uuid.New())
+	other.ShortKey = url.ShortKey
+
+	_, err = s.SetURL(ctx, other, storage.DedupeNone)
+	require.ErrorIs(t, err, storage.ErrShortKeyConflict)
+}
+
+func testConflictGlobalScope(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	url := newURL(uuid.New())
+
+	_, err := s.SetURL(ctx, url, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	saved, err := s.SetURL(ctx, withOriginalURL(url, uuid.New()), storage.DedupeGlobal)
+	require.ErrorIs(t, err, storage.ErrConflict)
+	require.NotNil(t, saved)
+	assert.Equal(t, url.ID, saved.ID)
+	assert.Equal(t, url.ShortKey, saved.ShortKey)
+}
+
+func testConflictUserScope(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	userID := uuid.New()
+	url := newURL(userID)
+
+	_, err := s.SetURL(ctx, url, storage.DedupeUser)
+	require.NoError(t, err)
+
+	other := withOriginalURL(url, uuid.New())
+	saved, err := s.SetURL(ctx, other, storage.DedupeUser)
+	require.NoError(t, err)
+	assert.Equal(t, other.ID, saved.ID)
+
+	saved, err = s.SetURL(ctx, withOriginalURL(url, userID), storage.DedupeUser)
+	require.ErrorIs(t, err, storage.ErrConflict)
+	require.NotNil(t, saved)
+	assert.Equal(t, url.ID, saved.ID)
+}
+
+func testNoConflictNoneScope(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	userID := uuid.New()
+	url := newURL(userID)
+
+	_, err := s.SetURL(ctx, url, storage.DedupeNone)
+	require.NoError(t, err)
+
+	other := withOriginalURL(url, userID)
+	saved, err := s.SetURL(ctx, other, storage.DedupeNone)
+	require.NoError(t, err)
+	assert.Equal(t, other.ID, saved.ID)
+}
+
+func testNoConflictNotReused(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	clicksLeft := int64(1)
+
+	protected := newURL(uuid.New())
+	protected.PasswordHash = "hash"
+	_, err := s.SetURL(ctx, protected, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	// A protected URL is not returned for a public one.
+	public := withOriginalURL(protected, uuid.New())
+	saved, err := s.SetURL(ctx, public, storage.DedupeGlobal)
+	require.NoError(t, err)
+	assert.Equal(t, public.ID, saved.ID)
+
+	// A public URL is not returned for a click-limited one.
+	limited := withOriginalURL(protected, uuid.New())
+	limited.ClicksLeft = &clicksLeft
+	saved, err = s.SetURL(ctx, limited, storage.DedupeGlobal)
+	require.NoError(t, err)
+	assert.Equal(t, limited.ID, saved.ID)
+}
+
+func testNoConflictInactive(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	expiredAt := time.Now().Add(-time.Minute)
+	expired := newURL(uuid.New())
+	expired.ExpiresAt = &expiredAt
+	_, err := s.SetURL(ctx, expired, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	other := withOriginalURL(expired, uuid.New())
+	saved, err := s.SetURL(ctx, other, storage.DedupeGlobal)
+	require.NoError(t, err)
+	assert.Equal(t, other.ID, saved.ID)
+
+	require.NoError(t, s.DeleteURLs(ctx, []uuid.UUID{other.ID}))
+
+	another := withOriginalURL(expired, uuid.New())
+	saved, err = s.SetURL(ctx, another, storage.DedupeGlobal)
+	require.NoError(t, err)
+	assert.Equal(t, another.ID, saved.ID)
+}
+
+func testConflictAfterUpdate(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	url := newURL(uuid.New())
+	_, err := s.SetURL(ctx, url, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	oldOriginalURL := url.OriginalURL
+	changed := *url
+	changed.OriginalURL = newURL(uuid.New()).OriginalURL
+	_, err = s.UpdateURL(ctx, &changed, storage.FieldOriginalURL)
+	require.NoError(t, err)
+
+	// The URL is reused for its new original URL only.
+	saved, err := s.SetURL(ctx, withOriginalURL(&changed, uuid.New()), storage.DedupeGlobal)
+	require.ErrorIs(t, err, storage.ErrConflict)
+	assert.Equal(t, url.ID, saved.ID)
+
+	other := newURL(uuid.New())
+	other.OriginalURL = oldOriginalURL
+	saved, err = s.SetURL(ctx, other, storage.DedupeGlobal)
+	require.NoError(t, err)
+	assert.Equal(t, other.ID, saved.ID)
+}
+
+func testBatchDedupe(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	existing := newURL(uuid.New())
+	_, err := s.SetURL(ctx, existing, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	first := newURL(uuid.New())
+	urls := []*model.URL{
+		first,
+		withOriginalURL(existing, uuid.New()),
+		withOriginalURL(first, uuid.New()),
+		newURL(uuid.New()),
+	}
+
+	saved, err := s.SetURLs(ctx, urls, storage.DedupeGlobal)
+	require.NoError(t, err)
+	require.Len(t, saved, len(urls))
+	assert.Equal(t, first.ID, saved[0].ID)
+	assert.Equal(t, existing.ID, saved[1].ID)
+	assert.Equal(t, first.ID, saved[2].ID)
+	assert.Equal(t, urls[3].ID, saved[3].ID)
+
+	_, err = s.GetURL(ctx, urls[2].ShortKey)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func testBatchShortKeyConflict(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	existing := newURL(uuid.New())
+	_, err := s.SetURL(ctx, existing, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	valid := newURL(uuid.New())
+	conflicting := newURL(uuid.New())
+	conflicting.ShortKey = existing.ShortKey
+
+	_, err = s.SetURLs(ctx, []*model.URL{valid, conflicting}, storage.DedupeGlobal)
+	require.ErrorIs(t, err, storage.ErrShortKeyConflict)
+
+	_, err = s.GetURL(ctx, valid.ShortKey)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func testDeleteAndRestore(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	userID := uuid.New()
+	url := newURL(userID)
+	_, err := s.SetURL(ctx, url, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeleteURLs(ctx, []uuid.UUID{url.ID}))
+
+	got, err := s.GetURL(ctx, url.ShortKey)
+	require.NoError(t, err)
+	assert.NotNil(t, got.DeletedAt)
+
+	deleted, err := s.GetDeletedURLsByUserID(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, url.ID, deleted[0].ID)
+
+	require.NoError(t, s.RestoreURLs(ctx, []uuid.UUID{url.ID}))
+
+	got, err = s.GetURL(ctx, url.ShortKey)
+	require.NoError(t, err)
+	assert.Nil(t, got.DeletedAt)
+
+	deleted, err = s.GetDeletedURLsByUserID(ctx, userID)
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+}
+
+func testDeleteKeepsFirstDeletion(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	url := newURL(uuid.New())
+	_, err := s.SetURL(ctx, url, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeleteURLs(ctx, []uuid.UUID{url.ID}))
+	deleted, err := s.GetURL(ctx, url.ShortKey)
+	require.NoError(t, err)
+	require.NotNil(t, deleted.DeletedAt)
+
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, s.DeleteURLs(ctx, []uuid.UUID{url.ID}))
+
+	got, err := s.GetURL(ctx, url.ShortKey)
+	require.NoError(t, err)
+	require.NotNil(t, got.DeletedAt)
+	assert.True(t, deleted.DeletedAt.Equal(*got.DeletedAt), "deleted at changed from %s to %s", deleted.DeletedAt, got.DeletedAt)
+}
+
+func testUpdateDeleted(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	url := newURL(uuid.New())
+	_, err := s.SetURL(ctx, url, storage.DedupeGlobal)
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteURLs(ctx, []uuid.UUID{url.ID}))
+
+	_, err = s.UpdateURL(ctx, url, storage.FieldOriginalURL)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func testUpdateKeepsConcurrentDecrement(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	clicksLeft := int64(2)
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Microsecond)
+	url := newURL(uuid.New())
+	url.ClicksLeft = &clicksLeft
+	url.ExpiresAt = &expiresAt
+	_, err := s.SetURL(ctx, url, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	read, err := s.GetURL(ctx, url.ShortKey)
+	require.NoError(t, err)
+
+	// A redirect uses up a click between the read and the update.
+	require.NoError(t, s.DecrementClicksLeft(ctx, url.ID))
+
+	changed := *read
+	changed.OriginalURL = newURL(uuid.New()).OriginalURL
+	updated, err := s.UpdateURL(ctx, &changed, storage.FieldOriginalURL)
+	require.NoError(t, err)
+	assert.Equal(t, changed.OriginalURL, updated.OriginalURL)
+	require.NotNil(t, updated.ClicksLeft)
+	assert.Equal(t, int64(1), *updated.ClicksLeft)
+
+	got, err := s.GetURL(ctx, url.ShortKey)
+	require.NoError(t, err)
+	require.NotNil(t, got.ClicksLeft)
+	assert.Equal(t, int64(1), *got.ClicksLeft)
+	require.NotNil(t, got.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*got.ExpiresAt))
+
+	// The limit and the expiration can be removed.
+	cleared := *got
+	cleared.ClicksLeft = nil
+	cleared.ExpiresAt = nil
+	updated, err = s.UpdateURL(ctx, &cleared, storage.FieldClicksLeft|storage.FieldExpiresAt)
+	require.NoError(t, err)
+	assert.Nil(t, updated.ClicksLeft)
+	assert.Nil(t, updated.ExpiresAt)
+	assert.Equal(t, changed.OriginalURL, updated.OriginalURL)
+}
+
+func testDecrementClicksLeft(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	clicksLeft := int64(2)
+	url := newURL(uuid.New())
+	url.ClicksLeft = &clicksLeft
+	_, err := s.SetURL(ctx, url, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	require.NoError(t, s.DecrementClicksLeft(ctx, url.ID))
+	require.NoError(t, s.DecrementClicksLeft(ctx, url.ID))
+	require.ErrorIs(t, s.DecrementClicksLeft(ctx, url.ID), storage.ErrNoClicksLeft)
+
+	unlimited := newURL(uuid.New())
+	_, err = s.SetURL(ctx, unlimited, storage.DedupeGlobal)
+	require.NoError(t, err)
+	require.ErrorIs(t, s.DecrementClicksLeft(ctx, unlimited.ID), storage.ErrNoClicksLeft)
+}
+
+func testPurgeDeleted(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	url := newURL(uuid.New())
+	_, err := s.SetURL(ctx, url, storage.DedupeGlobal)
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteURLs(ctx, []uuid.UUID{url.ID}))
+
+	_, err = s.PurgeDeletedURLs(ctx, time.Now().Add(-time.Hour), 100)
+	require.NoError(t, err)
+	_, err = s.GetURL(ctx, url.ShortKey)
+	require.NoError(t, err)
+
+	for {
+		removed, err := s.PurgeDeletedURLs(ctx, time.Now().Add(time.Hour), 100)
+		require.NoError(t, err)
+		if removed < 100 {
+			break
+		}
+	}
+	_, err = s.GetURL(ctx, url.ShortKey)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	// The purged original URL can be saved again.
+	other := withOriginalURL(url, uuid.New())
+	saved, err := s.SetURL(ctx, other, storage.DedupeGlobal)
+	require.NoError(t, err)
+	assert.Equal(t, other.ID, saved.ID)
+}
+
+func testGetURLsByUserIDExcludesDeleted(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	userID := uuid.New()
+	alive := newURL(userID)
+	deleted := newURL(userID)
+	_, err := s.SetURLs(ctx, []*model.URL{alive, deleted}, storage.DedupeGlobal)
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteURLs(ctx, []uuid.UUID{deleted.ID}))
+
+	urls, err := s.GetURLsByUserID(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, alive.ID, urls[0].ID)
+}
+
+func testClickStats(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	url := newURL(uuid.New())
+	_, err := s.SetURL(ctx, url, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	clickedAt := time.Date(2025, 10, 1, 12, 30, 0, 0, time.UTC)
+	clicks := []*model.Click{
+		model.NewClick(url.ID, clickedAt, "https://ya.ru/", "curl/8.0", "192.168.1.0"),
+		model.NewClick(url.ID, clickedAt.Add(10*time.Minute), "https://ya.ru/", "curl/8.0", "192.168.1.0"),
+		model.NewClick(url.ID, clickedAt.Add(2*time.Hour), "", "Mozilla/5.0", "10.0.0.0"),
+	}
+	for _, click := range clicks {
+		require.NoError(t, s.SaveClick(ctx, click))
+	}
+
+	stats, err := s.GetClickStats(ctx, url.ID, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, &model.ClickStats{
+		Total:          3,
+		UniqueVisitors: 2,
+		Buckets: map[time.Time]int{
+			time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC): 2,
+			time.Date(2025, 10, 1, 14, 0, 0, 0, time.UTC): 1,
+		},
+		Referrers:  map[string]int{"https://ya.ru/": 2, "": 1},
+		UserAgents: map[string]int{"curl/8.0": 2, "Mozilla/5.0": 1},
+	}, stats)
+
+	stats, err = s.GetClickStats(ctx, url.ID, 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, map[time.Time]int{time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC): 3}, stats.Buckets)
+
+	stats, err = s.GetClickStats(ctx, uuid.New(), time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, &model.ClickStats{
+		Buckets:    map[time.Time]int{},
+		Referrers:  map[string]int{},
+		UserAgents: map[string]int{},
+	}, stats)
+}
@@ -0,0 +1,236 @@
+package tiered
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/dtroode/urlshorter/internal/model"
+)
+
+// checkpointFileSuffix is appended to the journal file name to get the name of the file
+// holding the offset of the first record that hasn't been flushed.
+const checkpointFileSuffix = ".flushed"
+
+// journalRecord is a URL waiting in the journal to be flushed to the cold tier.
+type journalRecord struct {
+	url *model.URL
+	// start is the journal offset of the record.
+	start int64
+	// end is the journal offset right after the record.
+	end int64
+}
+
+// journal is an append-only file of URLs accepted by the hot tier, one JSON document per line.
+// Records before the checkpoint have been flushed to the cold tier. The journal is truncated
+// once every record has been flushed, so it only grows while the cold tier falls behind.
+type journal struct {
+	filename string
+	file     *os.File
+	size     int64
+}
+
+// openJournal opens the journal file, creating it if it doesn't exist,
+// and returns the records that haven't been flushed yet in order.
+// A broken last record, left by a crash in the middle of a write, is truncated.
+func openJournal(filename string) (*journal, []journalRecord, error) {
+	checkpoint, err := readCheckpoint(filename + checkpointFileSuffix)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	records, size, err := readJournal(filename, checkpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open journal for append: %w", err)
+	}
+
+	return &journal{filename: filename, file: file, size: size}, records, nil
+}
+
+// readCheckpoint reads the offset of the first record that hasn't been flushed.
+// A missing checkpoint file means that no record has been flushed.
+func readCheckpoint(filename string) (int64, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read journal checkpoint: %w", err)
+	}
+
+	checkpoint, err := strconv.ParseInt(string(bytes.TrimSpace(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse journal checkpoint: %w", err)
+	}
+
+	return checkpoint, nil
+}
+
+// readJournal reads the records of the journal starting at checkpoint.
+// Returns the records and the size of the journal after a torn tail is truncated.
+// A checkpoint past the end of the journal is ignored and every record is returned,
+// as flushing records again is safe, while skipping them would lose them.
+func readJournal(filename string, checkpoint int64) ([]journalRecord, int64, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("failed to open journal for read: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to stat journal: %w", err)
+	}
+	if checkpoint > info.Size() {
+		checkpoint = 0
+	}
+	if _, err := file.Seek(checkpoint, io.SeekStart); err != nil {
+		return nil, 0, fmt.Errorf("failed to seek journal: %w", err)
+	}
+
+	reader := bufio.NewReader(file)
+	offset := checkpoint
+	records := make([]journalRecord, 0)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, 0, fmt.Errorf("failed to read journal: %w", err)
+		}
+		if len(line) == 0 {
+			return records, offset, nil
+		}
+
+		url := &model.URL{}
+		complete := line[len(line)-1] == '\n'
+		var decodeErr error
+		if complete {
+			decodeErr = json.Unmarshal(line, url)
+		}
+
+		if !complete || decodeErr != nil {
+			if _, peekErr := reader.Peek(1); !errors.Is(peekErr, io.EOF) {
+				return nil, 0, fmt.Errorf("broken record at offset %d of %s: %w", offset, filename, decodeErr)
+			}
+			if err := os.Truncate(filename, offset); err != nil {
+				return nil, 0, fmt.Errorf("failed to truncate torn tail: %w", err)
+			}
+			return records, offset, nil
+		}
+
+		records = append(records, journalRecord{url: url, start: offset, end: offset + int64(len(line))})
+		offset += int64(len(line))
+	}
+}
+
+// append writes urls to the journal in a single write and syncs it to disk.
+// Returns the records of urls.
+func (j *journal) append(urls []*model.URL) ([]journalRecord, error) {
+	var buf []byte
+	records := make([]journalRecord, len(urls))
+	for i, url := range urls {
+		payload, err := json.Marshal(url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal url: %w", err)
+		}
+		start := j.size + int64(len(buf))
+		buf = append(buf, payload...)
+		buf = append(buf, '\n')
+		records[i] = journalRecord{url: url, start: start, end: j.size + int64(len(buf))}
+	}
+
+	if _, err := j.file.Write(buf); err != nil {
+		return nil, fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to sync journal: %w", err)
+	}
+	j.size += int64(len(buf))
+
+	return records, nil
+}
+
+// checkpoint records that every record before offset has been flushed.
+// The checkpoint file is replaced atomically, so a crash leaves either the old or the new one.
+func (j *journal) checkpoint(offset int64) error {
+	filename := j.filename + checkpointFileSuffix
+	tempFilename := filename + ".tmp"
+
+	if err := writeSynced(tempFilename, []byte(strconv.FormatInt(offset, 10))); err != nil {
+		os.Remove(tempFilename)
+		return fmt.Errorf("failed to write journal checkpoint: %w", err)
+	}
+	if err := os.Rename(tempFilename, filename); err != nil {
+		os.Remove(tempFilename)
+		return fmt.Errorf("failed to replace journal checkpoint: %w", err)
+	}
+
+	return syncDir(filepath.Dir(filename))
+}
+
+// reset truncates the journal once every record has been flushed.
+// The checkpoint is reset first: a crash in between makes flushed records flushed again,
+// which is safe, while the opposite order would make new records skipped.
+func (j *journal) reset() error {
+	if err := j.checkpoint(0); err != nil {
+		return err
+	}
+	if err := j.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate journal: %w", err)
+	}
+	j.size = 0
+
+	return nil
+}
+
+// close closes the journal file.
+func (j *journal) close() error {
+	return j.file.Close()
+}
+
+// writeSynced writes data to the file and syncs it to disk.
+func writeSynced(filename string, data []byte) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// syncDir syncs the directory to disk, so that renames inside it survive a crash.
+func syncDir(dirname string) error {
+	dir, err := os.Open(dirname)
+	if err != nil {
+		return fmt.Errorf("failed to open directory: %w", err)
+	}
+	defer dir.Close()
+
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync directory: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package tiered
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/model"
+)
+
+func newJournalURL(shortKey string) *model.URL {
+	return &model.URL{ID: uuid.New(), ShortKey: shortKey, OriginalURL: "https://ya.ru/" + shortKey, UserID: uuid.New()}
+}
+
+// recordKeys returns the short keys of the URLs of records.
+func recordKeys(records []journalRecord) []string {
+	shortKeys := make([]string, len(records))
+	for i, record := range records {
+		shortKeys[i] = record.url.ShortKey
+	}
+	return shortKeys
+}
+
+func TestJournal(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "journal")
+
+	j, records, err := openJournal(filename)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	first, err := j.append([]*model.URL{newJournalURL("a"), newJournalURL("b")})
+	require.NoError(t, err)
+	_, err = j.append([]*model.URL{newJournalURL("c")})
+	require.NoError(t, err)
+
+	require.NoError(t, j.checkpoint(first[1].end))
+	require.NoError(t, j.close())
+
+	j, records, err = openJournal(filename)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, recordKeys(records))
+
+	// New records are appended after the unflushed ones.
+	appended, err := j.append([]*model.URL{newJournalURL("d")})
+	require.NoError(t, err)
+	assert.Greater(t, appended[0].end, records[0].end)
+
+	require.NoError(t, j.reset())
+	_, err = j.append([]*model.URL{newJournalURL("e")})
+	require.NoError(t, err)
+	require.NoError(t, j.close())
+
+	_, records, err = openJournal(filename)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e"}, recordKeys(records))
+}
+
+func TestOpenJournal_Recovery(t *testing.T) {
+	tests := map[string]struct {
+		content      func(t *testing.T, filename string, records []journalRecord)
+		expectedKeys []string
+		expectedErr  bool
+	}{
+		"torn tail is truncated": {
+			content: func(t *testing.T, filename string, _ []journalRecord) {
+				appendToFile(t, filename, `{"id":"`)
+			},
+			expectedKeys: []string{"a", "b"},
+		},
+		"broken record before others": {
+			content: func(t *testing.T, filename string, _ []journalRecord) {
+				appendToFile(t, filename, "broken\n"+`{"short_key":"c"}`+"\n")
+			},
+			expectedErr: true,
+		},
+		"checkpoint past the end replays everything": {
+			content: func(t *testing.T, filename string, _ []journalRecord) {
+				require.NoError(t, os.WriteFile(filename+checkpointFileSuffix, []byte("100000"), 0600))
+			},
+			expectedKeys: []string{"a", "b"},
+		},
+		"checkpoint in the middle": {
+			content: func(t *testing.T, filename string, records []journalRecord) {
+				require.NoError(t, os.WriteFile(filename+checkpointFileSuffix, []byte("0"), 0600))
+				j := &journal{filename: filename}
+				require.NoError(t, j.checkpoint(records[0].end))
+			},
+			expectedKeys: []string{"b"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "journal")
+			j, _, err := openJournal(filename)
+			require.NoError(t, err)
+			records, err := j.append([]*model.URL{newJournalURL("a"), newJournalURL("b")})
+			require.NoError(t, err)
+			require.NoError(t, j.close())
+
+			tt.content(t, filename, records)
+
+			j, records, err = openJournal(filename)
+			if tt.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer j.close()
+			assert.Equal(t, tt.expectedKeys, recordKeys(records))
+
+			// Appends continue after the last complete record.
+			info, err := os.Stat(filename)
+			require.NoError(t, err)
+			assert.Equal(t, info.Size(), j.size)
+		})
+	}
+}
+
+func appendToFile(t *testing.T, filename, content string) {
+	t.Helper()
+
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(t, err)
+	defer file.Close()
+
+	_, err = file.WriteString(content)
+	require.NoError(t, err)
+}
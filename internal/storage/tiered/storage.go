@@ -0,0 +1,827 @@
+// Package tiered provides a storage that accepts new URLs into an in-memory hot tier
+// and writes them behind to a cold tier, such as Postgres.
+package tiered
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dtroode/urlshorter/internal/logger"
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/service/workerpool"
+	"github.com/dtroode/urlshorter/internal/storage"
+	"github.com/dtroode/urlshorter/internal/storage/inmemory"
+)
+
+const (
+	// defaultBatchSize is the maximum number of URLs flushed to the cold tier at once if none is set.
+	defaultBatchSize = 500
+	// defaultFlushInterval is the time between flushes if none is set.
+	defaultFlushInterval = time.Second
+	// flushTimeout is the time a batch may take to be flushed to the cold tier.
+	flushTimeout = 30 * time.Second
+)
+
+// Cold is the storage URLs are written behind to.
+type Cold interface {
+	storage.Storage
+
+	// ImportURLs inserts urls as they are, skipping URLs with IDs that are already stored.
+	// Returns the number of inserted URLs or storage.ErrShortKeyConflict if a short key is taken.
+	ImportURLs(ctx context.Context, urls []*model.URL) (int64, error)
+	// FindActiveURLs returns the active URLs that would be reused instead of saving urls within scope,
+	// at the indexes of urls. Indexes of urls without such a URL are nil.
+	// URLs must be looked up in the storage of record, not in a replica that may lag behind it.
+	FindActiveURLs(ctx context.Context, urls []*model.URL, scope storage.DedupeScope) ([]*model.URL, error)
+	// TakenShortKeys returns the short keys among shortKeys that are taken by stored URLs, including deleted ones.
+	// Short keys must be looked up in the storage of record, not in a replica that may lag behind it.
+	TakenShortKeys(ctx context.Context, shortKeys []string) ([]string, error)
+}
+
+// Option configures Storage.
+type Option func(*options)
+
+type options struct {
+	batchSize     int
+	flushInterval time.Duration
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithFlush sets the maximum number of URLs flushed to the cold tier at once and the time between flushes.
+// A flush starts early once a batch is full. Non-positive values keep the defaults of 500 URLs and 1 second.
+func WithFlush(batchSize int, interval time.Duration) Option {
+	return func(o *options) {
+		if batchSize > 0 {
+			o.batchSize = batchSize
+		}
+		if interval > 0 {
+			o.flushInterval = interval
+		}
+	}
+}
+
+// Storage is a storage.Storage for high write rates. New URLs are saved to an in-memory hot tier
+// and a journal file and acknowledged without waiting for the cold tier to write them.
+// Before a URL is acknowledged, the cold tier is checked for its short key and for URLs to reuse.
+// Pending URLs are flushed to the cold tier in batches by the worker pool, and the journal
+// keeps the ones that haven't been flushed, so they are flushed after a restart or a crash.
+//
+// The cold tier is the storage of record: the hot tier only holds pending URLs,
+// and everything except inserts and lookups by short key goes to the cold tier,
+// after flushing the pending URLs the operation depends on.
+// Original URLs are deduplicated against both pending URLs and URLs in the cold tier.
+//
+// Redirects of pending URLs don't wait for a flush: their clicks are held in memory
+// and their remaining clicks are counted down in the hot tier, and both reach the cold tier
+// right after the URLs are flushed. They are lost if the process crashes before that.
+//
+// If another writer of the cold tier takes the short key of an acknowledged URL before it's flushed,
+// the URL is kept in the hot tier and the journal and reported by ConflictedURLs for repair.
+// The journal is never checkpointed past such a URL, and it's flushed once the short key is freed.
+type Storage struct {
+	hot     *inmemory.Storage
+	hotDir  string
+	cold    Cold
+	journal *journal
+	pool    *workerpool.Pool
+	logger  *logger.Logger
+
+	batchSize     int
+	flushInterval time.Duration
+
+	// mu guards the hot tier writes, the journal and the pending URLs.
+	mu      sync.Mutex
+	pending []journalRecord
+	// pendingKeys and pendingIDs index pending URLs by short key and ID.
+	pendingKeys map[string]struct{}
+	pendingIDs  map[uuid.UUID]struct{}
+	// pendingClicks holds click events of pending URLs until the URLs are flushed.
+	pendingClicks map[uuid.UUID][]*model.Click
+	// conflicted holds journaled URLs whose short keys were taken in the cold tier after they were acknowledged.
+	// They stay indexed as pending, so they are served from the hot tier.
+	conflicted []journalRecord
+
+	// flushMu serializes flushes.
+	flushMu sync.Mutex
+	// batchFull signals Run that a batch is ready to be flushed.
+	batchFull chan struct{}
+}
+
+var _ storage.Storage = (*Storage)(nil)
+
+// NewStorage creates a new Storage instance.
+// URLs left in the journal by a previous run are loaded into the hot tier and flushed by Run.
+//
+// Parameters:
+//   - cold: The storage URLs are written behind to
+//   - journalFilename: The file pending URLs are journaled to
+//   - pool: The worker pool batches are flushed by
+//   - logger: The logger flush failures are reported to
+//   - opts: Options of the storage
+//
+// Returns a pointer to the newly created Storage instance or an error if the journal can't be loaded.
+func NewStorage(cold Cold, journalFilename string, pool *workerpool.Pool, logger *logger.Logger, opts ...Option) (*Storage, error) {
+	o := newOptions(opts)
+
+	journal, records, err := openJournal(journalFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	// The journal makes pending URLs durable, so the hot tier starts empty on every run.
+	hotDir, err := os.MkdirTemp("", "urlshorter-hot-")
+	if err != nil {
+		journal.close()
+		return nil, fmt.Errorf("failed to create hot tier directory: %w", err)
+	}
+	hot, err := inmemory.NewStorage(filepath.Join(hotDir, "urls"), inmemory.WithSync(inmemory.SyncNever, 0))
+	if err != nil {
+		journal.close()
+		os.RemoveAll(hotDir)
+		return nil, fmt.Errorf("failed to create hot tier: %w", err)
+	}
+
+	s := &Storage{
+		hot:           hot,
+		hotDir:        hotDir,
+		cold:          cold,
+		journal:       journal,
+		pool:          pool,
+		logger:        logger,
+		batchSize:     o.batchSize,
+		flushInterval: o.flushInterval,
+		pendingKeys:   make(map[string]struct{}),
+		pendingIDs:    make(map[uuid.UUID]struct{}),
+		pendingClicks: make(map[uuid.UUID][]*model.Click),
+		batchFull:     make(chan struct{}, 1),
+	}
+
+	if len(records) > 0 {
+		urls := make([]*model.URL, len(records))
+		for i, record := range records {
+			urls[i] = record.url
+		}
+		if _, err := hot.SetURLs(context.Background(), urls, storage.DedupeNone); err != nil {
+			s.closeLocal()
+			return nil, fmt.Errorf("failed to load journal into hot tier: %w", err)
+		}
+		s.addPending(records)
+	}
+
+	return s, nil
+}
+
+// Run flushes pending URLs to the cold tier every flush interval and whenever a batch is full,
+// until ctx is done. URLs left by a previous run are flushed immediately.
+func (s *Storage) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.flush(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("failed to flush urls to cold tier", "error", err, "pending", s.pendingCount())
+		}
+		if err := s.retryConflicted(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("failed to flush conflicted urls to cold tier", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.batchFull:
+		}
+	}
+}
+
+// Close flushes pending URLs and closes both tiers.
+// URLs that can't be flushed stay in the journal and are flushed on the next run.
+func (s *Storage) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	flushErr := s.flush(ctx)
+	if flushErr != nil {
+		flushErr = fmt.Errorf("failed to flush urls to cold tier: %w", flushErr)
+	}
+
+	return errors.Join(flushErr, s.closeLocal(), s.cold.Close())
+}
+
+// closeLocal closes the journal and the hot tier and removes the files of the hot tier.
+func (s *Storage) closeLocal() error {
+	return errors.Join(s.journal.close(), s.hot.Close(), os.RemoveAll(s.hotDir))
+}
+
+// pendingCount returns the number of URLs that haven't been flushed.
+func (s *Storage) pendingCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.pending)
+}
+
+// ConflictedURLs returns the acknowledged URLs that can't be flushed, because their short keys
+// were taken in the cold tier by another writer. They are served from the hot tier and kept in the journal
+// until the short keys are freed in the cold tier, e.g. by an operator, and need to be repaired.
+func (s *Storage) ConflictedURLs(ctx context.Context) []*model.URL {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.hotURLs(ctx, s.conflicted)
+}
+
+// addPending adds journaled records to the pending URLs. Callers must hold s.mu.
+func (s *Storage) addPending(records []journalRecord) {
+	s.pending = append(s.pending, records...)
+	for _, record := range records {
+		s.pendingKeys[record.url.ShortKey] = struct{}{}
+		s.pendingIDs[record.url.ID] = struct{}{}
+	}
+
+	if len(s.pending) >= s.batchSize {
+		select {
+		case s.batchFull <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// isPending reports whether the URL with the short key is pending or conflicted. Callers must hold s.mu.
+func (s *Storage) isPending(shortKey string) bool {
+	_, ok := s.pendingKeys[shortKey]
+	return ok
+}
+
+// anyPendingID reports whether any URL with one of ids is pending or conflicted. Callers must hold s.mu.
+func (s *Storage) anyPendingID(ids ...uuid.UUID) bool {
+	for _, id := range ids {
+		if _, ok := s.pendingIDs[id]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// flushIfPending flushes pending URLs if pending reports that the operation depends on them,
+// so that the cold tier has them before the operation.
+func (s *Storage) flushIfPending(ctx context.Context, pending func() bool) error {
+	s.mu.Lock()
+	ok := pending()
+	s.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	if err := s.flush(ctx); err != nil {
+		return fmt.Errorf("failed to flush urls to cold tier: %w", err)
+	}
+
+	return nil
+}
+
+// flush writes pending URLs to the cold tier in batches until none is left.
+// Flushed URLs are checkpointed in the journal and removed from the hot tier.
+// URLs with short keys taken in the cold tier become conflicted.
+func (s *Storage) flush(ctx context.Context) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	for {
+		s.mu.Lock()
+		batch := s.pending[:min(len(s.pending), s.batchSize)]
+		urls := s.hotURLs(ctx, batch)
+		s.mu.Unlock()
+
+		if len(batch) == 0 {
+			return nil
+		}
+
+		conflicts, err := s.submitImport(ctx, urls)
+		if err != nil {
+			return err
+		}
+
+		followUp, err := s.removePending(ctx, batch, urls, conflicts)
+		if err != nil {
+			return err
+		}
+		s.applyFollowUp(ctx, followUp)
+	}
+}
+
+// retryConflicted writes conflicted URLs to the cold tier, so that the ones
+// whose short keys have been freed in the meantime are flushed.
+func (s *Storage) retryConflicted(ctx context.Context) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	records := s.conflicted
+	urls := s.hotURLs(ctx, records)
+	s.mu.Unlock()
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	conflicts, err := s.submitImport(ctx, urls)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) == len(records) {
+		return nil
+	}
+
+	followUp, err := s.removeConflicted(ctx, records, urls, conflicts)
+	if err != nil {
+		return err
+	}
+	s.applyFollowUp(ctx, followUp)
+
+	return nil
+}
+
+// submitImport imports urls into the cold tier by the worker pool.
+// Returns the IDs of the URLs with short keys taken in the cold tier.
+func (s *Storage) submitImport(ctx context.Context, urls []*model.URL) (map[uuid.UUID]struct{}, error) {
+	job := s.pool.Submit(ctx, flushTimeout, func(ctx context.Context) (any, error) {
+		return s.importURLs(ctx, urls)
+	}, true)
+
+	res := <-job.ResCh
+	if res.Err != nil {
+		return nil, res.Err
+	}
+
+	return res.Value.(map[uuid.UUID]struct{}), nil
+}
+
+// hotURLs returns the current state of the pending URLs of batch in the hot tier,
+// which includes the clicks used up since they were journaled. Callers must hold s.mu.
+func (s *Storage) hotURLs(ctx context.Context, batch []journalRecord) []*model.URL {
+	urls := make([]*model.URL, len(batch))
+	for i, record := range batch {
+		url, err := s.hot.GetURL(ctx, record.url.ShortKey)
+		if err != nil {
+			url = record.url
+		}
+		urls[i] = url
+	}
+
+	return urls
+}
+
+// followUp holds the changes made to flushed URLs while they were pending
+// that are yet to be applied to the cold tier.
+type followUp struct {
+	// decrements is the number of clicks used up per URL ID while the URL was being flushed.
+	decrements map[uuid.UUID]int64
+	clicks     []*model.Click
+}
+
+// applyFollowUp applies the changes made to flushed URLs while they were pending to the cold tier.
+// Failures are only logged, as the URLs themselves have been flushed.
+func (s *Storage) applyFollowUp(ctx context.Context, f followUp) {
+	for id, n := range f.decrements {
+		for range n {
+			if err := s.cold.DecrementClicksLeft(ctx, id); err != nil {
+				s.logger.Error("failed to decrement clicks left in cold tier", "id", id, "error", err)
+				break
+			}
+		}
+	}
+
+	for _, click := range f.clicks {
+		if err := s.cold.SaveClick(ctx, click); err != nil {
+			s.logger.Error("failed to save click to cold tier", "url_id", click.URLID, "error", err)
+		}
+	}
+}
+
+// importURLs imports urls into the cold tier. Short keys are checked against the cold tier
+// before URLs are accepted, so a short key is only taken if another writer of the cold tier
+// took it in the meantime. In that case URLs are imported one by one, so that the URLs
+// with taken short keys don't block the others.
+// Returns the IDs of the URLs with taken short keys.
+func (s *Storage) importURLs(ctx context.Context, urls []*model.URL) (map[uuid.UUID]struct{}, error) {
+	conflicts := make(map[uuid.UUID]struct{})
+
+	_, err := s.cold.ImportURLs(ctx, urls)
+	if !errors.Is(err, storage.ErrShortKeyConflict) {
+		return conflicts, err
+	}
+
+	for _, url := range urls {
+		_, err := s.cold.ImportURLs(ctx, []*model.URL{url})
+		if errors.Is(err, storage.ErrShortKeyConflict) {
+			conflicts[url.ID] = struct{}{}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return conflicts, nil
+}
+
+// removePending removes the imported batch, which is at the front of the pending URLs, from the pending URLs.
+// flushed are the URLs written to the cold tier and conflicts are the IDs of the ones with taken short keys,
+// which become conflicted. Returns the changes to apply to the flushed URLs, as settle does.
+func (s *Storage) removePending(ctx context.Context, batch []journalRecord, flushed []*model.URL, conflicts map[uuid.UUID]struct{}) (followUp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conflicted := s.conflicted
+	for _, record := range batch {
+		if _, ok := conflicts[record.url.ID]; ok {
+			conflicted = append(conflicted, record)
+		}
+	}
+
+	f, err := s.settle(ctx, s.pending[len(batch):], conflicted, batch, flushed, conflicts)
+	if err != nil {
+		return f, err
+	}
+
+	for _, url := range flushed {
+		if _, ok := conflicts[url.ID]; ok {
+			s.logger.Error("url short key taken in cold tier, kept in hot tier for repair", "short_key", url.ShortKey, "id", url.ID)
+		}
+	}
+
+	return f, nil
+}
+
+// removeConflicted removes the imported conflicted URLs from the conflicted ones
+// unless their IDs are in conflicts. Returns the changes to apply to the flushed URLs, as settle does.
+func (s *Storage) removeConflicted(ctx context.Context, records []journalRecord, flushed []*model.URL, conflicts map[uuid.UUID]struct{}) (followUp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conflicted := make([]journalRecord, 0, len(conflicts))
+	for _, record := range s.conflicted {
+		if _, ok := conflicts[record.url.ID]; ok {
+			conflicted = append(conflicted, record)
+		}
+	}
+
+	return s.settle(ctx, s.pending, conflicted, records, flushed, conflicts)
+}
+
+// settle makes pending and conflicted the URLs that haven't reached the cold tier and checkpoints the journal
+// at the first of them, so that the journal is never checkpointed past a URL that hasn't been written.
+// The records written to the cold tier, which are the ones without IDs in conflicts, are removed from the hot tier.
+// Returns the clicks of the written records and the clicks used up after flushed were read from the hot tier.
+// Callers must hold s.mu.
+func (s *Storage) settle(
+	ctx context.Context,
+	pending, conflicted, records []journalRecord,
+	flushed []*model.URL,
+	conflicts map[uuid.UUID]struct{},
+) (followUp, error) {
+	f := followUp{decrements: make(map[uuid.UUID]int64)}
+
+	if err := s.checkpoint(pending, conflicted); err != nil {
+		return f, err
+	}
+	s.pending = pending
+	s.conflicted = conflicted
+
+	current := s.hotURLs(ctx, records)
+	ids := make([]uuid.UUID, 0, len(records))
+	for i, record := range records {
+		if _, ok := conflicts[record.url.ID]; ok {
+			continue
+		}
+
+		delete(s.pendingKeys, record.url.ShortKey)
+		delete(s.pendingIDs, record.url.ID)
+		ids = append(ids, record.url.ID)
+
+		f.clicks = append(f.clicks, s.pendingClicks[record.url.ID]...)
+		delete(s.pendingClicks, record.url.ID)
+
+		if flushed[i].ClicksLeft != nil && current[i].ClicksLeft != nil && *current[i].ClicksLeft < *flushed[i].ClicksLeft {
+			f.decrements[record.url.ID] = *flushed[i].ClicksLeft - *current[i].ClicksLeft
+		}
+	}
+	if len(ids) == 0 {
+		return f, nil
+	}
+
+	// The hot tier only holds pending URLs, flushed ones are evicted by deleting and purging them.
+	if err := s.hot.DeleteURLs(ctx, ids); err != nil {
+		return f, fmt.Errorf("failed to evict urls from hot tier: %w", err)
+	}
+	if _, err := s.hot.PurgeDeletedURLs(ctx, time.Now().Add(time.Second), len(ids)); err != nil {
+		return f, fmt.Errorf("failed to evict urls from hot tier: %w", err)
+	}
+
+	return f, nil
+}
+
+// checkpoint checkpoints the journal at the first of pending and conflicted,
+// or truncates it if both are empty. Callers must hold s.mu.
+func (s *Storage) checkpoint(pending, conflicted []journalRecord) error {
+	switch {
+	case len(pending) == 0 && len(conflicted) == 0:
+		return s.journal.reset()
+	case len(conflicted) == 0:
+		return s.journal.checkpoint(pending[0].start)
+	case len(pending) == 0:
+		return s.journal.checkpoint(conflicted[0].start)
+	default:
+		return s.journal.checkpoint(min(pending[0].start, conflicted[0].start))
+	}
+}
+
+// Ping checks if the cold tier is available.
+func (s *Storage) Ping(ctx context.Context) error {
+	return s.cold.Ping(ctx)
+}
+
+// PingComponents reports the components of the cold tier if it reports them.
+func (s *Storage) PingComponents(ctx context.Context) (map[string]error, error) {
+	if pinger, ok := s.cold.(interface {
+		PingComponents(ctx context.Context) (map[string]error, error)
+	}); ok {
+		return pinger.PingComponents(ctx)
+	}
+
+	err := s.cold.Ping(ctx)
+
+	return map[string]error{"cold": err}, err
+}
+
+// GetURL retrieves a URL by its short key from the hot tier if it's pending
+// and from the cold tier otherwise.
+func (s *Storage) GetURL(ctx context.Context, shortKey string) (*model.URL, error) {
+	s.mu.Lock()
+	pending := s.isPending(shortKey)
+	s.mu.Unlock()
+
+	if pending {
+		// The URL may have been flushed and evicted in the meantime.
+		url, err := s.hot.GetURL(ctx, shortKey)
+		if err == nil && url.DeletedAt == nil {
+			return url, nil
+		}
+	}
+
+	return s.cold.GetURL(ctx, shortKey)
+}
+
+// GetURLs retrieves multiple URLs by their short keys from the cold tier.
+func (s *Storage) GetURLs(ctx context.Context, shortKeys []string) ([]*model.URL, error) {
+	err := s.flushIfPending(ctx, func() bool {
+		for _, shortKey := range shortKeys {
+			if s.isPending(shortKey) {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.cold.GetURLs(ctx, shortKeys)
+}
+
+// GetURLsByUserID retrieves all URLs created by a specific user from the cold tier.
+func (s *Storage) GetURLsByUserID(ctx context.Context, userID uuid.UUID) ([]*model.URL, error) {
+	err := s.flushIfPending(ctx, func() bool {
+		urls, err := s.hot.GetURLsByUserID(ctx, userID)
+		return err != nil || len(urls) > 0
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.cold.GetURLsByUserID(ctx, userID)
+}
+
+// GetDeletedURLsByUserID retrieves all deleted URLs created by a specific user from the cold tier.
+// Pending URLs are never deleted, so they aren't flushed.
+func (s *Storage) GetDeletedURLsByUserID(ctx context.Context, userID uuid.UUID) ([]*model.URL, error) {
+	return s.cold.GetDeletedURLsByUserID(ctx, userID)
+}
+
+// SetURL saves a URL to the hot tier and the journal without waiting for the cold tier to write it.
+// Depending on scope, a pending URL or a URL in the cold tier with the same original URL
+// may be returned instead together with storage.ErrConflict.
+// Returns storage.ErrShortKeyConflict if the short key is taken in either tier.
+func (s *Storage) SetURL(ctx context.Context, url *model.URL, scope storage.DedupeScope) (*model.URL, error) {
+	found, err := s.checkCold(ctx, []*model.URL{url}, scope)
+	if err != nil {
+		return nil, err
+	}
+	if found[0] != nil {
+		return found[0], storage.ErrConflict
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	savedURL, err := s.hot.SetURL(ctx, url, scope)
+	if err != nil {
+		return savedURL, err
+	}
+
+	if err := s.appendPending(ctx, []*model.URL{savedURL}); err != nil {
+		return nil, err
+	}
+
+	return savedURL, nil
+}
+
+// SetURLs saves multiple URLs to the hot tier and the journal without waiting for the cold tier to write them.
+// Depending on scope, pending URLs or URLs in the cold tier with the same original URLs
+// may be returned in place of some of urls.
+// Returns storage.ErrShortKeyConflict and saves nothing if a short key is taken in either tier.
+func (s *Storage) SetURLs(ctx context.Context, urls []*model.URL, scope storage.DedupeScope) ([]*model.URL, error) {
+	savedURLs, err := s.checkCold(ctx, urls, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// hotURLs are the URLs without a URL to reuse in the cold tier.
+	hotURLs := make([]*model.URL, 0, len(urls))
+	for i, url := range urls {
+		if savedURLs[i] == nil {
+			hotURLs = append(hotURLs, url)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	savedHotURLs, err := s.hot.SetURLs(ctx, hotURLs, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	newURLs := make([]*model.URL, 0, len(hotURLs))
+	for i, url := range hotURLs {
+		// Reused URLs are returned in place of the new ones.
+		if savedHotURLs[i] == url {
+			newURLs = append(newURLs, url)
+		}
+	}
+
+	if err := s.appendPending(ctx, newURLs); err != nil {
+		return nil, err
+	}
+
+	j := 0
+	for i := range savedURLs {
+		if savedURLs[i] == nil {
+			savedURLs[i] = savedHotURLs[j]
+			j++
+		}
+	}
+
+	return savedURLs, nil
+}
+
+// checkCold looks up URLs in the cold tier that would be reused instead of saving urls within scope,
+// at the indexes of urls, and makes sure the short keys of the other urls aren't taken in the cold tier.
+// Both are read from the storage of record, so that a lagging replica doesn't hide them.
+// Returns storage.ErrShortKeyConflict if one of the short keys is taken.
+func (s *Storage) checkCold(ctx context.Context, urls []*model.URL, scope storage.DedupeScope) ([]*model.URL, error) {
+	found, err := s.cold.FindActiveURLs(ctx, urls, scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find urls in cold tier: %w", err)
+	}
+
+	shortKeys := make([]string, 0, len(urls))
+	for i, url := range urls {
+		if found[i] == nil {
+			shortKeys = append(shortKeys, url.ShortKey)
+		}
+	}
+	if len(shortKeys) == 0 {
+		return found, nil
+	}
+
+	taken, err := s.cold.TakenShortKeys(ctx, shortKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check short keys in cold tier: %w", err)
+	}
+	if len(taken) > 0 {
+		return nil, storage.ErrShortKeyConflict
+	}
+
+	return found, nil
+}
+
+// appendPending journals URLs just saved to the hot tier and makes them pending.
+// If the journal fails, the URLs are removed from the hot tier, as they wouldn't be flushed.
+// Callers must hold s.mu.
+func (s *Storage) appendPending(ctx context.Context, urls []*model.URL) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	records, err := s.journal.append(urls)
+	if err != nil {
+		ids := make([]uuid.UUID, len(urls))
+		for i, url := range urls {
+			ids[i] = url.ID
+		}
+		return errors.Join(err, s.hot.DeleteURLs(ctx, ids))
+	}
+
+	s.addPending(records)
+
+	return nil
+}
+
+// UpdateURL updates a URL in the cold tier after flushing it if it's pending.
+func (s *Storage) UpdateURL(ctx context.Context, url *model.URL, fields storage.URLFields) (*model.URL, error) {
+	err := s.flushIfPending(ctx, func() bool {
+		return s.isPending(url.ShortKey)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.cold.UpdateURL(ctx, url, fields)
+}
+
+// DeleteURLs marks URLs as deleted in the cold tier after flushing the pending ones.
+func (s *Storage) DeleteURLs(ctx context.Context, ids []uuid.UUID) error {
+	err := s.flushIfPending(ctx, func() bool {
+		return s.anyPendingID(ids...)
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.cold.DeleteURLs(ctx, ids)
+}
+
+// RestoreURLs clears the deletion mark of URLs in the cold tier.
+// Pending URLs are never deleted, so they aren't flushed.
+func (s *Storage) RestoreURLs(ctx context.Context, ids []uuid.UUID) error {
+	return s.cold.RestoreURLs(ctx, ids)
+}
+
+// PurgeDeletedURLs permanently removes deleted URLs from the cold tier.
+func (s *Storage) PurgeDeletedURLs(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return s.cold.PurgeDeletedURLs(ctx, before, limit)
+}
+
+// DecrementClicksLeft uses up one redirect of a click-limited URL in the hot tier if it's pending
+// and in the cold tier otherwise. Clicks used up in the hot tier reach the cold tier with the flushed URL.
+func (s *Storage) DecrementClicksLeft(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	if s.anyPendingID(id) {
+		defer s.mu.Unlock()
+		return s.hot.DecrementClicksLeft(ctx, id)
+	}
+	s.mu.Unlock()
+
+	return s.cold.DecrementClicksLeft(ctx, id)
+}
+
+// SaveClick stores a click event in the cold tier. Clicks of pending URLs are held in memory
+// and saved after the URLs are flushed, so redirects don't wait for a flush.
+func (s *Storage) SaveClick(ctx context.Context, click *model.Click) error {
+	s.mu.Lock()
+	if s.anyPendingID(click.URLID) {
+		defer s.mu.Unlock()
+		s.pendingClicks[click.URLID] = append(s.pendingClicks[click.URLID], click)
+		return nil
+	}
+	s.mu.Unlock()
+
+	return s.cold.SaveClick(ctx, click)
+}
+
+// GetClickStats aggregates the click events of a URL in the cold tier.
+// Clicks of pending URLs are saved only after the URLs are flushed, so they aren't flushed.
+func (s *Storage) GetClickStats(ctx context.Context, urlID uuid.UUID, bucket time.Duration) (*model.ClickStats, error) {
+	return s.cold.GetClickStats(ctx, urlID, bucket)
+}
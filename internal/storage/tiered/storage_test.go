@@ -0,0 +1,441 @@
+package tiered
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtroode/urlshorter/internal/logger"
+	"github.com/dtroode/urlshorter/internal/model"
+	"github.com/dtroode/urlshorter/internal/service/workerpool"
+	"github.com/dtroode/urlshorter/internal/storage"
+	"github.com/dtroode/urlshorter/internal/storage/inmemory"
+)
+
+var errCold = errors.New("cold tier is down")
+
+// coldStorage is a cold tier backed by an in-memory storage that fails imports on demand.
+type coldStorage struct {
+	*inmemory.Storage
+
+	mu        sync.Mutex
+	importErr error
+	imports   int
+}
+
+func newColdStorage(t *testing.T) *coldStorage {
+	s, err := inmemory.NewStorage(filepath.Join(t.TempDir(), "cold"), inmemory.WithSync(inmemory.SyncNever, 0))
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+
+	return &coldStorage{Storage: s}
+}
+
+func (c *coldStorage) ImportURLs(ctx context.Context, urls []*model.URL) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.imports++
+	if c.importErr != nil {
+		return 0, c.importErr
+	}
+
+	newURLs := make([]*model.URL, 0, len(urls))
+	for _, url := range urls {
+		if stored, err := c.GetURL(ctx, url.ShortKey); err == nil {
+			if stored.ID != url.ID {
+				return 0, storage.ErrShortKeyConflict
+			}
+			continue
+		}
+		newURLs = append(newURLs, url)
+	}
+
+	_, err := c.SetURLs(ctx, newURLs, storage.DedupeNone)
+
+	return int64(len(newURLs)), err
+}
+
+// Close keeps the cold tier open, so that it can be checked after the tiered storage is closed.
+func (c *coldStorage) Close() error {
+	return nil
+}
+
+func (c *coldStorage) failImports(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.importErr = err
+}
+
+func newTestPool() *workerpool.Pool {
+	pool := workerpool.NewPool(2, 0)
+	pool.Start()
+
+	return pool
+}
+
+func newTestStorage(t *testing.T, cold Cold, journalFilename string, opts ...Option) *Storage {
+	t.Helper()
+
+	s, err := NewStorage(cold, journalFilename, newTestPool(), logger.NewLog("ERROR"), opts...)
+	require.NoError(t, err)
+
+	return s
+}
+
+func newURL(shortKey string) *model.URL {
+	return &model.URL{ID: uuid.New(), ShortKey: shortKey, OriginalURL: "https://ya.ru/" + shortKey, UserID: uuid.New()}
+}
+
+func TestStorage_WriteBehind(t *testing.T) {
+	ctx := context.Background()
+	cold := newColdStorage(t)
+	s := newTestStorage(t, cold, filepath.Join(t.TempDir(), "journal"))
+	defer s.Close()
+
+	url := newURL("abc")
+	savedURL, err := s.SetURL(ctx, url, storage.DedupeGlobal)
+	require.NoError(t, err)
+	assert.Equal(t, url, savedURL)
+
+	// The URL is acknowledged before it reaches the cold tier.
+	_, err = cold.GetURL(ctx, "abc")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	got, err := s.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, url.ID, got.ID)
+	assert.Equal(t, 1, s.pendingCount())
+
+	require.NoError(t, s.flush(ctx))
+
+	got, err = cold.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, url.ID, got.ID)
+	assert.Equal(t, 0, s.pendingCount())
+	assert.Zero(t, s.journal.size)
+
+	// Flushed URLs are evicted from the hot tier and read from the cold tier.
+	_, err = s.hot.GetURL(ctx, "abc")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	got, err = s.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, url.ID, got.ID)
+}
+
+func TestStorage_SetURLs(t *testing.T) {
+	ctx := context.Background()
+	cold := newColdStorage(t)
+	s := newTestStorage(t, cold, filepath.Join(t.TempDir(), "journal"), WithFlush(2, 0))
+	defer s.Close()
+
+	urls := []*model.URL{newURL("a"), newURL("b"), newURL("c")}
+	duplicate := newURL("d")
+	duplicate.OriginalURL = urls[0].OriginalURL
+
+	savedURLs, err := s.SetURLs(ctx, append(urls, duplicate), storage.DedupeGlobal)
+	require.NoError(t, err)
+	assert.Equal(t, []*model.URL{urls[0], urls[1], urls[2], urls[0]}, savedURLs)
+	assert.Equal(t, 3, s.pendingCount())
+
+	// Pending URLs are deduplicated and their short keys are taken.
+	existing, err := s.SetURL(ctx, duplicate, storage.DedupeGlobal)
+	require.ErrorIs(t, err, storage.ErrConflict)
+	assert.Equal(t, urls[0], existing)
+	_, err = s.SetURL(ctx, newURL("a"), storage.DedupeNone)
+	require.ErrorIs(t, err, storage.ErrShortKeyConflict)
+
+	// A full batch triggers a flush.
+	select {
+	case <-s.batchFull:
+	default:
+		t.Fatal("full batch was not signaled")
+	}
+
+	require.NoError(t, s.flush(ctx))
+	assert.Equal(t, 2, cold.imports)
+
+	stored, err := cold.GetURLs(ctx, []string{"a", "b", "c", "d"})
+	require.NoError(t, err)
+	assert.Len(t, stored, 3)
+}
+
+func TestStorage_ReplayAfterCrash(t *testing.T) {
+	ctx := context.Background()
+	journalFilename := filepath.Join(t.TempDir(), "journal")
+	cold := newColdStorage(t)
+	cold.failImports(errCold)
+
+	crashed := newTestStorage(t, cold, journalFilename)
+	_, err := crashed.SetURLs(ctx, []*model.URL{newURL("a"), newURL("b")}, storage.DedupeGlobal)
+	require.NoError(t, err)
+	require.ErrorIs(t, crashed.flush(ctx), errCold)
+	// The process dies without flushing.
+	require.NoError(t, crashed.closeLocal())
+
+	cold.failImports(nil)
+	s := newTestStorage(t, cold, journalFilename)
+	defer s.Close()
+
+	// Replayed URLs are served before they are flushed.
+	got, err := s.GetURL(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "https://ya.ru/a", got.OriginalURL)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(runCtx)
+	}()
+	require.Eventually(t, func() bool {
+		return s.pendingCount() == 0
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	stored, err := cold.GetURLs(ctx, []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Len(t, stored, 2)
+}
+
+func TestStorage_ReplayFlushedTwice(t *testing.T) {
+	ctx := context.Background()
+	journalFilename := filepath.Join(t.TempDir(), "journal")
+	cold := newColdStorage(t)
+
+	crashed := newTestStorage(t, cold, journalFilename)
+	_, err := crashed.SetURL(ctx, newURL("a"), storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	// The process dies after the URL reached the cold tier but before the checkpoint.
+	_, err = cold.ImportURLs(ctx, []*model.URL{crashed.pending[0].url})
+	require.NoError(t, err)
+	require.NoError(t, crashed.closeLocal())
+
+	s := newTestStorage(t, cold, journalFilename)
+	require.NoError(t, s.Close())
+
+	stored, err := cold.GetURLs(ctx, []string{"a"})
+	require.NoError(t, err)
+	assert.Len(t, stored, 1)
+}
+
+func TestStorage_RejectsShortKeysTakenInColdTier(t *testing.T) {
+	ctx := context.Background()
+	cold := newColdStorage(t)
+	_, err := cold.SetURL(ctx, newURL("taken"), storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	s := newTestStorage(t, cold, filepath.Join(t.TempDir(), "journal"))
+	defer s.Close()
+
+	_, err = s.SetURL(ctx, newURL("taken"), storage.DedupeNone)
+	require.ErrorIs(t, err, storage.ErrShortKeyConflict)
+
+	_, err = s.SetURLs(ctx, []*model.URL{newURL("a"), newURL("taken"), newURL("b")}, storage.DedupeNone)
+	require.ErrorIs(t, err, storage.ErrShortKeyConflict)
+	assert.Equal(t, 0, s.pendingCount())
+}
+
+func TestStorage_KeepsURLsWithShortKeysTakenWhilePending(t *testing.T) {
+	ctx := context.Background()
+	journalFilename := filepath.Join(t.TempDir(), "journal")
+	cold := newColdStorage(t)
+
+	crashed := newTestStorage(t, cold, journalFilename)
+	url := newURL("b")
+	_, err := crashed.SetURLs(ctx, []*model.URL{newURL("a"), url, newURL("c")}, storage.DedupeNone)
+	require.NoError(t, err)
+
+	// Another writer of the cold tier takes the short key of an acknowledged URL.
+	other := newURL("b")
+	_, err = cold.SetURL(ctx, other, storage.DedupeNone)
+	require.NoError(t, err)
+
+	require.NoError(t, crashed.flush(ctx))
+	assert.Equal(t, 0, crashed.pendingCount())
+	assert.Equal(t, []*model.URL{url}, crashed.ConflictedURLs(ctx))
+	stored, err := cold.GetURLs(ctx, []string{"a", "c"})
+	require.NoError(t, err)
+	assert.Len(t, stored, 2)
+
+	// The URL is still served and isn't checkpointed.
+	got, err := crashed.GetURL(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, url.ID, got.ID)
+	assert.NotZero(t, crashed.journal.size)
+	require.NoError(t, crashed.closeLocal())
+
+	// The URL and the ones after it are replayed after a restart.
+	s := newTestStorage(t, cold, journalFilename)
+	defer s.Close()
+	assert.Equal(t, 2, s.pendingCount())
+	require.NoError(t, s.flush(ctx))
+	assert.Equal(t, []*model.URL{url}, s.ConflictedURLs(ctx))
+
+	// Retries keep the URL until the short key is freed.
+	require.NoError(t, s.retryConflicted(ctx))
+	assert.Len(t, s.ConflictedURLs(ctx), 1)
+
+	require.NoError(t, cold.DeleteURLs(ctx, []uuid.UUID{other.ID}))
+	_, err = cold.PurgeDeletedURLs(ctx, time.Now().Add(time.Second), 1)
+	require.NoError(t, err)
+	require.NoError(t, s.retryConflicted(ctx))
+
+	assert.Empty(t, s.ConflictedURLs(ctx))
+	assert.Zero(t, s.journal.size)
+	got, err = cold.GetURL(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, url.ID, got.ID)
+}
+
+func TestStorage_DedupesAgainstColdTier(t *testing.T) {
+	ctx := context.Background()
+	cold := newColdStorage(t)
+	existing := newURL("old")
+	_, err := cold.SetURL(ctx, existing, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	s := newTestStorage(t, cold, filepath.Join(t.TempDir(), "journal"))
+	defer s.Close()
+
+	url := newURL("new")
+	url.OriginalURL = existing.OriginalURL
+
+	savedURL, err := s.SetURL(ctx, url, storage.DedupeGlobal)
+	require.ErrorIs(t, err, storage.ErrConflict)
+	assert.Equal(t, existing.ID, savedURL.ID)
+
+	savedURLs, err := s.SetURLs(ctx, []*model.URL{newURL("a"), url, newURL("b")}, storage.DedupeGlobal)
+	require.NoError(t, err)
+	require.Len(t, savedURLs, 3)
+	assert.Equal(t, "a", savedURLs[0].ShortKey)
+	assert.Equal(t, existing.ID, savedURLs[1].ID)
+	assert.Equal(t, "b", savedURLs[2].ShortKey)
+	assert.Equal(t, 2, s.pendingCount())
+
+	// Only the owner's URLs are reused within the user scope.
+	savedURL, err = s.SetURL(ctx, url, storage.DedupeUser)
+	require.NoError(t, err)
+	assert.Equal(t, url.ID, savedURL.ID)
+	assert.Equal(t, 3, s.pendingCount())
+}
+
+func TestStorage_ClicksOfPendingURLs(t *testing.T) {
+	ctx := context.Background()
+	cold := newColdStorage(t)
+	s := newTestStorage(t, cold, filepath.Join(t.TempDir(), "journal"))
+	defer s.Close()
+
+	clicksLeft := int64(3)
+	url := newURL("abc")
+	url.ClicksLeft = &clicksLeft
+	_, err := s.SetURL(ctx, url, storage.DedupeGlobal)
+	require.NoError(t, err)
+
+	// Redirects of a pending URL don't flush it.
+	require.NoError(t, s.DecrementClicksLeft(ctx, url.ID))
+	require.NoError(t, s.SaveClick(ctx, model.NewClick(url.ID, time.Now().UTC(), "", "", "")))
+	assert.Equal(t, 1, s.pendingCount())
+	got, err := s.GetURL(ctx, url.ShortKey)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), *got.ClicksLeft)
+
+	// A redirect while the URL is being flushed.
+	s.mu.Lock()
+	batch := s.pending
+	urls := s.hotURLs(ctx, batch)
+	s.mu.Unlock()
+	_, err = cold.ImportURLs(ctx, urls)
+	require.NoError(t, err)
+	require.NoError(t, s.DecrementClicksLeft(ctx, url.ID))
+	require.NoError(t, s.SaveClick(ctx, model.NewClick(url.ID, time.Now().UTC(), "", "", "")))
+	f, err := s.removePending(ctx, batch, urls, nil)
+	require.NoError(t, err)
+	s.applyFollowUp(ctx, f)
+
+	got, err = cold.GetURL(ctx, url.ShortKey)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), *got.ClicksLeft)
+	stats, err := cold.GetClickStats(ctx, url.ID, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Total)
+
+	// Redirects of flushed URLs go to the cold tier.
+	require.NoError(t, s.DecrementClicksLeft(ctx, url.ID))
+	require.ErrorIs(t, s.DecrementClicksLeft(ctx, url.ID), storage.ErrNoClicksLeft)
+}
+
+func TestStorage_FlushesBeforeDependentOperations(t *testing.T) {
+	ctx := context.Background()
+
+	tests := map[string]struct {
+		operation func(s *Storage, url *model.URL) error
+	}{
+		"get urls": {
+			operation: func(s *Storage, url *model.URL) error {
+				urls, err := s.GetURLs(ctx, []string{url.ShortKey})
+				if err == nil && len(urls) != 1 {
+					return errors.New("url not found")
+				}
+				return err
+			},
+		},
+		"get urls by user id": {
+			operation: func(s *Storage, url *model.URL) error {
+				urls, err := s.GetURLsByUserID(ctx, url.UserID)
+				if err == nil && len(urls) != 1 {
+					return errors.New("url not found")
+				}
+				return err
+			},
+		},
+		"update url": {
+			operation: func(s *Storage, url *model.URL) error {
+				_, err := s.UpdateURL(ctx, url, storage.FieldOriginalURL)
+				return err
+			},
+		},
+		"delete urls": {
+			operation: func(s *Storage, url *model.URL) error {
+				return s.DeleteURLs(ctx, []uuid.UUID{url.ID})
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cold := newColdStorage(t)
+			s := newTestStorage(t, cold, filepath.Join(t.TempDir(), "journal"))
+			defer s.Close()
+
+			url := newURL("abc")
+			_, err := s.SetURL(ctx, url, storage.DedupeGlobal)
+			require.NoError(t, err)
+
+			require.NoError(t, tt.operation(s, url))
+			assert.Equal(t, 0, s.pendingCount())
+		})
+	}
+
+	t.Run("cold tier is down", func(t *testing.T) {
+		cold := newColdStorage(t)
+		s := newTestStorage(t, cold, filepath.Join(t.TempDir(), "journal"))
+
+		url := newURL("abc")
+		_, err := s.SetURL(ctx, url, storage.DedupeGlobal)
+		require.NoError(t, err)
+
+		cold.failImports(errCold)
+		require.ErrorIs(t, s.DeleteURLs(ctx, []uuid.UUID{url.ID}), errCold)
+		require.ErrorIs(t, s.Close(), errCold)
+	})
+}
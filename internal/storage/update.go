@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"github.com/dtroode/urlshorter/internal/model"
+)
+
+// URLFields is a set of mutable URL attributes changed by an update.
+// Attributes that are not in the set are left as they are stored, so that an update
+// doesn't overwrite concurrent changes of other attributes, such as used up clicks.
+type URLFields uint8
+
+const (
+	// FieldOriginalURL is the URL the short URL redirects to.
+	FieldOriginalURL URLFields = 1 << iota
+	// FieldExpiresAt is the moment the URL expires at.
+	FieldExpiresAt
+	// FieldPasswordHash is the hash of the password required to follow the URL.
+	FieldPasswordHash
+	// FieldClicksLeft is the number of redirects the URL still allows.
+	FieldClicksLeft
+)
+
+// Has reports whether the set contains field.
+func (f URLFields) Has(field URLFields) bool {
+	return f&field != 0
+}
+
+// Apply returns a copy of stored with the attributes in the set replaced by the ones of url.
+func (f URLFields) Apply(stored, url *model.URL) *model.URL {
+	updated := *stored
+	if f.Has(FieldOriginalURL) {
+		updated.OriginalURL = url.OriginalURL
+	}
+	if f.Has(FieldExpiresAt) {
+		updated.ExpiresAt = url.ExpiresAt
+	}
+	if f.Has(FieldPasswordHash) {
+		updated.PasswordHash = url.PasswordHash
+	}
+	if f.Has(FieldClicksLeft) {
+		updated.ClicksLeft = url.ClicksLeft
+	}
+
+	return &updated
+}
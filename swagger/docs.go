@@ -58,7 +58,7 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad request - invalid URL",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.Error"
                         }
                     },
                     "401": {
@@ -78,13 +78,19 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Service unavailable - storage is down",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
         "/api/shorten": {
             "post": {
-                "description": "Creates a shortened URL from the provided JSON request",
+                "description": "Creates a shortened URL from the provided JSON request.\nAn optional alias sets the short key. If the alias is used by another URL,\n409 is returned with an error body instead of a shortened URL.\nWith force_new a new shortened URL is created even if the URL has already been shortened.\nWith password the shortened URL opens only after the password is entered.\nWith max_clicks the shortened URL stops working after that many redirects.",
                 "consumes": [
                     "application/json"
                 ],
@@ -114,9 +120,9 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Bad request - invalid JSON",
+                        "description": "Bad request - invalid JSON, URL, alias, expiration, password or max clicks",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.Error"
                         }
                     },
                     "401": {
@@ -136,13 +142,19 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Service unavailable - storage is down",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
         "/api/shorten/batch": {
             "post": {
-                "description": "Creates multiple shortened URLs from the provided batch request",
+                "description": "Creates multiple shortened URLs from the provided batch request.\nWith force_new new shortened URLs are created even if the URLs have already been shortened.\nWith password a shortened URL opens only after the password is entered.\nWith max_clicks a shortened URL stops working after that many redirects.",
                 "consumes": [
                     "application/json"
                 ],
@@ -165,6 +177,12 @@ const docTemplate = `{
                                 "$ref": "#/definitions/request.CreateShortURLBatch"
                             }
                         }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Create new shortened URLs even if the URLs have already been shortened",
+                        "name": "force_new",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -178,9 +196,9 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Bad request - invalid JSON or empty batch",
+                        "description": "Bad request - invalid JSON, empty batch, URL, alias, expiration, password or max clicks",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.Error"
                         }
                     },
                     "401": {
@@ -189,18 +207,30 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "409": {
+                        "description": "Alias is already taken",
+                        "schema": {
+                            "$ref": "#/definitions/response.Error"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Service unavailable - storage is down",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
         "/api/user/urls": {
             "get": {
-                "description": "Retrieves all URLs created by the authenticated user",
+                "description": "Retrieves all URLs created by the authenticated user.\nExpired URLs are included and marked as expired.",
                 "consumes": [
                     "application/json"
                 ],
@@ -238,6 +268,12 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Service unavailable - storage is down",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             },
@@ -295,6 +331,308 @@ const docTemplate = `{
                 }
             }
         },
+        "/api/user/urls/restore": {
+            "post": {
+                "description": "Clears the deletion mark of the specified URLs created by the authenticated user.\nRestored URLs redirect again immediately. Unknown keys and URLs of other users are skipped.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Restore user's URLs",
+                "parameters": [
+                    {
+                        "description": "Array of short keys to restore",
+                        "name": "shortKeys",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "URLs restored",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid JSON",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - invalid or missing authentication",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable - storage is down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/urls/trash": {
+            "get": {
+                "description": "Retrieves all URLs created and then deleted by the authenticated user.\nDeleted URLs can be restored until they are purged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Get user's deleted URLs",
+                "responses": {
+                    "200": {
+                        "description": "User's deleted URLs",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/response.GetUserURL"
+                            }
+                        }
+                    },
+                    "204": {
+                        "description": "No deleted URLs found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - invalid or missing authentication",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable - storage is down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/urls/{key}": {
+            "patch": {
+                "description": "Changes the destination and other attributes of a URL created by the authenticated user.\nOmitted fields are left unchanged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Update user's URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL identifier",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "URL changes",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/request.UpdateURL"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated URL",
+                        "schema": {
+                            "$ref": "#/definitions/response.GetUserURL"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid JSON, URL, expiration, password or max clicks",
+                        "schema": {
+                            "$ref": "#/definitions/response.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - invalid or missing authentication",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "URL belongs to another user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "410": {
+                        "description": "URL has been deleted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable - storage is down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/urls/{key}/stats": {
+            "get": {
+                "description": "Retrieves click statistics of a URL created by the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Get URL statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL identifier",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "hour",
+                            "day"
+                        ],
+                        "type": "string",
+                        "default": "day",
+                        "description": "Size of time series buckets",
+                        "name": "bucket",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "URL statistics",
+                        "schema": {
+                            "$ref": "#/definitions/response.URLStats"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid bucket",
+                        "schema": {
+                            "$ref": "#/definitions/response.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - invalid or missing authentication",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "URL belongs to another user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable - storage is down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Report the status of the service and its components, like database pools",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Component health check",
+                "responses": {
+                    "200": {
+                        "description": "Service is healthy or degraded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Health"
+                        }
+                    },
+                    "500": {
+                        "description": "Service is unhealthy",
+                        "schema": {
+                            "$ref": "#/definitions/response.Health"
+                        }
+                    }
+                }
+            }
+        },
         "/ping": {
             "get": {
                 "description": "Check if the service is running and database is accessible",
@@ -326,12 +664,13 @@ const docTemplate = `{
         },
         "/{id}": {
             "get": {
-                "description": "Redirects to the original URL associated with the provided short key",
+                "description": "Redirects to the original URL associated with the provided short key.\nFor password-protected URLs a password form is shown unless the link access cookie is set.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/html"
                 ],
                 "tags": [
                     "URLs"
@@ -347,6 +686,12 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
+                    "200": {
+                        "description": "Password form of a password-protected URL",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "307": {
                         "description": "Temporary redirect to original URL",
                         "schema": {
@@ -366,7 +711,7 @@ const docTemplate = `{
                         }
                     },
                     "410": {
-                        "description": "URL has been deleted",
+                        "description": "URL has been deleted, has expired or has no clicks left",
                         "schema": {
                             "type": "string"
                         }
@@ -376,6 +721,91 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Service unavailable - storage is down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Checks the password of a password-protected URL. After a correct password\na short-lived link access cookie is set and the client is redirected back to the short URL.\nThe number of attempts per URL is limited.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "URLs"
+                ],
+                "summary": "Unlock password-protected URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL identifier",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password of the URL",
+                        "name": "password",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "303": {
+                        "description": "Redirect to the short URL",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - missing short key or invalid form",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Password form with an error - wrong password",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "410": {
+                        "description": "URL has been deleted or has expired",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Password form with an error - too many attempts",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable - storage is down",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
@@ -386,6 +816,36 @@ const docTemplate = `{
             "description": "Request structure for creating a shortened URL",
             "type": "object",
             "properties": {
+                "alias": {
+                    "description": "Alias is an optional custom short key to use instead of a generated one.\nMust be 3 to 32 characters long and contain only letters, digits, '-' and '_'.\n@Example \"q3-launch\"",
+                    "type": "string",
+                    "example": "q3-launch"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt is an optional moment after which the shortened URL stops working.\nMust be in the future. Can't be combined with TTL.\n@Example \"2026-01-01T00:00:00Z\"",
+                    "type": "string",
+                    "example": "2026-01-01T00:00:00Z"
+                },
+                "force_new": {
+                    "description": "ForceNew makes the service create a new shortened URL even if\nthe original URL has already been shortened.\n@Example true",
+                    "type": "boolean",
+                    "example": true
+                },
+                "max_clicks": {
+                    "description": "MaxClicks is an optional number of redirects after which the shortened URL stops working.\nClick-limited URLs are never reused for other requests.\n@Example 1",
+                    "type": "integer",
+                    "example": 1
+                },
+                "password": {
+                    "description": "Password is an optional password required to follow the shortened URL.\nPassword-protected URLs are never reused for other requests.\n@Example \"s3cret\"",
+                    "type": "string",
+                    "example": "s3cret"
+                },
+                "ttl": {
+                    "description": "TTL is an optional lifetime of the shortened URL in seconds.\nCan't be combined with ExpiresAt.\n@Example 86400",
+                    "type": "integer",
+                    "example": 86400
+                },
                 "url": {
                     "description": "URL is the original URL to be shortened.\nMust be a valid HTTP/HTTPS URL.\n@Example \"https://example.com/very-long-url-path\"",
                     "type": "string",
@@ -397,15 +857,71 @@ const docTemplate = `{
             "description": "Request structure for batch URL shortening operations",
             "type": "object",
             "properties": {
+                "alias": {
+                    "description": "Alias is an optional custom short key to use instead of a generated one.\nMust be 3 to 32 characters long and contain only letters, digits, '-' and '_'.\n@Example \"q3-launch\"",
+                    "type": "string",
+                    "example": "q3-launch"
+                },
                 "correlation_id": {
                     "description": "CorrelationID is a unique identifier for tracking the request in batch operations.\nUsed to correlate the response with the original request.\n@Example \"req-123\"",
                     "type": "string",
                     "example": "req-123"
                 },
+                "expires_at": {
+                    "description": "ExpiresAt is an optional moment after which the shortened URL stops working.\nMust be in the future. Can't be combined with TTL.\n@Example \"2026-01-01T00:00:00Z\"",
+                    "type": "string",
+                    "example": "2026-01-01T00:00:00Z"
+                },
+                "max_clicks": {
+                    "description": "MaxClicks is an optional number of redirects after which the shortened URL stops working.\nClick-limited URLs are never reused for other requests.\n@Example 1",
+                    "type": "integer",
+                    "example": 1
+                },
                 "original_url": {
                     "description": "OriginalURL is the original URL to be shortened.\nMust be a valid HTTP/HTTPS URL.\n@Example \"https://example.com/very-long-url-path\"",
                     "type": "string",
                     "example": "https://example.com/very-long-url-path"
+                },
+                "password": {
+                    "description": "Password is an optional password required to follow the shortened URL.\nPassword-protected URLs are never reused for other requests.\n@Example \"s3cret\"",
+                    "type": "string",
+                    "example": "s3cret"
+                },
+                "ttl": {
+                    "description": "TTL is an optional lifetime of the shortened URL in seconds.\nCan't be combined with ExpiresAt.\n@Example 86400",
+                    "type": "integer",
+                    "example": 86400
+                }
+            }
+        },
+        "request.UpdateURL": {
+            "description": "Request structure for changing a shortened URL",
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "description": "ExpiresAt is the new moment after which the shortened URL stops working.\nMust be in the future. Null removes the expiration. Can't be combined with TTL.\n@Example \"2026-01-01T00:00:00Z\"",
+                    "type": "string",
+                    "example": "2026-01-01T00:00:00Z"
+                },
+                "max_clicks": {
+                    "description": "MaxClicks is the new number of redirects after which the shortened URL stops working,\ncounted from now. Zero or null removes the click limit.\n@Example 10",
+                    "type": "integer",
+                    "example": 10
+                },
+                "original_url": {
+                    "description": "OriginalURL is the new URL the shortened URL redirects to.\n@Example \"https://example.com/new-destination\"",
+                    "type": "string",
+                    "example": "https://example.com/new-destination"
+                },
+                "password": {
+                    "description": "Password is the new password required to follow the shortened URL.\nAn empty string removes the password.\n@Example \"s3cret\"",
+                    "type": "string",
+                    "example": "s3cret"
+                },
+                "ttl": {
+                    "description": "TTL is the new lifetime of the shortened URL in seconds counted from now.\nZero removes the expiration. Can't be combined with ExpiresAt.\n@Example 86400",
+                    "type": "integer",
+                    "example": 86400
                 }
             }
         },
@@ -436,21 +952,169 @@ const docTemplate = `{
                 }
             }
         },
+        "response.Error": {
+            "description": "Response structure for a request that failed",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Error is the human readable description of what went wrong.\n@Example \"alias is already taken\"",
+                    "type": "string",
+                    "example": "alias is already taken"
+                }
+            }
+        },
         "response.GetUserURL": {
             "description": "Response structure for a user's URL entry",
             "type": "object",
             "properties": {
+                "clicks_left": {
+                    "description": "ClicksLeft is the number of redirects the shortened URL still allows.\nOmitted for URLs without click limit.\n@Example 1",
+                    "type": "integer",
+                    "example": 1
+                },
+                "deleted_at": {
+                    "description": "DeletedAt is the moment the shortened URL was deleted.\nOmitted for URLs that are not deleted.\n@Example \"2025-10-01T12:00:00Z\"",
+                    "type": "string",
+                    "example": "2025-10-01T12:00:00Z"
+                },
+                "expired": {
+                    "description": "Expired is true if the shortened URL has already expired.\n@Example true",
+                    "type": "boolean",
+                    "example": true
+                },
+                "expires_at": {
+                    "description": "ExpiresAt is the moment after which the shortened URL stops working.\nOmitted for URLs without expiration.\n@Example \"2026-01-01T00:00:00Z\"",
+                    "type": "string",
+                    "example": "2026-01-01T00:00:00Z"
+                },
                 "original_url": {
                     "description": "OriginalURL is the original URL that was shortened.\nContains the full original URL that was provided during creation.\n@Example \"https://example.com/very-long-url-path\"",
                     "type": "string",
                     "example": "https://example.com/very-long-url-path"
                 },
+                "protected": {
+                    "description": "Protected is true if following the shortened URL requires a password.\n@Example true",
+                    "type": "boolean",
+                    "example": true
+                },
                 "short_url": {
                     "description": "ShortURL is the shortened URL created by the user.\nContains the full shortened URL including the base URL.\n@Example \"https://shortener.example.com/abc123\"",
                     "type": "string",
                     "example": "https://shortener.example.com/abc123"
                 }
             }
+        },
+        "response.Health": {
+            "description": "Response structure for the service health check",
+            "type": "object",
+            "properties": {
+                "components": {
+                    "description": "Components is the status of every component the service depends on.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/response.HealthComponent"
+                    }
+                },
+                "status": {
+                    "description": "Status is ok if every component is up, degraded if some components are down\nbut the service still works, and unavailable if the service doesn't work.\n@Example \"ok\"",
+                    "type": "string",
+                    "enum": [
+                        "ok",
+                        "degraded",
+                        "unavailable"
+                    ],
+                    "example": "ok"
+                }
+            }
+        },
+        "response.HealthComponent": {
+            "description": "Response structure for the status of a service component",
+            "type": "object",
+            "properties": {
+                "name": {
+                    "description": "Name identifies the component, e.g. a database connection pool.\n@Example \"primary\"",
+                    "type": "string",
+                    "example": "primary"
+                },
+                "status": {
+                    "description": "Status is up if the component is available and down otherwise.\n@Example \"up\"",
+                    "type": "string",
+                    "enum": [
+                        "up",
+                        "down"
+                    ],
+                    "example": "up"
+                }
+            }
+        },
+        "response.StatsBucket": {
+            "description": "Response structure for a time bucket of click statistics",
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "description": "Clicks is the number of clicks in the bucket.\n@Example 5",
+                    "type": "integer",
+                    "example": 5
+                },
+                "start": {
+                    "description": "Start is the beginning of the bucket in UTC.\n@Example \"2025-10-01T00:00:00Z\"",
+                    "type": "string",
+                    "example": "2025-10-01T00:00:00Z"
+                }
+            }
+        },
+        "response.StatsCount": {
+            "description": "Response structure for a group of clicks in click statistics",
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "description": "Clicks is the number of clicks with the value.\n@Example 5",
+                    "type": "integer",
+                    "example": 5
+                },
+                "value": {
+                    "description": "Value is the value shared by the clicks, e.g. referrer host or client family.\n@Example \"google.com\"",
+                    "type": "string",
+                    "example": "google.com"
+                }
+            }
+        },
+        "response.URLStats": {
+            "description": "Response structure for click statistics of a shortened URL",
+            "type": "object",
+            "properties": {
+                "series": {
+                    "description": "Series is the number of clicks in consecutive time buckets\nfrom the first click to the last one.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/response.StatsBucket"
+                    }
+                },
+                "top_referrers": {
+                    "description": "TopReferrers are the referrer hosts that brought the most clicks.\nClicks without referrer are counted as \"direct\".",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/response.StatsCount"
+                    }
+                },
+                "total_clicks": {
+                    "description": "TotalClicks is the number of times the shortened URL was followed.\n@Example 42",
+                    "type": "integer",
+                    "example": 42
+                },
+                "unique_visitors": {
+                    "description": "UniqueVisitors is the number of distinct visitors that followed the shortened URL.\nVisitors are told apart by their anonymized address and user agent.\n@Example 17",
+                    "type": "integer",
+                    "example": 17
+                },
+                "user_agents": {
+                    "description": "UserAgents is the number of clicks per client family.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/response.StatsCount"
+                    }
+                }
+            }
         }
     },
     "securityDefinitions": {